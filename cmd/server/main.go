@@ -13,7 +13,6 @@ import (
 	"path/filepath"
 	"strconv"
 
-	mcpserver "github.com/mark3labs/mcp-go/server"
 	"github.com/tejzpr/medha-mcp/internal/auth"
 	"github.com/tejzpr/medha-mcp/internal/config"
 	"github.com/tejzpr/medha-mcp/internal/crypto"
@@ -21,7 +20,9 @@ import (
 	"github.com/tejzpr/medha-mcp/internal/git"
 	"github.com/tejzpr/medha-mcp/internal/rebuild"
 	"github.com/tejzpr/medha-mcp/internal/server"
+	"github.com/tejzpr/medha-mcp/internal/tools"
 	"github.com/tejzpr/medha-mcp/pkg/scheduler"
+	"gorm.io/gorm"
 	"gorm.io/gorm/logger"
 )
 
@@ -38,6 +39,8 @@ func main() {
 	rebuildDB := flag.Bool("rebuilddb", false, "Rebuild system database index from git repository")
 	rebuildUserDB := flag.String("rebuild-userdb", "", "Rebuild per-user database (requires 'all' or username/path)")
 	forceRebuild := flag.Bool("force", false, "Force rebuild (requires --rebuilddb or --rebuild-userdb)")
+	incrementalRebuild := flag.Bool("incremental", false, "Reindex only files changed since the last indexed commit (requires --rebuild-userdb)")
+	reindexEmbeddings := flag.String("reindex-embeddings", "", "Embed missing or stale memories (requires 'all' or username/path)")
 	dbType := flag.String("db-type", "", "Database type (sqlite or postgres)")
 	dbPath := flag.String("db-path", "", "Database path (for sqlite)")
 	dbDSN := flag.String("db-dsn", "", "Database DSN (for postgres)")
@@ -46,6 +49,7 @@ func main() {
 	
 	// Embedding flags
 	enableEmbeddings := flag.Bool("enable-embeddings", false, "Enable semantic search with embeddings")
+	embeddingProvider := flag.String("embedding-provider", "", "Embedding provider (openai, azure, ollama or local)")
 	embeddingURL := flag.String("embedding-url", "", "Embedding API base URL")
 	embeddingModel := flag.String("embedding-model", "", "Embedding model name")
 	embeddingKey := flag.String("embedding-key", "", "Embedding API key (alternative to env var)")
@@ -56,15 +60,19 @@ func main() {
 		fmt.Fprintf(os.Stderr, "Server Mode:\n")
 		fmt.Fprintf(os.Stderr, "  %s                          Start MCP server (stdio) using system user (whoami)\n", os.Args[0])
 		fmt.Fprintf(os.Stderr, "  %s --with-accessinguser     Start MCP server (stdio) using ACCESSING_USER env var\n", os.Args[0])
-		fmt.Fprintf(os.Stderr, "  %s --http                   Start HTTP server with SAML authentication\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s --http                   Start HTTP server (local, SAML or OIDC authentication)\n", os.Args[0])
 		fmt.Fprintf(os.Stderr, "\nDatabase Rebuild:\n")
 		fmt.Fprintf(os.Stderr, "  %s --rebuilddb                          Rebuild system database index\n", os.Args[0])
 		fmt.Fprintf(os.Stderr, "  %s --rebuilddb --force                  Rebuild and overwrite existing data\n", os.Args[0])
 		fmt.Fprintf(os.Stderr, "  %s --rebuild-userdb all                 Rebuild all users' per-user databases\n", os.Args[0])
 		fmt.Fprintf(os.Stderr, "  %s --rebuild-userdb <username>          Rebuild specific user's per-user database\n", os.Args[0])
 		fmt.Fprintf(os.Stderr, "  %s --rebuild-userdb <path> --force      Rebuild per-user database at path (force)\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s --rebuild-userdb all --incremental   Reindex files changed since each database's last rebuild\n", os.Args[0])
 		fmt.Fprintf(os.Stderr, "\nEmbeddings:\n")
 		fmt.Fprintf(os.Stderr, "  %s --enable-embeddings   Enable semantic search with embeddings\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s --enable-embeddings --embedding-provider ollama   Embed offline with a local Ollama server\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s --reindex-embeddings all             Embed missing or stale memories for all users\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s --reindex-embeddings <username>      Embed missing or stale memories for one user\n", os.Args[0])
 		fmt.Fprintf(os.Stderr, "\nOptions:\n")
 		flag.PrintDefaults()
 		fmt.Fprintf(os.Stderr, "\nEnvironment Variables:\n")
@@ -74,7 +82,7 @@ func main() {
 		fmt.Fprintf(os.Stderr, "  PORT               Server port (HTTP mode only)\n")
 		fmt.Fprintf(os.Stderr, "  ENCRYPTION_KEY     Encryption key for PAT tokens\n")
 		fmt.Fprintf(os.Stderr, "  ACCESSING_USER     Username (required with --with-accessinguser)\n")
-		fmt.Fprintf(os.Stderr, "  OPENAI_API_KEY     OpenAI API key (required when embeddings enabled)\n")
+		fmt.Fprintf(os.Stderr, "  OPENAI_API_KEY     OpenAI API key (required for the openai embedding provider)\n")
 	}
 
 	flag.Parse()
@@ -83,6 +91,9 @@ func main() {
 	if *forceRebuild && !*rebuildDB && *rebuildUserDB == "" {
 		log.Fatal("ERROR: --force can only be used with --rebuilddb or --rebuild-userdb")
 	}
+	if *incrementalRebuild && (*rebuildUserDB == "" || *forceRebuild) {
+		log.Fatal("ERROR: --incremental can only be used with --rebuild-userdb and without --force")
+	}
 	if *rebuildDB && *httpMode {
 		log.Fatal("ERROR: --rebuilddb and --http cannot be used together")
 	}
@@ -92,6 +103,9 @@ func main() {
 	if *rebuildDB && *rebuildUserDB != "" {
 		log.Fatal("ERROR: --rebuilddb and --rebuild-userdb cannot be used together")
 	}
+	if *reindexEmbeddings != "" && (*httpMode || *rebuildDB || *rebuildUserDB != "") {
+		log.Fatal("ERROR: --reindex-embeddings cannot be combined with --http, --rebuilddb or --rebuild-userdb")
+	}
 	if *withAccessingUser && *httpMode {
 		log.Fatal("ERROR: --with-accessinguser can only be used with stdio mode (not --http)")
 	}
@@ -100,6 +114,8 @@ func main() {
 		log.Println("Starting Medha system database rebuild...")
 	} else if *rebuildUserDB != "" {
 		log.Println("Starting Medha per-user database rebuild...")
+	} else if *reindexEmbeddings != "" {
+		log.Println("Starting Medha embedding reindex...")
 	} else {
 		log.Println("Starting Medha MCP Server...")
 	}
@@ -135,13 +151,10 @@ func main() {
 	applyCLIOverrides(cfg, *dbType, *dbPath, *dbDSN, *port)
 
 	// Apply embedding CLI overrides
-	applyEmbeddingCLIOverrides(cfg, *enableEmbeddings, *embeddingURL, *embeddingModel, *embeddingKey)
-
-	// Force local auth (always use local authentication)
-	cfg.Auth.Type = "local"
+	applyEmbeddingCLIOverrides(cfg, *enableEmbeddings, *embeddingProvider, *embeddingURL, *embeddingModel, *embeddingKey)
 
 	// Log final configuration
-	log.Printf("Configuration: database=%s", cfg.Database.Type)
+	log.Printf("Configuration: database=%s, auth=%s", cfg.Database.Type, cfg.Auth.Type)
 
 	// Create database manager (handles system DB connection and migrations)
 	dbCfg := &database.Config{
@@ -176,13 +189,19 @@ func main() {
 
 	// REBUILD MODE: Run rebuild and exit
 	if *rebuildDB {
-		runRebuildMode(cfg, dbMgr, *forceRebuild)
+		runRebuildMode(cfg, dbMgr, encryptionKey, *forceRebuild)
 		return
 	}
 
 	// REBUILD USER DB MODE: Run per-user database rebuild and exit
 	if *rebuildUserDB != "" {
-		runRebuildUserDBMode(cfg, dbMgr, *rebuildUserDB, *forceRebuild)
+		runRebuildUserDBMode(cfg, dbMgr, *rebuildUserDB, *forceRebuild, *incrementalRebuild)
+		return
+	}
+
+	// REINDEX EMBEDDINGS MODE: Backfill semantic search vectors and exit
+	if *reindexEmbeddings != "" {
+		runReindexEmbeddingsMode(cfg, dbMgr, encryptionKey, *reindexEmbeddings)
 		return
 	}
 
@@ -201,11 +220,11 @@ func main() {
 }
 
 // runRebuildMode authenticates user, finds repo, and runs database rebuild
-func runRebuildMode(cfg *config.Config, dbMgr *database.Manager, force bool) {
+func runRebuildMode(cfg *config.Config, dbMgr *database.Manager, encryptionKey []byte, force bool) {
 	db := dbMgr.SystemDB()
 
 	// Initialize local auth
-	tokenManager := auth.NewTokenManager(db, cfg.Security.TokenTTL)
+	tokenManager := auth.NewTokenManager(db, cfg.Security.TokenTTL, encryptionKey)
 	localAuth := auth.NewLocalAuthenticator(tokenManager)
 
 	// Get or create system user
@@ -276,9 +295,15 @@ func runRebuildMode(cfg *config.Config, dbMgr *database.Manager, force bool) {
 
 // runRebuildUserDBMode rebuilds the per-user database for specified target
 // target can be: "all" (all users), username, or filesystem path
-func runRebuildUserDBMode(cfg *config.Config, dbMgr *database.Manager, target string, force bool) {
+// incremental reindexes only files changed since the last indexed commit
+func runRebuildUserDBMode(cfg *config.Config, dbMgr *database.Manager, target string, force, incremental bool) {
 	db := dbMgr.SystemDB()
-	opts := rebuild.Options{Force: force}
+	rebuildUserIndex := func(userDB *gorm.DB, repoPath string) (*rebuild.Result, error) {
+		if incremental {
+			return rebuild.IncrementalRebuildUserIndex(userDB, repoPath)
+		}
+		return rebuild.RebuildUserIndex(userDB, repoPath, rebuild.Options{Force: force})
+	}
 
 	if target == "all" {
 		// Rebuild all users' per-user databases
@@ -310,7 +335,7 @@ func runRebuildUserDBMode(cfg *config.Config, dbMgr *database.Manager, target st
 				continue
 			}
 
-			result, err := rebuild.RebuildUserIndex(userDB, repo.RepoPath, opts)
+			result, err := rebuildUserIndex(userDB, repo.RepoPath)
 
 			// Close the database
 			sqlDB, _ := userDB.DB()
@@ -322,9 +347,9 @@ func runRebuildUserDBMode(cfg *config.Config, dbMgr *database.Manager, target st
 				continue
 			}
 
-			log.Printf("  ✓ Processed: %d, Created: %d, Skipped: %d, Associations: %d",
-				result.MemoriesProcessed, result.MemoriesCreated,
-				result.MemoriesSkipped, result.AssociationsCreated)
+			log.Printf("  ✓ Processed: %d, Created: %d, Updated: %d, Removed: %d, Skipped: %d, Associations: %d",
+				result.MemoriesProcessed, result.MemoriesCreated, result.MemoriesUpdated,
+				result.MemoriesRemoved, result.MemoriesSkipped, result.AssociationsCreated)
 			successCount++
 		}
 
@@ -333,32 +358,9 @@ func runRebuildUserDBMode(cfg *config.Config, dbMgr *database.Manager, target st
 	}
 
 	// Single target: could be username or path
-	var repoPath string
-
-	// Check if target is a path (absolute or relative)
-	if filepath.IsAbs(target) || target == "." || target == ".." || 
-		(len(target) > 0 && (target[0] == '.' || target[0] == '/')) {
-		// Treat as path
-		absPath, err := filepath.Abs(target)
-		if err != nil {
-			log.Fatalf("Invalid path: %v", err)
-		}
-		repoPath = absPath
-	} else {
-		// Treat as username - lookup in database
-		var repo database.MedhaGitRepo
-		err := db.Joins("JOIN medha_users ON medha_users.id = medha_git_repos.user_id").
-			Where("medha_users.username = ?", target).
-			First(&repo).Error
-
-		if err != nil {
-			// Also try partial match on repo path
-			err = db.Where("repo_path LIKE ?", "%"+target+"%").First(&repo).Error
-			if err != nil {
-				log.Fatalf("No repository found for user or path: %s", target)
-			}
-		}
-		repoPath = repo.RepoPath
+	repoPath, err := resolveRepoPath(db, target)
+	if err != nil {
+		log.Fatal(err)
 	}
 
 	// Verify path exists
@@ -379,7 +381,7 @@ func runRebuildUserDBMode(cfg *config.Config, dbMgr *database.Manager, target st
 	}()
 
 	// Run rebuild
-	result, err := rebuild.RebuildUserIndex(userDB, repoPath, opts)
+	result, err := rebuildUserIndex(userDB, repoPath)
 	if err != nil {
 		log.Fatalf("Rebuild failed: %v", err)
 	}
@@ -388,6 +390,8 @@ func runRebuildUserDBMode(cfg *config.Config, dbMgr *database.Manager, target st
 	log.Println("Per-user database rebuild completed successfully")
 	log.Printf("  Memories processed: %d", result.MemoriesProcessed)
 	log.Printf("  Memories created:   %d", result.MemoriesCreated)
+	log.Printf("  Memories updated:   %d", result.MemoriesUpdated)
+	log.Printf("  Memories removed:   %d", result.MemoriesRemoved)
 	log.Printf("  Memories skipped:   %d", result.MemoriesSkipped)
 	log.Printf("  Associations:       %d", result.AssociationsCreated)
 
@@ -399,6 +403,117 @@ func runRebuildUserDBMode(cfg *config.Config, dbMgr *database.Manager, target st
 	}
 }
 
+// resolveRepoPath maps a CLI target (username or repository path) to a repository path
+func resolveRepoPath(db *gorm.DB, target string) (string, error) {
+	// Check if target is a path (absolute or relative)
+	if filepath.IsAbs(target) || target == "." || target == ".." || 
+		(len(target) > 0 && (target[0] == '.' || target[0] == '/')) {
+		// Treat as path
+		absPath, err := filepath.Abs(target)
+		if err != nil {
+			return "", fmt.Errorf("invalid path: %w", err)
+		}
+		return absPath, nil
+	}
+
+	// Treat as username - lookup in database
+	var repo database.MedhaGitRepo
+	err := db.Joins("JOIN medha_users ON medha_users.id = medha_git_repos.user_id").
+		Where("medha_users.username = ?", target).
+		First(&repo).Error
+
+	if err != nil {
+		// Also try partial match on repo path
+		err = db.Where("repo_path LIKE ?", "%"+target+"%").First(&repo).Error
+		if err != nil {
+			return "", fmt.Errorf("no repository found for user or path: %s", target)
+		}
+	}
+	return repo.RepoPath, nil
+}
+
+// runReindexEmbeddingsMode embeds missing or stale memories for one or all users.
+// Vectors are stored after every batch, so rerunning after a failure resumes
+// where the previous run stopped.
+func runReindexEmbeddingsMode(cfg *config.Config, dbMgr *database.Manager, encryptionKey []byte, target string) {
+	if !cfg.Embeddings.Enabled {
+		log.Fatal("Embeddings are disabled; enable them in config or with --enable-embeddings")
+	}
+
+	mcpServer, err := server.NewMCPServer(cfg, dbMgr, encryptionKey)
+	if err != nil {
+		log.Fatalf("Failed to create MCP server: %v", err)
+	}
+	factory := mcpServer.GetEmbeddingFactory()
+	if factory == nil {
+		log.Fatal("Embedding provider is unavailable (see warning above)")
+	}
+
+	var repoPaths []string
+	if target == "all" {
+		var repos []database.MedhaGitRepo
+		if err := dbMgr.SystemDB().Find(&repos).Error; err != nil {
+			log.Fatalf("Failed to query repositories: %v", err)
+		}
+		if len(repos) == 0 {
+			log.Fatal("No repositories found in system database")
+		}
+		for _, repo := range repos {
+			repoPaths = append(repoPaths, repo.RepoPath)
+		}
+	} else {
+		repoPath, err := resolveRepoPath(dbMgr.SystemDB(), target)
+		if err != nil {
+			log.Fatal(err)
+		}
+		repoPaths = []string{repoPath}
+	}
+
+	log.Printf("Reindexing embeddings with %s (%d dimensions, batch size %d)",
+		factory.ModelName(), factory.Dimensions(), cfg.Embeddings.BatchSize)
+
+	var successCount, failCount int
+	for _, repoPath := range repoPaths {
+		log.Printf("Reindexing embeddings for: %s", repoPath)
+
+		if _, err := os.Stat(repoPath); os.IsNotExist(err) {
+			log.Printf("  WARNING: Repository path does not exist, skipping: %s", repoPath)
+			failCount++
+			continue
+		}
+
+		toolCtx, err := tools.NewToolContextWithManager(dbMgr, repoPath)
+		if err == nil {
+			err = toolCtx.EnableEmbeddings(factory)
+		}
+		if err != nil {
+			log.Printf("  ERROR: Failed to open per-user database: %v", err)
+			failCount++
+			continue
+		}
+
+		result, err := toolCtx.ReindexEmbeddings(cfg.Embeddings.BatchSize, func(done, total int) {
+			log.Printf("  %d/%d embedded", done, total)
+		})
+		if err != nil {
+			log.Printf("  ERROR: %v", err)
+			if result != nil {
+				log.Printf("  %d embedded before the failure; rerun to resume", result.Embedded)
+			}
+			failCount++
+			continue
+		}
+
+		log.Printf("  ✓ Memories: %d, Embedded: %d, Already fresh: %d", result.Total, result.Embedded, result.Fresh)
+		successCount++
+	}
+
+	log.Printf("\nReindex completed: %d succeeded, %d failed", successCount, failCount)
+	if failCount > 0 {
+		os.Exit(1)
+	}
+}
+
 // getOrGenerateEncryptionKey gets encryption key from config or generates a new one
 func getOrGenerateEncryptionKey(cfg *config.Config) []byte {
 	if cfg.Security.EncryptionKey != "" {
@@ -492,12 +607,17 @@ func getEnv(names ...string) string {
 }
 
 // applyEmbeddingCLIOverrides applies embedding-related CLI flag overrides
-func applyEmbeddingCLIOverrides(cfg *config.Config, enableEmbeddings bool, embeddingURL, embeddingModel, embeddingKey string) {
+func applyEmbeddingCLIOverrides(cfg *config.Config, enableEmbeddings bool, embeddingProvider, embeddingURL, embeddingModel, embeddingKey string) {
 	if enableEmbeddings {
 		cfg.Embeddings.Enabled = true
 		log.Printf("Embeddings enabled from CLI")
 	}
 
+	if embeddingProvider != "" {
+		cfg.Embeddings.Provider = embeddingProvider
+		log.Printf("Embedding provider from CLI: %s", embeddingProvider)
+	}
+
 	if embeddingURL != "" {
 		cfg.Embeddings.BaseURL = embeddingURL
 		log.Printf("Embedding URL from CLI")
@@ -521,7 +641,7 @@ func runStdioMode(cfg *config.Config, dbMgr *database.Manager, encryptionKey []b
 	db := dbMgr.SystemDB()
 
 	// Initialize local auth
-	tokenManager := auth.NewTokenManager(db, cfg.Security.TokenTTL)
+	tokenManager := auth.NewTokenManager(db, cfg.Security.TokenTTL, encryptionKey)
 	var localAuth *auth.LocalAuthenticator
 	if useAccessingUser {
 		localAuth = auth.NewLocalAuthenticatorWithAccessingUser(tokenManager)
@@ -604,6 +724,7 @@ func runStdioMode(cfg *config.Config, dbMgr *database.Manager, encryptionKey []b
 	if err != nil {
 		log.Fatalf("Failed to create MCP server: %v", err)
 	}
+	defer mcpServer.Close()
 
 	// Register all tools for this user
 	err = mcpServer.RegisterToolsForUser(user.ID, repo.RepoPath)
@@ -611,14 +732,23 @@ func runStdioMode(cfg *config.Config, dbMgr *database.Manager, encryptionKey []b
 		log.Fatalf("Failed to register tools: %v", err)
 	}
 
-	log.Println("MCP server ready (stdio mode) - 7 tools registered")
+	log.Println("MCP server ready (stdio mode) - 9 tools, 4 prompts and memory resources registered")
 	if mcpServer.HasEmbeddings() {
 		log.Println("Semantic search enabled")
 	}
 
+	// Pick up commits made outside Medha, such as a manual git pull
+	watcher := rebuild.NewWatcher(repo.RepoPath, func() (*gorm.DB, error) {
+		return dbMgr.GetUserDB(repo.RepoPath)
+	}, rebuild.DefaultWatchDelay, nil)
+	if err := watcher.Start(); err != nil {
+		log.Printf("Warning: failed to watch repository for outside commits: %v", err)
+	} else {
+		defer watcher.Stop()
+	}
+
 	// Serve via stdio
-	mcpGoServer := mcpServer.GetMCPServer()
-	if err := mcpserver.ServeStdio(mcpGoServer); err != nil {
+	if err := mcpServer.ServeStdio(); err != nil {
 		log.Fatalf("MCP server error: %v", err)
 	}
 }
@@ -627,32 +757,72 @@ func runStdioMode(cfg *config.Config, dbMgr *database.Manager, encryptionKey []b
 func runHTTPMode(cfg *config.Config, dbMgr *database.Manager, encryptionKey []byte) {
 	db := dbMgr.SystemDB()
 
-	// Initialize local auth
-	tokenManager := auth.NewTokenManager(db, cfg.Security.TokenTTL)
-	localAuth := auth.NewLocalAuthenticator(tokenManager)
-	username, _ := localAuth.GetLocalUsername()
-	log.Printf("Local authentication initialized (system user: %s)", username)
+	// Initialize auth
+	tokenManager := auth.NewTokenManager(db, cfg.Security.TokenTTL, encryptionKey)
+	var samlAuth *auth.SAMLAuthenticator
+	var oidcAuth *auth.OIDCAuthenticator
+	var localAuth *auth.LocalAuthenticator
+	switch cfg.Auth.Type {
+	case "saml":
+		var err error
+		samlAuth, err = auth.NewSAMLAuthenticator(&auth.SAMLConfig{
+			EntityID:          cfg.SAML.EntityID,
+			ACSURL:            cfg.SAML.ACSURL,
+			MetadataURL:       cfg.SAML.MetadataURL,
+			IDPMetadata:       cfg.SAML.IDPMetadata,
+			Certificate:       cfg.SAML.Certificate,
+			PrivateKey:        cfg.SAML.PrivateKey,
+			Provider:          cfg.SAML.Provider,
+			UsernameAttribute: cfg.SAML.UsernameAttribute,
+			EmailAttribute:    cfg.SAML.EmailAttribute,
+		}, tokenManager)
+		if err != nil {
+			log.Fatalf("Failed to initialize SAML authentication: %v", err)
+		}
+		log.Printf("SAML authentication initialized (entity ID: %s)", cfg.SAML.EntityID)
+	case "oidc":
+		var err error
+		oidcAuth, err = auth.NewOIDCAuthenticator(&auth.OIDCConfig{
+			Issuer:        cfg.OIDC.Issuer,
+			ClientID:      cfg.OIDC.ClientID,
+			ClientSecret:  cfg.OIDC.ClientSecret,
+			RedirectURL:   cfg.OIDC.RedirectURL,
+			Scopes:        cfg.OIDC.Scopes,
+			UsernameClaim: cfg.OIDC.UsernameClaim,
+			EmailClaim:    cfg.OIDC.EmailClaim,
+		}, tokenManager)
+		if err != nil {
+			log.Fatalf("Failed to initialize OIDC authentication: %v", err)
+		}
+		log.Printf("OIDC authentication initialized (issuer: %s)", cfg.OIDC.Issuer)
+	default:
+		localAuth = auth.NewLocalAuthenticator(tokenManager)
+		username, _ := localAuth.GetLocalUsername()
+		log.Printf("Local authentication initialized (system user: %s)", username)
+	}
 
 	// Create MCP server with database manager
 	mcpServer, err := server.NewMCPServer(cfg, dbMgr, encryptionKey)
 	if err != nil {
 		log.Fatalf("Failed to create MCP server: %v", err)
 	}
+	defer mcpServer.Close()
 
 	log.Println("MCP server initialized")
 	if mcpServer.HasEmbeddings() {
 		log.Println("Semantic search enabled")
 	}
 
-	// Create HTTP server (simplified, local-only)
-	httpServer := server.NewHTTPServer(mcpServer, nil, localAuth, "local", encryptionKey)
+	// Create HTTP server
+	httpServer := server.NewHTTPServer(mcpServer, samlAuth, oidcAuth, localAuth, cfg.Auth.Type, encryptionKey)
+	defer httpServer.Close()
 
 	// Register routes
 	mux := http.NewServeMux()
 	httpServer.RegisterRoutes(mux)
 
 	// Start background scheduler
-	sched := scheduler.NewScheduler(db, cfg.Git.SyncInterval, encryptionKey)
+	sched := scheduler.NewScheduler(dbMgr, cfg.Git.SyncInterval, encryptionKey)
 	sched.Start()
 	defer sched.Stop()
 
@@ -660,7 +830,7 @@ func runHTTPMode(cfg *config.Config, dbMgr *database.Manager, encryptionKey []by
 
 	// Start HTTP server
 	addr := fmt.Sprintf("%s:%d", cfg.Server.Host, cfg.Server.Port)
-	log.Printf("HTTP server starting on %s", addr)
+	log.Printf("HTTP server starting on %s (MCP endpoint: /mcp)", addr)
 
 	if cfg.Server.TLS.Enabled {
 		log.Println("TLS enabled")
@@ -0,0 +1,183 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package auth
+
+import (
+	"crypto/hmac"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/tejzpr/medha-mcp/internal/database"
+)
+
+// apiTokenMarker starts every API token, telling them apart from login tokens
+const apiTokenMarker = "medha_"
+
+// maxAPITokenNameLength bounds the name of an API token
+const maxAPITokenNameLength = 100
+
+// lastUsedResolution is how stale an API token's last-used time may get
+// before a request updates it, so busy tokens don't write on every call
+const lastUsedResolution = time.Minute
+
+// IsAPIToken reports whether a token is an API token rather than a login token
+func IsAPIToken(token string) bool {
+	return strings.HasPrefix(token, apiTokenMarker)
+}
+
+// CreateAPIToken creates a named API token for a user with the given scopes
+// and tool allow-list. A nil expiresAt creates a token that never expires.
+// The returned token is the only copy of its plaintext value.
+func (tm *TokenManager) CreateAPIToken(userID uint, name string, scopes, tools []string, expiresAt *time.Time) (*database.MedhaAPIToken, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, fmt.Errorf("API token name is required")
+	}
+	if len(name) > maxAPITokenNameLength {
+		return nil, fmt.Errorf("API token name must be at most %d characters", maxAPITokenNameLength)
+	}
+	for _, scope := range scopes {
+		if !slices.Contains(AllScopes, scope) {
+			return nil, fmt.Errorf("unknown scope %q (valid scopes: %s)", scope, strings.Join(AllScopes, ", "))
+		}
+	}
+	if len(scopes) == 0 && len(tools) == 0 {
+		return nil, fmt.Errorf("API token needs at least one scope or tool")
+	}
+	if expiresAt != nil && !expiresAt.After(time.Now()) {
+		return nil, fmt.Errorf("API token expiry must be in the future")
+	}
+
+	var existing int64
+	if err := tm.db.Model(&database.MedhaAPIToken{}).Where("user_id = ? AND name = ?", userID, name).Count(&existing).Error; err != nil {
+		return nil, fmt.Errorf("failed to check API token name: %w", err)
+	}
+	if existing > 0 {
+		return nil, fmt.Errorf("an API token named %q already exists", name)
+	}
+
+	secret, err := generateRandomToken(32)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate API token: %w", err)
+	}
+	plaintext := apiTokenMarker + secret
+
+	token := &database.MedhaAPIToken{
+		UserID:      userID,
+		Name:        name,
+		TokenPrefix: apiTokenPrefix(plaintext),
+		TokenHash:   tm.hashToken(plaintext),
+		Token:       plaintext,
+		Scopes:      strings.Join(slices.Compact(slices.Sorted(slices.Values(scopes))), ","),
+		Tools:       strings.Join(slices.Compact(slices.Sorted(slices.Values(tools))), ","),
+		ExpiresAt:   expiresAt,
+	}
+	if err := tm.db.Create(token).Error; err != nil {
+		return nil, fmt.Errorf("failed to store API token: %w", err)
+	}
+
+	return token, nil
+}
+
+// ListAPITokens returns a user's API tokens, oldest first
+func (tm *TokenManager) ListAPITokens(userID uint) ([]database.MedhaAPIToken, error) {
+	var tokens []database.MedhaAPIToken
+	if err := tm.db.Where("user_id = ?", userID).Order("created_at, id").Find(&tokens).Error; err != nil {
+		return nil, fmt.Errorf("failed to list API tokens: %w", err)
+	}
+	return tokens, nil
+}
+
+// RevokeAPIToken deletes one of a user's API tokens
+func (tm *TokenManager) RevokeAPIToken(userID, tokenID uint) error {
+	result := tm.db.Where("id = ? AND user_id = ?", tokenID, userID).Delete(&database.MedhaAPIToken{})
+	if result.Error != nil {
+		return fmt.Errorf("failed to revoke API token: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("API token not found")
+	}
+	return nil
+}
+
+// Authorize validates a login or API token, returning its user and the
+// permissions of an API token. Login tokens return nil permissions, which
+// allow everything.
+func (tm *TokenManager) Authorize(token string) (uint, *Permissions, error) {
+	if !IsAPIToken(token) {
+		authToken, err := tm.ValidateToken(token)
+		if err != nil {
+			return 0, nil, err
+		}
+		return authToken.UserID, nil, nil
+	}
+
+	apiToken, err := tm.ValidateAPIToken(token)
+	if err != nil {
+		return 0, nil, err
+	}
+	return apiToken.UserID, &Permissions{
+		TokenID: apiToken.ID,
+		Scopes:  splitList(apiToken.Scopes),
+		Tools:   splitList(apiToken.Tools),
+	}, nil
+}
+
+// ValidateAPIToken checks an API token is valid and not expired, and
+// records that it was used
+func (tm *TokenManager) ValidateAPIToken(token string) (*database.MedhaAPIToken, error) {
+	if !IsAPIToken(token) {
+		return nil, fmt.Errorf("token not found")
+	}
+
+	var candidates []database.MedhaAPIToken
+	if err := tm.db.Where("token_prefix = ?", apiTokenPrefix(token)).Find(&candidates).Error; err != nil {
+		return nil, fmt.Errorf("failed to query token: %w", err)
+	}
+
+	hash := []byte(tm.hashToken(token))
+	var apiToken *database.MedhaAPIToken
+	for i := range candidates {
+		if hmac.Equal([]byte(candidates[i].TokenHash), hash) {
+			apiToken = &candidates[i]
+			break
+		}
+	}
+	if apiToken == nil {
+		return nil, fmt.Errorf("token not found")
+	}
+
+	now := time.Now()
+	if apiToken.ExpiresAt != nil && now.After(*apiToken.ExpiresAt) {
+		return nil, fmt.Errorf("token expired")
+	}
+
+	if apiToken.LastUsedAt == nil || now.Sub(*apiToken.LastUsedAt) >= lastUsedResolution {
+		// Best effort: a failed update must not fail the request
+		if err := tm.db.Model(apiToken).UpdateColumn("last_used_at", now).Error; err == nil {
+			apiToken.LastUsedAt = &now
+		}
+	}
+
+	return apiToken, nil
+}
+
+// apiTokenPrefix returns the part of an API token stored in the clear
+func apiTokenPrefix(token string) string {
+	return tokenPrefix(strings.TrimPrefix(token, apiTokenMarker))
+}
+
+// splitList splits a comma-separated list, dropping empty entries
+func splitList(list string) []string {
+	var items []string
+	for _, item := range strings.Split(list, ",") {
+		if item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
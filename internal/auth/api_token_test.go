@@ -0,0 +1,177 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package auth
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tejzpr/medha-mcp/internal/database"
+	"gorm.io/gorm"
+)
+
+// setupAPITokenTest returns a token manager and a user to own API tokens
+func setupAPITokenTest(t *testing.T) (*gorm.DB, *TokenManager, uint) {
+	dbCfg := setupTestDB(t)
+	db, err := database.Connect(dbCfg)
+	require.NoError(t, err)
+	t.Cleanup(func() { database.Close(db) })
+
+	user := &database.MedhaUser{Username: "testuser"}
+	require.NoError(t, db.Create(user).Error)
+
+	return db, NewTokenManager(db, 24, testTokenSecret), user.ID
+}
+
+func TestCreateAPIToken(t *testing.T) {
+	db, tm, userID := setupAPITokenTest(t)
+
+	token, err := tm.CreateAPIToken(userID, " ci bot ", []string{ScopeSync, ScopeMemoriesRead}, []string{"medha_lock"}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "ci bot", token.Name)
+	assert.True(t, IsAPIToken(token.Token))
+	assert.Equal(t, "memories:read,sync", token.Scopes)
+	assert.Equal(t, "medha_lock", token.Tools)
+	assert.Nil(t, token.ExpiresAt)
+
+	// Only the hash is stored
+	var stored database.MedhaAPIToken
+	require.NoError(t, db.First(&stored, token.ID).Error)
+	assert.Empty(t, stored.Token)
+	assert.NotContains(t, stored.TokenHash, token.Token)
+
+	userIDFromToken, permissions, err := tm.Authorize(token.Token)
+	require.NoError(t, err)
+	assert.Equal(t, userID, userIDFromToken)
+	assert.Equal(t, token.ID, permissions.TokenID)
+	assert.Equal(t, []string{ScopeMemoriesRead, ScopeSync}, permissions.Scopes)
+	assert.Equal(t, []string{"medha_lock"}, permissions.Tools)
+}
+
+func TestCreateAPIToken_Invalid(t *testing.T) {
+	_, tm, userID := setupAPITokenTest(t)
+
+	_, err := tm.CreateAPIToken(userID, "existing", []string{ScopeMemoriesRead}, nil, nil)
+	require.NoError(t, err)
+
+	past := time.Now().Add(-time.Hour)
+	tests := []struct {
+		name      string
+		tokenName string
+		scopes    []string
+		tools     []string
+		expiresAt *time.Time
+		wantErr   string
+	}{
+		{"missing name", "  ", []string{ScopeMemoriesRead}, nil, nil, "name is required"},
+		{"unknown scope", "bot", []string{"memories:admin"}, nil, nil, "unknown scope"},
+		{"no permissions", "bot", nil, nil, nil, "at least one scope or tool"},
+		{"expired", "bot", []string{ScopeMemoriesRead}, nil, &past, "must be in the future"},
+		{"duplicate name", "existing", []string{ScopeSync}, nil, nil, "already exists"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := tm.CreateAPIToken(userID, tt.tokenName, tt.scopes, tt.tools, tt.expiresAt)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.wantErr)
+		})
+	}
+}
+
+func TestValidateAPIToken_Expired(t *testing.T) {
+	db, tm, userID := setupAPITokenTest(t)
+
+	expiresAt := time.Now().Add(time.Hour)
+	token, err := tm.CreateAPIToken(userID, "short-lived", []string{ScopeMemoriesRead}, nil, &expiresAt)
+	require.NoError(t, err)
+
+	_, err = tm.ValidateAPIToken(token.Token)
+	require.NoError(t, err)
+
+	require.NoError(t, db.Model(token).Update("expires_at", time.Now().Add(-time.Minute)).Error)
+	_, err = tm.ValidateAPIToken(token.Token)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "token expired")
+}
+
+func TestValidateAPIToken_TracksLastUsed(t *testing.T) {
+	db, tm, userID := setupAPITokenTest(t)
+
+	token, err := tm.CreateAPIToken(userID, "ci", []string{ScopeMemoriesRead}, nil, nil)
+	require.NoError(t, err)
+
+	_, err = tm.ValidateAPIToken(token.Token)
+	require.NoError(t, err)
+
+	var stored database.MedhaAPIToken
+	require.NoError(t, db.First(&stored, token.ID).Error)
+	require.NotNil(t, stored.LastUsedAt)
+	firstUse := *stored.LastUsedAt
+
+	// Uses within the resolution don't write
+	_, err = tm.ValidateAPIToken(token.Token)
+	require.NoError(t, err)
+	require.NoError(t, db.First(&stored, token.ID).Error)
+	assert.True(t, firstUse.Equal(*stored.LastUsedAt))
+
+	// Older uses are refreshed
+	require.NoError(t, db.Model(&stored).UpdateColumn("last_used_at", time.Now().Add(-time.Hour)).Error)
+	_, err = tm.ValidateAPIToken(token.Token)
+	require.NoError(t, err)
+	require.NoError(t, db.First(&stored, token.ID).Error)
+	assert.WithinDuration(t, time.Now(), *stored.LastUsedAt, time.Minute)
+}
+
+func TestListAndRevokeAPITokens(t *testing.T) {
+	db, tm, userID := setupAPITokenTest(t)
+
+	other := &database.MedhaUser{Username: "other"}
+	require.NoError(t, db.Create(other).Error)
+
+	first, err := tm.CreateAPIToken(userID, "first", []string{ScopeMemoriesRead}, nil, nil)
+	require.NoError(t, err)
+	_, err = tm.CreateAPIToken(userID, "second", []string{ScopeSync}, nil, nil)
+	require.NoError(t, err)
+	othersToken, err := tm.CreateAPIToken(other.ID, "first", []string{ScopeMemoriesRead}, nil, nil)
+	require.NoError(t, err)
+
+	tokens, err := tm.ListAPITokens(userID)
+	require.NoError(t, err)
+	require.Len(t, tokens, 2)
+	assert.Equal(t, "first", tokens[0].Name)
+	assert.Equal(t, "second", tokens[1].Name)
+
+	// Users can only revoke their own tokens
+	err = tm.RevokeAPIToken(userID, othersToken.ID)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "API token not found")
+
+	require.NoError(t, tm.RevokeAPIToken(userID, first.ID))
+	_, _, err = tm.Authorize(first.Token)
+	assert.Error(t, err)
+
+	tokens, err = tm.ListAPITokens(userID)
+	require.NoError(t, err)
+	assert.Len(t, tokens, 1)
+}
+
+func TestPermissions_AllowsTool(t *testing.T) {
+	var fullAccess *Permissions
+	assert.True(t, fullAccess.AllowsTool("medha_sync", ScopeSync))
+	assert.True(t, fullAccess.HasScope(ScopeMemoriesWrite))
+
+	readOnly := &Permissions{Scopes: []string{ScopeMemoriesRead}}
+	assert.True(t, readOnly.AllowsTool("medha_recall", ScopeMemoriesRead))
+	assert.False(t, readOnly.AllowsTool("medha_forget", ScopeMemoriesWrite))
+	assert.False(t, readOnly.AllowsTool("medha_unknown", ""))
+
+	allowList := &Permissions{Tools: []string{"medha_lock"}}
+	assert.True(t, allowList.AllowsTool("medha_lock", ScopeMemoriesWrite))
+	assert.False(t, allowList.AllowsTool("medha_remember", ScopeMemoriesWrite))
+	assert.False(t, allowList.HasScope(ScopeMemoriesRead))
+}
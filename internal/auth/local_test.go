@@ -15,7 +15,7 @@ import (
 )
 
 func TestGetLocalUsername(t *testing.T) {
-	tm := NewTokenManager(nil, 24)
+	tm := NewTokenManager(nil, 24, testTokenSecret)
 	localAuth := NewLocalAuthenticator(tm)
 
 	username, err := localAuth.GetLocalUsername()
@@ -43,7 +43,7 @@ func TestLocalAuthenticate(t *testing.T) {
 	require.NoError(t, err)
 
 	// Create token manager and local auth
-	tm := NewTokenManager(db, 24)
+	tm := NewTokenManager(db, 24, testTokenSecret)
 	localAuth := NewLocalAuthenticator(tm)
 
 	// Authenticate
@@ -77,7 +77,7 @@ func TestLocalAuthenticate_ExistingUser(t *testing.T) {
 	err = database.Migrate(db)
 	require.NoError(t, err)
 
-	tm := NewTokenManager(db, 24)
+	tm := NewTokenManager(db, 24, testTokenSecret)
 	localAuth := NewLocalAuthenticator(tm)
 
 	// First authentication
@@ -42,31 +42,50 @@ func (m *Middleware) RequireAuth(next http.Handler) http.Handler {
 			return
 		}
 
-		// Validate token
-		authToken, err := m.tokenManager.ValidateToken(token)
+		// Validate token; API tokens carry scoped permissions
+		userID, permissions, err := m.tokenManager.Authorize(token)
 		if err != nil {
 			http.Error(w, "Unauthorized: "+err.Error(), http.StatusUnauthorized)
 			return
 		}
 
-		// Add user ID and token to context
-		ctx := context.WithValue(r.Context(), UserIDKey, authToken.UserID)
+		// Add user ID, token and permissions to context
+		ctx := context.WithValue(r.Context(), UserIDKey, userID)
 		ctx = context.WithValue(ctx, TokenKey, token)
+		if permissions != nil {
+			ctx = WithPermissions(ctx, permissions)
+		}
 
 		// Call next handler with updated context
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
 
+// RequireLoginToken is middleware, used after RequireAuth, that rejects
+// requests made with an API token. It guards actions no scope grants, such
+// as managing API tokens.
+func (m *Middleware) RequireLoginToken(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if GetPermissionsFromContext(r.Context()) != nil {
+			http.Error(w, "Forbidden: API tokens cannot be used here", http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
 // OptionalAuth is middleware that extracts auth if present, but doesn't require it
 func (m *Middleware) OptionalAuth(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		token := extractToken(r)
 		if token != "" {
-			authToken, err := m.tokenManager.ValidateToken(token)
+			userID, permissions, err := m.tokenManager.Authorize(token)
 			if err == nil {
-				ctx := context.WithValue(r.Context(), UserIDKey, authToken.UserID)
+				ctx := context.WithValue(r.Context(), UserIDKey, userID)
 				ctx = context.WithValue(ctx, TokenKey, token)
+				if permissions != nil {
+					ctx = WithPermissions(ctx, permissions)
+				}
 				r = r.WithContext(ctx)
 			}
 		}
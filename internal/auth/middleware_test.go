@@ -40,7 +40,7 @@ func setupMiddlewareTest(t *testing.T) (*Middleware, *TokenManager, uint) {
 	}
 	db.Create(user)
 
-	tm := NewTokenManager(db, 24)
+	tm := NewTokenManager(db, 24, testTokenSecret)
 	middleware := NewMiddleware(tm)
 
 	return middleware, tm, user.ID
@@ -130,6 +130,66 @@ func TestRequireAuth_ExpiredToken(t *testing.T) {
 	assert.Equal(t, http.StatusUnauthorized, rec.Code)
 }
 
+func TestRequireAuth_APITokenPermissions(t *testing.T) {
+	middleware, tm, userID := setupMiddlewareTest(t)
+
+	apiToken, err := tm.CreateAPIToken(userID, "ci", []string{ScopeMemoriesRead}, nil, nil)
+	require.NoError(t, err)
+	loginToken, err := tm.GenerateToken(userID)
+	require.NoError(t, err)
+
+	var permissions *Permissions
+	handler := middleware.RequireAuth(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		extractedUserID, ok := GetUserIDFromContext(r.Context())
+		assert.True(t, ok)
+		assert.Equal(t, userID, extractedUserID)
+		permissions = GetPermissionsFromContext(r.Context())
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	req := httptest.NewRequest("GET", "/test", nil)
+	req.Header.Set("Authorization", "Bearer "+apiToken.Token)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	require.NotNil(t, permissions)
+	assert.Equal(t, apiToken.ID, permissions.TokenID)
+	assert.Equal(t, []string{ScopeMemoriesRead}, permissions.Scopes)
+
+	// Login tokens carry no restrictions
+	req = httptest.NewRequest("GET", "/test", nil)
+	req.Header.Set("Authorization", "Bearer "+loginToken.AccessToken)
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Nil(t, permissions)
+}
+
+func TestRequireLoginToken(t *testing.T) {
+	middleware, tm, userID := setupMiddlewareTest(t)
+
+	apiToken, err := tm.CreateAPIToken(userID, "ci", []string{ScopeMemoriesRead, ScopeMemoriesWrite, ScopeSync}, nil, nil)
+	require.NoError(t, err)
+	loginToken, err := tm.GenerateToken(userID)
+	require.NoError(t, err)
+
+	handler := middleware.RequireAuth(middleware.RequireLoginToken(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})))
+
+	req := httptest.NewRequest("GET", "/test", nil)
+	req.Header.Set("Authorization", "Bearer "+apiToken.Token)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+
+	req = httptest.NewRequest("GET", "/test", nil)
+	req.Header.Set("Authorization", "Bearer "+loginToken.AccessToken)
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code)
+}
+
 func TestOptionalAuth_WithToken(t *testing.T) {
 	middleware, tm, userID := setupMiddlewareTest(t)
 
@@ -0,0 +1,498 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package auth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/tejzpr/medha-mcp/internal/database"
+	"gorm.io/gorm"
+)
+
+const (
+	// oidcStateCookie binds a login to the browser that started it
+	oidcStateCookie = "medha_oidc_state"
+	// oidcLoginTTL is how long a user has to finish logging in at the issuer
+	oidcLoginTTL = 10 * time.Minute
+)
+
+// oidcSigningMethods are the ID token algorithms accepted from the issuer
+var oidcSigningMethods = []string{"RS256", "RS384", "RS512", "ES256", "ES384", "ES512"}
+
+// OIDCConfig holds OpenID Connect configuration
+type OIDCConfig struct {
+	Issuer       string
+	ClientID     string
+	ClientSecret string // Empty for public clients
+	RedirectURL  string
+	Scopes       []string // Default: openid, profile, email
+
+	// Claim mapping; empty uses the defaults described on HandleCallback
+	UsernameClaim string
+	EmailClaim    string
+}
+
+// OIDCAuthenticator handles OpenID Connect authentication with the
+// authorization code flow and PKCE
+type OIDCAuthenticator struct {
+	config       *OIDCConfig
+	provider     *oidcProvider
+	tokenManager *TokenManager
+	httpClient   *http.Client
+
+	mu      sync.Mutex
+	keys    map[string]crypto.PublicKey // Issuer signing keys by key ID
+	pending map[string]*oidcLogin       // Logins in progress by state
+}
+
+// OIDCUser represents a user from a validated ID token
+type OIDCUser struct {
+	Subject  string
+	Username string
+	Email    string
+	Claims   map[string]interface{}
+}
+
+// oidcProvider holds the endpoints from the issuer's discovery document
+type oidcProvider struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// oidcLogin is a login waiting for the issuer to redirect back
+type oidcLogin struct {
+	verifier  string
+	nonce     string
+	expiresAt time.Time
+}
+
+// NewOIDCAuthenticator creates a new OIDC authenticator, reading the issuer's
+// discovery document and signing keys
+func NewOIDCAuthenticator(config *OIDCConfig, tm *TokenManager) (*OIDCAuthenticator, error) {
+	o := &OIDCAuthenticator{
+		config:       config,
+		tokenManager: tm,
+		httpClient:   &http.Client{Timeout: 10 * time.Second},
+		keys:         make(map[string]crypto.PublicKey),
+		pending:      make(map[string]*oidcLogin),
+	}
+
+	discoveryURL := strings.TrimSuffix(config.Issuer, "/") + "/.well-known/openid-configuration"
+	var provider oidcProvider
+	if err := o.getJSON(discoveryURL, &provider); err != nil {
+		return nil, fmt.Errorf("failed to read OIDC discovery document: %w", err)
+	}
+	if provider.Issuer != config.Issuer {
+		return nil, fmt.Errorf("issuer mismatch: configured %q, discovery document says %q", config.Issuer, provider.Issuer)
+	}
+	if provider.AuthorizationEndpoint == "" || provider.TokenEndpoint == "" || provider.JWKSURI == "" {
+		return nil, fmt.Errorf("OIDC discovery document is missing endpoints")
+	}
+	o.provider = &provider
+
+	if err := o.refreshKeys(); err != nil {
+		return nil, err
+	}
+	return o, nil
+}
+
+// InitiateLogin redirects the user to the issuer for authentication
+func (o *OIDCAuthenticator) InitiateLogin(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	state, err1 := randomURLSafe()
+	nonce, err2 := randomURLSafe()
+	verifier, err3 := randomURLSafe()
+	if err1 != nil || err2 != nil || err3 != nil {
+		http.Error(w, "Failed to start login", http.StatusInternalServerError)
+		return
+	}
+
+	authURL, err := url.Parse(o.provider.AuthorizationEndpoint)
+	if err != nil {
+		http.Error(w, "Invalid authorization endpoint", http.StatusInternalServerError)
+		return
+	}
+
+	o.mu.Lock()
+	now := time.Now()
+	for key, login := range o.pending {
+		if now.After(login.expiresAt) {
+			delete(o.pending, key)
+		}
+	}
+	o.pending[state] = &oidcLogin{verifier: verifier, nonce: nonce, expiresAt: now.Add(oidcLoginTTL)}
+	o.mu.Unlock()
+
+	http.SetCookie(w, o.stateCookie(state, int(oidcLoginTTL.Seconds())))
+
+	challenge := sha256.Sum256([]byte(verifier))
+	scopes := o.config.Scopes
+	if len(scopes) == 0 {
+		scopes = []string{"openid", "profile", "email"}
+	}
+	query := authURL.Query()
+	query.Set("response_type", "code")
+	query.Set("client_id", o.config.ClientID)
+	query.Set("redirect_uri", o.config.RedirectURL)
+	query.Set("scope", strings.Join(scopes, " "))
+	query.Set("state", state)
+	query.Set("nonce", nonce)
+	query.Set("code_challenge", base64.RawURLEncoding.EncodeToString(challenge[:]))
+	query.Set("code_challenge_method", "S256")
+	authURL.RawQuery = query.Encode()
+
+	http.Redirect(w, r, authURL.String(), http.StatusFound)
+}
+
+// HandleCallback completes a login started by InitiateLogin in the same
+// browser: it redeems the authorization code and validates the ID token.
+//
+// The username comes from the configured username claim or, by default,
+// preferred_username, then a verified email, then sub. The email comes from
+// the configured email claim or, by default, email.
+func (o *OIDCAuthenticator) HandleCallback(w http.ResponseWriter, r *http.Request) (*OIDCUser, error) {
+	query := r.URL.Query()
+	if errCode := query.Get("error"); errCode != "" {
+		return nil, fmt.Errorf("issuer returned %s: %s", errCode, query.Get("error_description"))
+	}
+
+	state := query.Get("state")
+	cookie, err := r.Cookie(oidcStateCookie)
+	if err != nil || state == "" || cookie.Value != state {
+		return nil, fmt.Errorf("login state does not match this browser")
+	}
+	http.SetCookie(w, o.stateCookie("", -1))
+
+	o.mu.Lock()
+	login := o.pending[state]
+	delete(o.pending, state)
+	o.mu.Unlock()
+	if login == nil || time.Now().After(login.expiresAt) {
+		return nil, fmt.Errorf("unknown or expired login")
+	}
+
+	code := query.Get("code")
+	if code == "" {
+		return nil, fmt.Errorf("missing authorization code")
+	}
+
+	rawIDToken, err := o.exchangeCode(code, login.verifier)
+	if err != nil {
+		return nil, err
+	}
+	claims, err := o.verifyIDToken(rawIDToken, login.nonce)
+	if err != nil {
+		return nil, err
+	}
+	return o.userFromClaims(claims)
+}
+
+// Authenticate completes a login, creating the user on first login, and
+// generates a token
+func (o *OIDCAuthenticator) Authenticate(db *gorm.DB, w http.ResponseWriter, r *http.Request) (*database.MedhaUser, *database.MedhaAuthToken, error) {
+	oidcUser, err := o.HandleCallback(w, r)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	user, err := provisionUser(db, oidcUser.Username, oidcUser.Email)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Generate token
+	token, err := o.tokenManager.GenerateToken(user.ID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to generate token: %w", err)
+	}
+
+	return user, token, nil
+}
+
+// exchangeCode redeems an authorization code and returns the ID token
+func (o *OIDCAuthenticator) exchangeCode(code, verifier string) (string, error) {
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", o.config.RedirectURL)
+	form.Set("client_id", o.config.ClientID)
+	form.Set("code_verifier", verifier)
+
+	req, err := http.NewRequest(http.MethodPost, o.provider.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", fmt.Errorf("failed to create token request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if o.config.ClientSecret != "" {
+		req.SetBasicAuth(url.QueryEscape(o.config.ClientID), url.QueryEscape(o.config.ClientSecret))
+	}
+
+	resp, err := o.httpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to redeem authorization code: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var body struct {
+		IDToken          string `json:"id_token"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", fmt.Errorf("failed to decode token response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("token endpoint returned %d: %s %s", resp.StatusCode, body.Error, body.ErrorDescription)
+	}
+	if body.IDToken == "" {
+		return "", fmt.Errorf("token response has no ID token")
+	}
+	return body.IDToken, nil
+}
+
+// verifyIDToken checks an ID token's signature, issuer, audience, expiry and
+// nonce, and returns its claims
+func (o *OIDCAuthenticator) verifyIDToken(rawIDToken, nonce string) (jwt.MapClaims, error) {
+	claims := jwt.MapClaims{}
+	parser := jwt.NewParser(jwt.WithValidMethods(oidcSigningMethods))
+	if _, err := parser.ParseWithClaims(rawIDToken, claims, o.signingKey); err != nil {
+		return nil, fmt.Errorf("invalid ID token: %w", err)
+	}
+
+	if !claims.VerifyIssuer(o.provider.Issuer, true) {
+		return nil, fmt.Errorf("invalid ID token: wrong issuer")
+	}
+	if !claims.VerifyAudience(o.config.ClientID, true) {
+		return nil, fmt.Errorf("invalid ID token: wrong audience")
+	}
+	if audiences, ok := claims["aud"].([]interface{}); ok && len(audiences) > 1 && claims["azp"] != o.config.ClientID {
+		return nil, fmt.Errorf("invalid ID token: wrong authorized party")
+	}
+	if !claims.VerifyExpiresAt(time.Now().Unix(), true) {
+		return nil, fmt.Errorf("invalid ID token: missing expiry")
+	}
+	if claims["nonce"] != nonce {
+		return nil, fmt.Errorf("invalid ID token: wrong nonce")
+	}
+	return claims, nil
+}
+
+// signingKey returns the issuer key that signed a token, re-reading the
+// issuer's keys once when the key is unknown so rotated keys are picked up
+func (o *OIDCAuthenticator) signingKey(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	if key := o.lookupKey(kid); key != nil {
+		return key, nil
+	}
+	if err := o.refreshKeys(); err != nil {
+		return nil, err
+	}
+	if key := o.lookupKey(kid); key != nil {
+		return key, nil
+	}
+	return nil, fmt.Errorf("unknown signing key %q", kid)
+}
+
+// lookupKey returns the key with an ID, or the only key for tokens without one
+func (o *OIDCAuthenticator) lookupKey(kid string) crypto.PublicKey {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if kid == "" && len(o.keys) == 1 {
+		for _, key := range o.keys {
+			return key
+		}
+	}
+	return o.keys[kid]
+}
+
+// refreshKeys reads the issuer's signing keys from its JWKS endpoint
+func (o *OIDCAuthenticator) refreshKeys() error {
+	var jwks struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err := o.getJSON(o.provider.JWKSURI, &jwks); err != nil {
+		return fmt.Errorf("failed to read OIDC signing keys: %w", err)
+	}
+
+	keys := make(map[string]crypto.PublicKey)
+	for _, jwk := range jwks.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		key, err := jwk.publicKey()
+		if err != nil {
+			continue // Key types we cannot use are skipped
+		}
+		keys[jwk.Kid] = key
+	}
+
+	o.mu.Lock()
+	o.keys = keys
+	o.mu.Unlock()
+	return nil
+}
+
+// userFromClaims extracts the user from validated ID token claims
+func (o *OIDCAuthenticator) userFromClaims(claims jwt.MapClaims) (*OIDCUser, error) {
+	user := &OIDCUser{
+		Subject: stringClaim(claims, "sub"),
+		Claims:  claims,
+	}
+
+	if o.config.EmailClaim != "" {
+		user.Email = stringClaim(claims, o.config.EmailClaim)
+	} else {
+		user.Email = stringClaim(claims, "email")
+	}
+
+	if o.config.UsernameClaim != "" {
+		user.Username = stringClaim(claims, o.config.UsernameClaim)
+	} else {
+		user.Username = stringClaim(claims, "preferred_username")
+		// Anyone can claim an unverified email, so it never names a user
+		if verified, ok := claims["email_verified"].(bool); user.Username == "" && (!ok || verified) {
+			user.Username = user.Email
+		}
+		if user.Username == "" {
+			user.Username = user.Subject
+		}
+	}
+
+	if user.Username == "" {
+		return nil, fmt.Errorf("unable to extract username from ID token")
+	}
+	return user, nil
+}
+
+// stateCookie returns the cookie binding a login's state to the browser
+func (o *OIDCAuthenticator) stateCookie(state string, maxAge int) *http.Cookie {
+	cookie := &http.Cookie{
+		Name:     oidcStateCookie,
+		Value:    state,
+		Path:     "/",
+		MaxAge:   maxAge,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	}
+	if redirectURL, err := url.Parse(o.config.RedirectURL); err == nil {
+		cookie.Path = redirectURL.Path
+		cookie.Secure = redirectURL.Scheme == "https"
+	}
+	return cookie
+}
+
+// getJSON fetches and decodes a JSON document
+func (o *OIDCAuthenticator) getJSON(rawURL string, v interface{}) error {
+	resp, err := o.httpClient.Get(rawURL)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s returned %d", rawURL, resp.StatusCode)
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+// jsonWebKey is a public key from a JWKS document
+type jsonWebKey struct {
+	Kid string `json:"kid"`
+	Kty string `json:"kty"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// publicKey decodes an RSA or EC key
+func (k jsonWebKey) publicKey() (crypto.PublicKey, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeKeyInt(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeKeyInt(k.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := decodeKeyInt(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeKeyInt(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		if !curve.IsOnCurve(x, y) {
+			return nil, fmt.Errorf("EC key is not on curve %s", k.Crv)
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	default:
+		return nil, fmt.Errorf("unsupported key type %q", k.Kty)
+	}
+}
+
+// decodeKeyInt decodes a base64url big-endian integer
+func decodeKeyInt(s string) (*big.Int, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil || len(b) == 0 {
+		return nil, fmt.Errorf("invalid key parameter")
+	}
+	return new(big.Int).SetBytes(b), nil
+}
+
+// stringClaim returns a claim if it is a string
+func stringClaim(claims jwt.MapClaims, name string) string {
+	value, _ := claims[name].(string)
+	return value
+}
+
+// randomURLSafe returns 32 random bytes encoded for URLs, suitable for a
+// state, nonce or PKCE verifier
+func randomURLSafe() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
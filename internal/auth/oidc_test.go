@@ -0,0 +1,101 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package auth
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/base64"
+	"testing"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Full login flows run against a fake issuer in tests/integration/oidc_test.go.
+
+func TestOIDCAuthenticator_UserFromClaims(t *testing.T) {
+	tests := []struct {
+		name         string
+		config       OIDCConfig
+		claims       jwt.MapClaims
+		wantUsername string
+		wantEmail    string
+		wantErr      bool
+	}{
+		{
+			name:         "defaults use preferred_username and email",
+			claims:       jwt.MapClaims{"sub": "1", "preferred_username": "alice", "email": "alice@example.com"},
+			wantUsername: "alice",
+			wantEmail:    "alice@example.com",
+		},
+		{
+			name:         "defaults fall back to email",
+			claims:       jwt.MapClaims{"sub": "2", "email": "bob@example.com", "email_verified": true},
+			wantUsername: "bob@example.com",
+			wantEmail:    "bob@example.com",
+		},
+		{
+			name:         "unverified email falls back to sub",
+			claims:       jwt.MapClaims{"sub": "3", "email": "carol@example.com", "email_verified": false},
+			wantUsername: "3",
+			wantEmail:    "carol@example.com",
+		},
+		{
+			name:         "configured claims",
+			config:       OIDCConfig{UsernameClaim: "login", EmailClaim: "work_email"},
+			claims:       jwt.MapClaims{"sub": "4", "preferred_username": "ignored", "login": "dave", "work_email": "dave@corp.example"},
+			wantUsername: "dave",
+			wantEmail:    "dave@corp.example",
+		},
+		{
+			name:    "configured username claim missing",
+			config:  OIDCConfig{UsernameClaim: "login"},
+			claims:  jwt.MapClaims{"sub": "5", "preferred_username": "erin"},
+			wantErr: true,
+		},
+		{
+			name:    "non-string username",
+			config:  OIDCConfig{UsernameClaim: "login"},
+			claims:  jwt.MapClaims{"sub": "6", "login": 42},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o := &OIDCAuthenticator{config: &tt.config}
+			user, err := o.userFromClaims(tt.claims)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantUsername, user.Username)
+			assert.Equal(t, tt.wantEmail, user.Email)
+		})
+	}
+}
+
+func TestJSONWebKey_PublicKey(t *testing.T) {
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	encode := func(b []byte) string { return base64.RawURLEncoding.EncodeToString(b) }
+
+	key, err := jsonWebKey{Kty: "EC", Crv: "P-256", X: encode(ecKey.X.Bytes()), Y: encode(ecKey.Y.Bytes())}.publicKey()
+	require.NoError(t, err)
+	assert.True(t, ecKey.PublicKey.Equal(key))
+
+	_, err = jsonWebKey{Kty: "EC", Crv: "P-256", X: encode(ecKey.Y.Bytes()), Y: encode(ecKey.X.Bytes())}.publicKey()
+	assert.Error(t, err, "point not on the curve")
+
+	_, err = jsonWebKey{Kty: "oct"}.publicKey()
+	assert.Error(t, err)
+
+	_, err = jsonWebKey{Kty: "RSA", N: "!!", E: "AQAB"}.publicKey()
+	assert.Error(t, err)
+}
@@ -16,6 +16,8 @@ import (
 
 	"github.com/crewjam/saml"
 	"github.com/crewjam/saml/samlsp"
+	"github.com/tejzpr/medha-mcp/internal/database"
+	"gorm.io/gorm"
 )
 
 // SAMLConfig holds SAML configuration
@@ -27,13 +29,18 @@ type SAMLConfig struct {
 	Certificate  string
 	PrivateKey   string
 	Provider     string // "duo" or "okta"
+
+	// Attribute mapping; empty uses the defaults described on HandleACS
+	UsernameAttribute string
+	EmailAttribute    string
 }
 
 // SAMLAuthenticator handles SAML authentication
 type SAMLAuthenticator struct {
-	sp       *saml.ServiceProvider
-	config   *SAMLConfig
-	middleware *samlsp.Middleware
+	sp           *saml.ServiceProvider
+	config       *SAMLConfig
+	middleware   *samlsp.Middleware
+	tokenManager *TokenManager
 }
 
 // SAMLUser represents a user from SAML assertion
@@ -44,7 +51,7 @@ type SAMLUser struct {
 }
 
 // NewSAMLAuthenticator creates a new SAML authenticator
-func NewSAMLAuthenticator(config *SAMLConfig) (*SAMLAuthenticator, error) {
+func NewSAMLAuthenticator(config *SAMLConfig, tm *TokenManager) (*SAMLAuthenticator, error) {
 	// Parse certificate and private key
 	keyPair, err := parseCertificateAndKey(config.Certificate, config.PrivateKey)
 	if err != nil {
@@ -63,31 +70,31 @@ func NewSAMLAuthenticator(config *SAMLConfig) (*SAMLAuthenticator, error) {
 		return nil, fmt.Errorf("failed to parse IDP metadata: %w", err)
 	}
 
-	// Create service provider
-	sp := &saml.ServiceProvider{
-		EntityID:    config.EntityID,
-		Key:         keyPair.PrivateKey.(*rsa.PrivateKey),
-		Certificate: keyPair.Leaf,
-		MetadataURL: *mustParseURL(config.MetadataURL),
-		AcsURL:      *mustParseURL(config.ACSURL),
-		IDPMetadata: idpMetadata,
-	}
-
 	// Create middleware
 	middleware, err := samlsp.New(samlsp.Options{
+		EntityID:    config.EntityID,
 		URL:         *rootURL,
-		Key:         sp.Key,
-		Certificate: sp.Certificate,
+		Key:         keyPair.PrivateKey.(*rsa.PrivateKey),
+		Certificate: keyPair.Leaf,
 		IDPMetadata: idpMetadata,
 	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to create SAML middleware: %w", err)
 	}
 
+	// The middleware's service provider starts the login and tracks the
+	// request, so it must use the configured endpoints to validate responses
+	sp := &middleware.ServiceProvider
+	sp.AcsURL = *mustParseURL(config.ACSURL)
+	if config.MetadataURL != "" {
+		sp.MetadataURL = *mustParseURL(config.MetadataURL)
+	}
+
 	return &SAMLAuthenticator{
-		sp:         sp,
-		config:     config,
-		middleware: middleware,
+		sp:           sp,
+		config:       config,
+		middleware:   middleware,
+		tokenManager: tm,
 	}, nil
 }
 
@@ -97,7 +104,13 @@ func (s *SAMLAuthenticator) InitiateLogin(w http.ResponseWriter, r *http.Request
 	s.middleware.HandleStartAuthFlow(w, r)
 }
 
-// HandleACS processes the SAML assertion from the IdP
+// HandleACS processes the SAML assertion from the IdP. The response must
+// answer a login started by InitiateLogin in the same browser.
+//
+// The username comes from the configured username attribute or, by default,
+// the NameID, then a username, uid or user attribute, then the email. The
+// email comes from the configured email attribute or, by default, an email,
+// mail or emailAddress attribute.
 func (s *SAMLAuthenticator) HandleACS(w http.ResponseWriter, r *http.Request) (*SAMLUser, error) {
 	// Parse the SAML response
 	err := r.ParseForm()
@@ -111,48 +124,83 @@ func (s *SAMLAuthenticator) HandleACS(w http.ResponseWriter, r *http.Request) (*
 		return nil, fmt.Errorf("missing SAMLResponse")
 	}
 
+	// Only accept responses to requests this browser started
+	possibleRequestIDs := []string{}
+	for _, tracked := range s.middleware.RequestTracker.GetTrackedRequests(r) {
+		possibleRequestIDs = append(possibleRequestIDs, tracked.SAMLRequestID)
+	}
+
 	// Parse and validate the assertion
-	assertion, err := s.sp.ParseResponse(r, []string{""})
+	assertion, err := s.sp.ParseResponse(r, possibleRequestIDs)
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse SAML response: %w", err)
 	}
 
+	if relayState := r.FormValue("RelayState"); relayState != "" {
+		_ = s.middleware.RequestTracker.StopTrackingRequest(w, r, relayState)
+	}
+
+	return s.userFromAssertion(assertion)
+}
+
+// Authenticate processes the SAML assertion from the IdP, creating the user
+// on first login, and generates a token
+func (s *SAMLAuthenticator) Authenticate(db *gorm.DB, w http.ResponseWriter, r *http.Request) (*database.MedhaUser, *database.MedhaAuthToken, error) {
+	samlUser, err := s.HandleACS(w, r)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	user, err := provisionUser(db, samlUser.Username, samlUser.Email)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Generate token
+	token, err := s.tokenManager.GenerateToken(user.ID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to generate token: %w", err)
+	}
+
+	return user, token, nil
+}
+
+// userFromAssertion extracts the user from a validated assertion
+func (s *SAMLAuthenticator) userFromAssertion(assertion *saml.Assertion) (*SAMLUser, error) {
 	// Extract user information
 	user := &SAMLUser{
 		Attributes: make(map[string][]string),
 	}
 
-	// Extract username (subject)
-	if assertion.Subject != nil && assertion.Subject.NameID != nil {
-		user.Username = assertion.Subject.NameID.Value
-	}
-
 	// Extract attributes
-	if len(assertion.AttributeStatements) > 0 {
-		for _, attr := range assertion.AttributeStatements[0].Attributes {
-			values := make([]string, len(attr.Values))
-			for i, v := range attr.Values {
-				values[i] = v.Value
-			}
-			user.Attributes[attr.Name] = values
-
-			// Extract common attributes
-			switch attr.Name {
-			case "email", "mail", "emailAddress":
-				if len(values) > 0 {
-					user.Email = values[0]
-				}
-			case "username", "uid", "user":
-				if len(values) > 0 && user.Username == "" {
-					user.Username = values[0]
-				}
+	for _, statement := range assertion.AttributeStatements {
+		for _, attr := range statement.Attributes {
+			for _, v := range attr.Values {
+				user.Attributes[attr.Name] = append(user.Attributes[attr.Name], v.Value)
 			}
 		}
 	}
 
-	// If username is still empty, try to use email
-	if user.Username == "" && user.Email != "" {
-		user.Username = user.Email
+	if s.config.EmailAttribute != "" {
+		user.Email = firstAttribute(user.Attributes, s.config.EmailAttribute)
+	} else {
+		user.Email = firstAttribute(user.Attributes, "email", "mail", "emailAddress")
+	}
+
+	if s.config.UsernameAttribute != "" {
+		user.Username = firstAttribute(user.Attributes, s.config.UsernameAttribute)
+	} else {
+		// Extract username (subject)
+		if assertion.Subject != nil && assertion.Subject.NameID != nil {
+			user.Username = assertion.Subject.NameID.Value
+		}
+		if user.Username == "" {
+			user.Username = firstAttribute(user.Attributes, "username", "uid", "user")
+		}
+		// If username is still empty, try to use email
+		if user.Username == "" {
+			user.Username = user.Email
+		}
 	}
 
 	if user.Username == "" {
@@ -162,6 +210,16 @@ func (s *SAMLAuthenticator) HandleACS(w http.ResponseWriter, r *http.Request) (*
 	return user, nil
 }
 
+// firstAttribute returns the first value of the first named attribute present
+func firstAttribute(attributes map[string][]string, names ...string) string {
+	for _, name := range names {
+		if values := attributes[name]; len(values) > 0 && values[0] != "" {
+			return values[0]
+		}
+	}
+	return ""
+}
+
 // GetMetadata returns the SP metadata XML
 func (s *SAMLAuthenticator) GetMetadata() ([]byte, error) {
 	metadata := s.sp.Metadata()
@@ -7,7 +7,9 @@ package auth
 import (
 	"testing"
 
+	"github.com/crewjam/saml"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 // Note: Full SAML testing requires complex mocking of IdP responses.
@@ -76,6 +78,80 @@ func TestSAMLUser_Structure(t *testing.T) {
 	assert.Equal(t, []string{"developer"}, user.Attributes["role"])
 }
 
+func TestSAMLAuthenticator_UserFromAssertion(t *testing.T) {
+	assertion := func(nameID string, attrs map[string]string) *saml.Assertion {
+		a := &saml.Assertion{Subject: &saml.Subject{NameID: &saml.NameID{Value: nameID}}}
+		statement := saml.AttributeStatement{}
+		for name, value := range attrs {
+			statement.Attributes = append(statement.Attributes, saml.Attribute{
+				Name:   name,
+				Values: []saml.AttributeValue{{Value: value}},
+			})
+		}
+		a.AttributeStatements = []saml.AttributeStatement{statement}
+		return a
+	}
+
+	tests := []struct {
+		name         string
+		config       SAMLConfig
+		assertion    *saml.Assertion
+		wantUsername string
+		wantEmail    string
+		wantErr      bool
+	}{
+		{
+			name:         "defaults use NameID and mail",
+			assertion:    assertion("alice", map[string]string{"mail": "alice@example.com"}),
+			wantUsername: "alice",
+			wantEmail:    "alice@example.com",
+		},
+		{
+			name:         "defaults fall back to uid",
+			assertion:    assertion("", map[string]string{"uid": "bob"}),
+			wantUsername: "bob",
+		},
+		{
+			name:         "defaults fall back to email",
+			assertion:    assertion("", map[string]string{"email": "carol@example.com"}),
+			wantUsername: "carol@example.com",
+			wantEmail:    "carol@example.com",
+		},
+		{
+			name:         "configured attributes",
+			config:       SAMLConfig{UsernameAttribute: "login", EmailAttribute: "workEmail"},
+			assertion:    assertion("ignored", map[string]string{"login": "dave", "workEmail": "dave@corp.example", "mail": "other@example.com"}),
+			wantUsername: "dave",
+			wantEmail:    "dave@corp.example",
+		},
+		{
+			name:      "configured username attribute missing",
+			config:    SAMLConfig{UsernameAttribute: "login"},
+			assertion: assertion("alice", map[string]string{"mail": "alice@example.com"}),
+			wantErr:   true,
+		},
+		{
+			name:      "no username",
+			assertion: assertion("", nil),
+			wantErr:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &SAMLAuthenticator{config: &tt.config}
+			user, err := s.userFromAssertion(tt.assertion)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantUsername, user.Username)
+			assert.Equal(t, tt.wantEmail, user.Email)
+		})
+	}
+}
+
 // parseCertificateAndKey requires valid certificate and key
 func TestParseCertificateAndKey_Invalid(t *testing.T) {
 	tests := []struct {
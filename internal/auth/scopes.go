@@ -0,0 +1,64 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package auth
+
+import (
+	"context"
+	"slices"
+)
+
+// Scopes an API token can be granted
+const (
+	// ScopeMemoriesRead allows reading memories: recall, history, graph,
+	// resources and prompts
+	ScopeMemoriesRead = "memories:read"
+	// ScopeMemoriesWrite allows changing memories: remember, connect,
+	// forget, restore and lock
+	ScopeMemoriesWrite = "memories:write"
+	// ScopeSync allows git synchronization
+	ScopeSync = "sync"
+)
+
+// AllScopes lists every scope an API token can be granted
+var AllScopes = []string{ScopeMemoriesRead, ScopeMemoriesWrite, ScopeSync}
+
+// PermissionsKey is the context key for the permissions of an API token
+const PermissionsKey ContextKey = "permissions"
+
+// Permissions is what an API token allows. A nil *Permissions, as used for
+// login tokens and stdio mode, allows everything.
+type Permissions struct {
+	TokenID uint
+	Scopes  []string
+	Tools   []string // Tools allowed by name, whatever their scope
+}
+
+// HasScope reports whether the scope is granted
+func (p *Permissions) HasScope(scope string) bool {
+	if p == nil {
+		return true
+	}
+	return slices.Contains(p.Scopes, scope)
+}
+
+// AllowsTool reports whether a tool that needs scope may be called
+func (p *Permissions) AllowsTool(name, scope string) bool {
+	if p == nil {
+		return true
+	}
+	return (scope != "" && p.HasScope(scope)) || slices.Contains(p.Tools, name)
+}
+
+// WithPermissions adds API token permissions to a context
+func WithPermissions(ctx context.Context, permissions *Permissions) context.Context {
+	return context.WithValue(ctx, PermissionsKey, permissions)
+}
+
+// GetPermissionsFromContext returns the API token permissions of a request,
+// or nil when it was not made with an API token
+func GetPermissionsFromContext(ctx context.Context) *Permissions {
+	permissions, _ := ctx.Value(PermissionsKey).(*Permissions)
+	return permissions
+}
@@ -5,8 +5,12 @@
 package auth
 
 import (
+	"crypto/hmac"
 	"crypto/rand"
+	"crypto/sha256"
 	"encoding/base64"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"time"
 
@@ -14,37 +18,44 @@ import (
 	"gorm.io/gorm"
 )
 
+// tokenPrefixLength is how much of a token is stored in the clear to find
+// its row; the rest is only ever compared through its hash
+const tokenPrefixLength = 8
+
+// errRefreshTokenReused reports a refresh token presented after rotation
+var errRefreshTokenReused = errors.New("refresh token reuse detected, all tokens from this login have been revoked")
+
 // TokenManager handles authentication token operations
 type TokenManager struct {
-	db      *gorm.DB
+	db       *gorm.DB
 	ttlHours int
+	hashKey  []byte
 }
 
-// NewTokenManager creates a new token manager
-func NewTokenManager(db *gorm.DB, ttlHours int) *TokenManager {
+// NewTokenManager creates a new token manager. Tokens are stored as HMACs
+// keyed from secret (the server's encryption key), so tokens issued under one
+// secret are not valid under another.
+func NewTokenManager(db *gorm.DB, ttlHours int, secret []byte) *TokenManager {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte("medha auth token hash"))
 	return &TokenManager{
-		db:      db,
+		db:       db,
 		ttlHours: ttlHours,
+		hashKey:  mac.Sum(nil),
 	}
 }
 
-// GenerateToken creates a new access and refresh token for a user
+// GenerateToken creates a new access and refresh token for a user, starting a
+// new token family
 func (tm *TokenManager) GenerateToken(userID uint) (*database.MedhaAuthToken, error) {
-	accessToken, err := generateRandomToken(32)
+	familyID, err := generateRandomToken(16)
 	if err != nil {
-		return nil, fmt.Errorf("failed to generate access token: %w", err)
+		return nil, fmt.Errorf("failed to generate token family: %w", err)
 	}
 
-	refreshToken, err := generateRandomToken(32)
+	token, err := tm.newToken(userID, familyID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to generate refresh token: %w", err)
-	}
-
-	token := &database.MedhaAuthToken{
-		UserID:       userID,
-		AccessToken:  accessToken,
-		RefreshToken: refreshToken,
-		ExpiresAt:    time.Now().Add(time.Duration(tm.ttlHours) * time.Hour),
+		return nil, err
 	}
 
 	if err := tm.db.Create(token).Error; err != nil {
@@ -56,13 +67,19 @@ func (tm *TokenManager) GenerateToken(userID uint) (*database.MedhaAuthToken, er
 
 // ValidateToken checks if a token is valid and not expired
 func (tm *TokenManager) ValidateToken(accessToken string) (*database.MedhaAuthToken, error) {
-	var token database.MedhaAuthToken
-	err := tm.db.Where("access_token = ?", accessToken).First(&token).Error
+	token, err := tm.findToken(tm.db, "access_token_prefix", accessToken, func(t *database.MedhaAuthToken) string {
+		return t.AccessTokenHash
+	})
 	if err != nil {
-		if err == gorm.ErrRecordNotFound {
-			return nil, fmt.Errorf("token not found")
-		}
-		return nil, fmt.Errorf("failed to query token: %w", err)
+		return nil, err
+	}
+	if token == nil {
+		return nil, fmt.Errorf("token not found")
+	}
+
+	// A refreshed token is replaced by its successor
+	if token.RotatedAt != nil {
+		return nil, fmt.Errorf("token revoked")
 	}
 
 	// Check if token is expired
@@ -70,53 +87,87 @@ func (tm *TokenManager) ValidateToken(accessToken string) (*database.MedhaAuthTo
 		return nil, fmt.Errorf("token expired")
 	}
 
-	return &token, nil
+	return token, nil
 }
 
-// RefreshToken generates a new access token using a refresh token
+// RefreshToken exchanges a refresh token for a new access and refresh token.
+// Each refresh token works once: presenting it again means it was copied, so
+// every token in its family is revoked.
 func (tm *TokenManager) RefreshToken(refreshToken string) (*database.MedhaAuthToken, error) {
-	var oldToken database.MedhaAuthToken
-	err := tm.db.Where("refresh_token = ?", refreshToken).First(&oldToken).Error
-	if err != nil {
-		if err == gorm.ErrRecordNotFound {
-			return nil, fmt.Errorf("refresh token not found")
+	var newToken *database.MedhaAuthToken
+	reused := false
+	err := tm.db.Transaction(func(tx *gorm.DB) error {
+		oldToken, err := tm.findToken(tx, "refresh_token_prefix", refreshToken, func(t *database.MedhaAuthToken) string {
+			return t.RefreshTokenHash
+		})
+		if err != nil {
+			return err
+		}
+		if oldToken == nil {
+			return fmt.Errorf("refresh token not found")
+		}
+		if oldToken.RotatedAt != nil {
+			reused = true
+			return errRefreshTokenReused
 		}
-		return nil, fmt.Errorf("failed to query refresh token: %w", err)
-	}
-
-	// Check if refresh token is expired (use 2x TTL for refresh tokens)
-	refreshExpiry := oldToken.CreatedAt.Add(time.Duration(tm.ttlHours*2) * time.Hour)
-	if time.Now().After(refreshExpiry) {
-		return nil, fmt.Errorf("refresh token expired")
-	}
 
-	// Generate new access token
-	accessToken, err := generateRandomToken(32)
-	if err != nil {
-		return nil, fmt.Errorf("failed to generate new access token: %w", err)
-	}
+		// Check if refresh token is expired (use 2x TTL for refresh tokens)
+		refreshExpiry := oldToken.CreatedAt.Add(time.Duration(tm.ttlHours*2) * time.Hour)
+		if time.Now().After(refreshExpiry) {
+			return fmt.Errorf("refresh token expired")
+		}
 
-	// Update the token
-	oldToken.AccessToken = accessToken
-	oldToken.ExpiresAt = time.Now().Add(time.Duration(tm.ttlHours) * time.Hour)
-	oldToken.UpdatedAt = time.Now()
+		// Retire the old token; a concurrent refresh that got there first
+		// means the token was used twice
+		now := time.Now()
+		result := tx.Model(&database.MedhaAuthToken{}).
+			Where("id = ? AND rotated_at IS NULL", oldToken.ID).
+			Update("rotated_at", now)
+		if result.Error != nil {
+			return fmt.Errorf("failed to rotate token: %w", result.Error)
+		}
+		if result.RowsAffected == 0 {
+			reused = true
+			return errRefreshTokenReused
+		}
 
-	if err := tm.db.Save(&oldToken).Error; err != nil {
-		return nil, fmt.Errorf("failed to update token: %w", err)
+		newToken, err = tm.newToken(oldToken.UserID, oldToken.FamilyID)
+		if err != nil {
+			return err
+		}
+		if err := tx.Create(newToken).Error; err != nil {
+			return fmt.Errorf("failed to store token: %w", err)
+		}
+		return nil
+	})
+	if reused {
+		if revokeErr := tm.revokeFamily(refreshToken); revokeErr != nil {
+			return nil, fmt.Errorf("%w (revocation failed: %v)", errRefreshTokenReused, revokeErr)
+		}
+	}
+	if err != nil {
+		return nil, err
 	}
 
-	return &oldToken, nil
+	return newToken, nil
 }
 
-// RevokeToken invalidates a token
+// RevokeToken invalidates a token and every token refreshed from the same login
 func (tm *TokenManager) RevokeToken(accessToken string) error {
-	result := tm.db.Where("access_token = ?", accessToken).Delete(&database.MedhaAuthToken{})
-	if result.Error != nil {
-		return fmt.Errorf("failed to revoke token: %w", result.Error)
+	token, err := tm.findToken(tm.db, "access_token_prefix", accessToken, func(t *database.MedhaAuthToken) string {
+		return t.AccessTokenHash
+	})
+	if err != nil {
+		return fmt.Errorf("failed to revoke token: %w", err)
 	}
-	if result.RowsAffected == 0 {
+	if token == nil {
 		return fmt.Errorf("token not found")
 	}
+
+	result := tm.db.Where("family_id = ?", token.FamilyID).Delete(&database.MedhaAuthToken{})
+	if result.Error != nil {
+		return fmt.Errorf("failed to revoke token: %w", result.Error)
+	}
 	return nil
 }
 
@@ -147,6 +198,78 @@ func (tm *TokenManager) GetUserIDFromToken(accessToken string) (uint, error) {
 	return token.UserID, nil
 }
 
+// newToken creates an unsaved token in a family, with its plaintext tokens set
+func (tm *TokenManager) newToken(userID uint, familyID string) (*database.MedhaAuthToken, error) {
+	accessToken, err := generateRandomToken(32)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate access token: %w", err)
+	}
+
+	refreshToken, err := generateRandomToken(32)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate refresh token: %w", err)
+	}
+
+	return &database.MedhaAuthToken{
+		UserID:             userID,
+		FamilyID:           familyID,
+		AccessTokenPrefix:  tokenPrefix(accessToken),
+		AccessTokenHash:    tm.hashToken(accessToken),
+		RefreshTokenPrefix: tokenPrefix(refreshToken),
+		RefreshTokenHash:   tm.hashToken(refreshToken),
+		AccessToken:        accessToken,
+		RefreshToken:       refreshToken,
+		ExpiresAt:          time.Now().Add(time.Duration(tm.ttlHours) * time.Hour),
+	}, nil
+}
+
+// findToken finds the row whose hash, read by hashOf, matches a token. Rows
+// are looked up by prefix and the hashes compared in constant time.
+func (tm *TokenManager) findToken(db *gorm.DB, prefixColumn, token string, hashOf func(*database.MedhaAuthToken) string) (*database.MedhaAuthToken, error) {
+	if len(token) < tokenPrefixLength {
+		return nil, nil
+	}
+
+	var candidates []database.MedhaAuthToken
+	if err := db.Where(prefixColumn+" = ?", tokenPrefix(token)).Find(&candidates).Error; err != nil {
+		return nil, fmt.Errorf("failed to query token: %w", err)
+	}
+
+	hash := []byte(tm.hashToken(token))
+	for i := range candidates {
+		if hmac.Equal([]byte(hashOf(&candidates[i])), hash) {
+			return &candidates[i], nil
+		}
+	}
+	return nil, nil
+}
+
+// revokeFamily deletes every token in the family of a refresh token
+func (tm *TokenManager) revokeFamily(refreshToken string) error {
+	token, err := tm.findToken(tm.db, "refresh_token_prefix", refreshToken, func(t *database.MedhaAuthToken) string {
+		return t.RefreshTokenHash
+	})
+	if err != nil || token == nil {
+		return err
+	}
+	return tm.db.Where("family_id = ?", token.FamilyID).Delete(&database.MedhaAuthToken{}).Error
+}
+
+// hashToken returns the hex HMAC-SHA256 of a token
+func (tm *TokenManager) hashToken(token string) string {
+	mac := hmac.New(sha256.New, tm.hashKey)
+	mac.Write([]byte(token))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// tokenPrefix returns the part of a token stored in the clear
+func tokenPrefix(token string) string {
+	if len(token) < tokenPrefixLength {
+		return token
+	}
+	return token[:tokenPrefixLength]
+}
+
 // generateRandomToken creates a secure random token
 func generateRandomToken(length int) (string, error) {
 	bytes := make([]byte, length)
@@ -15,6 +15,9 @@ import (
 	"gorm.io/gorm/logger"
 )
 
+// testTokenSecret keys token hashes in tests
+var testTokenSecret = []byte("test token secret")
+
 func setupTestDB(t *testing.T) *database.Config {
 	tempDir := t.TempDir()
 	dbPath := filepath.Join(tempDir, "test.db")
@@ -46,7 +49,7 @@ func TestGenerateToken(t *testing.T) {
 	}
 	db.Create(user)
 
-	tm := NewTokenManager(db, 24)
+	tm := NewTokenManager(db, 24, testTokenSecret)
 
 	token, err := tm.GenerateToken(user.ID)
 	require.NoError(t, err)
@@ -65,7 +68,7 @@ func TestValidateToken_Success(t *testing.T) {
 	user := &database.MedhaUser{Username: "testuser"}
 	db.Create(user)
 
-	tm := NewTokenManager(db, 24)
+	tm := NewTokenManager(db, 24, testTokenSecret)
 
 	// Generate token
 	token, err := tm.GenerateToken(user.ID)
@@ -83,7 +86,7 @@ func TestValidateToken_NotFound(t *testing.T) {
 	db, _ := database.Connect(dbCfg)
 	defer database.Close(db)
 
-	tm := NewTokenManager(db, 24)
+	tm := NewTokenManager(db, 24, testTokenSecret)
 
 	_, err := tm.ValidateToken("invalid-token")
 	assert.Error(t, err)
@@ -98,7 +101,7 @@ func TestValidateToken_Expired(t *testing.T) {
 	user := &database.MedhaUser{Username: "testuser"}
 	db.Create(user)
 
-	tm := NewTokenManager(db, 24)
+	tm := NewTokenManager(db, 24, testTokenSecret)
 
 	// Generate token
 	token, err := tm.GenerateToken(user.ID)
@@ -122,7 +125,7 @@ func TestRefreshToken_Success(t *testing.T) {
 	user := &database.MedhaUser{Username: "testuser"}
 	db.Create(user)
 
-	tm := NewTokenManager(db, 24)
+	tm := NewTokenManager(db, 24, testTokenSecret)
 
 	// Generate token
 	originalToken, err := tm.GenerateToken(user.ID)
@@ -133,8 +136,81 @@ func TestRefreshToken_Success(t *testing.T) {
 	newToken, err := tm.RefreshToken(originalToken.RefreshToken)
 	require.NoError(t, err)
 	assert.NotEqual(t, originalAccessToken, newToken.AccessToken)
-	assert.Equal(t, originalToken.RefreshToken, newToken.RefreshToken)
+	assert.NotEqual(t, originalToken.RefreshToken, newToken.RefreshToken)
+	assert.Equal(t, originalToken.FamilyID, newToken.FamilyID)
 	assert.True(t, newToken.ExpiresAt.After(time.Now()))
+
+	// The refreshed token replaces the original
+	_, err = tm.ValidateToken(originalAccessToken)
+	assert.Error(t, err)
+	_, err = tm.ValidateToken(newToken.AccessToken)
+	assert.NoError(t, err)
+}
+
+func TestRefreshToken_ReuseRevokesFamily(t *testing.T) {
+	dbCfg := setupTestDB(t)
+	db, _ := database.Connect(dbCfg)
+	defer database.Close(db)
+
+	user := &database.MedhaUser{Username: "testuser"}
+	db.Create(user)
+
+	tm := NewTokenManager(db, 24, testTokenSecret)
+
+	originalToken, err := tm.GenerateToken(user.ID)
+	require.NoError(t, err)
+	otherLogin, err := tm.GenerateToken(user.ID)
+	require.NoError(t, err)
+
+	newToken, err := tm.RefreshToken(originalToken.RefreshToken)
+	require.NoError(t, err)
+
+	// Replaying the used refresh token revokes everything issued from it
+	_, err = tm.RefreshToken(originalToken.RefreshToken)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "reuse detected")
+
+	_, err = tm.ValidateToken(newToken.AccessToken)
+	assert.Error(t, err)
+	_, err = tm.RefreshToken(newToken.RefreshToken)
+	assert.Error(t, err)
+
+	// Other logins are unaffected
+	_, err = tm.ValidateToken(otherLogin.AccessToken)
+	assert.NoError(t, err)
+}
+
+func TestTokensStoredHashed(t *testing.T) {
+	dbCfg := setupTestDB(t)
+	db, _ := database.Connect(dbCfg)
+	defer database.Close(db)
+
+	user := &database.MedhaUser{Username: "testuser"}
+	db.Create(user)
+
+	tm := NewTokenManager(db, 24, testTokenSecret)
+
+	token, err := tm.GenerateToken(user.ID)
+	require.NoError(t, err)
+
+	var stored database.MedhaAuthToken
+	require.NoError(t, db.First(&stored, token.ID).Error)
+	assert.Empty(t, stored.AccessToken)
+	assert.Empty(t, stored.RefreshToken)
+	assert.NotContains(t, stored.AccessTokenHash, token.AccessToken)
+	assert.NotContains(t, stored.RefreshTokenHash, token.RefreshToken)
+	assert.Equal(t, token.AccessToken[:tokenPrefixLength], stored.AccessTokenPrefix)
+
+	var matches int64
+	db.Raw("SELECT COUNT(*) FROM medha_auth_tokens WHERE access_token_hash = ? OR refresh_token_hash = ?",
+		token.AccessToken, token.RefreshToken).Scan(&matches)
+	assert.Equal(t, int64(0), matches)
+
+	// Tokens are only valid under the secret that issued them
+	other := NewTokenManager(db, 24, []byte("another secret"))
+	_, err = other.ValidateToken(token.AccessToken)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "token not found")
 }
 
 func TestRefreshToken_NotFound(t *testing.T) {
@@ -142,7 +218,7 @@ func TestRefreshToken_NotFound(t *testing.T) {
 	db, _ := database.Connect(dbCfg)
 	defer database.Close(db)
 
-	tm := NewTokenManager(db, 24)
+	tm := NewTokenManager(db, 24, testTokenSecret)
 
 	_, err := tm.RefreshToken("invalid-refresh-token")
 	assert.Error(t, err)
@@ -157,7 +233,7 @@ func TestRefreshToken_Expired(t *testing.T) {
 	user := &database.MedhaUser{Username: "testuser"}
 	db.Create(user)
 
-	tm := NewTokenManager(db, 1) // 1 hour TTL
+	tm := NewTokenManager(db, 1, testTokenSecret) // 1 hour TTL
 
 	// Generate token
 	token, err := tm.GenerateToken(user.ID)
@@ -181,7 +257,7 @@ func TestRevokeToken(t *testing.T) {
 	user := &database.MedhaUser{Username: "testuser"}
 	db.Create(user)
 
-	tm := NewTokenManager(db, 24)
+	tm := NewTokenManager(db, 24, testTokenSecret)
 
 	// Generate token
 	token, err := tm.GenerateToken(user.ID)
@@ -201,7 +277,7 @@ func TestRevokeToken_NotFound(t *testing.T) {
 	db, _ := database.Connect(dbCfg)
 	defer database.Close(db)
 
-	tm := NewTokenManager(db, 24)
+	tm := NewTokenManager(db, 24, testTokenSecret)
 
 	err := tm.RevokeToken("invalid-token")
 	assert.Error(t, err)
@@ -216,7 +292,7 @@ func TestRevokeAllUserTokens(t *testing.T) {
 	user := &database.MedhaUser{Username: "testuser"}
 	db.Create(user)
 
-	tm := NewTokenManager(db, 24)
+	tm := NewTokenManager(db, 24, testTokenSecret)
 
 	// Generate multiple tokens
 	token1, _ := tm.GenerateToken(user.ID)
@@ -242,7 +318,7 @@ func TestCleanExpiredTokens(t *testing.T) {
 	user := &database.MedhaUser{Username: "testuser"}
 	db.Create(user)
 
-	tm := NewTokenManager(db, 24)
+	tm := NewTokenManager(db, 24, testTokenSecret)
 
 	// Generate tokens
 	token1, _ := tm.GenerateToken(user.ID)
@@ -274,7 +350,7 @@ func TestGetUserIDFromToken(t *testing.T) {
 	user := &database.MedhaUser{Username: "testuser"}
 	db.Create(user)
 
-	tm := NewTokenManager(db, 24)
+	tm := NewTokenManager(db, 24, testTokenSecret)
 
 	token, err := tm.GenerateToken(user.ID)
 	require.NoError(t, err)
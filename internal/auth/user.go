@@ -0,0 +1,37 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package auth
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/tejzpr/medha-mcp/internal/database"
+	"gorm.io/gorm"
+)
+
+// provisionUser finds or creates the user an identity provider signed in,
+// keeping their email current
+func provisionUser(db *gorm.DB, username, email string) (*database.MedhaUser, error) {
+	// The username names the user's repository folder
+	if username == "." || username == ".." || strings.ContainsAny(username, `/\`) {
+		return nil, fmt.Errorf("invalid username: %q", username)
+	}
+
+	var user database.MedhaUser
+	result := db.Where("username = ?", username).FirstOrCreate(&user, database.MedhaUser{
+		Username: username,
+		Email:    email,
+	})
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to create/find user: %w", result.Error)
+	}
+	if email != "" && user.Email != email {
+		if err := db.Model(&user).Update("email", email).Error; err != nil {
+			return nil, fmt.Errorf("failed to update user email: %w", err)
+		}
+	}
+	return &user, nil
+}
@@ -87,6 +87,7 @@ func setDefaults(v *viper.Viper) {
 	v.SetDefault("server.host", "localhost")
 	v.SetDefault("server.port", 8080)
 	v.SetDefault("server.tls.enabled", false)
+	v.SetDefault("server.max_user_servers", 100)
 
 	// Auth defaults
 	v.SetDefault("auth.type", "local")
@@ -108,10 +109,18 @@ func setDefaults(v *viper.Viper) {
 	v.SetDefault("embeddings.provider", "openai")
 	v.SetDefault("embeddings.base_url", "https://api.openai.com/v1")
 	v.SetDefault("embeddings.model", "text-embedding-3-small")
+	v.SetDefault("embeddings.api_version", "2024-02-01")
 	v.SetDefault("embeddings.api_key_env", "OPENAI_API_KEY")
 	v.SetDefault("embeddings.dimensions", 1536)
 	v.SetDefault("embeddings.lazy_index", true)
 	v.SetDefault("embeddings.batch_size", 100)
+
+	// Ranking defaults
+	v.SetDefault("ranking.k", 60)
+	v.SetDefault("ranking.keyword_weight", 1.0)
+	v.SetDefault("ranking.semantic_weight", 1.0)
+	v.SetDefault("ranking.tag_weight", 0.8)
+	v.SetDefault("ranking.association_weight", 0.5)
 }
 
 // loadFromDefaults creates a config from default values
@@ -126,8 +135,8 @@ func loadFromDefaults(v *viper.Viper) (*Config, error) {
 // validate checks if the configuration is valid
 func validate(cfg *Config) error {
 	// Validate auth type
-	if cfg.Auth.Type != "" && cfg.Auth.Type != "saml" && cfg.Auth.Type != "local" {
-		return fmt.Errorf("auth.type must be 'saml' or 'local', got '%s'", cfg.Auth.Type)
+	if cfg.Auth.Type != "" && cfg.Auth.Type != "saml" && cfg.Auth.Type != "oidc" && cfg.Auth.Type != "local" {
+		return fmt.Errorf("auth.type must be 'saml', 'oidc' or 'local', got '%s'", cfg.Auth.Type)
 	}
 
 	// Default to local if not specified
@@ -161,11 +170,29 @@ func validate(cfg *Config) error {
 		}
 	}
 
+	// Validate OIDC config only if auth type is oidc
+	if cfg.Auth.Type == "oidc" {
+		if cfg.OIDC.Issuer == "" {
+			return fmt.Errorf("oidc.issuer is required when auth.type='oidc'")
+		}
+		if cfg.OIDC.ClientID == "" {
+			return fmt.Errorf("oidc.client_id is required when auth.type='oidc'")
+		}
+		if cfg.OIDC.RedirectURL == "" {
+			return fmt.Errorf("oidc.redirect_url is required when auth.type='oidc'")
+		}
+	}
+
 	// Validate server port
 	if cfg.Server.Port < 1 || cfg.Server.Port > 65535 {
 		return fmt.Errorf("server.port must be between 1 and 65535, got %d", cfg.Server.Port)
 	}
 
+	// Validate per-user server cache size
+	if cfg.Server.MaxUserServers < 0 {
+		return fmt.Errorf("server.max_user_servers must not be negative, got %d", cfg.Server.MaxUserServers)
+	}
+
 	// Validate git sync interval
 	if cfg.Git.SyncInterval < 1 {
 		return fmt.Errorf("git.sync_interval_minutes must be at least 1, got %d", cfg.Git.SyncInterval)
@@ -191,8 +218,8 @@ func validate(cfg *Config) error {
 			return fmt.Errorf("embeddings.batch_size must be at least 1, got %d", cfg.Embeddings.BatchSize)
 		}
 
-		// Check if API key is available (when not using local provider)
-		if cfg.Embeddings.Provider != EmbeddingProviderLocal {
+		// Check if API key is available (hosted providers only)
+		if EmbeddingProviderNeedsAPIKey(cfg.Embeddings.Provider) {
 			apiKey := os.Getenv(cfg.Embeddings.APIKeyEnv)
 			if apiKey == "" {
 				return fmt.Errorf("embeddings enabled but API key not found in environment variable '%s'",
@@ -201,6 +228,25 @@ func validate(cfg *Config) error {
 		}
 	}
 
+	// Validate ranking settings; zero values fall back to defaults
+	if cfg.Ranking.K < 0 {
+		return fmt.Errorf("ranking.k must not be negative, got %d", cfg.Ranking.K)
+	}
+	weights := []struct {
+		name  string
+		value float64
+	}{
+		{"keyword_weight", cfg.Ranking.KeywordWeight},
+		{"semantic_weight", cfg.Ranking.SemanticWeight},
+		{"tag_weight", cfg.Ranking.TagWeight},
+		{"association_weight", cfg.Ranking.AssociationWeight},
+	}
+	for _, w := range weights {
+		if w.value < 0 {
+			return fmt.Errorf("ranking.%s must not be negative, got %g", w.name, w.value)
+		}
+	}
+
 	return nil
 }
 
@@ -225,8 +271,9 @@ func DefaultConfig() *Config {
 
 	return &Config{
 		Server: ServerConfig{
-			Host: "localhost",
-			Port: 8080,
+			Host:           "localhost",
+			Port:           8080,
+			MaxUserServers: 100,
 			TLS: struct {
 				Enabled  bool   `mapstructure:"enabled"`
 				CertFile string `mapstructure:"cert_file"`
@@ -253,11 +300,19 @@ func DefaultConfig() *Config {
 			Enabled:    false,
 			Provider:   EmbeddingProviderOpenAI,
 			BaseURL:    "https://api.openai.com/v1",
+			APIVersion: "2024-02-01",
 			Model:      "text-embedding-3-small",
 			APIKeyEnv:  "OPENAI_API_KEY",
 			Dimensions: 1536,
 			LazyIndex:  true,
 			BatchSize:  100,
 		},
+		Ranking: RankingConfig{
+			K:                 60,
+			KeywordWeight:     1.0,
+			SemanticWeight:    1.0,
+			TagWeight:         0.8,
+			AssociationWeight: 0.5,
+		},
 	}
 }
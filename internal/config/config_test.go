@@ -31,9 +31,12 @@ func TestLoad_Defaults(t *testing.T) {
 	// Check defaults
 	assert.Equal(t, "localhost", cfg.Server.Host)
 	assert.Equal(t, 8080, cfg.Server.Port)
+	assert.Equal(t, 100, cfg.Server.MaxUserServers)
 	assert.Equal(t, "sqlite", cfg.Database.Type)
 	assert.Equal(t, "main", cfg.Git.DefaultBranch)
 	assert.Equal(t, 60, cfg.Git.SyncInterval)
+	assert.Equal(t, 60, cfg.Ranking.K)
+	assert.Equal(t, 0.8, cfg.Ranking.TagWeight)
 }
 
 func TestLoadFromPath(t *testing.T) {
@@ -248,6 +251,33 @@ func TestValidate(t *testing.T) {
 			expectError: true,
 			errorMsg:    "saml.acs_url is required",
 		},
+		{
+			name: "incomplete OIDC config when auth type is oidc",
+			config: &Config{
+				Server: ServerConfig{
+					Port: 8080,
+				},
+				Database: DatabaseConfig{
+					Type:       "sqlite",
+					SQLitePath: "/tmp/test.db",
+				},
+				Auth: AuthConfig{
+					Type: "oidc",
+				},
+				OIDC: OIDCConfig{
+					Issuer: "https://idp.example.com",
+					// Missing ClientID and RedirectURL
+				},
+				Git: GitConfig{
+					SyncInterval: 60,
+				},
+				Security: SecurityConfig{
+					TokenTTL: 24,
+				},
+			},
+			expectError: true,
+			errorMsg:    "oidc.client_id is required",
+		},
 		{
 			name: "valid local auth config",
 			config: &Config{
@@ -499,14 +529,38 @@ func TestDefaultConfig_EmbeddingsDefaults(t *testing.T) {
 	assert.Equal(t, "https://api.openai.com/v1", cfg.Embeddings.BaseURL)
 	assert.Equal(t, "text-embedding-3-small", cfg.Embeddings.Model)
 	assert.Equal(t, "OPENAI_API_KEY", cfg.Embeddings.APIKeyEnv)
+	assert.Equal(t, "2024-02-01", cfg.Embeddings.APIVersion)
 	assert.Equal(t, 1536, cfg.Embeddings.Dimensions)
 	assert.True(t, cfg.Embeddings.LazyIndex)
 	assert.Equal(t, 100, cfg.Embeddings.BatchSize)
 }
 
+func TestDefaultConfig_RankingDefaults(t *testing.T) {
+	cfg := DefaultConfig()
+
+	assert.Equal(t, 60, cfg.Ranking.K)
+	assert.Equal(t, 1.0, cfg.Ranking.KeywordWeight)
+	assert.Equal(t, 1.0, cfg.Ranking.SemanticWeight)
+	assert.Equal(t, 0.8, cfg.Ranking.TagWeight)
+	assert.Equal(t, 0.5, cfg.Ranking.AssociationWeight)
+}
+
+func TestConfig_Ranking_NegativeWeight(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Ranking.AssociationWeight = -1
+
+	err := validate(cfg)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "ranking.association_weight must not be negative")
+
+	cfg.Ranking.AssociationWeight = 0
+	assert.NoError(t, validate(cfg), "zero disables a signal")
+}
+
 func TestIsValidEmbeddingProvider(t *testing.T) {
 	assert.True(t, IsValidEmbeddingProvider("openai"))
 	assert.True(t, IsValidEmbeddingProvider("azure"))
+	assert.True(t, IsValidEmbeddingProvider("ollama"))
 	assert.True(t, IsValidEmbeddingProvider("local"))
 	assert.False(t, IsValidEmbeddingProvider("invalid"))
 	assert.False(t, IsValidEmbeddingProvider(""))
@@ -516,6 +570,14 @@ func TestValidEmbeddingProviders(t *testing.T) {
 	providers := ValidEmbeddingProviders()
 	assert.Contains(t, providers, "openai")
 	assert.Contains(t, providers, "azure")
+	assert.Contains(t, providers, "ollama")
 	assert.Contains(t, providers, "local")
-	assert.Len(t, providers, 3)
+	assert.Len(t, providers, 4)
+}
+
+func TestEmbeddingProviderNeedsAPIKey(t *testing.T) {
+	assert.True(t, EmbeddingProviderNeedsAPIKey("openai"))
+	assert.True(t, EmbeddingProviderNeedsAPIKey("azure"))
+	assert.False(t, EmbeddingProviderNeedsAPIKey("ollama"))
+	assert.False(t, EmbeddingProviderNeedsAPIKey("local"))
 }
@@ -10,16 +10,19 @@ type Config struct {
 	Database   DatabaseConfig   `mapstructure:"database"`
 	Auth       AuthConfig       `mapstructure:"auth"`
 	SAML       SAMLConfig       `mapstructure:"saml"`
+	OIDC       OIDCConfig       `mapstructure:"oidc"`
 	Git        GitConfig        `mapstructure:"git"`
 	Security   SecurityConfig   `mapstructure:"security"`
 	Embeddings EmbeddingConfig  `mapstructure:"embeddings"`
+	Ranking    RankingConfig    `mapstructure:"ranking"`
 }
 
 // ServerConfig holds HTTP server configuration
 type ServerConfig struct {
-	Host string `mapstructure:"host"`
-	Port int    `mapstructure:"port"`
-	TLS  struct {
+	Host           string `mapstructure:"host"`
+	Port           int    `mapstructure:"port"`
+	MaxUserServers int    `mapstructure:"max_user_servers"` // Per-user MCP servers kept in the LRU cache (HTTP mode)
+	TLS            struct {
 		Enabled  bool   `mapstructure:"enabled"`
 		CertFile string `mapstructure:"cert_file"`
 		KeyFile  string `mapstructure:"key_file"`
@@ -35,7 +38,7 @@ type DatabaseConfig struct {
 
 // AuthConfig holds authentication type configuration
 type AuthConfig struct {
-	Type string `mapstructure:"type"` // "saml" or "local"
+	Type string `mapstructure:"type"` // "saml", "oidc" or "local"
 }
 
 // SAMLConfig holds SAML authentication configuration
@@ -47,6 +50,19 @@ type SAMLConfig struct {
 	Certificate  string `mapstructure:"certificate"`
 	PrivateKey   string `mapstructure:"private_key"`
 	Provider     string `mapstructure:"provider"` // "duo" or "okta"
+	UsernameAttribute string `mapstructure:"username_attribute"` // Assertion attribute holding the username (default: NameID, then username/uid/user)
+	EmailAttribute    string `mapstructure:"email_attribute"`    // Assertion attribute holding the email (default: email/mail/emailAddress)
+}
+
+// OIDCConfig holds OpenID Connect authentication configuration
+type OIDCConfig struct {
+	Issuer        string   `mapstructure:"issuer"`         // Issuer URL; endpoints come from its discovery document
+	ClientID      string   `mapstructure:"client_id"`      // Client registered with the issuer
+	ClientSecret  string   `mapstructure:"client_secret"`  // Empty for public clients, which rely on PKCE alone
+	RedirectURL   string   `mapstructure:"redirect_url"`   // e.g. https://medha.example.com/oidc/callback
+	Scopes        []string `mapstructure:"scopes"`         // Default: openid, profile, email
+	UsernameClaim string   `mapstructure:"username_claim"` // ID token claim holding the username (default: preferred_username, then email, then sub)
+	EmailClaim    string   `mapstructure:"email_claim"`    // ID token claim holding the email (default: email)
 }
 
 // GitConfig holds git-related configuration
@@ -64,8 +80,9 @@ type SecurityConfig struct {
 // EmbeddingConfig holds configuration for semantic search embeddings
 type EmbeddingConfig struct {
 	Enabled    bool   `mapstructure:"enabled"`              // Feature flag for embeddings
-	Provider   string `mapstructure:"provider"`             // "openai", "azure", "local"
-	BaseURL    string `mapstructure:"base_url"`             // API base URL
+	Provider   string `mapstructure:"provider"`             // "openai", "azure", "ollama", "local"
+	BaseURL    string `mapstructure:"base_url"`             // API base URL (Azure: deployment URL)
+	APIVersion string `mapstructure:"api_version"`          // Azure OpenAI api-version query parameter
 	Model      string `mapstructure:"model"`                // Model name (e.g., "text-embedding-3-small")
 	APIKeyEnv  string `mapstructure:"api_key_env"`          // Environment variable name for API key
 	Dimensions int    `mapstructure:"dimensions"`           // Vector dimensions (e.g., 1536)
@@ -73,11 +90,21 @@ type EmbeddingConfig struct {
 	BatchSize  int    `mapstructure:"batch_size"`           // Batch size for bulk embedding operations
 }
 
+// RankingConfig holds the reciprocal rank fusion weights used by medha_recall
+type RankingConfig struct {
+	K                 int     `mapstructure:"k"`                  // RRF rank constant; larger values flatten the top ranks
+	KeywordWeight     float64 `mapstructure:"keyword_weight"`     // BM25 over title, tags and content
+	SemanticWeight    float64 `mapstructure:"semantic_weight"`    // Embedding similarity (needs embeddings enabled)
+	TagWeight         float64 `mapstructure:"tag_weight"`         // Tag name hits
+	AssociationWeight float64 `mapstructure:"association_weight"` // Links from other matching memories
+}
+
 // EmbeddingProviders defines valid embedding providers
 const (
 	EmbeddingProviderOpenAI = "openai"
 	EmbeddingProviderAzure  = "azure"
-	EmbeddingProviderLocal  = "local"
+	EmbeddingProviderOllama = "ollama"
+	EmbeddingProviderLocal  = "local" // Keyless OpenAI-compatible server (e.g. llama.cpp)
 )
 
 // ValidEmbeddingProviders returns all valid embedding provider values
@@ -85,6 +112,7 @@ func ValidEmbeddingProviders() []string {
 	return []string{
 		EmbeddingProviderOpenAI,
 		EmbeddingProviderAzure,
+		EmbeddingProviderOllama,
 		EmbeddingProviderLocal,
 	}
 }
@@ -103,3 +131,9 @@ func isValidType(aType string, validTypes []string) bool {
 func IsValidEmbeddingProvider(provider string) bool {
 	return isValidType(provider, ValidEmbeddingProviders())
 }
+
+// EmbeddingProviderNeedsAPIKey reports whether a provider requires an API key.
+// Ollama and local servers run offline without credentials.
+func EmbeddingProviderNeedsAPIKey(provider string) bool {
+	return provider == EmbeddingProviderOpenAI || provider == EmbeddingProviderAzure
+}
@@ -0,0 +1,166 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package database
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// AccessStatsFile is where a repository's memory access stats are exported,
+// relative to the repository. Everything else in the per-user database is
+// rebuilt from the markdown files, so this is the only part git carries.
+const AccessStatsFile = ".medha/access.jsonl"
+
+// AccessStat is one memory's line in the access stats export
+type AccessStat struct {
+	Slug           string    `json:"slug"`
+	AccessCount    int       `json:"access_count"`
+	LastAccessedAt time.Time `json:"last_accessed_at"`
+}
+
+// GetAccessStatsPath returns the path of a repository's access stats export
+func GetAccessStatsPath(repoPath string) string {
+	return filepath.Join(repoPath, filepath.FromSlash(AccessStatsFile))
+}
+
+// ExportAccessStats writes the access stats of every accessed memory, sorted
+// by slug, so unchanged stats give an unchanged file
+func ExportAccessStats(db *gorm.DB, repoPath string) error {
+	var memories []UserMemory
+	if err := db.Unscoped().Where("access_count > 0").Order("slug").Find(&memories).Error; err != nil {
+		return fmt.Errorf("failed to read access stats: %w", err)
+	}
+
+	stats := make([]AccessStat, 0, len(memories))
+	for _, mem := range memories {
+		stats = append(stats, AccessStat{Slug: mem.Slug, AccessCount: mem.AccessCount, LastAccessedAt: mem.LastAccessedAt.UTC()})
+	}
+
+	path := GetAccessStatsPath(repoPath)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create .medha directory: %w", err)
+	}
+	if err := os.WriteFile(path, FormatAccessStats(stats), 0644); err != nil {
+		return fmt.Errorf("failed to write access stats: %w", err)
+	}
+	return nil
+}
+
+// ImportAccessStats copies an exported file's access stats onto the memories
+// in the database. A missing file imports nothing.
+func ImportAccessStats(db *gorm.DB, repoPath string) error {
+	data, err := os.ReadFile(GetAccessStatsPath(repoPath))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read access stats: %w", err)
+	}
+	stats, err := ParseAccessStats(data)
+	if err != nil {
+		return err
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		for _, stat := range stats {
+			err := tx.Unscoped().Model(&UserMemory{}).Where("slug = ?", stat.Slug).UpdateColumns(map[string]interface{}{
+				"access_count":     stat.AccessCount,
+				"last_accessed_at": stat.LastAccessedAt,
+			}).Error
+			if err != nil {
+				return fmt.Errorf("failed to import access stats for %s: %w", stat.Slug, err)
+			}
+		}
+		return nil
+	})
+}
+
+// ParseAccessStats reads an access stats export
+func ParseAccessStats(data []byte) ([]AccessStat, error) {
+	var stats []AccessStat
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for line := 1; scanner.Scan(); line++ {
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+		var stat AccessStat
+		if err := json.Unmarshal(scanner.Bytes(), &stat); err != nil {
+			return nil, fmt.Errorf("invalid access stats on line %d: %w", line, err)
+		}
+		stats = append(stats, stat)
+	}
+	return stats, scanner.Err()
+}
+
+// FormatAccessStats writes access stats as JSON lines sorted by slug
+func FormatAccessStats(stats []AccessStat) []byte {
+	sorted := append([]AccessStat{}, stats...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Slug < sorted[j].Slug })
+
+	var buf bytes.Buffer
+	for _, stat := range sorted {
+		line, _ := json.Marshal(stat)
+		buf.Write(line)
+		buf.WriteByte('\n')
+	}
+	return buf.Bytes()
+}
+
+// MergeAccessStats merges two machines' exports: access counts add what each
+// side recorded since base, and the later access time wins
+func MergeAccessStats(base, ours, theirs []byte) ([]byte, error) {
+	bySlug := func(data []byte) (map[string]AccessStat, error) {
+		stats, err := ParseAccessStats(data)
+		if err != nil {
+			return nil, err
+		}
+		result := make(map[string]AccessStat, len(stats))
+		for _, stat := range stats {
+			result[stat.Slug] = stat
+		}
+		return result, nil
+	}
+	baseStats, err := bySlug(base)
+	if err != nil {
+		return nil, err
+	}
+	ourStats, err := bySlug(ours)
+	if err != nil {
+		return nil, err
+	}
+	theirStats, err := bySlug(theirs)
+	if err != nil {
+		return nil, err
+	}
+
+	merged := make(map[string]AccessStat, len(ourStats)+len(theirStats))
+	for slug, stat := range ourStats {
+		merged[slug] = stat
+	}
+	for slug, their := range theirStats {
+		our := merged[slug]
+		count := our.AccessCount + their.AccessCount - baseStats[slug].AccessCount
+		stat := AccessStat{Slug: slug, AccessCount: max(count, our.AccessCount, their.AccessCount), LastAccessedAt: our.LastAccessedAt}
+		if their.LastAccessedAt.After(stat.LastAccessedAt) {
+			stat.LastAccessedAt = their.LastAccessedAt
+		}
+		merged[slug] = stat
+	}
+
+	stats := make([]AccessStat, 0, len(merged))
+	for _, stat := range merged {
+		stats = append(stats, stat)
+	}
+	return FormatAccessStats(stats), nil
+}
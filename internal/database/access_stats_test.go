@@ -0,0 +1,81 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package database
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// openUserTestDB opens a migrated per-user database
+func openUserTestDB(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "user.db")), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	require.NoError(t, err)
+	require.NoError(t, MigrateUserDB(db))
+	return db
+}
+
+func TestAccessStats_ExportImport(t *testing.T) {
+	repoPath := t.TempDir()
+	accessed := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	db := openUserTestDB(t)
+	require.NoError(t, db.Create(&UserMemory{Slug: "read", FilePath: "read.md", AccessCount: 3, LastAccessedAt: accessed}).Error)
+	require.NoError(t, db.Create(&UserMemory{Slug: "unread", FilePath: "unread.md"}).Error)
+	require.NoError(t, ExportAccessStats(db, repoPath))
+
+	data, err := os.ReadFile(GetAccessStatsPath(repoPath))
+	require.NoError(t, err)
+	assert.Equal(t, "{\"slug\":\"read\",\"access_count\":3,\"last_accessed_at\":\"2026-03-01T12:00:00Z\"}\n", string(data))
+
+	// A rebuilt database gets the stats back
+	rebuilt := openUserTestDB(t)
+	require.NoError(t, rebuilt.Create(&UserMemory{Slug: "read", FilePath: "read.md"}).Error)
+	require.NoError(t, ImportAccessStats(rebuilt, repoPath))
+
+	var mem UserMemory
+	require.NoError(t, rebuilt.Where("slug = ?", "read").First(&mem).Error)
+	assert.Equal(t, 3, mem.AccessCount)
+	assert.True(t, accessed.Equal(mem.LastAccessedAt))
+
+	// Nothing exported yet is not an error
+	assert.NoError(t, ImportAccessStats(rebuilt, t.TempDir()))
+}
+
+func TestMergeAccessStats(t *testing.T) {
+	at := func(day int) time.Time { return time.Date(2026, 3, day, 0, 0, 0, 0, time.UTC) }
+	base := FormatAccessStats([]AccessStat{{Slug: "shared", AccessCount: 2, LastAccessedAt: at(1)}})
+	ours := FormatAccessStats([]AccessStat{
+		{Slug: "shared", AccessCount: 5, LastAccessedAt: at(3)},
+		{Slug: "local", AccessCount: 1, LastAccessedAt: at(2)},
+	})
+	theirs := FormatAccessStats([]AccessStat{
+		{Slug: "shared", AccessCount: 4, LastAccessedAt: at(4)},
+		{Slug: "remote", AccessCount: 7, LastAccessedAt: at(2)},
+	})
+
+	merged, err := MergeAccessStats(base, ours, theirs)
+	require.NoError(t, err)
+	stats, err := ParseAccessStats(merged)
+	require.NoError(t, err)
+	assert.Equal(t, []AccessStat{
+		{Slug: "local", AccessCount: 1, LastAccessedAt: at(2)},
+		{Slug: "remote", AccessCount: 7, LastAccessedAt: at(2)},
+		{Slug: "shared", AccessCount: 7, LastAccessedAt: at(4)},
+	}, stats)
+
+	_, err = MergeAccessStats(base, []byte("not json\n"), theirs)
+	assert.Error(t, err)
+}
@@ -85,7 +85,10 @@ func TestMigrate(t *testing.T) {
 	tables := []string{
 		"medha_users",
 		"medha_auth_tokens",
+		"medha_api_tokens",
 		"medha_git_repos",
+		"medha_spaces",
+		"medha_space_members",
 		"medha_memories",
 		"medha_memory_associations",
 		"medha_tags",
@@ -105,7 +108,10 @@ func TestModels_TableNames(t *testing.T) {
 	}{
 		{MedhaUser{}, "medha_users"},
 		{MedhaAuthToken{}, "medha_auth_tokens"},
+		{MedhaAPIToken{}, "medha_api_tokens"},
 		{MedhaGitRepo{}, "medha_git_repos"},
+		{MedhaSpace{}, "medha_spaces"},
+		{MedhaSpaceMember{}, "medha_space_members"},
 		{MedhaMemory{}, "medha_memories"},
 		{MedhaMemoryAssociation{}, "medha_memory_associations"},
 		{MedhaTag{}, "medha_tags"},
@@ -120,8 +126,14 @@ func TestModels_TableNames(t *testing.T) {
 				actualName = m.TableName()
 			case MedhaAuthToken:
 				actualName = m.TableName()
+			case MedhaAPIToken:
+				actualName = m.TableName()
 			case MedhaGitRepo:
 				actualName = m.TableName()
+			case MedhaSpace:
+				actualName = m.TableName()
+			case MedhaSpaceMember:
+				actualName = m.TableName()
 			case MedhaMemory:
 				actualName = m.TableName()
 			case MedhaMemoryAssociation:
@@ -159,6 +171,28 @@ func TestIsValidAssociationType(t *testing.T) {
 	}
 }
 
+func TestSpaceRoleAllows(t *testing.T) {
+	tests := []struct {
+		role   string
+		needed string
+		allows bool
+	}{
+		{SpaceRoleReader, SpaceRoleReader, true},
+		{SpaceRoleReader, SpaceRoleWriter, false},
+		{SpaceRoleWriter, SpaceRoleReader, true},
+		{SpaceRoleWriter, SpaceRoleAdmin, false},
+		{SpaceRoleAdmin, SpaceRoleWriter, true},
+		{"owner", SpaceRoleReader, false},
+		{SpaceRoleAdmin, "owner", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.role+"/"+tt.needed, func(t *testing.T) {
+			assert.Equal(t, tt.allows, SpaceRoleAllows(tt.role, tt.needed))
+		})
+	}
+}
+
 func TestCreateIndexes(t *testing.T) {
 	tempDir := t.TempDir()
 	dbPath := filepath.Join(tempDir, "test.db")
@@ -0,0 +1,251 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package database
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/tejzpr/medha-mcp/internal/memory"
+	"gorm.io/gorm"
+)
+
+// UserFTSTable is the full-text index over memory title, body, tags and annotations
+const UserFTSTable = "memories_fts"
+
+// Full-text engines. FTS5 needs the sqlite_fts5 build tag; FTS4 is always
+// compiled into go-sqlite3 and supports the same query syntax.
+const (
+	FTSEngine5 = "fts5"
+	FTSEngine4 = "fts4"
+)
+
+// BM25 column weights: slug, title, body, tags, annotations
+var ftsColumnWeights = []float64{0, 10, 1, 5, 2}
+
+// FTSDocument is a memory's entry in the full-text index
+type FTSDocument struct {
+	Slug        string
+	Title       string
+	Body        string
+	Tags        []string
+	Annotations []string
+}
+
+// FTSHit is a full-text match with its BM25 score (higher is better)
+type FTSHit struct {
+	Slug  string
+	Score float64
+}
+
+// MemoryFTSDocument builds the full-text index entry for a parsed memory
+func MemoryFTSDocument(slug string, mem *memory.Memory) FTSDocument {
+	annotations := make([]string, len(mem.Annotations))
+	for i, a := range mem.Annotations {
+		annotations[i] = a.Content
+	}
+	return FTSDocument{
+		Slug:        slug,
+		Title:       mem.Title,
+		Body:        mem.Content,
+		Tags:        mem.Tags,
+		Annotations: annotations,
+	}
+}
+
+// IsFTS5Available checks if SQLite was built with FTS5
+func IsFTS5Available(db *gorm.DB) bool {
+	var used int
+	err := db.Raw("SELECT sqlite_compileoption_used('ENABLE_FTS5')").Scan(&used).Error
+	return err == nil && used == 1
+}
+
+// CreateUserFTS creates the full-text index if it doesn't exist, using FTS5
+// when available. The porter tokenizer lets "caching" match "cache".
+func CreateUserFTS(db *gorm.DB) error {
+	engine := FTSEngine4
+	if IsFTS5Available(db) {
+		engine = FTSEngine5
+	}
+	return createUserFTS(db, engine)
+}
+
+// createUserFTS creates the full-text index with a specific engine
+func createUserFTS(db *gorm.DB, engine string) error {
+	var sql string
+	switch engine {
+	case FTSEngine5:
+		sql = `CREATE VIRTUAL TABLE IF NOT EXISTS memories_fts USING fts5(
+			slug UNINDEXED, title, body, tags, annotations,
+			tokenize = 'porter unicode61'
+		)`
+	case FTSEngine4:
+		sql = `CREATE VIRTUAL TABLE IF NOT EXISTS memories_fts USING fts4(
+			slug, title, body, tags, annotations,
+			notindexed=slug, tokenize=porter
+		)`
+	default:
+		return fmt.Errorf("unsupported full-text engine: %s", engine)
+	}
+
+	if err := db.Exec(sql).Error; err != nil {
+		return fmt.Errorf("failed to create full-text index: %w", err)
+	}
+	return nil
+}
+
+// UserFTSEngine returns the engine backing the full-text index, or "" if
+// the index doesn't exist
+func UserFTSEngine(db *gorm.DB) string {
+	var sql string
+	db.Raw("SELECT sql FROM sqlite_master WHERE type = 'table' AND name = ?", UserFTSTable).Scan(&sql)
+	sql = strings.ToLower(sql)
+	switch {
+	case strings.Contains(sql, "using fts5"):
+		return FTSEngine5
+	case strings.Contains(sql, "using fts4"):
+		return FTSEngine4
+	}
+	return ""
+}
+
+// IndexMemoryFTS adds or replaces a memory's full-text index entry
+func IndexMemoryFTS(db *gorm.DB, doc FTSDocument) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("DELETE FROM memories_fts WHERE slug = ?", doc.Slug).Error; err != nil {
+			return err
+		}
+		return tx.Exec(
+			"INSERT INTO memories_fts (slug, title, body, tags, annotations) VALUES (?, ?, ?, ?, ?)",
+			doc.Slug, doc.Title, doc.Body, strings.Join(doc.Tags, " "), strings.Join(doc.Annotations, "\n"),
+		).Error
+	})
+}
+
+// DeleteMemoryFTS removes a memory from the full-text index
+func DeleteMemoryFTS(db *gorm.DB, slug string) error {
+	return db.Exec("DELETE FROM memories_fts WHERE slug = ?", slug).Error
+}
+
+// ClearUserFTS removes every entry from the full-text index
+func ClearUserFTS(db *gorm.DB) error {
+	return db.Exec("DELETE FROM memories_fts").Error
+}
+
+// UnindexedMemorySlugs returns live memories missing from the full-text index
+func UnindexedMemorySlugs(db *gorm.DB) ([]string, error) {
+	var slugs []string
+	err := db.Raw(`SELECT slug FROM memories
+		WHERE deleted_at IS NULL AND slug NOT IN (SELECT slug FROM memories_fts)
+		ORDER BY slug`).Scan(&slugs).Error
+	return slugs, err
+}
+
+// SearchFTS runs a full-text query and returns up to limit matches, best first.
+// See CompileFTSQuery for the supported syntax.
+func SearchFTS(db *gorm.DB, query string, limit int) ([]FTSHit, error) {
+	match := CompileFTSQuery(query)
+	if match == "" {
+		return nil, nil
+	}
+
+	switch UserFTSEngine(db) {
+	case FTSEngine5:
+		return searchFTS5(db, match, limit)
+	case FTSEngine4:
+		return searchFTS4(db, match, limit)
+	}
+	return nil, fmt.Errorf("full-text index not found")
+}
+
+// searchFTS5 ranks matches with FTS5's built-in bm25()
+func searchFTS5(db *gorm.DB, match string, limit int) ([]FTSHit, error) {
+	weights := make([]string, len(ftsColumnWeights))
+	for i, w := range ftsColumnWeights {
+		weights[i] = fmt.Sprintf("%g", w)
+	}
+
+	// bm25() is negative, with the best match most negative
+	sql := fmt.Sprintf(`SELECT slug, -bm25(memories_fts, %s) AS score
+		FROM memories_fts WHERE memories_fts MATCH ?
+		ORDER BY score DESC, slug LIMIT ?`, strings.Join(weights, ", "))
+
+	var hits []FTSHit
+	if err := db.Raw(sql, match, limit).Scan(&hits).Error; err != nil {
+		return nil, fmt.Errorf("full-text search failed: %w", err)
+	}
+	return hits, nil
+}
+
+// searchFTS4 ranks matches with BM25 computed from matchinfo()
+func searchFTS4(db *gorm.DB, match string, limit int) ([]FTSHit, error) {
+	var rows []struct {
+		Slug string
+		Info []byte
+	}
+	err := db.Raw(`SELECT slug, matchinfo(memories_fts, 'pcnalx') AS info
+		FROM memories_fts WHERE memories_fts MATCH ?`, match).Scan(&rows).Error
+	if err != nil {
+		return nil, fmt.Errorf("full-text search failed: %w", err)
+	}
+
+	hits := make([]FTSHit, len(rows))
+	for i, row := range rows {
+		hits[i] = FTSHit{Slug: row.Slug, Score: bm25FromMatchinfo(row.Info)}
+	}
+
+	sort.Slice(hits, func(i, j int) bool {
+		if hits[i].Score != hits[j].Score {
+			return hits[i].Score > hits[j].Score
+		}
+		return hits[i].Slug < hits[j].Slug
+	})
+	if len(hits) > limit {
+		hits = hits[:limit]
+	}
+	return hits, nil
+}
+
+// bm25FromMatchinfo scores a row from FTS4 matchinfo 'pcnalx' output, using
+// the same parameters and column weights as FTS5's bm25()
+func bm25FromMatchinfo(info []byte) float64 {
+	const k1, b = 1.2, 0.75
+
+	values := make([]uint32, len(info)/4)
+	for i := range values {
+		values[i] = binary.NativeEndian.Uint32(info[i*4:])
+	}
+	if len(values) < 3 {
+		return 0
+	}
+
+	phrases, cols, rows := int(values[0]), int(values[1]), float64(values[2])
+	avgLen := values[3 : 3+cols]
+	rowLen := values[3+cols : 3+2*cols]
+	hits := values[3+2*cols:]
+
+	score := 0.0
+	for p := 0; p < phrases; p++ {
+		for c := 0; c < cols && c < len(ftsColumnWeights); c++ {
+			x := hits[3*(p*cols+c):]
+			tf, docs := float64(x[0]), float64(x[2])
+			if tf == 0 || ftsColumnWeights[c] == 0 {
+				continue
+			}
+
+			avg := float64(avgLen[c])
+			if avg == 0 {
+				avg = 1
+			}
+			idf := math.Log(1 + (rows-docs+0.5)/(docs+0.5))
+			norm := tf * (k1 + 1) / (tf + k1*(1-b+b*float64(rowLen[c])/avg))
+			score += ftsColumnWeights[c] * idf * norm
+		}
+	}
+	return score
+}
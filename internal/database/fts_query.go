@@ -0,0 +1,158 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package database
+
+import (
+	"strings"
+	"unicode"
+)
+
+// ftsItem is one element of a compiled full-text query
+type ftsItem struct {
+	kind string // "term", "op", "(" or ")"
+	text string
+}
+
+// CompileFTSQuery turns user search text into a safe MATCH expression.
+//
+// Supported syntax, the same for FTS5 and FTS4:
+//   - "exact phrase"
+//   - prefix*
+//   - AND, OR, NOT      (uppercase) and parentheses
+//
+// Plain text without any of these is treated as a question: its words are
+// ORed so that memories matching more of them rank higher instead of
+// requiring every word. Punctuation never reaches SQLite, so any input
+// compiles to a valid query. Returns "" when there is nothing to search.
+func CompileFTSQuery(text string) string {
+	var items []ftsItem
+	explicit := false
+
+	for i := 0; i < len(text); {
+		c := text[i]
+		switch {
+		case c == '"':
+			end := strings.IndexByte(text[i+1:], '"')
+			phrase := text[i+1:]
+			next := len(text)
+			if end >= 0 {
+				phrase = text[i+1 : i+1+end]
+				next = i + end + 2
+			}
+			if words := ftsWords(phrase); len(words) > 0 {
+				items = append(items, ftsItem{"term", `"` + strings.Join(words, " ") + `"`})
+			}
+			explicit = true
+			i = next
+
+		case c == '(' || c == ')':
+			items = append(items, ftsItem{string(c), string(c)})
+			explicit = true
+			i++
+
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+
+		default:
+			end := strings.IndexAny(text[i:], " \t\n\r\"()")
+			if end < 0 {
+				end = len(text) - i
+			}
+			word := text[i : i+end]
+			i += end
+
+			if word == "AND" || word == "OR" || word == "NOT" {
+				items = append(items, ftsItem{"op", word})
+				explicit = true
+				continue
+			}
+
+			prefix := strings.HasSuffix(word, "*")
+			words := ftsWords(word)
+			switch {
+			case len(words) == 0:
+			case len(words) == 1 && prefix:
+				items = append(items, ftsItem{"term", words[0] + "*"})
+				explicit = true
+			case len(words) == 1:
+				items = append(items, ftsItem{"term", words[0]})
+			default:
+				// "api-keys" is searched as the phrase "api keys"
+				items = append(items, ftsItem{"term", `"` + strings.Join(words, " ") + `"`})
+			}
+		}
+	}
+
+	if !explicit {
+		terms := make([]string, 0, len(items))
+		for _, item := range items {
+			terms = append(terms, item.text)
+		}
+		return strings.Join(terms, " OR ")
+	}
+
+	items = balanceFTSItems(items)
+	parts := make([]string, len(items))
+	for i, item := range items {
+		parts[i] = item.text
+	}
+	return strings.Join(parts, " ")
+}
+
+// balanceFTSItems drops unmatched parentheses, empty groups and operators
+// without an operand on both sides until the expression is well formed
+func balanceFTSItems(items []ftsItem) []ftsItem {
+	// Match parentheses
+	var balanced []ftsItem
+	depth := 0
+	for _, item := range items {
+		if item.kind == ")" {
+			if depth == 0 {
+				continue
+			}
+			depth--
+		} else if item.kind == "(" {
+			depth++
+		}
+		balanced = append(balanced, item)
+	}
+	for ; depth > 0; depth-- {
+		balanced = append(balanced, ftsItem{")", ")"})
+	}
+	items = balanced
+
+	for changed := true; changed; {
+		changed = false
+		var kept []ftsItem
+		for i, item := range items {
+			prev, next := "", ""
+			if len(kept) > 0 {
+				prev = kept[len(kept)-1].kind
+			}
+			if i+1 < len(items) {
+				next = items[i+1].kind
+			}
+
+			switch {
+			case item.kind == "op" && (prev == "" || prev == "op" || prev == "(" || next == "" || next == "op" || next == ")"):
+				changed = true
+			case item.kind == ")" && prev == "(":
+				kept = kept[:len(kept)-1] // Empty group
+				changed = true
+			default:
+				kept = append(kept, item)
+			}
+		}
+		items = kept
+	}
+	return items
+}
+
+// ftsWords lowercases text and splits it into letter/digit runs
+func ftsWords(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
@@ -0,0 +1,150 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package database
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tejzpr/medha-mcp/internal/memory"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// openFTSTestDB opens a user database whose full-text index uses engine
+func openFTSTestDB(t *testing.T, engine string) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "fts.db")), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	require.NoError(t, err)
+	require.NoError(t, MigrateUserDB(db))
+
+	if engine == FTSEngine5 && !IsFTS5Available(db) {
+		t.Skip("SQLite built without FTS5 (build with -tags sqlite_fts5)")
+	}
+	require.NoError(t, createUserFTS(db, engine))
+	return db
+}
+
+// indexFTSTestDocs stores a small corpus in the full-text index
+func indexFTSTestDocs(t *testing.T, db *gorm.DB) {
+	docs := []FTSDocument{
+		{Slug: "cache-design", Title: "Cache design", Body: "Redis sits in front of Postgres", Tags: []string{"performance"}},
+		{Slug: "meeting", Title: "Weekly meeting", Body: "We talked about caching and the database migration"},
+		{Slug: "auth", Title: "Authentication", Body: "OAuth2 with refresh tokens", Annotations: []string{"Moved to passkeys in March"}},
+		{Slug: "lunch", Title: "Lunch", Body: "Tacos", Tags: []string{"food"}},
+	}
+	for _, doc := range docs {
+		require.NoError(t, IndexMemoryFTS(db, doc))
+	}
+}
+
+// ftsSlugs returns the slugs of hits in order
+func ftsSlugs(hits []FTSHit) []string {
+	slugs := make([]string, len(hits))
+	for i, h := range hits {
+		slugs[i] = h.Slug
+	}
+	return slugs
+}
+
+func TestSearchFTS(t *testing.T) {
+	for _, engine := range []string{FTSEngine5, FTSEngine4} {
+		t.Run(engine, func(t *testing.T) {
+			db := openFTSTestDB(t, engine)
+			indexFTSTestDocs(t, db)
+			assert.Equal(t, engine, UserFTSEngine(db))
+
+			search := func(query string) []string {
+				hits, err := SearchFTS(db, query, 10)
+				require.NoError(t, err, query)
+				return ftsSlugs(hits)
+			}
+
+			// Stemming: "cached" and "caching" both match "cache"
+			assert.Equal(t, []string{"cache-design", "meeting"}, search("cached"), "title matches rank first")
+			assert.Equal(t, []string{"cache-design"}, search("performance"), "tags are indexed")
+			assert.Equal(t, []string{"auth"}, search("passkeys"), "annotations are indexed")
+
+			// Questions match any word, best match first
+			assert.Equal(t, []string{"lunch", "meeting"}, search("what did we eat for lunch?"))
+
+			// Phrase, prefix and boolean operators
+			assert.Equal(t, []string{"meeting"}, search(`"database migration"`))
+			assert.Empty(t, search(`"migration database"`))
+			assert.Equal(t, []string{"auth"}, search("authent*"))
+			assert.Equal(t, []string{"meeting"}, search("caching AND database"))
+			assert.Equal(t, []string{"cache-design"}, search("cache NOT meeting NOT database"))
+			assert.ElementsMatch(t, []string{"auth", "lunch"}, search("tacos OR oauth2"))
+			assert.ElementsMatch(t, []string{"meeting", "lunch"}, search("(database OR tacos) NOT redis"))
+
+			// Re-indexing replaces the entry; deleting removes it
+			require.NoError(t, IndexMemoryFTS(db, FTSDocument{Slug: "lunch", Title: "Lunch", Body: "Ramen"}))
+			assert.Empty(t, search("tacos"))
+			assert.Equal(t, []string{"lunch"}, search("ramen"))
+
+			require.NoError(t, DeleteMemoryFTS(db, "lunch"))
+			assert.Empty(t, search("ramen"))
+
+			require.NoError(t, ClearUserFTS(db))
+			assert.Empty(t, search("cache"))
+		})
+	}
+}
+
+func TestUnindexedMemorySlugs(t *testing.T) {
+	db := openFTSTestDB(t, FTSEngine4)
+	require.NoError(t, db.Create(&UserMemory{Slug: "indexed", Title: "Indexed", FilePath: "a.md"}).Error)
+	require.NoError(t, db.Create(&UserMemory{Slug: "missing", Title: "Missing", FilePath: "b.md"}).Error)
+	require.NoError(t, IndexMemoryFTS(db, FTSDocument{Slug: "indexed", Title: "Indexed"}))
+
+	slugs, err := UnindexedMemorySlugs(db)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"missing"}, slugs)
+}
+
+func TestMemoryFTSDocument(t *testing.T) {
+	doc := MemoryFTSDocument("s", &memory.Memory{
+		Title:       "T",
+		Content:     "Body",
+		Tags:        []string{"a", "b"},
+		Annotations: []memory.Annotation{{Type: "context", Content: "note"}},
+	})
+	assert.Equal(t, FTSDocument{Slug: "s", Title: "T", Body: "Body", Tags: []string{"a", "b"}, Annotations: []string{"note"}}, doc)
+}
+
+func TestCompileFTSQuery(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"", ""},
+		{"  ?! ", ""},
+		{"caching", "caching"},
+		{"What did we decide about caching?", "what OR did OR we OR decide OR about OR caching"},
+		{"api-keys rotation", `"api keys" OR rotation`},
+		{`"refresh token" rotation`, `"refresh token" rotation`},
+		{`"unterminated phrase`, `"unterminated phrase"`},
+		{"auth*", "auth*"},
+		{"cache AND (redis OR memcached)", "cache AND ( redis OR memcached )"},
+		{"NOT draft", "draft"},
+		{"cache AND", "cache"},
+		{"cache OR OR redis", "cache OR redis"},
+		{"(cache", "( cache )"},
+		{"cache) redis (", "cache redis"},
+		{"a AND () b", "a AND b"},
+		{"title:secret", `"title secret"`},
+		{"and or not", "and OR or OR not"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			assert.Equal(t, tt.expected, CompileFTSQuery(tt.input))
+		})
+	}
+}
@@ -0,0 +1,43 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package database
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// indexStateLastCommit names the commit the per-user index was last built from
+const indexStateLastCommit = "last_indexed_commit"
+
+// UserIndexState records how far the per-user index has been built
+type UserIndexState struct {
+	Key       string    `gorm:"primaryKey;column:key" json:"key"`
+	Value     string    `gorm:"not null" json:"value"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// TableName specifies the table name for UserIndexState
+func (UserIndexState) TableName() string {
+	return "index_state"
+}
+
+// GetLastIndexedCommit returns the commit the per-user index was last built
+// from, or "" if it was never recorded
+func GetLastIndexedCommit(db *gorm.DB) (string, error) {
+	var state UserIndexState
+	err := db.Where("key = ?", indexStateLastCommit).Limit(1).Find(&state).Error
+	return state.Value, err
+}
+
+// SetLastIndexedCommit records the commit the per-user index was built from
+func SetLastIndexedCommit(db *gorm.DB, commit string) error {
+	return db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "key"}},
+		DoUpdates: clause.AssignmentColumns([]string{"value", "updated_at"}),
+	}).Create(&UserIndexState{Key: indexStateLastCommit, Value: commit}).Error
+}
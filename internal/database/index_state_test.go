@@ -0,0 +1,26 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package database
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLastIndexedCommit(t *testing.T) {
+	db := openUserTestDB(t)
+
+	commit, err := GetLastIndexedCommit(db)
+	require.NoError(t, err)
+	assert.Empty(t, commit)
+
+	require.NoError(t, SetLastIndexedCommit(db, "abc123"))
+	require.NoError(t, SetLastIndexedCommit(db, "def456"))
+	commit, err = GetLastIndexedCommit(db)
+	require.NoError(t, err)
+	assert.Equal(t, "def456", commit)
+}
@@ -157,6 +157,11 @@ func OpenUserDB(repoPath string) (*gorm.DB, error) {
 		return nil, fmt.Errorf("failed to create user indexes: %w", err)
 	}
 
+	// Create full-text index for recall
+	if err := CreateUserFTS(db); err != nil {
+		return nil, err
+	}
+
 	return db, nil
 }
 
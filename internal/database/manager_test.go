@@ -9,6 +9,7 @@ import (
 	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -266,7 +267,7 @@ func TestMigrateUserDB(t *testing.T) {
 	}()
 
 	// Verify tables exist
-	tables := []string{"memories", "associations", "tags", "memory_tags", "annotations"}
+	tables := []string{"memories", "associations", "tags", "memory_tags", "annotations", "memory_locks"}
 	for _, table := range tables {
 		hasTable := userDB.Migrator().HasTable(table)
 		assert.True(t, hasTable, "Table %s should exist", table)
@@ -288,9 +289,52 @@ func TestMigrateSystemDB(t *testing.T) {
 	defer mgr.Close()
 
 	// Verify system tables exist
-	tables := []string{"medha_users", "medha_auth_tokens", "medha_git_repos"}
+	tables := []string{"medha_users", "medha_auth_tokens", "medha_api_tokens", "medha_git_repos", "medha_spaces", "medha_space_members"}
 	for _, table := range tables {
 		hasTable := mgr.SystemDB().Migrator().HasTable(table)
 		assert.True(t, hasTable, "Table %s should exist", table)
 	}
 }
+
+func TestMigrateSystemDB_DropsPlaintextTokens(t *testing.T) {
+	tmpDir := t.TempDir()
+	cfg := &Config{
+		Type:       "sqlite",
+		SQLitePath: filepath.Join(tmpDir, "system.db"),
+		LogLevel:   logger.Silent,
+	}
+
+	// A database from before tokens were hashed
+	type legacyAuthToken struct {
+		ID           uint      `gorm:"primaryKey"`
+		UserID       uint      `gorm:"index;not null"`
+		AccessToken  string    `gorm:"type:text;not null"`
+		RefreshToken string    `gorm:"type:text"`
+		ExpiresAt    time.Time `gorm:"not null"`
+		CreatedAt    time.Time
+		UpdatedAt    time.Time
+	}
+	legacy, err := Connect(cfg)
+	require.NoError(t, err)
+	require.NoError(t, legacy.Table("medha_auth_tokens").AutoMigrate(&legacyAuthToken{}))
+	require.NoError(t, legacy.Table("medha_auth_tokens").Create(&legacyAuthToken{
+		UserID:       1,
+		AccessToken:  "plaintext-access",
+		RefreshToken: "plaintext-refresh",
+		ExpiresAt:    time.Now().Add(24 * time.Hour),
+	}).Error)
+	require.NoError(t, Close(legacy))
+
+	mgr, err := NewManager(cfg)
+	require.NoError(t, err)
+	defer mgr.Close()
+
+	db := mgr.SystemDB()
+	assert.False(t, db.Migrator().HasColumn(&MedhaAuthToken{}, "access_token"))
+	assert.False(t, db.Migrator().HasColumn(&MedhaAuthToken{}, "refresh_token"))
+	assert.True(t, db.Migrator().HasColumn(&MedhaAuthToken{}, "access_token_hash"))
+
+	var count int64
+	db.Model(&MedhaAuthToken{}).Count(&count)
+	assert.Equal(t, int64(0), count)
+}
@@ -16,7 +16,10 @@ func AllModels() []interface{} {
 	return []interface{}{
 		&MedhaUser{},
 		&MedhaAuthToken{},
+		&MedhaAPIToken{},
 		&MedhaGitRepo{},
+		&MedhaSpace{},
+		&MedhaSpaceMember{},
 		&MedhaMemory{},
 		&MedhaMemoryAssociation{},
 		&MedhaTag{},
@@ -28,6 +31,9 @@ func AllModels() []interface{} {
 // Migrate runs database migrations for all models (v1 backward compatibility)
 // In v2, the system DB uses MigrateSystemDB and per-user DBs use MigrateUserDB
 func Migrate(db *gorm.DB) error {
+	if err := migratePlaintextTokens(db); err != nil {
+		return err
+	}
 	if err := db.AutoMigrate(AllModels()...); err != nil {
 		return fmt.Errorf("failed to run migrations: %w", err)
 	}
@@ -43,7 +49,10 @@ func DropAllTables(db *gorm.DB) error {
 		&MedhaTag{},
 		&MedhaMemoryAssociation{},
 		&MedhaMemory{},
+		&MedhaSpaceMember{},
+		&MedhaSpace{},
 		&MedhaGitRepo{},
+		&MedhaAPIToken{},
 		&MedhaAuthToken{},
 		&MedhaUser{},
 	}
@@ -5,6 +5,7 @@
 package database
 
 import (
+	"slices"
 	"time"
 
 	"gorm.io/gorm"
@@ -25,15 +26,24 @@ func (MedhaUser) TableName() string {
 	return "medha_users"
 }
 
-// MedhaAuthToken represents authentication tokens for users
+// MedhaAuthToken represents authentication tokens for users. Only keyed
+// hashes of the tokens are stored, with a short prefix of each to find the
+// row; the tokens themselves are set only on the value returned when they are
+// issued. Refreshing replaces a token with a new one in the same family.
 type MedhaAuthToken struct {
-	ID           uint      `gorm:"primaryKey" json:"id"`
-	UserID       uint      `gorm:"index;not null" json:"user_id"`
-	AccessToken  string    `gorm:"type:text;not null" json:"access_token"`
-	RefreshToken string    `gorm:"type:text" json:"refresh_token"`
-	ExpiresAt    time.Time `gorm:"not null" json:"expires_at"`
-	CreatedAt    time.Time `json:"created_at"`
-	UpdatedAt    time.Time `json:"updated_at"`
+	ID                 uint       `gorm:"primaryKey" json:"id"`
+	UserID             uint       `gorm:"index;not null" json:"user_id"`
+	FamilyID           string     `gorm:"index;not null" json:"family_id"`
+	AccessTokenPrefix  string     `gorm:"index;not null" json:"access_token_prefix"`
+	AccessTokenHash    string     `gorm:"not null" json:"-"`
+	RefreshTokenPrefix string     `gorm:"index;not null" json:"refresh_token_prefix"`
+	RefreshTokenHash   string     `gorm:"not null" json:"-"`
+	AccessToken        string     `gorm:"-" json:"access_token,omitempty"`
+	RefreshToken       string     `gorm:"-" json:"refresh_token,omitempty"`
+	ExpiresAt          time.Time  `gorm:"not null" json:"expires_at"`
+	RotatedAt          *time.Time `json:"rotated_at,omitempty"` // Set once refreshed; using it again revokes the family
+	CreatedAt          time.Time  `json:"created_at"`
+	UpdatedAt          time.Time  `json:"updated_at"`
 
 	// Foreign key relationship
 	User MedhaUser `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE" json:"-"`
@@ -44,6 +54,33 @@ func (MedhaAuthToken) TableName() string {
 	return "medha_auth_tokens"
 }
 
+// MedhaAPIToken is a named, long-lived token a user creates for scripts and
+// bots. Like MedhaAuthToken only a keyed hash and a prefix are stored. Scopes
+// and Tools are comma-separated; a tool may be called when its scope is
+// granted or it is listed by name.
+type MedhaAPIToken struct {
+	ID          uint       `gorm:"primaryKey" json:"id"`
+	UserID      uint       `gorm:"uniqueIndex:idx_api_tokens_user_name;not null" json:"user_id"`
+	Name        string     `gorm:"uniqueIndex:idx_api_tokens_user_name;not null" json:"name"`
+	TokenPrefix string     `gorm:"index;not null" json:"token_prefix"`
+	TokenHash   string     `gorm:"not null" json:"-"`
+	Token       string     `gorm:"-" json:"token,omitempty"` // Set only when created
+	Scopes      string     `json:"scopes"`
+	Tools       string     `json:"tools"`
+	ExpiresAt   *time.Time `json:"expires_at,omitempty"` // Nil for tokens that never expire
+	LastUsedAt  *time.Time `json:"last_used_at,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+
+	// Foreign key relationship
+	User MedhaUser `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE" json:"-"`
+}
+
+// TableName specifies the table name for MedhaAPIToken
+func (MedhaAPIToken) TableName() string {
+	return "medha_api_tokens"
+}
+
 // MedhaGitRepo represents a git repository for a user
 type MedhaGitRepo struct {
 	ID                uint      `gorm:"primaryKey" json:"id"`
@@ -65,6 +102,44 @@ func (MedhaGitRepo) TableName() string {
 	return "medha_git_repos"
 }
 
+// MedhaSpace is a memory repository shared by a team. Members reach it
+// through the space parameter of the tools, with the access of their role.
+type MedhaSpace struct {
+	ID                uint      `gorm:"primaryKey" json:"id"`
+	Name              string    `gorm:"uniqueIndex;not null" json:"name"`
+	Description       string    `json:"description"`
+	RepoURL           string    `json:"repo_url"`
+	RepoPath          string    `gorm:"not null" json:"repo_path"`
+	PATTokenEncrypted string    `gorm:"type:text" json:"-"` // Never expose in JSON
+	CreatedBy         uint      `gorm:"index" json:"created_by"`
+	CreatedAt         time.Time `json:"created_at"`
+	UpdatedAt         time.Time `json:"updated_at"`
+}
+
+// TableName specifies the table name for MedhaSpace
+func (MedhaSpace) TableName() string {
+	return "medha_spaces"
+}
+
+// MedhaSpaceMember gives a user a role in a space
+type MedhaSpaceMember struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	SpaceID   uint      `gorm:"uniqueIndex:idx_space_members_space_user;not null" json:"space_id"`
+	UserID    uint      `gorm:"uniqueIndex:idx_space_members_space_user;index;not null" json:"user_id"`
+	Role      string    `gorm:"not null" json:"role"` // reader, writer or admin
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	// Foreign key relationships
+	Space MedhaSpace `gorm:"foreignKey:SpaceID;constraint:OnDelete:CASCADE" json:"-"`
+	User  MedhaUser  `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE" json:"-"`
+}
+
+// TableName specifies the table name for MedhaSpaceMember
+func (MedhaSpaceMember) TableName() string {
+	return "medha_space_members"
+}
+
 // MedhaMemory represents a memory stored in the git repository
 type MedhaMemory struct {
 	ID        uint           `gorm:"primaryKey" json:"id"`
@@ -230,3 +305,31 @@ func IsDirectionalType(assocType string) bool {
 		return false
 	}
 }
+
+// SpaceRole constants for space memberships, from least to most access
+const (
+	SpaceRoleReader = "reader" // Recall, history and graph
+	SpaceRoleWriter = "writer" // Also remember, connect, forget, restore, lock and sync
+	SpaceRoleAdmin  = "admin"  // Also manage members
+)
+
+// ValidSpaceRoles returns all valid space roles
+func ValidSpaceRoles() []string {
+	return []string{
+		SpaceRoleReader,
+		SpaceRoleWriter,
+		SpaceRoleAdmin,
+	}
+}
+
+// IsValidSpaceRole checks if a space role is valid
+func IsValidSpaceRole(role string) bool {
+	return isValidType(role, ValidSpaceRoles())
+}
+
+// SpaceRoleAllows reports whether role grants at least the access of needed
+func SpaceRoleAllows(role, needed string) bool {
+	roles := ValidSpaceRoles()
+	have, want := slices.Index(roles, role), slices.Index(roles, needed)
+	return have >= 0 && want >= 0 && have >= want
+}
@@ -5,6 +5,8 @@
 package database
 
 import (
+	"fmt"
+
 	"gorm.io/gorm"
 )
 
@@ -15,15 +17,43 @@ func SystemModels() []interface{} {
 	return []interface{}{
 		&MedhaUser{},
 		&MedhaAuthToken{},
+		&MedhaAPIToken{},
 		&MedhaGitRepo{},
+		&MedhaSpace{},
+		&MedhaSpaceMember{},
 	}
 }
 
 // MigrateSystemDB runs migrations for the system database
 func MigrateSystemDB(db *gorm.DB) error {
+	if err := migratePlaintextTokens(db); err != nil {
+		return err
+	}
 	return db.AutoMigrate(SystemModels()...)
 }
 
+// migratePlaintextTokens drops the plaintext token columns of databases from
+// before tokens were hashed. Their tokens are deleted rather than rehashed,
+// since anyone who could read the database may have copied them; users sign
+// in again.
+func migratePlaintextTokens(db *gorm.DB) error {
+	migrator := db.Migrator()
+	if !migrator.HasTable(&MedhaAuthToken{}) || !migrator.HasColumn(&MedhaAuthToken{}, "access_token") {
+		return nil
+	}
+	if err := db.Exec("DELETE FROM medha_auth_tokens").Error; err != nil {
+		return fmt.Errorf("failed to delete plaintext tokens: %w", err)
+	}
+	for _, column := range []string{"access_token", "refresh_token"} {
+		if migrator.HasColumn(&MedhaAuthToken{}, column) {
+			if err := migrator.DropColumn(&MedhaAuthToken{}, column); err != nil {
+				return fmt.Errorf("failed to drop %s column: %w", column, err)
+			}
+		}
+	}
+	return nil
+}
+
 // CreateSystemIndexes creates indexes for the system database
 func CreateSystemIndexes(db *gorm.DB) error {
 	indexes := []struct {
@@ -7,11 +7,12 @@ package database
 import (
 	"time"
 
+	"github.com/tejzpr/medha-mcp/internal/locking"
 	"gorm.io/gorm"
 )
 
-// UserModels returns all models for the per-user database (memories, associations, tags)
-// The per-user database is stored inside the git repository and syncs with git
+// UserModels returns all models for the per-user database (memories, associations, tags, write locks, index state)
+// The per-user database is stored inside the git repository but kept out of git; it is rebuilt from the memory files
 func UserModels() []interface{} {
 	return []interface{}{
 		&UserMemory{},
@@ -19,6 +20,8 @@ func UserModels() []interface{} {
 		&UserTag{},
 		&UserMemoryTag{},
 		&UserAnnotation{},
+		&locking.MemoryLock{},
+		&UserIndexState{},
 	}
 }
 
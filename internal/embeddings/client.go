@@ -10,6 +10,8 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
+	"strings"
 	"time"
 )
 
@@ -33,19 +35,27 @@ type ModelInfo struct {
 	Provider   string
 }
 
-// OpenAIClient implements the Client interface for OpenAI embeddings
+// OpenAIClient implements the Client interface for OpenAI embeddings.
+// It also serves OpenAI-compatible endpoints such as Azure OpenAI deployments
+// and local llama.cpp servers.
 type OpenAIClient struct {
 	baseURL    string
 	apiKey     string
 	model      string
 	dimensions int
 	httpClient *http.Client
+
+	provider       string // Reported in ModelInfo and error messages
+	endpoint       string // Full embeddings URL; defaults to baseURL + "/embeddings"
+	authHeader     string // Header carrying the API key; defaults to Bearer Authorization
+	sendModel      bool   // Azure deployments take the model from the URL
+	sendDimensions bool   // Only OpenAI's v3 models accept a dimensions parameter
 }
 
 // OpenAIEmbeddingRequest represents the request body for OpenAI embeddings API
 type OpenAIEmbeddingRequest struct {
 	Input          interface{} `json:"input"` // string or []string
-	Model          string      `json:"model"`
+	Model          string      `json:"model,omitempty"`
 	EncodingFormat string      `json:"encoding_format,omitempty"`
 	Dimensions     int         `json:"dimensions,omitempty"`
 }
@@ -84,7 +94,33 @@ func NewOpenAIClient(baseURL, apiKey, model string, dimensions int) *OpenAIClien
 		httpClient: &http.Client{
 			Timeout: 30 * time.Second,
 		},
+		provider:       "openai",
+		endpoint:       strings.TrimRight(baseURL, "/") + "/embeddings",
+		sendModel:      true,
+		sendDimensions: true,
+	}
+}
+
+// NewLocalClient creates a keyless client for a local OpenAI-compatible server
+// such as llama.cpp's llama-server. The dimensions are discovered by ProbeDimensions.
+func NewLocalClient(baseURL, model string) *OpenAIClient {
+	c := NewOpenAIClient(baseURL, "", model, 0)
+	c.provider = "local"
+	c.sendDimensions = false
+	return c
+}
+
+// NewAzureOpenAIClient creates a client for an Azure OpenAI deployment.
+// deploymentURL has the form https://{resource}.openai.azure.com/openai/deployments/{deployment}.
+func NewAzureOpenAIClient(deploymentURL, apiKey, apiVersion, model string, dimensions int) *OpenAIClient {
+	c := NewOpenAIClient(deploymentURL, apiKey, model, dimensions)
+	c.provider = "azure"
+	c.authHeader = "api-key"
+	c.sendModel = false
+	if apiVersion != "" {
+		c.endpoint += "?api-version=" + url.QueryEscape(apiVersion)
 	}
+	return c
 }
 
 // Embed generates an embedding vector for the given text
@@ -107,11 +143,13 @@ func (c *OpenAIClient) EmbedBatch(texts []string) ([][]float32, error) {
 
 	reqBody := OpenAIEmbeddingRequest{
 		Input: texts,
-		Model: c.model,
+	}
+	if c.sendModel {
+		reqBody.Model = c.model
 	}
 
 	// Only include dimensions if explicitly set and supported by model
-	if c.dimensions > 0 {
+	if c.sendDimensions && c.dimensions > 0 {
 		reqBody.Dimensions = c.dimensions
 	}
 
@@ -120,13 +158,19 @@ func (c *OpenAIClient) EmbedBatch(texts []string) ([][]float32, error) {
 		return nil, fmt.Errorf("failed to marshal request: %w", err)
 	}
 
-	req, err := http.NewRequest("POST", c.baseURL+"/embeddings", bytes.NewReader(jsonBody))
+	req, err := http.NewRequest("POST", c.endpoint, bytes.NewReader(jsonBody))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
 
 	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", "Bearer "+c.apiKey)
+	if c.apiKey != "" {
+		if c.authHeader != "" {
+			req.Header.Set(c.authHeader, c.apiKey)
+		} else {
+			req.Header.Set("Authorization", "Bearer "+c.apiKey)
+		}
+	}
 
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
@@ -142,9 +186,9 @@ func (c *OpenAIClient) EmbedBatch(texts []string) ([][]float32, error) {
 	if resp.StatusCode != http.StatusOK {
 		var errResp OpenAIErrorResponse
 		if err := json.Unmarshal(body, &errResp); err == nil && errResp.Error.Message != "" {
-			return nil, fmt.Errorf("OpenAI API error: %s", errResp.Error.Message)
+			return nil, fmt.Errorf("%s API error: %s", c.provider, errResp.Error.Message)
 		}
-		return nil, fmt.Errorf("OpenAI API error: status %d", resp.StatusCode)
+		return nil, fmt.Errorf("%s API error: status %d", c.provider, resp.StatusCode)
 	}
 
 	var embResp OpenAIEmbeddingResponse
@@ -169,10 +213,15 @@ func (c *OpenAIClient) GetModelInfo() ModelInfo {
 		Name:       c.model,
 		Version:    "v1",
 		Dimensions: c.dimensions,
-		Provider:   "openai",
+		Provider:   c.provider,
 	}
 }
 
+// setDimensions records the vector size discovered by ProbeDimensions
+func (c *OpenAIClient) setDimensions(dimensions int) {
+	c.dimensions = dimensions
+}
+
 // MockClient is a mock implementation for testing
 type MockClient struct {
 	EmbedFunc      func(text string) ([]float32, error)
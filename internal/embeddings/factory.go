@@ -0,0 +1,56 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package embeddings
+
+import (
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+// Factory holds a probed embedding client and creates a Service for each
+// per-user database. Vectors live next to the memories they describe, so
+// two users with the same slug never share an embedding.
+type Factory struct {
+	client       Client
+	modelName    string
+	modelVersion string
+	dimensions   int
+}
+
+// NewFactory creates a factory for services backed by the given client
+func NewFactory(client Client, modelName string, dimensions int) *Factory {
+	return &Factory{
+		client:       client,
+		modelName:    modelName,
+		modelVersion: ModelVersion(modelName, dimensions),
+		dimensions:   dimensions,
+	}
+}
+
+// ModelVersion identifies the vector space an embedding belongs to.
+// Vectors from a different model or size are treated as stale.
+func ModelVersion(modelName string, dimensions int) string {
+	return fmt.Sprintf("%s@%d", modelName, dimensions)
+}
+
+// Dimensions returns the vector size produced by the factory's model
+func (f *Factory) Dimensions() int {
+	return f.dimensions
+}
+
+// ModelName returns the name of the factory's embedding model
+func (f *Factory) ModelName() string {
+	return f.modelName
+}
+
+// NewUserService creates an embedding service that stores vectors in a
+// per-user database, migrating the embeddings tables if needed
+func (f *Factory) NewUserService(userDB *gorm.DB) (*Service, error) {
+	if err := MigrateEmbeddings(userDB); err != nil {
+		return nil, fmt.Errorf("failed to migrate embeddings table: %w", err)
+	}
+	return NewServiceWithVec(userDB, f.client, f.modelName, f.modelVersion, f.dimensions)
+}
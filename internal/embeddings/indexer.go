@@ -0,0 +1,112 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package embeddings
+
+import (
+	"fmt"
+	"os"
+	"sync"
+)
+
+// Indexer embeds memories in the background as they are written.
+//
+// Requests for the same slug are deduplicated while they wait: a newer
+// request replaces the queued content instead of adding a second job.
+// A single worker goroutine runs only while there is work, so an idle
+// indexer holds no resources.
+type Indexer struct {
+	service func() *Service // Resolved per job; the service may be replaced meanwhile
+
+	mu      sync.Mutex
+	idle    *sync.Cond
+	pending map[string]string // slug -> latest content
+	order   []string          // FIFO of pending slugs
+	running bool
+	closed  bool // Set by Close; later requests are dropped
+}
+
+// NewIndexer creates an indexer that embeds into the service returned by service
+func NewIndexer(service func() *Service) *Indexer {
+	ix := &Indexer{
+		service: service,
+		pending: make(map[string]string),
+	}
+	ix.idle = sync.NewCond(&ix.mu)
+	return ix
+}
+
+// Enqueue schedules a memory for embedding. Content that is already
+// embedded with the same hash and model is skipped by the service.
+func (ix *Indexer) Enqueue(slug, content string) {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	if ix.closed {
+		return
+	}
+
+	if _, queued := ix.pending[slug]; !queued {
+		ix.order = append(ix.order, slug)
+	}
+	ix.pending[slug] = content
+
+	if !ix.running {
+		ix.running = true
+		go ix.run()
+	}
+}
+
+// Pending returns the number of memories waiting to be embedded
+func (ix *Indexer) Pending() int {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	return len(ix.order)
+}
+
+// Wait blocks until all queued memories have been processed
+func (ix *Indexer) Wait() {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	for ix.running {
+		ix.idle.Wait()
+	}
+}
+
+// Close embeds what is already queued and stops accepting more. Memories
+// written afterwards are left for the next backfill.
+func (ix *Indexer) Close() {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	ix.closed = true
+	for ix.running {
+		ix.idle.Wait()
+	}
+}
+
+// run processes the queue until it is empty
+func (ix *Indexer) run() {
+	for {
+		ix.mu.Lock()
+		if len(ix.order) == 0 {
+			ix.running = false
+			ix.idle.Broadcast()
+			ix.mu.Unlock()
+			return
+		}
+		slug := ix.order[0]
+		ix.order = ix.order[1:]
+		content := ix.pending[slug]
+		delete(ix.pending, slug)
+		ix.mu.Unlock()
+
+		svc := ix.service()
+		if svc == nil || !svc.IsEnabled() {
+			continue
+		}
+		if _, err := svc.GetEmbedding(slug, content); err != nil {
+			// The memory stays stale and is picked up by the next update or backfill
+			fmt.Fprintf(os.Stderr, "Warning: Failed to embed memory %s: %v\n", slug, err)
+		}
+	}
+}
@@ -0,0 +1,125 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package embeddings
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// blockingClient records embedded texts and blocks each call until released
+type blockingClient struct {
+	MockClient
+	mu      sync.Mutex
+	texts   []string
+	started chan struct{}
+	release chan struct{}
+}
+
+func newBlockingClient() *blockingClient {
+	return &blockingClient{
+		started: make(chan struct{}, 16),
+		release: make(chan struct{}, 16),
+	}
+}
+
+func (c *blockingClient) Embed(text string) ([]float32, error) {
+	c.started <- struct{}{}
+	<-c.release
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.texts = append(c.texts, text)
+	return []float32{float32(len(text)), 1}, nil
+}
+
+func (c *blockingClient) embedded() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]string(nil), c.texts...)
+}
+
+func TestIndexer_EmbedsQueuedMemories(t *testing.T) {
+	db := setupTestDB(t)
+	svc := NewService(db, &MockClient{}, "test-model", "v1", 1536)
+	ix := NewIndexer(func() *Service { return svc })
+
+	ix.Enqueue("a", "alpha")
+	ix.Enqueue("b", "beta")
+	ix.Wait()
+
+	count, err := svc.CountEmbeddings()
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), count)
+	assert.Equal(t, 0, ix.Pending())
+}
+
+func TestIndexer_DeduplicatesPendingSlugs(t *testing.T) {
+	db := setupTestDB(t)
+	client := newBlockingClient()
+	svc := NewService(db, client, "test-model", "v1", 2)
+	ix := NewIndexer(func() *Service { return svc })
+
+	// Hold the worker on the first job while more requests arrive
+	ix.Enqueue("busy", "first job")
+	<-client.started
+
+	ix.Enqueue("note", "draft 1")
+	ix.Enqueue("note", "draft 2")
+	ix.Enqueue("note", "draft 3")
+	assert.Equal(t, 1, ix.Pending())
+
+	client.release <- struct{}{}
+	<-client.started
+	client.release <- struct{}{}
+	ix.Wait()
+
+	// Only the latest content of the repeated slug was embedded
+	assert.Equal(t, []string{"first job", "draft 3"}, client.embedded())
+
+	hash, err := svc.GetContentHash("note")
+	require.NoError(t, err)
+	assert.Equal(t, CalculateContentHash("draft 3"), hash)
+}
+
+func TestIndexer_SkipsFreshContent(t *testing.T) {
+	db := setupTestDB(t)
+	client := &MockClient{}
+	svc := NewService(db, client, "test-model", "v1", 1536)
+	ix := NewIndexer(func() *Service { return svc })
+
+	ix.Enqueue("note", "same content")
+	ix.Wait()
+	ix.Enqueue("note", "same content")
+	ix.Wait()
+
+	assert.Equal(t, 1, client.CallCount)
+}
+
+func TestIndexer_NoService(t *testing.T) {
+	ix := NewIndexer(func() *Service { return nil })
+	ix.Enqueue("note", "content")
+	ix.Wait()
+	assert.Equal(t, 0, ix.Pending())
+}
+
+func TestIndexer_CloseFinishesQueueAndRefusesMore(t *testing.T) {
+	db := setupTestDB(t)
+	svc := NewService(db, &MockClient{}, "test-model", "v1", 1536)
+	ix := NewIndexer(func() *Service { return svc })
+
+	ix.Enqueue("a", "alpha")
+	ix.Close()
+	ix.Enqueue("b", "beta")
+	ix.Wait()
+
+	count, err := svc.CountEmbeddings()
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), count)
+	assert.Equal(t, 0, ix.Pending())
+}
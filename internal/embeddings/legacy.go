@@ -0,0 +1,56 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package embeddings
+
+import (
+	"fmt"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ImportLegacyEmbeddings copies embeddings that older releases stored in the
+// shared system database into this service's per-user database.
+//
+// Legacy rows are keyed only by slug, so a row is imported only when its
+// content hash matches the user's current content for that slug and it was
+// produced by the same model. Another user's vector for a colliding slug is
+// therefore never adopted. contentFor returns false for slugs the user does
+// not have. Slugs that already have a per-user embedding are left alone.
+// Returns the number of rows imported.
+func (s *Service) ImportLegacyEmbeddings(legacyDB *gorm.DB, contentFor func(slug string) (string, bool)) (int, error) {
+	if legacyDB == nil || legacyDB == s.db || !legacyDB.Migrator().HasTable(&Embedding{}) {
+		return 0, nil
+	}
+
+	var legacy []Embedding
+	if err := legacyDB.Where("model_name = ? AND dimensions = ?", s.modelName, s.dimensions).Find(&legacy).Error; err != nil {
+		return 0, fmt.Errorf("failed to read legacy embeddings: %w", err)
+	}
+
+	imported := 0
+	for _, row := range legacy {
+		content, ok := contentFor(row.Slug)
+		if !ok || CalculateContentHash(content) != row.ContentHash {
+			continue
+		}
+
+		row.ModelVersion = s.modelVersion
+		result := s.db.Clauses(clause.OnConflict{DoNothing: true}).Create(&row)
+		if result.Error != nil {
+			return imported, fmt.Errorf("failed to import embedding for %s: %w", row.Slug, result.Error)
+		}
+		if result.RowsAffected == 0 {
+			continue
+		}
+
+		if s.IsVecEnabled() {
+			_ = InsertVecEmbedding(s.db, row.Slug, BlobToFloat32Slice(row.Vector)) // Best effort
+		}
+		imported++
+	}
+
+	return imported, nil
+}
@@ -8,6 +8,8 @@ import (
 	"encoding/binary"
 	"fmt"
 	"math"
+	"regexp"
+	"strconv"
 	"time"
 
 	"gorm.io/gorm"
@@ -45,6 +47,15 @@ func MigrateVecEmbeddings(db *gorm.DB, dimensions int) error {
 		dimensions = DefaultEmbeddingDimensions
 	}
 
+	// A vec0 table has a fixed size; recreate it when the model changes.
+	// The embeddings table keeps the vectors, which are then marked stale
+	// by their model version and regenerated.
+	if existing := vecTableDimensions(db); existing > 0 && existing != dimensions {
+		if err := db.Exec("DROP TABLE vec_embeddings").Error; err != nil {
+			return fmt.Errorf("failed to drop vec_embeddings with %d dimensions: %w", existing, err)
+		}
+	}
+
 	// Create the vec_embeddings virtual table using vec0 module
 	// The vec0 module provides efficient KNN search
 	sql := fmt.Sprintf(`
@@ -61,6 +72,25 @@ func MigrateVecEmbeddings(db *gorm.DB, dimensions int) error {
 	return nil
 }
 
+// vecTableDimensions returns the vector size declared by an existing
+// vec_embeddings table, or 0 if there is none
+func vecTableDimensions(db *gorm.DB) int {
+	var ddl string
+	err := db.Raw("SELECT sql FROM sqlite_master WHERE type='table' AND name='vec_embeddings'").Scan(&ddl).Error
+	if err != nil || ddl == "" {
+		return 0
+	}
+	match := vecDimensionsPattern.FindStringSubmatch(ddl)
+	if match == nil {
+		return 0
+	}
+	dimensions, _ := strconv.Atoi(match[1])
+	return dimensions
+}
+
+// vecDimensionsPattern extracts N from "embedding FLOAT[N]"
+var vecDimensionsPattern = regexp.MustCompile(`(?i)float\[(\d+)\]`)
+
 // IsVecTableAvailable checks if the vec_embeddings virtual table exists
 func IsVecTableAvailable(db *gorm.DB) bool {
 	var count int64
@@ -0,0 +1,133 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package embeddings
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Ollama defaults
+const (
+	DefaultOllamaBaseURL = "http://localhost:11434"
+	DefaultOllamaModel   = "nomic-embed-text"
+)
+
+// OllamaClient implements the Client interface for Ollama's /api/embed endpoint
+type OllamaClient struct {
+	baseURL    string
+	model      string
+	dimensions int
+	httpClient *http.Client
+}
+
+// OllamaEmbedRequest represents the request body for Ollama's /api/embed endpoint
+type OllamaEmbedRequest struct {
+	Model string   `json:"model"`
+	Input []string `json:"input"`
+}
+
+// OllamaEmbedResponse represents the response from Ollama's /api/embed endpoint
+type OllamaEmbedResponse struct {
+	Model      string      `json:"model"`
+	Embeddings [][]float32 `json:"embeddings"`
+	Error      string      `json:"error,omitempty"`
+}
+
+// NewOllamaClient creates a new Ollama embedding client.
+// Ollama needs no API key; the dimensions are discovered by ProbeDimensions.
+func NewOllamaClient(baseURL, model string) *OllamaClient {
+	if baseURL == "" {
+		baseURL = DefaultOllamaBaseURL
+	}
+	return &OllamaClient{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		model:   model,
+		httpClient: &http.Client{
+			// Local models can be slow to load on first use
+			Timeout: 120 * time.Second,
+		},
+	}
+}
+
+// Embed generates an embedding vector for the given text
+func (c *OllamaClient) Embed(text string) ([]float32, error) {
+	vectors, err := c.EmbedBatch([]string{text})
+	if err != nil {
+		return nil, err
+	}
+	if len(vectors) == 0 {
+		return nil, fmt.Errorf("no embedding returned")
+	}
+	return vectors[0], nil
+}
+
+// EmbedBatch generates embedding vectors for multiple texts
+func (c *OllamaClient) EmbedBatch(texts []string) ([][]float32, error) {
+	if len(texts) == 0 {
+		return [][]float32{}, nil
+	}
+
+	jsonBody, err := json.Marshal(OllamaEmbedRequest{Model: c.model, Input: texts})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	req, err := http.NewRequest("POST", c.baseURL+"/api/embed", bytes.NewReader(jsonBody))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to make request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+
+	var embResp OllamaEmbedResponse
+	parseErr := json.Unmarshal(body, &embResp)
+
+	if resp.StatusCode != http.StatusOK {
+		if parseErr == nil && embResp.Error != "" {
+			return nil, fmt.Errorf("ollama API error: %s", embResp.Error)
+		}
+		return nil, fmt.Errorf("ollama API error: status %d", resp.StatusCode)
+	}
+	if parseErr != nil {
+		return nil, fmt.Errorf("failed to parse response: %w", parseErr)
+	}
+
+	if len(embResp.Embeddings) != len(texts) {
+		return nil, fmt.Errorf("ollama returned %d embeddings for %d inputs", len(embResp.Embeddings), len(texts))
+	}
+
+	return embResp.Embeddings, nil
+}
+
+// GetModelInfo returns information about the embedding model
+func (c *OllamaClient) GetModelInfo() ModelInfo {
+	return ModelInfo{
+		Name:       c.model,
+		Version:    "v1",
+		Dimensions: c.dimensions,
+		Provider:   "ollama",
+	}
+}
+
+// setDimensions records the vector size discovered by ProbeDimensions
+func (c *OllamaClient) setDimensions(dimensions int) {
+	c.dimensions = dimensions
+}
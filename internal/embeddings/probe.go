@@ -0,0 +1,33 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package embeddings
+
+import "fmt"
+
+// probeText is embedded at startup to discover a model's vector size
+const probeText = "medha dimension probe"
+
+// dimensionSetter is implemented by clients whose dimensions are discovered at runtime
+type dimensionSetter interface {
+	setDimensions(dimensions int)
+}
+
+// ProbeDimensions embeds a short text to verify the provider is reachable and
+// returns the model's vector size. Clients that support it remember the result
+// so GetModelInfo reports the real dimensions.
+func ProbeDimensions(client Client) (int, error) {
+	vector, err := client.Embed(probeText)
+	if err != nil {
+		return 0, fmt.Errorf("embedding probe failed: %w", err)
+	}
+	if len(vector) == 0 {
+		return 0, fmt.Errorf("embedding probe returned an empty vector")
+	}
+
+	if setter, ok := client.(dimensionSetter); ok {
+		setter.setDimensions(len(vector))
+	}
+	return len(vector), nil
+}
@@ -0,0 +1,145 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package embeddings
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeVector returns a deterministic vector of the given size
+func fakeVector(dimensions int, seed float32) []float32 {
+	v := make([]float32, dimensions)
+	for i := range v {
+		v[i] = seed
+	}
+	return v
+}
+
+func TestOllamaClient_EmbedBatch(t *testing.T) {
+	var got OllamaEmbedRequest
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/embed", r.URL.Path)
+		assert.Empty(t, r.Header.Get("Authorization"))
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&got))
+
+		resp := OllamaEmbedResponse{Model: got.Model}
+		for i := range got.Input {
+			resp.Embeddings = append(resp.Embeddings, fakeVector(768, float32(i+1)))
+		}
+		_ = json.NewEncoder(w).Encode(resp)
+	}))
+	defer srv.Close()
+
+	client := NewOllamaClient(srv.URL+"/", "nomic-embed-text")
+	vectors, err := client.EmbedBatch([]string{"first", "second"})
+	require.NoError(t, err)
+
+	assert.Equal(t, "nomic-embed-text", got.Model)
+	assert.Equal(t, []string{"first", "second"}, got.Input)
+	require.Len(t, vectors, 2)
+	assert.Equal(t, float32(1), vectors[0][0])
+	assert.Equal(t, float32(2), vectors[1][0])
+}
+
+func TestOllamaClient_Error(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte(`{"error":"model \"missing\" not found, try pulling it first"}`))
+	}))
+	defer srv.Close()
+
+	_, err := NewOllamaClient(srv.URL, "missing").Embed("text")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "not found")
+}
+
+func TestOllamaClient_DefaultBaseURL(t *testing.T) {
+	client := NewOllamaClient("", "nomic-embed-text")
+	assert.Equal(t, DefaultOllamaBaseURL, client.baseURL)
+	assert.Equal(t, "ollama", client.GetModelInfo().Provider)
+}
+
+// newOpenAICompatibleServer serves /embeddings responses and records the last request
+func newOpenAICompatibleServer(t *testing.T, dimensions int, onRequest func(r *http.Request, body OpenAIEmbeddingRequest)) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body OpenAIEmbeddingRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		onRequest(r, body)
+
+		inputs, _ := body.Input.([]interface{})
+		resp := OpenAIEmbeddingResponse{Object: "list"}
+		for i := range inputs {
+			resp.Data = append(resp.Data, struct {
+				Object    string    `json:"object"`
+				Index     int       `json:"index"`
+				Embedding []float32 `json:"embedding"`
+			}{Object: "embedding", Index: i, Embedding: fakeVector(dimensions, 0.5)})
+		}
+		_ = json.NewEncoder(w).Encode(resp)
+	}))
+}
+
+func TestAzureOpenAIClient_DeploymentURL(t *testing.T) {
+	srv := newOpenAICompatibleServer(t, 1536, func(r *http.Request, body OpenAIEmbeddingRequest) {
+		assert.Equal(t, "/openai/deployments/embed-small/embeddings", r.URL.Path)
+		assert.Equal(t, "2024-02-01", r.URL.Query().Get("api-version"))
+		assert.Equal(t, "azure-key", r.Header.Get("api-key"))
+		assert.Empty(t, r.Header.Get("Authorization"))
+		assert.Empty(t, body.Model, "Azure takes the model from the deployment URL")
+	})
+	defer srv.Close()
+
+	client := NewAzureOpenAIClient(srv.URL+"/openai/deployments/embed-small", "azure-key", "2024-02-01", "text-embedding-3-small", 1536)
+	vector, err := client.Embed("hello")
+	require.NoError(t, err)
+	assert.Len(t, vector, 1536)
+	assert.Equal(t, "azure", client.GetModelInfo().Provider)
+}
+
+func TestLocalClient_Keyless(t *testing.T) {
+	srv := newOpenAICompatibleServer(t, 384, func(r *http.Request, body OpenAIEmbeddingRequest) {
+		assert.Equal(t, "/v1/embeddings", r.URL.Path)
+		assert.Empty(t, r.Header.Get("Authorization"))
+		assert.Equal(t, "all-minilm", body.Model)
+		assert.Zero(t, body.Dimensions)
+	})
+	defer srv.Close()
+
+	client := NewLocalClient(srv.URL+"/v1", "all-minilm")
+	vector, err := client.Embed("hello")
+	require.NoError(t, err)
+	assert.Len(t, vector, 384)
+	assert.Equal(t, "local", client.GetModelInfo().Provider)
+}
+
+func TestProbeDimensions(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(OllamaEmbedResponse{Embeddings: [][]float32{fakeVector(768, 1)}})
+	}))
+	defer srv.Close()
+
+	client := NewOllamaClient(srv.URL, "nomic-embed-text")
+	assert.Zero(t, client.GetModelInfo().Dimensions)
+
+	dimensions, err := ProbeDimensions(client)
+	require.NoError(t, err)
+	assert.Equal(t, 768, dimensions)
+	assert.Equal(t, 768, client.GetModelInfo().Dimensions)
+}
+
+func TestProbeDimensions_Unreachable(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	srv.Close()
+
+	_, err := ProbeDimensions(NewOllamaClient(srv.URL, "nomic-embed-text"))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "embedding probe failed")
+}
@@ -0,0 +1,87 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package embeddings
+
+import "fmt"
+
+// DefaultReindexBatchSize is used when no batch size is configured
+const DefaultReindexBatchSize = 100
+
+// ReindexResult summarizes a backfill run
+type ReindexResult struct {
+	Total    int // Memories considered
+	Fresh    int // Already embedded with the current content and model
+	Embedded int // Newly embedded in this run
+}
+
+// ReindexProgress is called after each batch with the number of stale
+// memories embedded so far and the number that needed embedding
+type ReindexProgress func(done, total int)
+
+// Reindex embeds every stale memory using EmbedBatch in chunks of batchSize.
+//
+// Each batch is stored as soon as it is embedded, so a run that fails part
+// way can simply be repeated: memories embedded by the earlier run are fresh
+// and skipped, and work resumes with the first failed batch. On failure the
+// partial result is returned alongside the error.
+func (s *Service) Reindex(memories []MemoryContent, batchSize int, progress ReindexProgress) (*ReindexResult, error) {
+	result := &ReindexResult{Total: len(memories)}
+	if !s.enabled {
+		return result, nil
+	}
+	if batchSize <= 0 {
+		batchSize = DefaultReindexBatchSize
+	}
+
+	var stale []MemoryContent
+	for _, mem := range memories {
+		isStale, err := s.IsStale(mem.Slug, mem.Content)
+		if err != nil {
+			return result, err
+		}
+		if isStale {
+			stale = append(stale, mem)
+		} else {
+			result.Fresh++
+		}
+	}
+
+	for start := 0; start < len(stale); start += batchSize {
+		end := start + batchSize
+		if end > len(stale) {
+			end = len(stale)
+		}
+		batch := stale[start:end]
+
+		texts := make([]string, len(batch))
+		for i, mem := range batch {
+			texts[i] = mem.Content
+		}
+
+		vectors, err := s.client.EmbedBatch(texts)
+		if err != nil {
+			return result, fmt.Errorf("batch %d-%d of %d failed: %w", start+1, end, len(stale), err)
+		}
+		if len(vectors) != len(batch) {
+			return result, fmt.Errorf("batch %d-%d of %d: got %d vectors for %d memories", start+1, end, len(stale), len(vectors), len(batch))
+		}
+
+		for i, mem := range batch {
+			if len(vectors[i]) == 0 {
+				return result, fmt.Errorf("no embedding returned for %s", mem.Slug)
+			}
+			if err := s.storeEmbedding(mem.Slug, CalculateContentHash(mem.Content), vectors[i]); err != nil {
+				return result, err
+			}
+			result.Embedded++
+		}
+
+		if progress != nil {
+			progress(result.Embedded, len(stale))
+		}
+	}
+
+	return result, nil
+}
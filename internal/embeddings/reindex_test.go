@@ -0,0 +1,117 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package embeddings
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testMemories(n int) []MemoryContent {
+	memories := make([]MemoryContent, n)
+	for i := range memories {
+		memories[i] = MemoryContent{Slug: fmt.Sprintf("memory-%02d", i), Content: fmt.Sprintf("content %d", i)}
+	}
+	return memories
+}
+
+func TestReindex_BatchesAndProgress(t *testing.T) {
+	db := setupTestDB(t)
+	var batchSizes []int
+	client := &MockClient{
+		EmbedBatchFunc: func(texts []string) ([][]float32, error) {
+			batchSizes = append(batchSizes, len(texts))
+			vectors := make([][]float32, len(texts))
+			for i := range vectors {
+				vectors[i] = []float32{1, 2, 3}
+			}
+			return vectors, nil
+		},
+	}
+	svc := NewService(db, client, "test-model", "v1", 3)
+
+	var progress []int
+	result, err := svc.Reindex(testMemories(7), 3, func(done, total int) {
+		assert.Equal(t, 7, total)
+		progress = append(progress, done)
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, []int{3, 3, 1}, batchSizes)
+	assert.Equal(t, []int{3, 6, 7}, progress)
+	assert.Equal(t, 7, result.Total)
+	assert.Equal(t, 7, result.Embedded)
+	assert.Equal(t, 0, result.Fresh)
+
+	count, err := svc.CountEmbeddings()
+	require.NoError(t, err)
+	assert.Equal(t, int64(7), count)
+}
+
+func TestReindex_ResumesAfterFailure(t *testing.T) {
+	db := setupTestDB(t)
+	calls := 0
+	client := &MockClient{
+		EmbedBatchFunc: func(texts []string) ([][]float32, error) {
+			calls++
+			if calls == 2 {
+				return nil, errors.New("provider went away")
+			}
+			vectors := make([][]float32, len(texts))
+			for i := range vectors {
+				vectors[i] = []float32{1, 2}
+			}
+			return vectors, nil
+		},
+	}
+	svc := NewService(db, client, "test-model", "v1", 2)
+	memories := testMemories(5)
+
+	result, err := svc.Reindex(memories, 2, nil)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "provider went away")
+	assert.Equal(t, 2, result.Embedded)
+
+	// The rerun skips the batch that was stored and embeds the rest
+	result, err = svc.Reindex(memories, 2, nil)
+	require.NoError(t, err)
+	assert.Equal(t, 2, result.Fresh)
+	assert.Equal(t, 3, result.Embedded)
+
+	count, err := svc.CountEmbeddings()
+	require.NoError(t, err)
+	assert.Equal(t, int64(5), count)
+}
+
+func TestReindex_DetectsChangedContent(t *testing.T) {
+	db := setupTestDB(t)
+	svc := NewService(db, &MockClient{}, "test-model", "v1", 1536)
+	memories := testMemories(3)
+
+	_, err := svc.Reindex(memories, 10, nil)
+	require.NoError(t, err)
+
+	memories[1].Content = "edited"
+	result, err := svc.Reindex(memories, 10, nil)
+	require.NoError(t, err)
+	assert.Equal(t, 2, result.Fresh)
+	assert.Equal(t, 1, result.Embedded)
+}
+
+func TestReindex_DisabledService(t *testing.T) {
+	db := setupTestDB(t)
+	client := &MockClient{}
+	svc := NewService(db, client, "test-model", "v1", 1536)
+	svc.SetEnabled(false)
+
+	result, err := svc.Reindex(testMemories(2), 10, nil)
+	require.NoError(t, err)
+	assert.Equal(t, 0, result.Embedded)
+	assert.Equal(t, 0, client.CallCount)
+}
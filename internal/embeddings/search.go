@@ -7,6 +7,7 @@ package embeddings
 import (
 	"sort"
 
+	"github.com/tejzpr/medha-mcp/internal/ranking"
 	"gorm.io/gorm"
 )
 
@@ -260,75 +261,39 @@ func (s *SemanticSearch) SearchWithThreshold(query string, threshold float32, li
 	return s.search.SearchWithThreshold(queryVector, threshold, limit)
 }
 
-// HybridSearch combines keyword and semantic search results
-// Returns results that match both keyword and semantic criteria
+// HybridSearch fuses semantic results with keyword matches (ordered best
+// first) using reciprocal rank fusion. Similarity holds the fused score,
+// so slugs found by both searches rank above those found by one.
 func (s *SemanticSearch) HybridSearch(query string, keywordMatches []string, limit int) ([]SearchResult, error) {
 	semanticResults, err := s.Search(query, limit*2)
 	if err != nil {
 		return nil, err
 	}
 
-	// If no semantic results, just return keyword matches as results with default similarity
-	if len(semanticResults) == 0 {
-		results := make([]SearchResult, 0, len(keywordMatches))
-		for _, slug := range keywordMatches {
-			results = append(results, SearchResult{
-				Slug:       slug,
-				Similarity: 0.5, // Default similarity for keyword matches
-			})
-		}
-		if len(results) > limit {
-			results = results[:limit]
-		}
-		return results, nil
+	keywordHits := make([]ranking.Hit, len(keywordMatches))
+	for i, slug := range keywordMatches {
+		keywordHits[i] = ranking.Hit{Slug: slug, Score: float64(len(keywordMatches) - i)}
 	}
-
-	// Create a set of keyword matches for fast lookup
-	keywordSet := make(map[string]bool)
-	for _, slug := range keywordMatches {
-		keywordSet[slug] = true
+	semanticHits := make([]ranking.Hit, len(semanticResults))
+	for i, r := range semanticResults {
+		semanticHits[i] = ranking.Hit{Slug: r.Slug, Score: float64(r.Similarity)}
 	}
 
-	// Score results: boost semantic results that also have keyword matches
-	boostedResults := make([]SearchResult, 0, len(semanticResults))
-	for _, r := range semanticResults {
-		if keywordSet[r.Slug] {
-			// Boost similarity for keyword matches
-			r.Similarity = r.Similarity * 1.2
-			if r.Similarity > 1.0 {
-				r.Similarity = 1.0
-			}
-		}
-		boostedResults = append(boostedResults, r)
-	}
+	fusion := ranking.NewFusion(ranking.DefaultWeights())
+	fusion.Add(ranking.SignalKeyword, keywordHits)
+	fusion.Add(ranking.SignalSemantic, semanticHits)
 
-	// Add keyword matches that weren't in semantic results
-	for slug := range keywordSet {
-		found := false
-		for _, r := range boostedResults {
-			if r.Slug == slug {
-				found = true
-				break
-			}
-		}
-		if !found {
-			boostedResults = append(boostedResults, SearchResult{
-				Slug:       slug,
-				Similarity: 0.4, // Lower similarity for keyword-only matches
-			})
-		}
+	fused := fusion.Results()
+	results := make([]SearchResult, 0, len(fused))
+	for _, r := range fused {
+		results = append(results, SearchResult{Slug: r.Slug, Similarity: float32(r.Score)})
 	}
 
-	// Re-sort after boosting
-	sort.Slice(boostedResults, func(i, j int) bool {
-		return boostedResults[i].Similarity > boostedResults[j].Similarity
-	})
-
-	if len(boostedResults) > limit {
-		boostedResults = boostedResults[:limit]
+	if len(results) > limit {
+		results = results[:limit]
 	}
 
-	return boostedResults, nil
+	return results, nil
 }
 
 // cosineSimilarity calculates cosine similarity between two vectors
@@ -93,7 +93,17 @@ func (s *Service) GetEmbedding(slug, content string) ([]float32, error) {
 		return nil, fmt.Errorf("failed to generate embedding: %w", err)
 	}
 
-	// Store for next time (upsert)
+	// Store for next time
+	if err := s.storeEmbedding(slug, contentHash, vector); err != nil {
+		return nil, err
+	}
+
+	return vector, nil
+}
+
+// storeEmbedding upserts a vector into the metadata table and, when
+// available, the sqlite-vec table
+func (s *Service) storeEmbedding(slug, contentHash string, vector []float32) error {
 	embedding := Embedding{
 		Slug:         slug,
 		ContentHash:  contentHash,
@@ -104,13 +114,13 @@ func (s *Service) GetEmbedding(slug, content string) ([]float32, error) {
 		CreatedAt:    time.Now(),
 	}
 
-	err = s.db.Clauses(clause.OnConflict{
+	err := s.db.Clauses(clause.OnConflict{
 		Columns:   []clause.Column{{Name: "slug"}},
 		DoUpdates: clause.AssignmentColumns([]string{"content_hash", "model_version", "vector", "created_at", "dimensions"}),
 	}).Create(&embedding).Error
 
 	if err != nil {
-		return nil, fmt.Errorf("failed to cache embedding: %w", err)
+		return fmt.Errorf("failed to cache embedding: %w", err)
 	}
 
 	// Also store in vec table if available
@@ -118,7 +128,7 @@ func (s *Service) GetEmbedding(slug, content string) ([]float32, error) {
 		_ = InsertVecEmbedding(s.db, slug, vector) // Best effort, don't fail
 	}
 
-	return vector, nil
+	return nil
 }
 
 // GetCachedEmbedding retrieves a cached embedding without regeneration
@@ -37,6 +37,13 @@ func (r *Repository) CommitFile(filePath, message string) error {
 	return r.AddAndCommit([]string{filePath}, opts)
 }
 
+// CommitFiles commits the given files, including removals, and nothing else
+func (r *Repository) CommitFiles(filePaths []string, message string) error {
+	opts := DefaultCommitOptions()
+	opts.Message = message
+	return r.AddAndCommit(filePaths, opts)
+}
+
 // AddAndCommit adds files and commits them
 func (r *Repository) AddAndCommit(files []string, opts *CommitOptions) error {
 	if opts == nil {
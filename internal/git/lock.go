@@ -0,0 +1,26 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package git
+
+import (
+	"path/filepath"
+	"sync"
+)
+
+// repoLocks holds one mutex per repository path
+var repoLocks sync.Map
+
+// LockRepository takes the process-wide lock for a repository's working tree,
+// index and HEAD, and returns the function that releases it. Every write,
+// commit, reset and sync of the repository must hold it, since go-git's index
+// is not safe for concurrent use.
+func LockRepository(path string) (unlock func()) {
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+	mu, _ := repoLocks.LoadOrStore(filepath.Clean(path), &sync.Mutex{})
+	mu.(*sync.Mutex).Lock()
+	return mu.(*sync.Mutex).Unlock
+}
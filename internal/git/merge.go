@@ -0,0 +1,476 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package git
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/tejzpr/medha-mcp/internal/database"
+	"github.com/tejzpr/medha-mcp/internal/memory"
+	"github.com/tejzpr/medha-mcp/internal/merge"
+)
+
+// fileMerge is the content a merge gives one file; nil removes it
+type fileMerge struct {
+	path    string
+	content []byte
+}
+
+// fileOutcome is how a merge settles one file both sides changed
+type fileOutcome struct {
+	update   *fileMerge     // Change to the worktree, if any
+	review   *memory.Memory // Conflict memory to add for review, if any
+	conflict bool           // Whether the file could not be merged
+}
+
+// mergeRemote merges the fetched origin branch into HEAD when the two have
+// diverged. Files changed on one side take that side; files changed on both
+// are merged three ways. Memory files always merge: what cannot be merged is
+// saved as a conflict memory for review, as is a memory their side moved or
+// archived while ours changed it elsewhere. Other files that cannot be merged
+// are recorded in status; with keepOurs they keep the local version and the
+// merge is committed, otherwise nothing is changed.
+func (r *Repository) mergeRemote(keepOurs bool, status *SyncStatus) error {
+	head, err := r.repo.Head()
+	if err != nil {
+		return fmt.Errorf("failed to get HEAD: %w", err)
+	}
+	remoteRef, err := r.repo.Reference(plumbing.NewRemoteReferenceName("origin", head.Name().Short()), true)
+	if err != nil {
+		return fmt.Errorf("failed to find remote branch: %w", err)
+	}
+
+	ours, err := r.repo.CommitObject(head.Hash())
+	if err != nil {
+		return fmt.Errorf("failed to get local commit: %w", err)
+	}
+	theirs, err := r.repo.CommitObject(remoteRef.Hash())
+	if err != nil {
+		return fmt.Errorf("failed to get remote commit: %w", err)
+	}
+	if ahead, err := theirs.IsAncestor(ours); err == nil && ahead {
+		return nil
+	}
+
+	bases, err := ours.MergeBase(theirs)
+	if err != nil {
+		return fmt.Errorf("failed to find merge base: %w", err)
+	}
+	if len(bases) == 0 {
+		return fmt.Errorf("local and remote histories are unrelated")
+	}
+
+	baseTree, err := bases[0].Tree()
+	if err != nil {
+		return fmt.Errorf("failed to get merge base tree: %w", err)
+	}
+	ourTree, err := ours.Tree()
+	if err != nil {
+		return fmt.Errorf("failed to get local tree: %w", err)
+	}
+	theirTree, err := theirs.Tree()
+	if err != nil {
+		return fmt.Errorf("failed to get remote tree: %w", err)
+	}
+
+	ourChanges, err := changedFiles(baseTree, ourTree)
+	if err != nil {
+		return err
+	}
+	theirChanges, err := changedFiles(baseTree, theirTree)
+	if err != nil {
+		return err
+	}
+
+	ourSlugs, err := memorySlugs(ourTree, ourChanges)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	organizer := memory.NewOrganizer(r.Path)
+	var updates []fileMerge
+	var conflicts, reviews []string
+	addReview := func(review *memory.Memory) error {
+		content, err := review.ToMarkdown()
+		if err != nil {
+			return err
+		}
+		reviewPath, err := filepath.Rel(r.Path, organizer.GetMemoryPath(review.ID, review.Tags, "", now))
+		if err != nil {
+			return err
+		}
+		updates = append(updates, fileMerge{path: filepath.ToSlash(reviewPath), content: []byte(content)})
+		reviews = append(reviews, review.ID)
+		return nil
+	}
+	for path, theirHash := range theirChanges {
+		ourHash, changedByUs := ourChanges[path]
+		switch {
+		case !changedByUs:
+			content, err := blobContent(theirTree, path, theirHash)
+			if err != nil {
+				return err
+			}
+			// One file per memory: ours stays where this machine has it
+			if review := movedMemory(path, content, ourSlugs, now); review != nil {
+				if err := addReview(review); err != nil {
+					return err
+				}
+				continue
+			}
+			updates = append(updates, fileMerge{path: path, content: content})
+		case ourHash == theirHash:
+			// Both sides made the same change
+		default:
+			outcome, err := mergeFile(path, baseTree, ourTree, theirTree, ourHash, theirHash, now)
+			if err != nil {
+				return err
+			}
+			if outcome.conflict {
+				conflicts = append(conflicts, path)
+			}
+			if outcome.update != nil {
+				updates = append(updates, *outcome.update)
+			}
+			if outcome.review != nil {
+				if err := addReview(outcome.review); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	sort.Strings(conflicts)
+	sort.Strings(reviews)
+
+	if len(conflicts) > 0 {
+		status.HasConflicts = true
+		status.ConflictFiles = conflicts
+		if !keepOurs {
+			return nil
+		}
+	}
+	if err := r.commitMerge(updates, ours.Hash, theirs.Hash); err != nil {
+		return err
+	}
+	status.ConflictMemories = reviews
+	return nil
+}
+
+// changedFiles maps each file that differs between two trees to its blob in
+// the second, or the zero hash when it was removed
+func changedFiles(from, to *object.Tree) (map[string]plumbing.Hash, error) {
+	changes, err := object.DiffTree(from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to diff trees: %w", err)
+	}
+
+	files := make(map[string]plumbing.Hash, len(changes))
+	for _, change := range changes {
+		if change.From.Name != "" {
+			files[change.From.Name] = plumbing.ZeroHash
+		}
+		if change.To.Name != "" {
+			files[change.To.Name] = change.To.TreeEntry.Hash
+		}
+	}
+	return files, nil
+}
+
+// memorySlugs maps the slug of each memory file a change set writes to its path
+func memorySlugs(tree *object.Tree, changes map[string]plumbing.Hash) (map[string]string, error) {
+	slugs := make(map[string]string)
+	for path, hash := range changes {
+		if hash.IsZero() || !strings.EqualFold(filepath.Ext(path), ".md") {
+			continue
+		}
+		content, err := blobContent(tree, path, hash)
+		if err != nil {
+			return nil, err
+		}
+		if mem, err := memory.ParseMarkdown(string(content)); err == nil && mem.ID != "" {
+			slugs[mem.ID] = path
+		}
+	}
+	return slugs, nil
+}
+
+// movedMemory returns a conflict memory for a memory file their side wrote at
+// path when ours wrote the same memory at another path, as when one machine
+// archives or moves a memory the other edits. It returns nil otherwise.
+func movedMemory(path string, content []byte, ourSlugs map[string]string, now time.Time) *memory.Memory {
+	if !isMemoryFile(path, content) {
+		return nil
+	}
+	theirMem, err := memory.ParseMarkdown(string(content))
+	if err != nil {
+		return nil
+	}
+	ourPath, ok := ourSlugs[theirMem.ID]
+	if !ok || ourPath == path {
+		return nil
+	}
+	return merge.ConflictMemory(theirMem, theirMem.Content,
+		fmt.Sprintf("Another machine moved it to `%s` while this machine changed it at `%s`, which it keeps; the other machine's text is below.", path, ourPath), now)
+}
+
+// ChangedFilesBetween lists the files that differ between two commits, sorted
+// and relative to the repository. The zero hash stands for an empty tree.
+func (r *Repository) ChangedFilesBetween(from, to plumbing.Hash) ([]string, error) {
+	trees := make([]*object.Tree, 2)
+	for i, hash := range []plumbing.Hash{from, to} {
+		if hash.IsZero() {
+			continue
+		}
+		commit, err := r.repo.CommitObject(hash)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get commit %s: %w", hash, err)
+		}
+		if trees[i], err = commit.Tree(); err != nil {
+			return nil, fmt.Errorf("failed to get tree of %s: %w", hash, err)
+		}
+	}
+
+	changes, err := changedFiles(trees[0], trees[1])
+	if err != nil {
+		return nil, err
+	}
+	files := make([]string, 0, len(changes))
+	for path := range changes {
+		files = append(files, path)
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// mergeFile merges a file both sides changed. Memory files resolve through
+// merge.ResolveMarkdown; a memory one side removed keeps that side, with the
+// other side's edits kept as a conflict memory if they were lost. Other files
+// conflict when the changes overlap, one side removed them or they are not text.
+func mergeFile(path string, baseTree, ourTree, theirTree *object.Tree, ourHash, theirHash plumbing.Hash, now time.Time) (fileOutcome, error) {
+	var base []byte
+	if file, err := baseTree.File(path); err == nil {
+		base, err = blobContent(baseTree, path, file.Hash)
+		if err != nil {
+			return fileOutcome{}, err
+		}
+	}
+	ours, err := blobContent(ourTree, path, ourHash)
+	if err != nil {
+		return fileOutcome{}, err
+	}
+	theirs, err := blobContent(theirTree, path, theirHash)
+	if err != nil {
+		return fileOutcome{}, err
+	}
+
+	// Access stats add up; a side that removed them takes the other's
+	if path == database.AccessStatsFile {
+		switch {
+		case theirs == nil:
+			return fileOutcome{}, nil
+		case ours == nil:
+			return fileOutcome{update: &fileMerge{path: path, content: theirs}}, nil
+		}
+		if merged, err := database.MergeAccessStats(base, ours, theirs); err == nil {
+			return fileOutcome{update: &fileMerge{path: path, content: merged}}, nil
+		}
+	}
+
+	// Memory files have frontmatter; other markdown merges as plain text
+	if isMemoryFile(path, base, ours, theirs) {
+		switch {
+		case theirs == nil:
+			return fileOutcome{}, nil
+		case ours == nil:
+			theirMem, err := memory.ParseMarkdown(string(theirs))
+			if err == nil {
+				return fileOutcome{review: merge.ConflictMemory(theirMem, theirMem.Content,
+					"It was archived or removed on this machine while another machine edited it; the edited text is below.", now)}, nil
+			}
+		default:
+			merged, review, err := merge.ResolveMarkdown(string(base), string(ours), string(theirs), now)
+			if err == nil {
+				return fileOutcome{update: &fileMerge{path: path, content: []byte(merged)}, review: review}, nil
+			}
+		}
+	}
+
+	if ours == nil || theirs == nil || !isTextFile(path) {
+		return fileOutcome{conflict: true}, nil
+	}
+	merged, hasConflict := merge.ThreeWayMerge(string(base), string(ours), string(theirs))
+	if hasConflict {
+		return fileOutcome{conflict: true}, nil
+	}
+	return fileOutcome{update: &fileMerge{path: path, content: []byte(merged)}}, nil
+}
+
+// isMemoryFile reports whether a markdown file has frontmatter in any of the
+// versions being merged
+func isMemoryFile(path string, versions ...[]byte) bool {
+	if !strings.EqualFold(filepath.Ext(path), ".md") {
+		return false
+	}
+	for _, content := range versions {
+		if strings.HasPrefix(string(content), "---") {
+			return true
+		}
+	}
+	return false
+}
+
+// blobContent reads a file's content from a tree, or nil for the zero hash
+func blobContent(tree *object.Tree, path string, hash plumbing.Hash) ([]byte, error) {
+	if hash.IsZero() {
+		return nil, nil
+	}
+	file, err := tree.File(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	reader, err := file.Reader()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	defer reader.Close()
+	return io.ReadAll(reader)
+}
+
+// commitMerge writes merged files to the worktree and commits them with both
+// heads as parents
+func (r *Repository) commitMerge(updates []fileMerge, ours, theirs plumbing.Hash) error {
+	worktree, err := r.repo.Worktree()
+	if err != nil {
+		return fmt.Errorf("failed to get worktree: %w", err)
+	}
+	if err := r.applyUpdates(worktree, updates); err != nil {
+		return err
+	}
+
+	opts := DefaultCommitOptions()
+	_, err = worktree.Commit("chore: Merge remote changes", &git.CommitOptions{
+		Author: &object.Signature{
+			Name:  opts.Author,
+			Email: opts.Email,
+			When:  time.Now(),
+		},
+		Parents:           []plumbing.Hash{ours, theirs},
+		AllowEmptyCommits: true,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to commit merge: %w", err)
+	}
+	return nil
+}
+
+// applyUpdates writes or removes files in the worktree and stages them
+func (r *Repository) applyUpdates(worktree *git.Worktree, updates []fileMerge) error {
+	for _, update := range updates {
+		if update.content == nil {
+			if _, err := worktree.Remove(update.path); err != nil {
+				return fmt.Errorf("failed to remove %s: %w", update.path, err)
+			}
+			continue
+		}
+		absPath := filepath.Join(r.Path, filepath.FromSlash(update.path))
+		if err := os.MkdirAll(filepath.Dir(absPath), 0755); err != nil {
+			return fmt.Errorf("failed to create directory: %w", err)
+		}
+		if err := os.WriteFile(absPath, update.content, 0644); err != nil {
+			return fmt.Errorf("failed to write %s: %w", update.path, err)
+		}
+		if _, err := worktree.Add(update.path); err != nil {
+			return fmt.Errorf("failed to stage %s: %w", update.path, err)
+		}
+	}
+	return nil
+}
+
+// fastForward moves the branch to the fetched origin branch when that
+// descends from HEAD, writing only the files that changed. go-git's own pull
+// resets the whole worktree, which deletes untracked and ignored files such
+// as the open per-user database. Diverged histories return
+// git.ErrNonFastForwardUpdate.
+func (r *Repository) fastForward() error {
+	head, err := r.repo.Head()
+	if err != nil {
+		return fmt.Errorf("failed to get HEAD: %w", err)
+	}
+	remoteRef, err := r.repo.Reference(plumbing.NewRemoteReferenceName("origin", head.Name().Short()), true)
+	if err == plumbing.ErrReferenceNotFound || (err == nil && remoteRef.Hash() == head.Hash()) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to find remote branch: %w", err)
+	}
+
+	ours, err := r.repo.CommitObject(head.Hash())
+	if err != nil {
+		return fmt.Errorf("failed to get local commit: %w", err)
+	}
+	theirs, err := r.repo.CommitObject(remoteRef.Hash())
+	if err != nil {
+		return fmt.Errorf("failed to get remote commit: %w", err)
+	}
+	if ahead, err := theirs.IsAncestor(ours); err == nil && ahead {
+		return nil
+	}
+	if behind, err := ours.IsAncestor(theirs); err != nil || !behind {
+		return git.ErrNonFastForwardUpdate
+	}
+
+	ourTree, err := ours.Tree()
+	if err != nil {
+		return fmt.Errorf("failed to get local tree: %w", err)
+	}
+	theirTree, err := theirs.Tree()
+	if err != nil {
+		return fmt.Errorf("failed to get remote tree: %w", err)
+	}
+	changes, err := changedFiles(ourTree, theirTree)
+	if err != nil {
+		return err
+	}
+
+	// Local edits to the incoming files would be overwritten
+	worktree, err := r.repo.Worktree()
+	if err != nil {
+		return fmt.Errorf("failed to get worktree: %w", err)
+	}
+	status, err := worktree.Status()
+	if err != nil {
+		return fmt.Errorf("failed to get status: %w", err)
+	}
+	updates := make([]fileMerge, 0, len(changes))
+	for path, hash := range changes {
+		if file, ok := status[path]; ok && file.Worktree != git.Unmodified && file.Worktree != git.Untracked {
+			return git.ErrUnstagedChanges
+		}
+		content, err := blobContent(theirTree, path, hash)
+		if err != nil {
+			return err
+		}
+		updates = append(updates, fileMerge{path: path, content: content})
+	}
+	sort.Slice(updates, func(i, j int) bool { return updates[i].path < updates[j].path })
+
+	if err := r.applyUpdates(worktree, updates); err != nil {
+		return err
+	}
+	if err := r.repo.Storer.SetReference(plumbing.NewHashReference(head.Name(), theirs.Hash)); err != nil {
+		return fmt.Errorf("failed to move %s: %w", head.Name().Short(), err)
+	}
+	return nil
+}
@@ -12,6 +12,7 @@ import (
 	"github.com/go-git/go-git/v5"
 	"github.com/go-git/go-git/v5/config"
 	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/format/index"
 	"github.com/go-git/go-git/v5/plumbing/transport/http"
 )
 
@@ -124,6 +125,43 @@ func (r *Repository) GetHeadCommit() (*plumbing.Reference, error) {
 	return ref, nil
 }
 
+// HeadHash returns the commit HEAD points to, or the zero hash when the
+// repository has no commits yet
+func (r *Repository) HeadHash() (plumbing.Hash, error) {
+	ref, err := r.repo.Head()
+	if err == plumbing.ErrReferenceNotFound {
+		return plumbing.ZeroHash, nil
+	}
+	if err != nil {
+		return plumbing.ZeroHash, fmt.Errorf("failed to get HEAD: %w", err)
+	}
+	return ref.Hash(), nil
+}
+
+// ResetHead moves HEAD back to a commit and resets the index to match,
+// leaving the working tree alone. The zero hash undoes the first commit.
+func (r *Repository) ResetHead(hash plumbing.Hash) error {
+	if hash.IsZero() {
+		head, err := r.repo.Storer.Reference(plumbing.HEAD)
+		if err != nil {
+			return fmt.Errorf("failed to get HEAD: %w", err)
+		}
+		if err := r.repo.Storer.RemoveReference(head.Target()); err != nil {
+			return fmt.Errorf("failed to remove %s: %w", head.Target(), err)
+		}
+		return r.repo.Storer.SetIndex(&index.Index{Version: 2})
+	}
+
+	worktree, err := r.repo.Worktree()
+	if err != nil {
+		return fmt.Errorf("failed to get worktree: %w", err)
+	}
+	if err := worktree.Reset(&git.ResetOptions{Commit: hash, Mode: git.MixedReset}); err != nil {
+		return fmt.Errorf("failed to reset to %s: %w", hash, err)
+	}
+	return nil
+}
+
 // AddRemote adds a remote to the repository
 func (r *Repository) AddRemote(name, url string) error {
 	_, err := r.repo.CreateRemote(&config.RemoteConfig{
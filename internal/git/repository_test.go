@@ -160,3 +160,43 @@ func TestGetRemoteURL_NotExist(t *testing.T) {
 	_, err = repo.GetRemoteURL("origin")
 	assert.Error(t, err)
 }
+
+func TestResetHead(t *testing.T) {
+	tempDir := t.TempDir()
+	repoPath := filepath.Join(tempDir, "test-repo")
+
+	repo, err := InitRepository(repoPath)
+	require.NoError(t, err)
+
+	unborn, err := repo.HeadHash()
+	require.NoError(t, err)
+	assert.True(t, unborn.IsZero())
+
+	file := filepath.Join(repoPath, "note.md")
+	require.NoError(t, os.WriteFile(file, []byte("one"), 0644))
+	require.NoError(t, repo.CommitFile(file, "first"))
+	first, err := repo.HeadHash()
+	require.NoError(t, err)
+
+	require.NoError(t, os.WriteFile(file, []byte("two"), 0644))
+	require.NoError(t, repo.CommitFile(file, "second"))
+
+	// Undo the second commit; the working tree keeps its content
+	require.NoError(t, repo.ResetHead(first))
+	head, err := repo.HeadHash()
+	require.NoError(t, err)
+	assert.Equal(t, first, head)
+	content, err := os.ReadFile(file)
+	require.NoError(t, err)
+	assert.Equal(t, "two", string(content))
+	hasChanges, err := repo.HasChanges()
+	require.NoError(t, err)
+	assert.True(t, hasChanges)
+
+	// Undo the first commit
+	require.NoError(t, os.WriteFile(file, []byte("one"), 0644))
+	require.NoError(t, repo.ResetHead(unborn))
+	head, err = repo.HeadHash()
+	require.NoError(t, err)
+	assert.True(t, head.IsZero())
+}
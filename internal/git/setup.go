@@ -8,6 +8,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 )
 
 // SetupConfig holds configuration for repository setup
@@ -129,6 +130,15 @@ func createInitialStructure(repoPath string) error {
 		}
 	}
 
+	// Keep the per-user database out of git
+	gitignore := filepath.Join(repoPath, ".gitignore")
+	if _, err := os.Stat(gitignore); os.IsNotExist(err) {
+		err = os.WriteFile(gitignore, []byte(strings.Join(perUserDBIgnore, "\n")+"\n"), 0644)
+		if err != nil {
+			return fmt.Errorf("failed to create .gitignore: %w", err)
+		}
+	}
+
 	return nil
 }
 
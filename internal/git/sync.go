@@ -5,12 +5,19 @@
 package git
 
 import (
+	"errors"
 	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
 	"time"
 
 	"github.com/go-git/go-git/v5"
 	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/format/index"
 	"github.com/go-git/go-git/v5/plumbing/transport/http"
+	"github.com/tejzpr/medha-mcp/internal/database"
 )
 
 // SyncStatus represents the status of a git sync operation
@@ -20,6 +27,9 @@ type SyncStatus struct {
 	RemoteCommits   int
 	HasConflicts    bool
 	ConflictFiles   []string
+	ConflictMemories []string // Conflict memories sync wrote for review
+	Pulled          bool     // Whether remote changes were pulled or merged into HEAD
+	ChangedFiles    []string // Files the pull or merge changed, relative to the repository
 	SyncSuccessful  bool
 	Error           string
 }
@@ -50,31 +60,16 @@ func (r *Repository) Push(pat string) error {
 	return nil
 }
 
-// Pull pulls changes from the remote repository
+// Pull fetches the remote and fast-forwards to it (see fastForward)
 func (r *Repository) Pull(pat string) error {
 	if pat == "" {
 		return fmt.Errorf("PAT token is required for pull")
 	}
 
-	auth := &http.BasicAuth{
-		Username: "git",
-		Password: pat,
-	}
-
-	worktree, err := r.repo.Worktree()
-	if err != nil {
-		return fmt.Errorf("failed to get worktree: %w", err)
+	if err := r.Fetch(pat); err != nil {
+		return fmt.Errorf("failed to pull: %w", err)
 	}
-
-	err = worktree.Pull(&git.PullOptions{
-		RemoteName: "origin",
-		Auth:       auth,
-	})
-
-	if err != nil {
-		if err == git.NoErrAlreadyUpToDate {
-			return nil // Not an error, just already up to date
-		}
+	if err := r.fastForward(); err != nil {
 		return fmt.Errorf("failed to pull: %w", err)
 	}
 
@@ -194,6 +189,12 @@ func (r *Repository) Sync(pat string, forceLastWriteWins bool) (*SyncStatus, err
 		return status, nil
 	}
 
+	if err := r.IgnorePerUserDB(); err != nil {
+		status.Error = fmt.Sprintf("failed to untrack per-user DB: %v", err)
+		return status, fmt.Errorf("failed to untrack per-user DB: %w", err)
+	}
+	headBefore, _ := r.HeadHash()
+
 	// Fetch first to check for conflicts
 	err := r.Fetch(pat)
 	if err != nil {
@@ -204,20 +205,15 @@ func (r *Repository) Sync(pat string, forceLastWriteWins bool) (*SyncStatus, err
 	// Try to pull
 	err = r.Pull(pat)
 	if err != nil {
-		// Check if it's a merge conflict
+		// Diverged histories are merged; conflicting files keep our version when forced
 		if isConflictError(err) {
-			status.HasConflicts = true
-			
-			if forceLastWriteWins {
-				// Resolve conflicts by keeping our version
-				err = r.resolveConflictsLastWriteWins()
-				if err != nil {
-					status.Error = fmt.Sprintf("conflict resolution failed: %v", err)
-					return status, fmt.Errorf("conflict resolution failed: %w", err)
-				}
-			} else {
+			if mergeErr := r.mergeRemote(forceLastWriteWins, status); mergeErr != nil {
+				status.Error = fmt.Sprintf("merge failed: %v", mergeErr)
+				return status, fmt.Errorf("merge failed: %w", mergeErr)
+			}
+			if status.HasConflicts && !forceLastWriteWins {
 				status.Error = "merge conflicts detected, manual resolution required"
-				return status, fmt.Errorf("merge conflicts detected")
+				return status, fmt.Errorf("merge conflicts detected in %d files", len(status.ConflictFiles))
 			}
 		} else {
 			status.Error = fmt.Sprintf("pull failed: %v", err)
@@ -232,59 +228,49 @@ func (r *Repository) Sync(pat string, forceLastWriteWins bool) (*SyncStatus, err
 		return status, fmt.Errorf("push failed: %w", err)
 	}
 
+	if err := r.recordPulled(headBefore, status); err != nil {
+		status.Error = err.Error()
+	}
 	status.SyncSuccessful = true
 	return status, nil
 }
 
-// isConflictError checks if the error is a merge conflict
+// isConflictError checks if a pull failed because local and remote have
+// diverged and need a merge
 func isConflictError(err error) bool {
-	if err == nil {
-		return false
-	}
-	// go-git returns specific errors for conflicts
-	return err.Error() == "merge conflict"
+	return errors.Is(err, git.ErrNonFastForwardUpdate)
 }
 
-// resolveConflictsLastWriteWins resolves conflicts by keeping the local version
-func (r *Repository) resolveConflictsLastWriteWins() error {
-	worktree, err := r.repo.Worktree()
-	if err != nil {
-		return fmt.Errorf("failed to get worktree: %w", err)
+// recordPulled records in status whether HEAD moved since before and which
+// files changed
+func (r *Repository) recordPulled(before plumbing.Hash, status *SyncStatus) error {
+	after, err := r.HeadHash()
+	if err != nil || after == before {
+		return err
 	}
-
-	// Get status to find conflicted files
-	status, err := worktree.Status()
+	status.Pulled = true
+	files, err := r.ChangedFilesBetween(before, after)
 	if err != nil {
-		return fmt.Errorf("failed to get status: %w", err)
-	}
-
-	// Add all files (choosing ours)
-	for file := range status {
-		_, err := worktree.Add(file)
-		if err != nil {
-			return fmt.Errorf("failed to add file %s: %w", file, err)
-		}
+		return fmt.Errorf("failed to list pulled changes: %w", err)
 	}
-
-	// Commit the resolution
-	opts := DefaultCommitOptions()
-	opts.Message = "chore: Resolve merge conflicts (last-write-wins)"
-	opts.AllowEmpty = true
-
-	return r.AddAndCommit([]string{"."}, opts)
+	status.ChangedFiles = files
+	return nil
 }
 
 // SyncV2Options configures the v2 sync operation
 type SyncV2Options struct {
 	PAT               string
 	ForceLastWriteWins bool
-	IncludePerUserDB  bool
-	OnBeforeSync      func() error // Called before sync (close DB)
+	IncludePerUserDB  bool         // Commit the per-user DB's access stats export; the DB itself never is
+	OnBeforeSync      func() error // Called before sync (export access stats, close DB)
 	OnAfterSync       func() error // Called after sync (reopen DB)
+	OnAfterPull       func(changedFiles []string) error // Called after OnAfterSync when the sync changed HEAD (reindex the changed files)
 }
 
 // SyncV2 performs a full sync with support for per-user database
-// This is the v2 sync that includes the .medha/medha.db in git operations
+// The database is an index kept out of git; only its access stats export is
+// synced, and OnAfterPull regenerates the index from the pulled files.
+// Callers hold LockRepository for the whole sync, hooks included.
 func (r *Repository) SyncV2(opts SyncV2Options) (*SyncStatus, error) {
 	status := &SyncStatus{
 		LastSync:       time.Now(),
@@ -298,6 +284,11 @@ func (r *Repository) SyncV2(opts SyncV2Options) (*SyncStatus, error) {
 		return status, nil
 	}
 
+	if err := r.IgnorePerUserDB(); err != nil {
+		status.Error = fmt.Sprintf("failed to untrack per-user DB: %v", err)
+		return status, fmt.Errorf("failed to untrack per-user DB: %w", err)
+	}
+
 	// Call before sync hook (close DB)
 	if opts.OnBeforeSync != nil {
 		if err := opts.OnBeforeSync(); err != nil {
@@ -306,15 +297,13 @@ func (r *Repository) SyncV2(opts SyncV2Options) (*SyncStatus, error) {
 		}
 	}
 
-	// Stage the per-user database if requested
+	// Commit the per-user database's access stats if requested
 	if opts.IncludePerUserDB {
-		if err := r.StagePerUserDB(); err != nil {
-			// Not a fatal error if file doesn't exist
-			if err.Error() != "no changes to stage" {
-				status.Error = fmt.Sprintf("failed to stage per-user DB: %v", err)
-			}
+		if err := r.CommitAccessStats(); err != nil {
+			status.Error = fmt.Sprintf("failed to commit access stats: %v", err)
 		}
 	}
+	headBefore, _ := r.HeadHash()
 
 	// Fetch first to check for conflicts
 	err := r.Fetch(opts.PAT)
@@ -330,23 +319,19 @@ func (r *Repository) SyncV2(opts SyncV2Options) (*SyncStatus, error) {
 	err = r.Pull(opts.PAT)
 	if err != nil {
 		if isConflictError(err) {
-			status.HasConflicts = true
-			
-			if opts.ForceLastWriteWins {
-				err = r.resolveConflictsLastWriteWins()
-				if err != nil {
-					status.Error = fmt.Sprintf("conflict resolution failed: %v", err)
-					if opts.OnAfterSync != nil {
-						opts.OnAfterSync() //nolint:errcheck
-					}
-					return status, fmt.Errorf("conflict resolution failed: %w", err)
+			if mergeErr := r.mergeRemote(opts.ForceLastWriteWins, status); mergeErr != nil {
+				status.Error = fmt.Sprintf("merge failed: %v", mergeErr)
+				if opts.OnAfterSync != nil {
+					opts.OnAfterSync() //nolint:errcheck
 				}
-			} else {
+				return status, fmt.Errorf("merge failed: %w", mergeErr)
+			}
+			if status.HasConflicts && !opts.ForceLastWriteWins {
 				status.Error = "merge conflicts detected, manual resolution required"
 				if opts.OnAfterSync != nil {
 					opts.OnAfterSync() //nolint:errcheck
 				}
-				return status, fmt.Errorf("merge conflicts detected")
+				return status, fmt.Errorf("merge conflicts detected in %d files", len(status.ConflictFiles))
 			}
 		} else {
 			status.Error = fmt.Sprintf("pull failed: %v", err)
@@ -364,6 +349,8 @@ func (r *Repository) SyncV2(opts SyncV2Options) (*SyncStatus, error) {
 		if opts.OnAfterSync != nil {
 			opts.OnAfterSync() //nolint:errcheck
 		}
+		// What was pulled is in HEAD even though the push failed
+		r.afterPull(headBefore, status, opts) //nolint:errcheck
 		return status, fmt.Errorf("push failed: %w", err)
 	}
 
@@ -376,25 +363,119 @@ func (r *Repository) SyncV2(opts SyncV2Options) (*SyncStatus, error) {
 		}
 	}
 
+	// Reindex what was pulled
+	if err := r.afterPull(headBefore, status, opts); err != nil {
+		status.SyncSuccessful = true
+		status.Error = fmt.Sprintf("post-pull hook failed (sync was successful): %v", err)
+		return status, nil
+	}
+
 	status.SyncSuccessful = true
 	return status, nil
 }
 
-// StagePerUserDB stages the per-user database file for commit
-func (r *Repository) StagePerUserDB() error {
+// afterPull records the files the sync changed since before and passes them
+// to the OnAfterPull hook
+func (r *Repository) afterPull(before plumbing.Hash, status *SyncStatus, opts SyncV2Options) error {
+	if err := r.recordPulled(before, status); err != nil {
+		return err
+	}
+	if !status.Pulled || opts.OnAfterPull == nil {
+		return nil
+	}
+	return opts.OnAfterPull(status.ChangedFiles)
+}
+
+// perUserDBIgnore keeps the per-user database, an index rebuilt from the
+// memory files, and its journal out of git
+var perUserDBIgnore = []string{".medha/medha.db", ".medha/medha.db-*"}
+
+// IgnorePerUserDB adds the per-user database to .gitignore and, in
+// repositories that committed it before, stops tracking it without deleting
+// the local file. Changes are committed.
+func (r *Repository) IgnorePerUserDB() error {
+	gitignore := filepath.Join(r.Path, ".gitignore")
+	existing, err := os.ReadFile(gitignore)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to read .gitignore: %w", err)
+	}
+	lines := strings.Split(string(existing), "\n")
+	content := strings.TrimRight(string(existing), "\n")
+	for _, pattern := range perUserDBIgnore {
+		if !slices.Contains(lines, pattern) {
+			if content != "" {
+				content += "\n"
+			}
+			content += pattern
+		}
+	}
+	if content+"\n" != string(existing) {
+		if err := os.WriteFile(gitignore, []byte(content+"\n"), 0644); err != nil {
+			return fmt.Errorf("failed to write .gitignore: %w", err)
+		}
+	}
+
+	idx, err := r.repo.Storer.Index()
+	if err != nil {
+		return fmt.Errorf("failed to read index: %w", err)
+	}
+	untracked := false
+	for _, entry := range append([]*index.Entry{}, idx.Entries...) {
+		if strings.HasPrefix(entry.Name, ".medha/medha.db") {
+			if _, err := idx.Remove(entry.Name); err != nil {
+				return fmt.Errorf("failed to untrack %s: %w", entry.Name, err)
+			}
+			untracked = true
+		}
+	}
+	if untracked {
+		if err := r.repo.Storer.SetIndex(idx); err != nil {
+			return fmt.Errorf("failed to write index: %w", err)
+		}
+	}
+
 	worktree, err := r.repo.Worktree()
 	if err != nil {
 		return fmt.Errorf("failed to get worktree: %w", err)
 	}
+	status, err := worktree.Status()
+	if err != nil {
+		return fmt.Errorf("failed to get status: %w", err)
+	}
+	if _, dirty := status[".gitignore"]; !untracked && !dirty {
+		return nil
+	}
+
+	opts := DefaultCommitOptions()
+	opts.Message = "chore: Stop tracking the per-user database"
+	return r.AddAndCommit([]string{gitignore}, opts)
+}
+
+// CommitAccessStats commits the per-user database's access stats export
+// (database.AccessStatsFile) when it changed
+func (r *Repository) CommitAccessStats() error {
+	if _, err := os.Stat(database.GetAccessStatsPath(r.Path)); os.IsNotExist(err) {
+		return nil
+	}
 
-	dbPath := ".medha/medha.db"
-	_, err = worktree.Add(dbPath)
+	worktree, err := r.repo.Worktree()
+	if err != nil {
+		return fmt.Errorf("failed to get worktree: %w", err)
+	}
+	if _, err := worktree.Add(database.AccessStatsFile); err != nil {
+		return fmt.Errorf("failed to stage access stats: %w", err)
+	}
+	status, err := worktree.Status()
 	if err != nil {
-		// Check if it's just because the file doesn't exist
-		return fmt.Errorf("failed to stage per-user DB: %w", err)
+		return fmt.Errorf("failed to get status: %w", err)
+	}
+	if file, ok := status[database.AccessStatsFile]; !ok || file.Staging == git.Unmodified {
+		return nil
 	}
 
-	return nil
+	opts := DefaultCommitOptions()
+	opts.Message = "chore: Update access stats"
+	return r.AddAndCommit(nil, opts)
 }
 
 // HasPerUserDB checks if the per-user database exists in the repository
@@ -0,0 +1,263 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package git
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testPAT stands in for a PAT; local remotes ignore it
+const testPAT = "test-pat"
+
+// setupDivergedClones returns two clones of a bare remote holding one file
+// and the .gitignore sync adds
+func setupDivergedClones(t *testing.T, name, content string) (*Repository, *Repository) {
+	tempDir := t.TempDir()
+	remotePath := filepath.Join(tempDir, "remote.git")
+	_, err := git.PlainInit(remotePath, true)
+	require.NoError(t, err)
+
+	first, err := InitRepository(filepath.Join(tempDir, "first"))
+	require.NoError(t, err)
+	writeAndCommit(t, first, name, content)
+	require.NoError(t, first.IgnorePerUserDB())
+	require.NoError(t, first.AddRemote("origin", remotePath))
+	require.NoError(t, first.Push(testPAT))
+
+	second, err := Clone(remotePath, testPAT, filepath.Join(tempDir, "second"))
+	require.NoError(t, err)
+	return first, second
+}
+
+// writeAndCommit writes a file in a repository and commits it
+func writeAndCommit(t *testing.T, repo *Repository, name, content string) {
+	path := filepath.Join(repo.Path, name)
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+	require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+	require.NoError(t, repo.CommitFile(path, "update "+name))
+}
+
+func readFile(t *testing.T, repo *Repository, name string) string {
+	content, err := os.ReadFile(filepath.Join(repo.Path, name))
+	require.NoError(t, err)
+	return string(content)
+}
+
+func TestSync_MergesNonOverlappingEdits(t *testing.T) {
+	memoryFile := "---\nid: doc\ntitle: Doc\ntags:\n    - go\n---\n\nLine 1\nLine 2\nLine 3\n"
+	first, second := setupDivergedClones(t, "notes/doc.md", memoryFile)
+
+	writeAndCommit(t, first, "notes/doc.md", "---\nid: doc\ntitle: Doc\ntags:\n    - go\n    - laptop\n---\n\nLine 0\nLine 1\nLine 2\nLine 3\n")
+	writeAndCommit(t, first, "notes/other.md", "---\nid: other\ntitle: Other\n---\n\nOnly on the first machine\n")
+	require.NoError(t, first.Push(testPAT))
+
+	writeAndCommit(t, second, "notes/doc.md", "---\nid: doc\ntitle: Doc\ntags:\n    - go\n---\n\nLine 1\nLine 2\nLine 3 changed\n")
+
+	status, err := second.Sync(testPAT, false)
+	require.NoError(t, err)
+	assert.True(t, status.SyncSuccessful)
+	assert.False(t, status.HasConflicts)
+
+	merged := readFile(t, second, "notes/doc.md")
+	assert.Contains(t, merged, "Line 0\nLine 1\nLine 2\nLine 3 changed")
+	assert.Contains(t, merged, "- laptop")
+	assert.Contains(t, readFile(t, second, "notes/other.md"), "Only on the first machine")
+
+	clean, err := second.IsClean()
+	require.NoError(t, err)
+	assert.True(t, clean)
+
+	// The merge was pushed, so the first machine fast-forwards to it
+	_, err = first.Sync(testPAT, false)
+	require.NoError(t, err)
+	assert.Equal(t, merged, readFile(t, first, "notes/doc.md"))
+	head, err := first.GetLastCommit()
+	require.NoError(t, err)
+	assert.Len(t, head.ParentHashes, 2)
+}
+
+func TestSync_OverlappingEditsConflict(t *testing.T) {
+	first, second := setupDivergedClones(t, "notes/doc.txt", "Shared line\n")
+
+	writeAndCommit(t, first, "notes/doc.txt", "First machine\n")
+	require.NoError(t, first.Push(testPAT))
+	writeAndCommit(t, second, "notes/doc.txt", "Second machine\n")
+	before, err := second.HeadHash()
+	require.NoError(t, err)
+
+	// Without force nothing changes
+	status, err := second.Sync(testPAT, false)
+	require.Error(t, err)
+	assert.True(t, status.HasConflicts)
+	assert.Equal(t, []string{"notes/doc.txt"}, status.ConflictFiles)
+	after, err := second.HeadHash()
+	require.NoError(t, err)
+	assert.Equal(t, before, after)
+	assert.Equal(t, "Second machine\n", readFile(t, second, "notes/doc.txt"))
+
+	// Forcing keeps the local version and pushes the merge
+	status, err = second.Sync(testPAT, true)
+	require.NoError(t, err)
+	assert.True(t, status.SyncSuccessful)
+	assert.Equal(t, []string{"notes/doc.txt"}, status.ConflictFiles)
+	assert.Equal(t, "Second machine\n", readFile(t, second, "notes/doc.txt"))
+}
+
+func TestSync_OverlappingMemoryEditsBecomeConflictMemories(t *testing.T) {
+	memoryFile := func(tags, content string) string {
+		return "---\nid: doc\ntitle: Doc\ntags:\n" + tags + "---\n\n" + content + "\n"
+	}
+	first, second := setupDivergedClones(t, "tags/go/doc.md", memoryFile("    - go\n", "Intro\nShared\nOutro"))
+	writeAndCommit(t, first, "tags/go/gone.md", "---\nid: gone\ntitle: Gone\n---\n\nOriginal\n")
+	require.NoError(t, first.Push(testPAT))
+	require.NoError(t, second.Pull(testPAT))
+
+	writeAndCommit(t, first, "tags/go/doc.md", memoryFile("    - go\n    - laptop\n", "Intro\nFirst machine\nOutro"))
+	writeAndCommit(t, first, "tags/go/gone.md", "---\nid: gone\ntitle: Gone\n---\n\nEdited on the first machine\n")
+	require.NoError(t, first.Push(testPAT))
+
+	writeAndCommit(t, second, "tags/go/doc.md", memoryFile("    - go\n", "Intro\nSecond machine\nOutro"))
+	require.NoError(t, os.Remove(filepath.Join(second.Path, "tags/go/gone.md")))
+	require.NoError(t, second.CommitAll("archive gone"))
+
+	// Memory conflicts never fail a sync
+	status, err := second.Sync(testPAT, false)
+	require.NoError(t, err)
+	assert.False(t, status.HasConflicts)
+	require.Len(t, status.ConflictMemories, 2)
+
+	merged := readFile(t, second, "tags/go/doc.md")
+	assert.Contains(t, merged, "Intro\nSecond machine\nOutro")
+	assert.Contains(t, merged, "- laptop")
+	assert.Contains(t, merged, "type: conflict")
+
+	review := readFile(t, second, filepath.Join("tags", "merge-conflict", status.ConflictMemories[0]+".md"))
+	assert.Contains(t, review, "<<<<<<< OURS\nSecond machine\n=======\nFirst machine\n>>>>>>> THEIRS")
+	edited := readFile(t, second, filepath.Join("tags", "merge-conflict", status.ConflictMemories[1]+".md"))
+	assert.Contains(t, edited, "Edited on the first machine")
+	_, err = os.Stat(filepath.Join(second.Path, "tags/go/gone.md"))
+	assert.True(t, os.IsNotExist(err), "the local archive stands")
+}
+
+func TestSync_ArchivedWhileEditedKeepsOneFile(t *testing.T) {
+	memoryFile := "---\nid: doc\ntitle: Doc\n---\n\n"
+	first, second := setupDivergedClones(t, "2026/10/doc.md", memoryFile+"Original\n")
+
+	// The first machine archives the memory the second one edits
+	require.NoError(t, os.MkdirAll(filepath.Join(first.Path, "archive"), 0755))
+	require.NoError(t, os.Rename(filepath.Join(first.Path, "2026/10/doc.md"), filepath.Join(first.Path, "archive/doc.md")))
+	require.NoError(t, first.CommitAll("archive doc"))
+	require.NoError(t, first.Push(testPAT))
+
+	writeAndCommit(t, second, "2026/10/doc.md", memoryFile+"Edited on the second machine\n")
+
+	status, err := second.Sync(testPAT, false)
+	require.NoError(t, err)
+	assert.False(t, status.HasConflicts)
+	require.Len(t, status.ConflictMemories, 1)
+
+	assert.Contains(t, readFile(t, second, "2026/10/doc.md"), "Edited on the second machine")
+	_, err = os.Stat(filepath.Join(second.Path, "archive/doc.md"))
+	assert.True(t, os.IsNotExist(err), "the memory is not duplicated in the archive")
+
+	review := readFile(t, second, filepath.Join("tags", "merge-conflict", status.ConflictMemories[0]+".md"))
+	assert.Contains(t, review, "moved it to `archive/doc.md`")
+	assert.Contains(t, review, "Original")
+}
+
+func TestIgnorePerUserDB_UntracksCommittedDatabase(t *testing.T) {
+	repo, err := InitRepository(t.TempDir())
+	require.NoError(t, err)
+	writeAndCommit(t, repo, ".medha/medha.db", "database")
+
+	require.NoError(t, repo.IgnorePerUserDB())
+	assert.Equal(t, "database", readFile(t, repo, ".medha/medha.db"), "the local database stays")
+	assert.Contains(t, readFile(t, repo, ".gitignore"), ".medha/medha.db\n")
+
+	head, err := repo.GetLastCommit()
+	require.NoError(t, err)
+	tree, err := head.Tree()
+	require.NoError(t, err)
+	_, err = tree.File(".medha/medha.db")
+	assert.Error(t, err, "the database is no longer committed")
+
+	// Later database changes leave the repository clean
+	require.NoError(t, os.WriteFile(filepath.Join(repo.Path, ".medha/medha.db"), []byte("changed"), 0644))
+	clean, err := repo.IsClean()
+	require.NoError(t, err)
+	assert.True(t, clean)
+
+	// Running it again changes nothing
+	require.NoError(t, repo.IgnorePerUserDB())
+	again, err := repo.GetLastCommit()
+	require.NoError(t, err)
+	assert.Equal(t, head.Hash, again.Hash)
+}
+
+func TestSyncV2_MergesAccessStatsAndRunsAfterPull(t *testing.T) {
+	stats := func(count int) string {
+		return `{"slug":"doc","access_count":` + strconv.Itoa(count) + `,"last_accessed_at":"2026-03-01T00:00:00Z"}` + "\n"
+	}
+	first, second := setupDivergedClones(t, ".medha/access.jsonl", stats(2))
+
+	writeAndCommit(t, first, ".medha/access.jsonl", stats(5))
+	require.NoError(t, first.Push(testPAT))
+	require.NoError(t, os.WriteFile(filepath.Join(second.Path, ".medha/access.jsonl"), []byte(stats(4)), 0644))
+
+	var pulled [][]string
+	afterPull := func(changedFiles []string) error {
+		pulled = append(pulled, changedFiles)
+		return nil
+	}
+	status, err := second.SyncV2(SyncV2Options{
+		PAT:              testPAT,
+		IncludePerUserDB: true,
+		OnAfterPull:      afterPull,
+	})
+	require.NoError(t, err)
+	assert.True(t, status.SyncSuccessful)
+	assert.True(t, status.Pulled)
+	assert.Equal(t, [][]string{{".medha/access.jsonl"}}, pulled)
+	assert.Equal(t, stats(7), readFile(t, second, ".medha/access.jsonl"))
+
+	// Nothing new to pull skips the hook
+	status, err = second.SyncV2(SyncV2Options{PAT: testPAT, OnAfterPull: afterPull})
+	require.NoError(t, err)
+	assert.False(t, status.Pulled)
+	assert.Len(t, pulled, 1)
+}
+
+func TestPull_KeepsUntrackedFiles(t *testing.T) {
+	first, second := setupDivergedClones(t, "notes/doc.md", "Line 1\n")
+	writeAndCommit(t, first, "notes/doc.md", "Line 1\nLine 2\n")
+	writeAndCommit(t, first, "notes/new.md", "New\n")
+	require.NoError(t, first.Push(testPAT))
+
+	// The per-user database is ignored and must survive a fast-forward
+	dbPath := filepath.Join(second.Path, ".medha", "medha.db")
+	require.NoError(t, os.MkdirAll(filepath.Dir(dbPath), 0755))
+	require.NoError(t, os.WriteFile(dbPath, []byte("index"), 0644))
+
+	require.NoError(t, second.Pull(testPAT))
+	assert.Equal(t, "Line 1\nLine 2\n", readFile(t, second, "notes/doc.md"))
+	assert.Equal(t, "New\n", readFile(t, second, "notes/new.md"))
+	assert.Equal(t, "index", readFile(t, second, ".medha/medha.db"))
+
+	clean, err := second.IsClean()
+	require.NoError(t, err)
+	assert.True(t, clean)
+	head, err := second.HeadHash()
+	require.NoError(t, err)
+	remoteHead, err := first.HeadHash()
+	require.NoError(t, err)
+	assert.Equal(t, remoteHead, head)
+}
@@ -0,0 +1,271 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package graph
+
+import (
+	"fmt"
+
+	"github.com/tejzpr/medha-mcp/internal/database"
+)
+
+// Traversal directions for slug-based graph walks
+const (
+	DirectionOutgoing = "outgoing"
+	DirectionIncoming = "incoming"
+	DirectionBoth     = "both"
+)
+
+// MaxTraversalHops is the safety limit for graph traversal depth
+const MaxTraversalHops = 5
+
+// SlugNode represents a node in the per-user memory graph
+type SlugNode struct {
+	Slug         string `json:"slug"`
+	Title        string `json:"title"`
+	Depth        int    `json:"depth"`
+	Parent       string `json:"parent,omitempty"`       // Slug this node was reached from
+	Relationship string `json:"relationship,omitempty"` // Relationship of the edge used to reach this node
+	Incoming     bool   `json:"incoming,omitempty"`     // The edge points from this node to its parent
+	Superseded   bool   `json:"superseded,omitempty"`
+}
+
+// SlugEdge represents an edge in the per-user memory graph
+type SlugEdge struct {
+	Source       string  `json:"source"`
+	Target       string  `json:"target"`
+	Relationship string  `json:"relationship"`
+	Strength     float64 `json:"strength"`
+}
+
+// SlugGraph represents a memory graph built from the per-user associations table
+type SlugGraph struct {
+	Start string     `json:"start"`
+	Nodes []SlugNode `json:"nodes"`
+	Edges []SlugEdge `json:"edges"`
+}
+
+// TraversalOptions controls a slug-based graph traversal
+type TraversalOptions struct {
+	MaxHops       int      // Maximum hops from the start node (capped at MaxTraversalHops)
+	Direction     string   // outgoing, incoming or both
+	Relationships []string // Only follow these relationship types (empty = all)
+	MinStrength   float64  // Only follow edges with at least this strength
+	BreadthFirst  bool     // BFS when true, DFS otherwise
+}
+
+// slugTraversal holds the state of a single slug-based traversal
+type slugTraversal struct {
+	m         *Manager
+	opts      TraversalOptions
+	relFilter map[string]bool
+	graph     *SlugGraph
+	nodes     map[string]int // Slug to index in graph.Nodes
+	seenEdges map[string]bool
+}
+
+// TraverseUserGraph walks the per-user associations table starting at startSlug.
+// The manager must be created with a per-user database.
+func (m *Manager) TraverseUserGraph(startSlug string, opts TraversalOptions) (*SlugGraph, error) {
+	if opts.MaxHops <= 0 {
+		opts.MaxHops = 1
+	}
+	if opts.MaxHops > MaxTraversalHops {
+		opts.MaxHops = MaxTraversalHops // Safety limit
+	}
+
+	switch opts.Direction {
+	case "":
+		opts.Direction = DirectionBoth
+	case DirectionOutgoing, DirectionIncoming, DirectionBoth:
+	default:
+		return nil, fmt.Errorf("invalid direction: %s", opts.Direction)
+	}
+
+	var start database.UserMemory
+	if err := m.db.Where("slug = ?", startSlug).First(&start).Error; err != nil {
+		return nil, fmt.Errorf("memory not found: %s", startSlug)
+	}
+
+	t := &slugTraversal{
+		m:         m,
+		opts:      opts,
+		relFilter: make(map[string]bool),
+		graph:     &SlugGraph{Start: startSlug, Nodes: []SlugNode{}, Edges: []SlugEdge{}},
+		nodes:     make(map[string]int),
+		seenEdges: make(map[string]bool),
+	}
+	for _, rel := range opts.Relationships {
+		t.relFilter[rel] = true
+	}
+
+	t.addNode(&start, 0, "", "", false)
+
+	if opts.BreadthFirst {
+		if err := t.bfs(startSlug); err != nil {
+			return nil, err
+		}
+	} else {
+		if err := t.dfs(startSlug, 0); err != nil {
+			return nil, err
+		}
+	}
+
+	return t.graph, nil
+}
+
+// bfs performs breadth-first traversal over slugs
+func (t *slugTraversal) bfs(startSlug string) error {
+	type queueItem struct {
+		slug  string
+		depth int
+	}
+
+	queue := []queueItem{{startSlug, 0}}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+
+		if current.depth >= t.opts.MaxHops {
+			continue
+		}
+
+		neighbors, err := t.expand(current.slug, current.depth)
+		if err != nil {
+			return err
+		}
+		for _, n := range neighbors {
+			queue = append(queue, queueItem{n, current.depth + 1})
+		}
+	}
+	return nil
+}
+
+// dfs performs depth-first traversal over slugs
+func (t *slugTraversal) dfs(slug string, depth int) error {
+	if depth >= t.opts.MaxHops {
+		return nil
+	}
+
+	neighbors, err := t.expand(slug, depth)
+	if err != nil {
+		return err
+	}
+	for _, n := range neighbors {
+		if err := t.dfs(n, depth+1); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// expand records the edges around slug and returns the neighbors that were
+// newly discovered or are now reached by a shorter path, so DFS walks them
+// again with the hops it has left
+func (t *slugTraversal) expand(slug string, depth int) ([]string, error) {
+	associations, err := t.m.getUserAssociations(slug, t.opts.Direction)
+	if err != nil {
+		return nil, err
+	}
+
+	var discovered []string
+	for _, assoc := range associations {
+		if len(t.relFilter) > 0 && !t.relFilter[assoc.AssociationType] {
+			continue
+		}
+		if assoc.Strength < t.opts.MinStrength {
+			continue
+		}
+
+		neighbor := assoc.TargetSlug
+		if assoc.SourceSlug != slug {
+			neighbor = assoc.SourceSlug
+		}
+
+		incoming := assoc.SourceSlug != slug && database.IsDirectionalType(assoc.AssociationType)
+		if i, ok := t.nodes[neighbor]; ok {
+			if node := &t.graph.Nodes[i]; depth+1 < node.Depth {
+				node.Depth, node.Parent, node.Relationship, node.Incoming = depth+1, slug, assoc.AssociationType, incoming
+				discovered = append(discovered, neighbor)
+			}
+		} else {
+			var mem database.UserMemory
+			if err := t.m.db.Where("slug = ?", neighbor).First(&mem).Error; err != nil {
+				// Dangling or archived target - don't walk into it
+				continue
+			}
+			t.addNode(&mem, depth+1, slug, assoc.AssociationType, incoming)
+			discovered = append(discovered, neighbor)
+		}
+
+		t.addEdge(assoc)
+	}
+
+	return discovered, nil
+}
+
+// addNode appends a memory to the graph
+func (t *slugTraversal) addNode(mem *database.UserMemory, depth int, parent, relationship string, incoming bool) {
+	t.nodes[mem.Slug] = len(t.graph.Nodes)
+	t.graph.Nodes = append(t.graph.Nodes, SlugNode{
+		Slug:         mem.Slug,
+		Title:        mem.Title,
+		Depth:        depth,
+		Parent:       parent,
+		Relationship: relationship,
+		Incoming:     incoming,
+		Superseded:   mem.SupersededBy != nil,
+	})
+}
+
+// addEdge appends an association to the graph, collapsing the reverse rows
+// that are stored for bidirectional relationship types
+func (t *slugTraversal) addEdge(assoc database.UserMemoryAssociation) {
+	a, b := assoc.SourceSlug, assoc.TargetSlug
+	if !database.IsDirectionalType(assoc.AssociationType) && b < a {
+		a, b = b, a
+	}
+	key := a + "|" + b + "|" + assoc.AssociationType
+	if t.seenEdges[key] {
+		return
+	}
+	t.seenEdges[key] = true
+
+	t.graph.Edges = append(t.graph.Edges, SlugEdge{
+		Source:       assoc.SourceSlug,
+		Target:       assoc.TargetSlug,
+		Relationship: assoc.AssociationType,
+		Strength:     assoc.Strength,
+	})
+}
+
+// getUserAssociations retrieves per-user associations touching a slug in the given direction
+func (m *Manager) getUserAssociations(slug, direction string) ([]database.UserMemoryAssociation, error) {
+	query := m.db.Model(&database.UserMemoryAssociation{})
+	switch direction {
+	case DirectionOutgoing:
+		query = query.Where("source_slug = ?", slug)
+	case DirectionIncoming:
+		query = query.Where("target_slug = ?", slug)
+	default:
+		query = query.Where("source_slug = ? OR target_slug = ?", slug, slug)
+	}
+
+	var associations []database.UserMemoryAssociation
+	if err := query.Order("strength DESC, id ASC").Find(&associations).Error; err != nil {
+		return nil, fmt.Errorf("failed to get associations: %w", err)
+	}
+	return associations, nil
+}
+
+// Children returns the nodes that were reached directly from parent, in traversal order
+func (g *SlugGraph) Children(parent string) []SlugNode {
+	var children []SlugNode
+	for _, n := range g.Nodes {
+		if n.Parent == parent && n.Slug != g.Start {
+			children = append(children, n)
+		}
+	}
+	return children
+}
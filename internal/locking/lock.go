@@ -5,6 +5,7 @@
 package locking
 
 import (
+	"fmt"
 	"time"
 
 	"gorm.io/gorm"
@@ -42,8 +43,7 @@ type ConflictError struct {
 }
 
 func (e *ConflictError) Error() string {
-	return "version conflict: expected " + string(rune('0'+e.ExpectedVersion)) +
-		", got " + string(rune('0'+e.ActualVersion))
+	return fmt.Sprintf("version conflict on '%s': expected version %d, found %d", e.Slug, e.ExpectedVersion, e.ActualVersion)
 }
 
 // LockError represents a locking failure
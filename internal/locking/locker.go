@@ -63,20 +63,22 @@ func (l *Locker) Acquire(slug, agentID string) (bool, error) {
 		ExpiresAt: expiresAt,
 	}
 
-	// Try to insert or update if expired
+	// Insert the lock, or take over one that expired or is already ours, in
+	// one statement so racing agents never see a unique constraint violation
 	err := l.db.Clauses(clause.OnConflict{
 		Columns: []clause.Column{{Name: "slug"}},
+		Where: clause.Where{Exprs: []clause.Expression{
+			clause.Expr{SQL: "memory_locks.expires_at < ? OR memory_locks.locked_by = ?", Vars: []interface{}{now, agentID}},
+		}},
 		DoUpdates: clause.Assignments(map[string]interface{}{
-			"locked_by":  clause.Column{Name: "CASE WHEN expires_at < ? THEN ? ELSE locked_by END", Table: now.Format(time.RFC3339), Raw: true},
-			"locked_at":  clause.Column{Name: "CASE WHEN expires_at < ? THEN ? ELSE locked_at END", Table: now.Format(time.RFC3339), Raw: true},
-			"expires_at": clause.Column{Name: "CASE WHEN expires_at < ? THEN ? ELSE expires_at END", Table: now.Format(time.RFC3339), Raw: true},
-			"version":    gorm.Expr("version + 1"),
+			"locked_by":  agentID,
+			"locked_at":  now,
+			"expires_at": expiresAt,
+			"version":    gorm.Expr("memory_locks.version + 1"),
 		}),
 	}).Create(&lock).Error
-
 	if err != nil {
-		// Fallback: manual check and update
-		return l.acquireFallback(slug, agentID, now, expiresAt)
+		return false, err
 	}
 
 	// Verify the lock was acquired by us
@@ -88,48 +90,6 @@ func (l *Locker) Acquire(slug, agentID string) (bool, error) {
 	return current.LockedBy == agentID, nil
 }
 
-// acquireFallback provides a fallback lock acquisition mechanism
-func (l *Locker) acquireFallback(slug, agentID string, now, expiresAt time.Time) (bool, error) {
-	var existing MemoryLock
-	err := l.db.Where("slug = ?", slug).First(&existing).Error
-
-	if err != nil {
-		// No lock exists, create one
-		lock := MemoryLock{
-			Slug:      slug,
-			Version:   1,
-			LockedBy:  agentID,
-			LockedAt:  now,
-			ExpiresAt: expiresAt,
-		}
-		if err := l.db.Create(&lock).Error; err != nil {
-			return false, err
-		}
-		return true, nil
-	}
-
-	// Lock exists - check if expired or owned by us
-	if existing.IsExpired() || existing.LockedBy == agentID {
-		// Take over the lock
-		result := l.db.Model(&MemoryLock{}).
-			Where("slug = ? AND version = ?", slug, existing.Version).
-			Updates(map[string]interface{}{
-				"locked_by":  agentID,
-				"locked_at":  now,
-				"expires_at": expiresAt,
-				"version":    existing.Version + 1,
-			})
-
-		if result.Error != nil {
-			return false, result.Error
-		}
-		return result.RowsAffected > 0, nil
-	}
-
-	// Locked by someone else and not expired
-	return false, nil
-}
-
 // Release releases a lock held by the specified agent
 func (l *Locker) Release(slug, agentID string) error {
 	result := l.db.Where("slug = ? AND locked_by = ?", slug, agentID).
@@ -159,6 +119,22 @@ func (l *Locker) IsLocked(slug string) (bool, string, error) {
 	return true, lock.LockedBy, nil
 }
 
+// Current returns the unexpired lock on a memory, or nil if it is not locked
+func (l *Locker) Current(slug string) (*MemoryLock, error) {
+	var lock MemoryLock
+	err := l.db.Where("slug = ?", slug).First(&lock).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if lock.IsExpired() {
+		return nil, nil
+	}
+	return &lock, nil
+}
+
 // Extend extends the TTL of an existing lock
 func (l *Locker) Extend(slug, agentID string) error {
 	expiresAt := time.Now().Add(l.lockTTL)
@@ -219,13 +195,13 @@ func UpdateWithVersion(db *gorm.DB, table string, slug string, currentVersion in
 
 	if result.RowsAffected == 0 {
 		// Check if record exists with different version
-		var count int64
-		db.Table(table).Where("slug = ?", slug).Count(&count)
-		if count > 0 {
+		var versions []int64
+		db.Table(table).Where("slug = ?", slug).Pluck("version", &versions)
+		if len(versions) > 0 {
 			return &ConflictError{
 				Slug:            slug,
 				ExpectedVersion: currentVersion,
-				ActualVersion:   -1, // Unknown
+				ActualVersion:   versions[0],
 			}
 		}
 		return fmt.Errorf("record not found: %s", slug)
@@ -252,13 +228,13 @@ func UpdateWithVersionUnscoped(db *gorm.DB, table string, slug string, currentVe
 
 	if result.RowsAffected == 0 {
 		// Check if record exists with different version (also unscoped)
-		var count int64
-		db.Unscoped().Table(table).Where("slug = ?", slug).Count(&count)
-		if count > 0 {
+		var versions []int64
+		db.Unscoped().Table(table).Where("slug = ?", slug).Pluck("version", &versions)
+		if len(versions) > 0 {
 			return &ConflictError{
 				Slug:            slug,
 				ExpectedVersion: currentVersion,
-				ActualVersion:   -1, // Unknown
+				ActualVersion:   versions[0],
 			}
 		}
 		return fmt.Errorf("record not found: %s", slug)
@@ -249,3 +249,20 @@ Body`,
 		})
 	}
 }
+
+func TestToMarkdown_Version(t *testing.T) {
+	mem := &Memory{ID: "versioned", Title: "Versioned", Version: 3, Content: "Body"}
+	markdown, err := mem.ToMarkdown()
+	require.NoError(t, err)
+	assert.Contains(t, markdown, "version: 3")
+
+	parsed, err := ParseMarkdown(markdown)
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), parsed.Version)
+
+	// Files written before versions were recorded omit it
+	mem.Version = 0
+	markdown, err = mem.ToMarkdown()
+	require.NoError(t, err)
+	assert.NotContains(t, markdown, "version:")
+}
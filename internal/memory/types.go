@@ -13,6 +13,7 @@ type Memory struct {
 	Tags         []string      `yaml:"tags" json:"tags"`
 	Created      time.Time     `yaml:"created" json:"created"`
 	Updated      time.Time     `yaml:"updated" json:"updated"`
+	Version      int64         `yaml:"version,omitempty" json:"version,omitempty"` // Database version this file was written at
 	SupersededBy string        `yaml:"superseded_by,omitempty" json:"superseded_by,omitempty"`
 	Associations []Association `yaml:"associations,omitempty" json:"associations,omitempty"`
 	Annotations  []Annotation  `yaml:"annotations,omitempty" json:"annotations,omitempty"`
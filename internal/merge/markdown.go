@@ -0,0 +1,235 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package merge
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/tejzpr/medha-mcp/internal/memory"
+)
+
+// Marking of merge conflicts left for review
+const (
+	ConflictTag            = "merge-conflict" // Tag of conflict memories
+	ConflictAnnotationType = "conflict"       // Annotation pointing a memory at its conflict memory
+)
+
+// MergeMarkdown merges three versions of a memory file. Frontmatter fields
+// take the side that changed them, falling back to MergeFrontmatter when both
+// did; tags, associations and annotations are combined; the body is merged
+// with diff3. base is empty when both sides added the file. It returns the
+// merged file and whether its body has conflict markers.
+func MergeMarkdown(base, ours, theirs string) (string, bool, error) {
+	baseMem, ourMem, theirMem, err := parseVersions(base, ours, theirs)
+	if err != nil {
+		return "", false, err
+	}
+
+	merged, hasConflict := mergeMemories(baseMem, ourMem, theirMem)
+	merged.Version = mergedVersion(merged, ourMem, theirMem)
+	content, err := merged.ToMarkdown()
+	if err != nil {
+		return "", false, err
+	}
+	return content, hasConflict, nil
+}
+
+// parseVersions parses the three versions of a memory file being merged
+func parseVersions(base, ours, theirs string) (*memory.Memory, *memory.Memory, *memory.Memory, error) {
+	baseMem := &memory.Memory{}
+	if base != "" {
+		parsed, err := memory.ParseMarkdown(base)
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("failed to parse base: %w", err)
+		}
+		baseMem = parsed
+	}
+	ourMem, err := memory.ParseMarkdown(ours)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to parse ours: %w", err)
+	}
+	theirMem, err := memory.ParseMarkdown(theirs)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to parse theirs: %w", err)
+	}
+	return baseMem, ourMem, theirMem, nil
+}
+
+// ResolveMarkdown merges three versions of a memory file like MergeMarkdown,
+// but never leaves conflict markers in it. When the bodies overlap, the file
+// keeps our body and gains an annotation naming a conflict memory, which
+// holds the marked-up body for review and is returned alongside.
+func ResolveMarkdown(base, ours, theirs string, now time.Time) (string, *memory.Memory, error) {
+	baseMem, ourMem, theirMem, err := parseVersions(base, ours, theirs)
+	if err != nil {
+		return "", nil, err
+	}
+
+	merged, hasConflict := mergeMemories(baseMem, ourMem, theirMem)
+	var review *memory.Memory
+	if hasConflict {
+		review = ConflictMemory(merged, merged.Content, "The memory kept this machine's text; the other machine's overlapping edits are marked below.", now)
+		merged.Content = ourMem.Content
+		merged.Annotations = append(merged.Annotations, conflictAnnotation(review.ID, now))
+	}
+	merged.Version = mergedVersion(merged, ourMem, theirMem)
+
+	content, err := merged.ToMarkdown()
+	if err != nil {
+		return "", nil, err
+	}
+	return content, review, nil
+}
+
+// ConflictMemory builds a memory holding text sync could not merge into
+// original, tagged for review and linked back to it
+func ConflictMemory(original *memory.Memory, content, explanation string, now time.Time) *memory.Memory {
+	return &memory.Memory{
+		ID:      fmt.Sprintf("%s-conflict-%s", original.ID, now.UTC().Format("20060102-150405")),
+		Title:   "Merge conflict: " + original.Title,
+		Tags:    []string{ConflictTag},
+		Created: now,
+		Updated: now,
+		Associations: []memory.Association{
+			{Target: original.ID, Type: memory.AssociationTypeReferences, Strength: 1},
+		},
+		Content: fmt.Sprintf("Sync could not merge concurrent edits to `%s`. %s Merge what should stay into `%s`, then forget this memory.\n\n%s",
+			original.ID, explanation, original.ID, content),
+	}
+}
+
+// conflictAnnotation points a memory at its conflict memory
+func conflictAnnotation(conflictSlug string, now time.Time) memory.Annotation {
+	return memory.Annotation{
+		Type:      ConflictAnnotationType,
+		Content:   fmt.Sprintf("Sync kept this version over conflicting edits from another machine; review them in '%s'.", conflictSlug),
+		CreatedAt: now,
+	}
+}
+
+// mergeMemories merges parsed memory files; see MergeMarkdown
+func mergeMemories(base, ours, theirs *memory.Memory) (*memory.Memory, bool) {
+	meta := MergeFrontmatter(fromMemory(ours), fromMemory(theirs))
+
+	merged := *ours
+	merged.Title = pickChanged(base.Title, ours.Title, theirs.Title, meta.Title)
+	merged.SupersededBy = pickChanged(base.SupersededBy, ours.SupersededBy, theirs.SupersededBy, meta.SupersededBy)
+	merged.Tags = withoutRemoved(meta.Tags, base.Tags, ours.Tags, theirs.Tags)
+	merged.Associations = mergeAssociations(base.Associations, ours.Associations, theirs.Associations)
+	if theirs.Updated.After(ours.Updated) {
+		merged.Updated = theirs.Updated
+	}
+
+	merged.Annotations = make([]memory.Annotation, 0, len(meta.Annotations))
+	for _, a := range meta.Annotations {
+		merged.Annotations = append(merged.Annotations, memory.Annotation{Type: a.Type, Content: a.Content, CreatedAt: a.CreatedAt})
+	}
+
+	body := MergeContent(base.Content, ours.Content, theirs.Content)
+	merged.Content = body.Content
+	return &merged, body.HasConflict
+}
+
+// mergedVersion returns the version of a merged memory. It keeps the higher
+// version only when the merge is exactly the side that had it; otherwise it
+// is a new version, so an agent holding either side's version as
+// expected_version sees the change instead of overwriting it.
+func mergedVersion(merged, ours, theirs *memory.Memory) int64 {
+	latest := max(ours.Version, theirs.Version)
+	for _, side := range []*memory.Memory{ours, theirs} {
+		if side.Version == latest && !sameMemory(merged, side) {
+			return latest + 1
+		}
+	}
+	return latest
+}
+
+// sameMemory reports whether two memories have the same body and metadata,
+// ignoring their version and update time
+func sameMemory(a, b *memory.Memory) bool {
+	x, y := *a, *b
+	x.Version, y.Version = 0, 0
+	x.Updated, y.Updated = time.Time{}, time.Time{}
+	xs, err := x.ToMarkdown()
+	if err != nil {
+		return false
+	}
+	ys, err := y.ToMarkdown()
+	return err == nil && xs == ys
+}
+
+// fromMemory converts a parsed memory file for MergeFrontmatter
+func fromMemory(m *memory.Memory) *Memory {
+	annotations := make([]Annotation, 0, len(m.Annotations))
+	for _, a := range m.Annotations {
+		annotations = append(annotations, Annotation{Type: a.Type, Content: a.Content, CreatedAt: a.CreatedAt})
+	}
+	return &Memory{
+		Slug:         m.ID,
+		Title:        m.Title,
+		Content:      m.Content,
+		Tags:         m.Tags,
+		SupersededBy: m.SupersededBy,
+		UpdatedAt:    m.Updated,
+		Annotations:  annotations,
+	}
+}
+
+// pickChanged returns the value of the side that changed a field, or
+// fallback when both changed it differently
+func pickChanged(base, ours, theirs, fallback string) string {
+	switch {
+	case ours == theirs, theirs == base:
+		return ours
+	case ours == base:
+		return theirs
+	}
+	return fallback
+}
+
+// mergeAssociations combines both sides' associations, ours first, dropping
+// those either side removed from base
+func mergeAssociations(base, ours, theirs []memory.Association) []memory.Association {
+	keys := func(associations []memory.Association) []string {
+		result := make([]string, 0, len(associations))
+		for _, a := range associations {
+			result = append(result, a.Type+"|"+a.Target)
+		}
+		return result
+	}
+	kept := withoutRemoved(MergeTags(keys(ours), keys(theirs)), keys(base), keys(ours), keys(theirs))
+
+	byKey := make(map[string]memory.Association, len(ours)+len(theirs))
+	for _, a := range append(append([]memory.Association{}, theirs...), ours...) {
+		byKey[a.Type+"|"+a.Target] = a
+	}
+	var result []memory.Association
+	for _, key := range kept {
+		result = append(result, byKey[key])
+	}
+	return result
+}
+
+// withoutRemoved drops tags from a union that either side removed from base
+func withoutRemoved(union, base, ours, theirs []string) []string {
+	kept := func(tags []string, tag string) bool {
+		for _, t := range tags {
+			if t == tag {
+				return true
+			}
+		}
+		return false
+	}
+
+	result := make([]string, 0, len(union))
+	for _, tag := range union {
+		if kept(base, tag) && (!kept(ours, tag) || !kept(theirs, tag)) {
+			continue
+		}
+		result = append(result, tag)
+	}
+	return result
+}
@@ -32,99 +32,183 @@ type Conflict struct {
 	Theirs    string
 }
 
+// Conflict markers written around unmergeable sections
+const (
+	markerOurs   = "<<<<<<< OURS"
+	markerSep    = "======="
+	markerTheirs = ">>>>>>> THEIRS"
+)
+
 // ThreeWayMerge performs a three-way merge of content
 // Returns merged content and whether there were conflicts
 func ThreeWayMerge(base, ours, theirs string) (string, bool) {
-	// Split into lines
+	result := MergeContent(base, ours, theirs)
+	return result.Content, result.HasConflict
+}
+
+// MergeContent performs a line-based diff3 merge. Lines are matched against
+// the base with a longest common subsequence, so a change on one side only
+// conflicts with a change on the other that touches the same lines.
+func MergeContent(base, ours, theirs string) Result {
+	// Simple cases: identical content or only one side changed
+	if ours == theirs || theirs == base {
+		return Result{Content: ours}
+	}
+	if ours == base {
+		return Result{Content: theirs}
+	}
+
 	baseLines := strings.Split(base, "\n")
 	ourLines := strings.Split(ours, "\n")
 	theirLines := strings.Split(theirs, "\n")
-
-	// Simple case: identical content
-	if ours == theirs {
-		return ours, false
+	ourMatch, ourOK := matchLines(baseLines, ourLines)
+	theirMatch, theirOK := matchLines(baseLines, theirLines)
+
+	result := Result{}
+	if !ourOK || !theirOK {
+		// Too much changed to match lines: the whole file conflicts
+		result.Content = strings.Join(mergeChunk(nil, &result, baseLines, ourLines, theirLines), "\n")
+		return result
 	}
+	merged := make([]string, 0, max(len(ourLines), len(theirLines)))
+	b, o, t := 0, 0, 0
+	for {
+		// The next base line kept by both sides ends the current chunk
+		stable := -1
+		for i := b; i < len(baseLines); i++ {
+			if ourMatch[i] >= 0 && theirMatch[i] >= 0 {
+				stable = i
+				break
+			}
+		}
+		bEnd, oEnd, tEnd := len(baseLines), len(ourLines), len(theirLines)
+		if stable >= 0 {
+			bEnd, oEnd, tEnd = stable, ourMatch[stable], theirMatch[stable]
+		}
 
-	// Simple case: only one side changed
-	if ours == base {
-		return theirs, false
-	}
-	if theirs == base {
-		return ours, false
+		merged = mergeChunk(merged, &result, baseLines[b:bEnd], ourLines[o:oEnd], theirLines[t:tEnd])
+		if stable < 0 {
+			break
+		}
+		merged = append(merged, baseLines[stable])
+		b, o, t = stable+1, oEnd+1, tEnd+1
 	}
 
-	// Both changed - attempt line-by-line merge
-	merged, hasConflict := mergeLines(baseLines, ourLines, theirLines)
-	return strings.Join(merged, "\n"), hasConflict
+	result.Content = strings.Join(merged, "\n")
+	return result
 }
 
-// mergeLines merges lines using longest common subsequence
-func mergeLines(base, ours, theirs []string) ([]string, bool) {
-	// Find common parts
-	hasConflict := false
-	merged := make([]string, 0)
-
-	// Simple approach: try to merge section by section
-	// This is a basic implementation - production would use more sophisticated diff3
-
-	maxLen := max(len(base), max(len(ours), len(theirs)))
-
-	for i := 0; i < maxLen; i++ {
-		baseLine := getLine(base, i)
-		ourLine := getLine(ours, i)
-		theirLine := getLine(theirs, i)
-
-		if ourLine == theirLine {
-			// Both agree
-			merged = append(merged, ourLine)
-		} else if ourLine == baseLine {
-			// Only theirs changed
-			merged = append(merged, theirLine)
-		} else if theirLine == baseLine {
-			// Only ours changed
-			merged = append(merged, ourLine)
-		} else {
-			// Both changed differently - conflict
-			hasConflict = true
-			merged = append(merged, "<<<<<<< OURS")
-			merged = append(merged, ourLine)
-			merged = append(merged, "=======")
-			merged = append(merged, theirLine)
-			merged = append(merged, ">>>>>>> THEIRS")
-		}
+// mergeChunk appends one section between stable lines to merged, taking the
+// side that changed it or marking a conflict when both did
+func mergeChunk(merged []string, result *Result, base, ours, theirs []string) []string {
+	switch {
+	case equalLines(ours, theirs), equalLines(theirs, base):
+		return append(merged, ours...)
+	case equalLines(ours, base):
+		return append(merged, theirs...)
 	}
 
-	return merged, hasConflict
+	result.HasConflict = true
+	start := len(merged) + 1
+	merged = append(merged, markerOurs)
+	merged = append(merged, ours...)
+	merged = append(merged, markerSep)
+	merged = append(merged, theirs...)
+	merged = append(merged, markerTheirs)
+	result.Conflicts = append(result.Conflicts, Conflict{
+		StartLine: start,
+		EndLine:   len(merged),
+		Ours:      strings.Join(ours, "\n"),
+		Theirs:    strings.Join(theirs, "\n"),
+	})
+	return merged
 }
 
-func getLine(lines []string, idx int) string {
-	if idx < len(lines) {
-		return lines[idx]
+// maxMatchCells bounds the longest common subsequence table of matchLines,
+// 16 MB of int32 cells
+const maxMatchCells = 4 << 20
+
+// matchLines returns, for each line of a, the index of the line of b it is
+// paired with in a longest common subsequence, or -1. It returns false when
+// the lines that differ would need a table larger than maxMatchCells.
+func matchLines(a, b []string) ([]int, bool) {
+	match := make([]int, len(a))
+	for i := range match {
+		match[i] = -1
+	}
+
+	// Common prefix and suffix need no table
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		match[prefix] = prefix
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		match[len(a)-1-suffix] = len(b) - 1 - suffix
+		suffix++
+	}
+
+	midA, midB := a[prefix:len(a)-suffix], b[prefix:len(b)-suffix]
+	n, m := len(midA), len(midB)
+	if n == 0 || m == 0 {
+		return match, true
+	}
+	if (n+1)*(m+1) > maxMatchCells {
+		return nil, false
 	}
-	return ""
+
+	// lcs[i*(m+1)+j] is the LCS length of midA[i:] and midB[j:]
+	lcs := make([]int32, (n+1)*(m+1))
+	for i := n - 1; i >= 0; i-- {
+		for j := m - 1; j >= 0; j-- {
+			if midA[i] == midB[j] {
+				lcs[i*(m+1)+j] = lcs[(i+1)*(m+1)+j+1] + 1
+			} else {
+				lcs[i*(m+1)+j] = max(lcs[(i+1)*(m+1)+j], lcs[i*(m+1)+j+1])
+			}
+		}
+	}
+
+	for i, j := 0, 0; i < n && j < m; {
+		switch {
+		case midA[i] == midB[j]:
+			match[prefix+i] = prefix + j
+			i++
+			j++
+		case lcs[(i+1)*(m+1)+j] >= lcs[i*(m+1)+j+1]:
+			i++
+		default:
+			j++
+		}
+	}
+	return match, true
 }
 
-func max(a, b int) int {
-	if a > b {
-		return a
+// equalLines reports whether two line slices are identical
+func equalLines(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
 	}
-	return b
+	return true
 }
 
-// MergeTags merges two sets of tags (union)
+// MergeTags merges two sets of tags (union), keeping our order followed by
+// tags only they have
 func MergeTags(ours, theirs []string) []string {
 	tagSet := make(map[string]bool)
+	result := make([]string, 0, len(ours)+len(theirs))
 
-	for _, tag := range ours {
-		tagSet[tag] = true
-	}
-	for _, tag := range theirs {
-		tagSet[tag] = true
-	}
-
-	result := make([]string, 0, len(tagSet))
-	for tag := range tagSet {
-		result = append(result, tag)
+	for _, tag := range append(append([]string{}, ours...), theirs...) {
+		if !tagSet[tag] {
+			tagSet[tag] = true
+			result = append(result, tag)
+		}
 	}
 
 	return result
@@ -5,11 +5,15 @@
 package merge
 
 import (
+	"fmt"
 	"sort"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tejzpr/medha-mcp/internal/memory"
 )
 
 func TestThreeWayMerge_NoConflict_Identical(t *testing.T) {
@@ -245,3 +249,185 @@ func TestCombineFrontmatterAndContent_NoFrontmatter(t *testing.T) {
 
 	assert.Equal(t, body, combined)
 }
+
+func TestThreeWayMerge_InsertedLineDoesNotShift(t *testing.T) {
+	base := "Line 1\nLine 2\nLine 3\nLine 4"
+	ours := "Line 0\nLine 1\nLine 2\nLine 3\nLine 4"
+	theirs := "Line 1\nLine 2\nLine 3\nLine 4 modified"
+
+	merged, hasConflict := ThreeWayMerge(base, ours, theirs)
+
+	assert.False(t, hasConflict)
+	assert.Equal(t, "Line 0\nLine 1\nLine 2\nLine 3\nLine 4 modified", merged)
+}
+
+func TestThreeWayMerge_DeletionAndEdit(t *testing.T) {
+	base := "Intro\nObsolete\nMiddle\nOutro"
+	ours := "Intro\nMiddle\nOutro"
+	theirs := "Intro\nObsolete\nMiddle\nOutro, revised"
+
+	merged, hasConflict := ThreeWayMerge(base, ours, theirs)
+
+	assert.False(t, hasConflict)
+	assert.Equal(t, "Intro\nMiddle\nOutro, revised", merged)
+}
+
+func TestMergeContent_ConflictKeepsSurroundingLines(t *testing.T) {
+	base := "Header\nShared\nFooter"
+	ours := "Header\nOur line\nFooter"
+	theirs := "Header\nTheir line\nFooter"
+
+	result := MergeContent(base, ours, theirs)
+
+	assert.True(t, result.HasConflict)
+	assert.Equal(t, "Header\n<<<<<<< OURS\nOur line\n=======\nTheir line\n>>>>>>> THEIRS\nFooter", result.Content)
+	require.Len(t, result.Conflicts, 1)
+	assert.Equal(t, 2, result.Conflicts[0].StartLine)
+	assert.Equal(t, 6, result.Conflicts[0].EndLine)
+	assert.Equal(t, "Our line", result.Conflicts[0].Ours)
+	assert.Equal(t, "Their line", result.Conflicts[0].Theirs)
+}
+
+func TestMergeContent_LargeRewriteConflictsWhole(t *testing.T) {
+	lines := func(prefix string, n int) string {
+		result := make([]string, n)
+		for i := range result {
+			result[i] = fmt.Sprintf("%s %d", prefix, i)
+		}
+		return strings.Join(result, "\n")
+	}
+	base := "Title\n" + lines("base", 2100) + "\nEnd"
+	ours := "Title\n" + lines("ours", 2100) + "\nEnd"
+	theirs := "Title\n" + lines("theirs", 2100) + "\nEnd"
+
+	// Past the matching table limit the files aren't diffed line by line
+	_, ok := matchLines(strings.Split(base, "\n"), strings.Split(ours, "\n"))
+	assert.False(t, ok)
+
+	result := MergeContent(base, ours, theirs)
+	assert.True(t, result.HasConflict)
+	require.Len(t, result.Conflicts, 1)
+	assert.Equal(t, ours, result.Conflicts[0].Ours)
+	assert.Equal(t, theirs, result.Conflicts[0].Theirs)
+
+	// One side alone still merges without a table
+	assert.Equal(t, ours, MergeContent(base, ours, base).Content)
+}
+
+func TestMergeTags_KeepsOrder(t *testing.T) {
+	assert.Equal(t, []string{"go", "api", "react"}, MergeTags([]string{"go", "api"}, []string{"react", "go"}))
+}
+
+func TestMergeMarkdown(t *testing.T) {
+	updated := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	file := func(title string, tags []string, annotations []memory.Annotation, updated time.Time, version int64, content string) string {
+		mem := &memory.Memory{ID: "doc", Title: title, Tags: tags, Updated: updated, Version: version, Annotations: annotations, Content: content}
+		markdown, err := mem.ToMarkdown()
+		require.NoError(t, err)
+		return markdown
+	}
+	note := memory.Annotation{Type: "context", Content: "Added on laptop", CreatedAt: updated}
+
+	base := file("Doc", []string{"go", "old"}, nil, updated, 1, "Line 1\nLine 2\nLine 3")
+	ours := file("Doc renamed", []string{"go", "old", "laptop"}, []memory.Annotation{note}, updated.Add(time.Hour), 2, "Line 1 ours\nLine 2\nLine 3")
+	theirs := file("Doc", []string{"go", "desktop"}, nil, updated.Add(2*time.Hour), 3, "Line 1\nLine 2\nLine 3 theirs")
+
+	merged, hasConflict, err := MergeMarkdown(base, ours, theirs)
+	require.NoError(t, err)
+	assert.False(t, hasConflict)
+
+	mem, err := memory.ParseMarkdown(merged)
+	require.NoError(t, err)
+	assert.Equal(t, "Doc renamed", mem.Title, "only ours changed the title")
+	assert.Equal(t, []string{"go", "laptop", "desktop"}, mem.Tags, "removed tags stay removed")
+	assert.Len(t, mem.Annotations, 1)
+	assert.Equal(t, int64(4), mem.Version, "the merge is newer than either side")
+	assert.True(t, mem.Updated.Equal(updated.Add(2*time.Hour)))
+	assert.Equal(t, "Line 1 ours\nLine 2\nLine 3 theirs", mem.Content)
+
+	// Overlapping body edits are reported
+	theirs = file("Doc", []string{"go"}, nil, updated, 2, "Line 1 theirs\nLine 2\nLine 3")
+	merged, hasConflict, err = MergeMarkdown(base, ours, theirs)
+	require.NoError(t, err)
+	assert.True(t, hasConflict)
+	assert.True(t, HasConflictMarkers(merged))
+}
+
+func TestMergeMarkdown_Version(t *testing.T) {
+	updated := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	file := func(tags []string, version int64, content string) string {
+		mem := &memory.Memory{ID: "doc", Title: "Doc", Tags: tags, Updated: updated, Version: version, Content: content}
+		markdown, err := mem.ToMarkdown()
+		require.NoError(t, err)
+		return markdown
+	}
+	versionOf := func(merged string) int64 {
+		mem, err := memory.ParseMarkdown(merged)
+		require.NoError(t, err)
+		return mem.Version
+	}
+	base := file([]string{"go"}, 1, "Line 1\nLine 2")
+
+	// Theirs already holds everything ours changed, so it stays current
+	ours := file([]string{"go", "api"}, 2, "Line 1\nLine 2")
+	theirs := file([]string{"go", "api"}, 3, "Line 1\nLine 2 theirs")
+	merged, _, err := MergeMarkdown(base, ours, theirs)
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), versionOf(merged))
+
+	// Equal versions with different edits: neither side's version matches
+	ours = file([]string{"go"}, 2, "Line 1 ours\nLine 2")
+	theirs = file([]string{"go"}, 2, "Line 1\nLine 2 theirs")
+	merged, _, err = MergeMarkdown(base, ours, theirs)
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), versionOf(merged))
+
+	// A tag from the older side alone makes the merge newer
+	ours = file([]string{"go"}, 3, "Line 1\nLine 2 ours")
+	theirs = file([]string{"go", "api"}, 2, "Line 1\nLine 2")
+	merged, _, err = MergeMarkdown(base, ours, theirs)
+	require.NoError(t, err)
+	assert.Equal(t, int64(4), versionOf(merged))
+}
+
+func TestResolveMarkdown_ConflictMemory(t *testing.T) {
+	now := time.Date(2026, 2, 3, 4, 5, 6, 0, time.UTC)
+	file := func(associations []memory.Association, content string) string {
+		mem := &memory.Memory{ID: "doc", Title: "Doc", Associations: associations, Content: content}
+		markdown, err := mem.ToMarkdown()
+		require.NoError(t, err)
+		return markdown
+	}
+	spec := memory.Association{Target: "spec", Type: memory.AssociationTypeReferences, Strength: 0.5}
+	team := memory.Association{Target: "team", Type: memory.AssociationTypePerson, Strength: 0.5}
+	plan := memory.Association{Target: "plan", Type: memory.AssociationTypeFollows, Strength: 0.5}
+
+	base := file([]memory.Association{spec}, "Intro\nShared\nMiddle\nOutro")
+	ours := file([]memory.Association{spec, team}, "Intro\nOur line\nMiddle\nOutro")
+	theirs := file([]memory.Association{plan}, "Intro\nTheir line\nMiddle\nOutro")
+
+	merged, review, err := ResolveMarkdown(base, ours, theirs, now)
+	require.NoError(t, err)
+	assert.False(t, HasConflictMarkers(merged))
+
+	mem, err := memory.ParseMarkdown(merged)
+	require.NoError(t, err)
+	assert.Equal(t, "Intro\nOur line\nMiddle\nOutro", mem.Content)
+	assert.Equal(t, []memory.Association{team, plan}, mem.Associations, "removed associations stay removed")
+	require.Len(t, mem.Annotations, 1)
+	assert.Equal(t, ConflictAnnotationType, mem.Annotations[0].Type)
+	assert.Contains(t, mem.Annotations[0].Content, "doc-conflict-20260203-040506")
+
+	require.NotNil(t, review)
+	assert.Equal(t, "doc-conflict-20260203-040506", review.ID)
+	assert.Equal(t, []string{ConflictTag}, review.Tags)
+	assert.Equal(t, "doc", review.Associations[0].Target)
+	assert.Contains(t, review.Content, "<<<<<<< OURS\nOur line\n=======\nTheir line\n>>>>>>> THEIRS")
+
+	// Clean merges need no review
+	theirs = file([]memory.Association{spec}, "Intro\nShared\nMiddle\nOutro, revised")
+	merged, review, err = ResolveMarkdown(base, ours, theirs, now)
+	require.NoError(t, err)
+	assert.Nil(t, review)
+	assert.Contains(t, merged, "Our line\nMiddle\nOutro, revised")
+}
@@ -0,0 +1,203 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+// Package ranking combines independent relevance signals into one ordering.
+//
+// Raw scores from different signals (BM25, cosine similarity, tag counts,
+// association strength) live on different scales and shift with the size
+// of the corpus, so they are never added together. Each signal instead
+// produces a ranked list, and reciprocal rank fusion combines the ranks:
+//
+//	score(d) = Σ weight(s) / (k + rank_s(d))
+package ranking
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Signal names
+const (
+	SignalKeyword     = "keyword"     // Full-text BM25 over title, body, tags and annotations
+	SignalSemantic    = "semantic"    // Embedding similarity
+	SignalTag         = "tag"         // Tag name hits
+	SignalAssociation = "association" // Proximity to other matches in the graph
+)
+
+// DefaultK is the RRF rank constant. Larger values flatten the difference
+// between the top ranks and the tail.
+const DefaultK = 60
+
+// Weights controls how much each signal contributes to the fused score
+type Weights struct {
+	K           int
+	Keyword     float64
+	Semantic    float64
+	Tag         float64
+	Association float64
+}
+
+// DefaultWeights returns the weights used when none are configured
+func DefaultWeights() Weights {
+	return Weights{
+		K:           DefaultK,
+		Keyword:     1.0,
+		Semantic:    1.0,
+		Tag:         0.8,
+		Association: 0.5,
+	}
+}
+
+// weight returns the weight of a signal
+func (w Weights) weight(signal string) float64 {
+	switch signal {
+	case SignalKeyword:
+		return w.Keyword
+	case SignalSemantic:
+		return w.Semantic
+	case SignalTag:
+		return w.Tag
+	case SignalAssociation:
+		return w.Association
+	}
+	return 0
+}
+
+// Hit is a document's raw score from one signal; higher is better
+type Hit struct {
+	Slug  string
+	Score float64
+}
+
+// Contribution records how one signal ranked a result
+type Contribution struct {
+	Signal string  `json:"signal"`
+	Rank   int     `json:"rank"`   // 1-based position in the signal's list
+	Raw    float64 `json:"raw"`    // The signal's own score
+	Weight float64 `json:"weight"` // Configured weight of the signal
+	Score  float64 `json:"score"`  // weight / (k + rank)
+}
+
+// Result is a fused result with the contributions that produced its score
+type Result struct {
+	Slug          string         `json:"slug"`
+	Score         float64        `json:"score"`
+	Contributions []Contribution `json:"contributions"`
+}
+
+// Signals returns the names of the signals that matched, strongest first
+func (r Result) Signals() []string {
+	names := make([]string, len(r.Contributions))
+	for i, c := range r.Contributions {
+		names[i] = c.Signal
+	}
+	return names
+}
+
+// Explain describes why the result ranked where it did
+func (r Result) Explain() string {
+	parts := make([]string, len(r.Contributions))
+	for i, c := range r.Contributions {
+		parts[i] = fmt.Sprintf("%s #%d (%.3g) ×%.2g = %.4f", c.Signal, c.Rank, c.Raw, c.Weight, c.Score)
+	}
+	return fmt.Sprintf("%s → %.4f", strings.Join(parts, " + "), r.Score)
+}
+
+// Fusion accumulates ranked lists and fuses them with reciprocal rank fusion
+type Fusion struct {
+	weights Weights
+	results map[string]*Result
+}
+
+// NewFusion creates an empty fusion using the given weights.
+// Unset weights fall back to DefaultWeights.
+func NewFusion(weights Weights) *Fusion {
+	if weights == (Weights{}) {
+		weights = DefaultWeights()
+	}
+	if weights.K <= 0 {
+		weights.K = DefaultK
+	}
+	return &Fusion{
+		weights: weights,
+		results: make(map[string]*Result),
+	}
+}
+
+// Add ranks a signal's hits by score and adds their contributions.
+// Signals with a zero weight are ignored. Ties are ranked by slug so the
+// order is stable.
+func (f *Fusion) Add(signal string, hits []Hit) {
+	weight := f.weights.weight(signal)
+	if weight <= 0 || len(hits) == 0 {
+		return
+	}
+
+	ranked := make([]Hit, len(hits))
+	copy(ranked, hits)
+	sortHits(ranked)
+
+	seen := make(map[string]bool, len(ranked))
+	rank := 0
+	for _, hit := range ranked {
+		if seen[hit.Slug] {
+			continue // Keep only a document's best hit per signal
+		}
+		seen[hit.Slug] = true
+		rank++
+
+		score := weight / float64(f.weights.K+rank)
+		r, ok := f.results[hit.Slug]
+		if !ok {
+			r = &Result{Slug: hit.Slug}
+			f.results[hit.Slug] = r
+		}
+		r.Score += score
+		r.Contributions = append(r.Contributions, Contribution{
+			Signal: signal,
+			Rank:   rank,
+			Raw:    hit.Score,
+			Weight: weight,
+			Score:  score,
+		})
+	}
+}
+
+// Contains reports whether any signal has matched the slug
+func (f *Fusion) Contains(slug string) bool {
+	_, ok := f.results[slug]
+	return ok
+}
+
+// Results returns the fused results, best first
+func (f *Fusion) Results() []Result {
+	results := make([]Result, 0, len(f.results))
+	for _, r := range f.results {
+		res := *r
+		res.Contributions = append([]Contribution(nil), r.Contributions...)
+		sort.SliceStable(res.Contributions, func(i, j int) bool {
+			return res.Contributions[i].Score > res.Contributions[j].Score
+		})
+		results = append(results, res)
+	}
+
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Score != results[j].Score {
+			return results[i].Score > results[j].Score
+		}
+		return results[i].Slug < results[j].Slug
+	})
+	return results
+}
+
+// sortHits orders hits by score (descending), then slug
+func sortHits(hits []Hit) {
+	sort.Slice(hits, func(i, j int) bool {
+		if hits[i].Score != hits[j].Score {
+			return hits[i].Score > hits[j].Score
+		}
+		return hits[i].Slug < hits[j].Slug
+	})
+}
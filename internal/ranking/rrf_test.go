@@ -0,0 +1,76 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package ranking
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFusion_AgreementBeatsSingleSignal(t *testing.T) {
+	f := NewFusion(DefaultWeights())
+	f.Add(SignalKeyword, []Hit{{"a", 9}, {"both", 5}})
+	f.Add(SignalSemantic, []Hit{{"b", 0.9}, {"both", 0.8}})
+
+	results := f.Results()
+	require.Len(t, results, 3)
+	assert.Equal(t, "both", results[0].Slug)
+	assert.Equal(t, []string{SignalKeyword, SignalSemantic}, results[0].Signals())
+}
+
+func TestFusion_RanksNotRawScores(t *testing.T) {
+	// A huge raw BM25 score must not drown out a first-place semantic hit
+	f := NewFusion(DefaultWeights())
+	f.Add(SignalKeyword, []Hit{{"keyword", 1000}})
+	f.Add(SignalSemantic, []Hit{{"semantic", 0.31}})
+
+	results := f.Results()
+	require.Len(t, results, 2)
+	assert.InDelta(t, results[0].Score, results[1].Score, 1e-12)
+}
+
+func TestFusion_Weights(t *testing.T) {
+	w := DefaultWeights()
+	w.Semantic = 2
+	f := NewFusion(w)
+	f.Add(SignalKeyword, []Hit{{"keyword", 1}})
+	f.Add(SignalSemantic, []Hit{{"semantic", 1}})
+	assert.Equal(t, "semantic", f.Results()[0].Slug)
+
+	w.Tag = 0
+	f = NewFusion(w)
+	f.Add(SignalTag, []Hit{{"tagged", 1}})
+	assert.Empty(t, f.Results(), "zero weight disables a signal")
+}
+
+func TestFusion_Contributions(t *testing.T) {
+	f := NewFusion(Weights{K: 10, Keyword: 1, Association: 0.5})
+	f.Add(SignalKeyword, []Hit{{"x", 3}, {"y", 2}, {"y", 1}})
+	f.Add(SignalAssociation, []Hit{{"y", 0.5}})
+
+	results := f.Results()
+	require.Len(t, results, 2)
+
+	y := results[0]
+	assert.Equal(t, "y", y.Slug)
+	require.Len(t, y.Contributions, 2, "duplicate hits keep only the best")
+	assert.Equal(t, Contribution{Signal: SignalKeyword, Rank: 2, Raw: 2, Weight: 1, Score: 1.0 / 12}, y.Contributions[0])
+	assert.Equal(t, 1, y.Contributions[1].Rank)
+	assert.InDelta(t, 1.0/12+0.5/11, y.Score, 1e-12)
+	assert.Contains(t, y.Explain(), "keyword #2")
+	assert.Contains(t, y.Explain(), "association #1")
+}
+
+func TestFusion_UnsetWeightsUseDefaults(t *testing.T) {
+	f := NewFusion(Weights{})
+	f.Add(SignalKeyword, []Hit{{"b", 1}, {"a", 1}})
+
+	results := f.Results()
+	require.Len(t, results, 2)
+	assert.Equal(t, "a", results[0].Slug, "ties are ordered by slug")
+	assert.Equal(t, 1.0/float64(DefaultK+1), results[0].Score)
+}
@@ -0,0 +1,16 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package ranking
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTokenize(t *testing.T) {
+	assert.Equal(t, []string{"oauth2", "api", "keys", "v1"}, Tokenize("OAuth2 / API-keys (v1)"))
+	assert.Empty(t, Tokenize("  -- "))
+}
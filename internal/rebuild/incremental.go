@@ -0,0 +1,88 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package rebuild
+
+import (
+	"fmt"
+	"log"
+	"sync"
+
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/tejzpr/medha-mcp/internal/database"
+	"github.com/tejzpr/medha-mcp/internal/git"
+	"gorm.io/gorm"
+)
+
+// incrementalLocks serializes incremental rebuilds of each repository, which
+// sync, the CLI and a watcher may start at once
+var incrementalLocks sync.Map
+
+// IncrementalRebuildUserIndex brings the per-user index up to date with HEAD.
+// It reindexes only the files changed between the last indexed commit and
+// HEAD (see ReindexUserFiles), then records HEAD. An index with no recorded
+// commit, or one whose commit is no longer in the history, is rebuilt in full.
+func IncrementalRebuildUserIndex(userDB *gorm.DB, repoPath string) (*Result, error) {
+	lock, _ := incrementalLocks.LoadOrStore(repoPath, &sync.Mutex{})
+	lock.(*sync.Mutex).Lock()
+	defer lock.(*sync.Mutex).Unlock()
+
+	repo, err := git.OpenRepository(repoPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open repository: %w", err)
+	}
+	head, err := repo.HeadHash()
+	if err != nil {
+		return nil, err
+	}
+	if head.IsZero() {
+		return &Result{}, nil
+	}
+
+	last, err := database.GetLastIndexedCommit(userDB)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read last indexed commit: %w", err)
+	}
+	if last == head.String() {
+		return &Result{}, nil
+	}
+
+	var files []string
+	if last != "" {
+		files, err = repo.ChangedFilesBetween(plumbing.NewHash(last), head)
+		if err != nil {
+			log.Printf("Last indexed commit %s is unavailable (%v), rebuilding in full", last, err)
+			last = ""
+		}
+	}
+
+	var result *Result
+	if last == "" {
+		result, err = RegenerateUserIndex(userDB, repoPath)
+	} else {
+		result, err = ReindexUserFiles(userDB, repoPath, files)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := database.SetLastIndexedCommit(userDB, head.String()); err != nil {
+		return nil, fmt.Errorf("failed to record last indexed commit: %w", err)
+	}
+	return result, nil
+}
+
+// recordIndexedCommit records HEAD as indexed after a full rebuild. A
+// directory that is not a git repository has nothing to record.
+func recordIndexedCommit(userDB *gorm.DB, repoPath string) error {
+	repo, err := git.OpenRepository(repoPath)
+	if err != nil {
+		return nil
+	}
+	head, err := repo.HeadHash()
+	if err != nil || head.IsZero() {
+		return err
+	}
+	return database.SetLastIndexedCommit(userDB, head.String())
+}
@@ -32,6 +32,8 @@ type Result struct {
 	MemoriesProcessed int
 	MemoriesCreated   int
 	MemoriesSkipped   int
+	MemoriesUpdated   int // Incremental reindex only
+	MemoriesRemoved   int // Incremental reindex only
 	TagsCreated       int
 	AssociationsCreated int
 	Errors            []string
@@ -319,6 +321,11 @@ const querySlugEqualsV2 = "slug = ?"
 func RebuildUserIndex(userDB *gorm.DB, repoPath string, opts Options) (*Result, error) {
 	result := &Result{}
 
+	// Make sure the full-text index exists before it is cleared or filled
+	if err := database.CreateUserFTS(userDB); err != nil {
+		return nil, err
+	}
+
 	// Handle existing data check and force clear
 	if err := handleExistingUserData(userDB, opts); err != nil {
 		return nil, err
@@ -351,17 +358,22 @@ func RebuildUserIndex(userDB *gorm.DB, repoPath string, opts Options) (*Result,
 		}
 
 		result.MemoriesCreated++
+		if err := createUserAnnotations(userDB, mem); err != nil {
+			result.Errors = append(result.Errors, fmt.Sprintf("%s: %v", filePath, err))
+		}
 
 		// Track associations for second pass
 		if len(mem.Associations) > 0 {
 			memoryAssociations[mem.ID] = mem.Associations
 		}
 
-		// Handle archived files
+		// Handle archived files; only live memories are indexed for full-text search
 		if isArchived {
 			// Set deleted_at for archived memories
 			userDB.Model(&database.UserMemory{}).Where(querySlugEqualsV2, mem.ID).
 				Update("deleted_at", time.Now())
+		} else if err := database.IndexMemoryFTS(userDB, database.MemoryFTSDocument(mem.ID, mem)); err != nil {
+			result.Errors = append(result.Errors, fmt.Sprintf("%s: failed to index for search: %v", filePath, err))
 		}
 	}
 
@@ -370,6 +382,25 @@ func RebuildUserIndex(userDB *gorm.DB, repoPath string, opts Options) (*Result,
 	result.AssociationsCreated = assocCount
 	result.Errors = append(result.Errors, assocErrors...)
 
+	// Later incremental rebuilds start from here
+	if err := recordIndexedCommit(userDB, repoPath); err != nil {
+		result.Errors = append(result.Errors, fmt.Sprintf("failed to record last indexed commit: %v", err))
+	}
+
+	return result, nil
+}
+
+// RegenerateUserIndex rebuilds the per-user database from the memory files,
+// as after a pull, and restores the access stats exported to git. Embeddings
+// are keyed by slug and content hash, so unchanged memories keep theirs.
+func RegenerateUserIndex(userDB *gorm.DB, repoPath string) (*Result, error) {
+	result, err := RebuildUserIndex(userDB, repoPath, Options{Force: true})
+	if err != nil {
+		return nil, err
+	}
+	if err := database.ImportAccessStats(userDB, repoPath); err != nil {
+		result.Errors = append(result.Errors, err.Error())
+	}
 	return result, nil
 }
 
@@ -421,6 +452,11 @@ func clearUserDBIndex(userDB *gorm.DB) error {
 		return fmt.Errorf("failed to delete tags: %w", err)
 	}
 
+	// 6. Clear full-text index
+	if err := database.ClearUserFTS(userDB); err != nil {
+		return fmt.Errorf("failed to clear full-text index: %w", err)
+	}
+
 	return nil
 }
 
@@ -503,13 +539,16 @@ func processUserMemoryFile(userDB *gorm.DB, repoPath, filePath string) (*memory.
 	// Calculate content hash
 	contentHash := CalculateContentHash(string(content))
 
-	// Create memory record
+	// Create memory record; tools read FilePath directly, so it is absolute
 	dbMem := &database.UserMemory{
 		Slug:        mem.ID,
 		Title:       mem.Title,
-		FilePath:    relPath, // Store relative path for portability
+		FilePath:    filePath,
 		ContentHash: contentHash,
 	}
+	if mem.Version > 0 {
+		dbMem.Version = mem.Version
+	}
 
 	// Handle superseded_by from frontmatter
 	if mem.SupersededBy != "" {
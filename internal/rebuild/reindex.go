@@ -0,0 +1,235 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package rebuild
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/tejzpr/medha-mcp/internal/database"
+	"github.com/tejzpr/medha-mcp/internal/memory"
+	"gorm.io/gorm"
+)
+
+// ReindexUserFiles updates the per-user index for files that changed in the
+// repository, such as the ones a sync pulled, instead of rebuilding it.
+// Paths are relative to repoPath. Memory files that exist are re-read,
+// replacing their memory's record, tags, outgoing associations and
+// annotations; files under archive/ are soft-deleted; memories whose file is
+// gone without another taking its slug are removed. A changed access stats
+// export is imported.
+func ReindexUserFiles(userDB *gorm.DB, repoPath string, files []string) (*Result, error) {
+	result := &Result{}
+	if err := database.CreateUserFTS(userDB); err != nil {
+		return nil, err
+	}
+
+	var removedPaths []string
+	upserted := make(map[string]bool)
+	memoryAssociations := make(map[string][]memory.Association)
+	importStats := false
+	for _, file := range files {
+		relPath := filepath.ToSlash(file)
+		if relPath == database.AccessStatsFile {
+			importStats = true
+			continue
+		}
+		if !isUserMemoryPath(relPath) {
+			continue
+		}
+		result.MemoriesProcessed++
+
+		filePath := filepath.Join(repoPath, filepath.FromSlash(relPath))
+		if _, err := os.Stat(filePath); os.IsNotExist(err) {
+			removedPaths = append(removedPaths, filePath)
+			continue
+		}
+
+		mem, created, err := upsertUserMemoryFile(userDB, repoPath, filePath)
+		if err != nil {
+			result.Errors = append(result.Errors, fmt.Sprintf("%s: %v", relPath, err))
+			continue
+		}
+		if created {
+			result.MemoriesCreated++
+		} else {
+			result.MemoriesUpdated++
+		}
+		upserted[mem.ID] = true
+		if len(mem.Associations) > 0 {
+			memoryAssociations[mem.ID] = mem.Associations
+		}
+	}
+
+	// A file that moved shows up removed under its old path; its slug was
+	// re-read under the new one
+	for _, filePath := range removedPaths {
+		var stale []database.UserMemory
+		if err := userDB.Unscoped().Where("file_path = ?", filePath).Find(&stale).Error; err != nil {
+			result.Errors = append(result.Errors, fmt.Sprintf("%s: %v", filePath, err))
+			continue
+		}
+		for _, mem := range stale {
+			if upserted[mem.Slug] {
+				continue
+			}
+			if err := removeUserMemory(userDB, mem.Slug); err != nil {
+				result.Errors = append(result.Errors, fmt.Sprintf("%s: %v", mem.Slug, err))
+				continue
+			}
+			result.MemoriesRemoved++
+		}
+	}
+
+	// Associations go last, so targets pulled alongside their sources exist
+	for slug := range upserted {
+		if err := userDB.Where("source_slug = ?", slug).Delete(&database.UserMemoryAssociation{}).Error; err != nil {
+			result.Errors = append(result.Errors, fmt.Sprintf("%s: failed to clear associations: %v", slug, err))
+			delete(memoryAssociations, slug)
+		}
+	}
+	assocCount, assocErrors := processUserAssociations(userDB, memoryAssociations)
+	result.AssociationsCreated = assocCount
+	result.Errors = append(result.Errors, assocErrors...)
+
+	if importStats {
+		if err := database.ImportAccessStats(userDB, repoPath); err != nil {
+			result.Errors = append(result.Errors, err.Error())
+		}
+	}
+	return result, nil
+}
+
+// isUserMemoryPath reports whether a repository-relative path can hold a
+// memory, matching what scanRepositoryV2 indexes
+func isUserMemoryPath(relPath string) bool {
+	if strings.HasPrefix(relPath, ".medha/") || strings.HasPrefix(relPath, ".git/") {
+		return false
+	}
+	name := strings.ToLower(filepath.Base(relPath))
+	return strings.HasSuffix(name, ".md") && name != "readme.md"
+}
+
+// upsertUserMemoryFile parses a memory file and creates or replaces its
+// record, tags and annotations, keeping access stats. It returns the parsed
+// memory and whether its record is new.
+func upsertUserMemoryFile(userDB *gorm.DB, repoPath, filePath string) (*memory.Memory, bool, error) {
+	content, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to read file: %w", err)
+	}
+	mem, err := memory.ParseMarkdown(string(content))
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to parse markdown: %w", err)
+	}
+	if mem.ID == "" {
+		mem.ID = strings.TrimSuffix(filepath.Base(filePath), ".md")
+	}
+	relPath, _ := filepath.Rel(repoPath, filePath)
+	isArchived := strings.HasPrefix(filepath.ToSlash(relPath), "archive/")
+
+	created := false
+	err = userDB.Transaction(func(tx *gorm.DB) error {
+		var dbMem database.UserMemory
+		err := tx.Unscoped().Where(querySlugEqualsV2, mem.ID).First(&dbMem).Error
+		if err == gorm.ErrRecordNotFound {
+			created = true
+			dbMem = database.UserMemory{Slug: mem.ID}
+		} else if err != nil {
+			return err
+		}
+
+		dbMem.Title = mem.Title
+		dbMem.FilePath = filePath
+		dbMem.ContentHash = CalculateContentHash(string(content))
+		dbMem.SupersededBy = nil
+		if mem.SupersededBy != "" {
+			dbMem.SupersededBy = &mem.SupersededBy
+		}
+		if mem.Version > 0 {
+			dbMem.Version = mem.Version
+		}
+		if !isArchived {
+			dbMem.DeletedAt = gorm.DeletedAt{}
+		} else if !dbMem.DeletedAt.Valid {
+			dbMem.DeletedAt = gorm.DeletedAt{Time: time.Now(), Valid: true}
+		}
+		if err := tx.Unscoped().Save(&dbMem).Error; err != nil {
+			return fmt.Errorf("failed to save memory record: %w", err)
+		}
+
+		if err := tx.Where("memory_slug = ?", mem.ID).Delete(&database.UserMemoryTag{}).Error; err != nil {
+			return fmt.Errorf("failed to clear tags: %w", err)
+		}
+		for _, tagName := range mem.Tags {
+			if tagName == "" {
+				continue
+			}
+			var tag database.UserTag
+			if err := tx.Where("name = ?", tagName).FirstOrCreate(&tag, database.UserTag{Name: tagName}).Error; err != nil {
+				return fmt.Errorf("failed to create tag %s: %w", tagName, err)
+			}
+			if err := tx.Create(&database.UserMemoryTag{MemorySlug: mem.ID, TagName: tagName}).Error; err != nil {
+				return fmt.Errorf("failed to tag memory with %s: %w", tagName, err)
+			}
+		}
+
+		if err := tx.Where("memory_slug = ?", mem.ID).Delete(&database.UserAnnotation{}).Error; err != nil {
+			return fmt.Errorf("failed to clear annotations: %w", err)
+		}
+		if err := createUserAnnotations(tx, mem); err != nil {
+			return err
+		}
+
+		// Only live memories are indexed for full-text search
+		if isArchived {
+			return database.DeleteMemoryFTS(tx, mem.ID)
+		}
+		return database.IndexMemoryFTS(tx, database.MemoryFTSDocument(mem.ID, mem))
+	})
+	if err != nil {
+		return nil, false, err
+	}
+	return mem, created, nil
+}
+
+// createUserAnnotations stores a memory file's annotations
+func createUserAnnotations(userDB *gorm.DB, mem *memory.Memory) error {
+	for _, a := range mem.Annotations {
+		annotation := &database.UserAnnotation{
+			MemorySlug: mem.ID,
+			Type:       a.Type,
+			Content:    a.Content,
+			CreatedAt:  a.CreatedAt,
+		}
+		if err := userDB.Create(annotation).Error; err != nil {
+			return fmt.Errorf("failed to create annotation: %w", err)
+		}
+	}
+	return nil
+}
+
+// removeUserMemory deletes a memory whose file is gone, with everything that
+// points at it
+func removeUserMemory(userDB *gorm.DB, slug string) error {
+	return userDB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("memory_slug = ?", slug).Delete(&database.UserMemoryTag{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("memory_slug = ?", slug).Delete(&database.UserAnnotation{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("source_slug = ? OR target_slug = ?", slug, slug).Delete(&database.UserMemoryAssociation{}).Error; err != nil {
+			return err
+		}
+		if err := database.DeleteMemoryFTS(tx, slug); err != nil {
+			return err
+		}
+		return tx.Unscoped().Where(querySlugEqualsV2, slug).Delete(&database.UserMemory{}).Error
+	})
+}
@@ -0,0 +1,132 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package rebuild
+
+import (
+	"fmt"
+	"log"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"gorm.io/gorm"
+)
+
+// DefaultWatchDelay is how long a Watcher waits for git to settle after a
+// change before reindexing
+const DefaultWatchDelay = 2 * time.Second
+
+// Watcher keeps a per-user index current with commits made outside Medha,
+// such as a manual git pull, by running IncrementalRebuildUserIndex whenever
+// the repository's HEAD or branches change. Uncommitted edits are not indexed.
+type Watcher struct {
+	repoPath string
+	userDB   func() (*gorm.DB, error)
+	delay    time.Duration
+	onResult func(*Result, error)
+
+	watcher *fsnotify.Watcher
+	done    chan struct{}
+	wg      sync.WaitGroup
+}
+
+// NewWatcher creates a watcher for a repository. userDB is called for each
+// rebuild, so a reopened database is picked up; onResult, if set, is told
+// about each rebuild.
+func NewWatcher(repoPath string, userDB func() (*gorm.DB, error), delay time.Duration, onResult func(*Result, error)) *Watcher {
+	return &Watcher{
+		repoPath: repoPath,
+		userDB:   userDB,
+		delay:    delay,
+		onResult: onResult,
+		done:     make(chan struct{}),
+	}
+}
+
+// Start begins watching the repository's git metadata
+func (w *Watcher) Start() error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("failed to create watcher: %w", err)
+	}
+	gitDir := filepath.Join(w.repoPath, ".git")
+	for _, dir := range []string{gitDir, filepath.Join(gitDir, "refs", "heads")} {
+		if err := watcher.Add(dir); err != nil {
+			watcher.Close()
+			return fmt.Errorf("failed to watch %s: %w", dir, err)
+		}
+	}
+	w.watcher = watcher
+
+	w.wg.Add(1)
+	go w.run()
+	return nil
+}
+
+// Stop stops watching and waits for a running rebuild to finish
+func (w *Watcher) Stop() {
+	if w.watcher == nil {
+		return
+	}
+	close(w.done)
+	w.watcher.Close()
+	w.wg.Wait()
+}
+
+// run reindexes once changes to HEAD, refs or packed refs have settled
+func (w *Watcher) run() {
+	defer w.wg.Done()
+
+	timer := time.NewTimer(w.delay)
+	timer.Stop()
+	defer timer.Stop()
+	for {
+		select {
+		case <-w.done:
+			return
+		case event, ok := <-w.watcher.Events:
+			if !ok {
+				return
+			}
+			if isRefChange(event) {
+				timer.Reset(w.delay)
+			}
+		case err, ok := <-w.watcher.Errors:
+			if !ok {
+				return
+			}
+			log.Printf("Warning: repository watcher error: %v", err)
+		case <-timer.C:
+			w.rebuild()
+		}
+	}
+}
+
+// rebuild runs one incremental rebuild
+func (w *Watcher) rebuild() {
+	userDB, err := w.userDB()
+	var result *Result
+	if err == nil {
+		result, err = IncrementalRebuildUserIndex(userDB, w.repoPath)
+	}
+	if w.onResult != nil {
+		w.onResult(result, err)
+	} else if err != nil {
+		log.Printf("Warning: incremental rebuild of %s failed: %v", w.repoPath, err)
+	}
+}
+
+// isRefChange reports whether a change under .git can move HEAD
+func isRefChange(event fsnotify.Event) bool {
+	if !event.Has(fsnotify.Write) && !event.Has(fsnotify.Create) && !event.Has(fsnotify.Rename) {
+		return false
+	}
+	name := filepath.Base(event.Name)
+	if name == "HEAD" || name == "packed-refs" {
+		return true
+	}
+	return filepath.Base(filepath.Dir(event.Name)) == "heads"
+}
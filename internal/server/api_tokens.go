@@ -0,0 +1,112 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/tejzpr/medha-mcp/internal/auth"
+)
+
+// createAPITokenRequest is the body of POST /api/tokens
+type createAPITokenRequest struct {
+	Name          string   `json:"name"`
+	Scopes        []string `json:"scopes"`
+	Tools         []string `json:"tools"`
+	ExpiresInDays int      `json:"expires_in_days"` // 0 for a token that never expires
+}
+
+// HandleAPITokens lists (GET) and creates (POST) the authenticated user's API tokens
+func (h *HTTPServer) HandleAPITokens(w http.ResponseWriter, r *http.Request) {
+	userID, ok := auth.GetUserIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	tokenManager := h.mcpServer.GetTokenManager()
+
+	switch r.Method {
+	case http.MethodGet:
+		tokens, err := tokenManager.ListAPITokens(userID)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		writeJSON(w, http.StatusOK, tokens)
+
+	case http.MethodPost:
+		var req createAPITokenRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request body: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		for _, tool := range req.Tools {
+			if !slices.Contains(ToolNames(), tool) {
+				http.Error(w, fmt.Sprintf("unknown tool %q", tool), http.StatusBadRequest)
+				return
+			}
+		}
+		if req.ExpiresInDays < 0 {
+			http.Error(w, "expires_in_days must not be negative", http.StatusBadRequest)
+			return
+		}
+		var expiresAt *time.Time
+		if req.ExpiresInDays > 0 {
+			expiry := time.Now().AddDate(0, 0, req.ExpiresInDays)
+			expiresAt = &expiry
+		}
+
+		token, err := tokenManager.CreateAPIToken(userID, req.Name, req.Scopes, req.Tools, expiresAt)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		writeJSON(w, http.StatusCreated, token)
+
+	default:
+		w.Header().Set("Allow", "GET, POST")
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// HandleRevokeAPIToken revokes (DELETE) one of the authenticated user's API tokens
+func (h *HTTPServer) HandleRevokeAPIToken(w http.ResponseWriter, r *http.Request) {
+	userID, ok := auth.GetUserIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	if r.Method != http.MethodDelete {
+		w.Header().Set("Allow", "DELETE")
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	tokenID, err := strconv.ParseUint(r.PathValue("id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid token ID", http.StatusBadRequest)
+		return
+	}
+	if err := h.mcpServer.GetTokenManager().RevokeAPIToken(userID, uint(tokenID)); err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// writeJSON writes a JSON response with the given status
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to write response: %v\n", err)
+	}
+}
@@ -5,12 +5,16 @@
 package server
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
+	"net/url"
 	"os"
 	"path/filepath"
 
+	mcpserver "github.com/mark3labs/mcp-go/server"
 	"github.com/tejzpr/medha-mcp/internal/auth"
 	"github.com/tejzpr/medha-mcp/internal/crypto"
 	"github.com/tejzpr/medha-mcp/internal/database"
@@ -20,31 +24,66 @@ import (
 // HTTPServer handles HTTP routes
 type HTTPServer struct {
 	mcpServer      *MCPServer
+	samlAuth       *auth.SAMLAuthenticator
+	oidcAuth       *auth.OIDCAuthenticator
 	localAuth      *auth.LocalAuthenticator
+	authType       string
 	authMiddleware *auth.Middleware
 	encryptionKey  []byte
+
+	// Streamable-HTTP transports, one per user, kept in an LRU cache.
+	// Each wraps a dedicated mcp-go server so users never share tool
+	// handlers. Session ownership is tracked separately so a session is
+	// only valid for the user that created it, even across eviction.
+	transports *userServerCache
+	sessions   *sessionRegistry
 }
 
-// NewHTTPServer creates a new HTTP server (local auth only)
-func NewHTTPServer(mcpServer *MCPServer, samlAuth *auth.SAMLAuthenticator, localAuth *auth.LocalAuthenticator, authType string, encryptionKey []byte) *HTTPServer {
+// NewHTTPServer creates a new HTTP server. authType is "saml", "oidc" or
+// "local"; only the authenticator for authType is needed.
+func NewHTTPServer(mcpServer *MCPServer, samlAuth *auth.SAMLAuthenticator, oidcAuth *auth.OIDCAuthenticator, localAuth *auth.LocalAuthenticator, authType string, encryptionKey []byte) *HTTPServer {
 	authMiddleware := auth.NewMiddleware(mcpServer.GetTokenManager())
 
 	return &HTTPServer{
 		mcpServer:      mcpServer,
+		samlAuth:       samlAuth,
+		oidcAuth:       oidcAuth,
 		localAuth:      localAuth,
+		authType:       authType,
 		authMiddleware: authMiddleware,
 		encryptionKey:  encryptionKey,
+		transports:     newUserServerCache(mcpServer.config.Server.MaxUserServers),
+		sessions:       newSessionRegistry(),
 	}
 }
 
-// RegisterRoutes registers all HTTP routes (local auth only)
+// RegisterRoutes registers all HTTP routes for the configured auth type
 func (h *HTTPServer) RegisterRoutes(mux *http.ServeMux) {
 	// Auth routes
 	mux.HandleFunc("/auth", h.ServeAuthPage)
-	mux.HandleFunc("/auth/local", h.HandleLocalAuth)
+	switch {
+	case h.authType == "saml" && h.samlAuth != nil:
+		mux.HandleFunc("/saml/login", h.samlAuth.InitiateLogin)
+		mux.HandleFunc("/saml/acs", h.HandleSAMLACS)
+		mux.HandleFunc("/saml/metadata", h.samlAuth.ServeMetadata)
+	case h.authType == "oidc" && h.oidcAuth != nil:
+		mux.HandleFunc("/oidc/login", h.oidcAuth.InitiateLogin)
+		mux.HandleFunc("/oidc/callback", h.HandleOIDCCallback)
+	default:
+		mux.HandleFunc("/auth/local", h.HandleLocalAuth)
+	}
 
-	// MCP routes (protected)
+	// MCP streamable-HTTP transport (protected; POST, GET and DELETE share one endpoint)
 	mux.Handle("/mcp", h.authMiddleware.RequireAuth(http.HandlerFunc(h.HandleMCP)))
+
+	// API token management needs a login token, so API tokens cannot mint others
+	mux.Handle("/api/tokens", h.authMiddleware.RequireAuth(h.authMiddleware.RequireLoginToken(http.HandlerFunc(h.HandleAPITokens))))
+	mux.Handle("/api/tokens/{id}", h.authMiddleware.RequireAuth(h.authMiddleware.RequireLoginToken(http.HandlerFunc(h.HandleRevokeAPIToken))))
+
+	// Team space management, like token management, needs a login token
+	mux.Handle("/api/spaces", h.authMiddleware.RequireAuth(h.authMiddleware.RequireLoginToken(http.HandlerFunc(h.HandleSpaces))))
+	mux.Handle("/api/spaces/{name}/members", h.authMiddleware.RequireAuth(h.authMiddleware.RequireLoginToken(http.HandlerFunc(h.HandleSpaceMembers))))
+	mux.Handle("/api/spaces/{name}/members/{username}", h.authMiddleware.RequireAuth(h.authMiddleware.RequireLoginToken(http.HandlerFunc(h.HandleRemoveSpaceMember))))
 }
 
 // ServeAuthPage serves the authentication web interface
@@ -70,6 +109,68 @@ func (h *HTTPServer) HandleLocalAuth(w http.ResponseWriter, r *http.Request) {
 	repoURL := r.FormValue("repo_url")
 	localOnly := r.FormValue("local_only") == "true"
 
+	if err := h.provisionRepository(user, repoURL, patToken, localOnly); err != nil {
+		http.Error(w, "Failed to setup repository: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	// Return success
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]string{
+		"success":  "true",
+		"token":    token.AccessToken,
+		"username": user.Username,
+	})
+}
+
+// HandleSAMLACS completes a SAML login: it provisions the user and their
+// repository on first login and hands a token to the auth page
+func (h *HTTPServer) HandleSAMLACS(w http.ResponseWriter, r *http.Request) {
+	db := h.mcpServer.dbMgr.SystemDB()
+
+	user, token, err := h.samlAuth.Authenticate(db, w, r)
+	if err != nil {
+		http.Error(w, "SAML authentication failed: "+err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	h.completeLogin(w, r, user, token)
+}
+
+// HandleOIDCCallback completes an OpenID Connect login: it provisions the
+// user and their repository on first login and hands a token to the auth page
+func (h *HTTPServer) HandleOIDCCallback(w http.ResponseWriter, r *http.Request) {
+	db := h.mcpServer.dbMgr.SystemDB()
+
+	user, token, err := h.oidcAuth.Authenticate(db, w, r)
+	if err != nil {
+		http.Error(w, "OIDC authentication failed: "+err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	h.completeLogin(w, r, user, token)
+}
+
+// completeLogin sets up the repository of a user signed in through an
+// identity provider and sends the browser back to the auth page with a token
+func (h *HTTPServer) completeLogin(w http.ResponseWriter, r *http.Request, user *database.MedhaUser, token *database.MedhaAuthToken) {
+	// A remote can be configured later; the IdP knows nothing about it
+	if err := h.provisionRepository(user, "", "", true); err != nil {
+		http.Error(w, "Failed to setup repository: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	query := url.Values{}
+	query.Set("success", "true")
+	query.Set("token", token.AccessToken)
+	query.Set("username", user.Username)
+	http.Redirect(w, r, "/auth?"+query.Encode(), http.StatusSeeOther)
+}
+
+// provisionRepository sets up the user's repository unless they have one
+func (h *HTTPServer) provisionRepository(user *database.MedhaUser, repoURL, patToken string, localOnly bool) error {
+	db := h.mcpServer.dbMgr.SystemDB()
+
 	// Setup user repository
 	homeDir, _ := os.UserHomeDir()
 	storePath := filepath.Join(homeDir, ".medha", "store")
@@ -86,8 +187,7 @@ func (h *HTTPServer) HandleLocalAuth(w http.ResponseWriter, r *http.Request) {
 	var existingRepo database.MedhaGitRepo
 	expectedRepoPath := git.GetUserRepositoryPath(storePath, user.Username)
 
-	err = db.Where("user_id = ?", user.ID).First(&existingRepo).Error
-	if err != nil {
+	if err := db.Where("user_id = ?", user.ID).First(&existingRepo).Error; err != nil {
 		// No repo in database, check if folder exists on disk (recovery scenario)
 		if _, statErr := os.Stat(expectedRepoPath); statErr == nil {
 			// Folder exists but not in DB - recover by adding to DB
@@ -103,13 +203,14 @@ func (h *HTTPServer) HandleLocalAuth(w http.ResponseWriter, r *http.Request) {
 				RepoPath:          expectedRepoPath,
 				PATTokenEncrypted: encryptedPAT,
 			}
-			db.Create(repo)
+			if err := db.Create(repo).Error; err != nil {
+				return fmt.Errorf("failed to store repository: %w", err)
+			}
 		} else {
 			// Create new repository
 			result, err := git.SetupUserRepository(setupCfg)
 			if err != nil {
-				http.Error(w, "Failed to setup repository: "+err.Error(), http.StatusInternalServerError)
-				return
+				return err
 			}
 
 			// Encrypt PAT if provided
@@ -127,20 +228,16 @@ func (h *HTTPServer) HandleLocalAuth(w http.ResponseWriter, r *http.Request) {
 				RepoPath:          result.RepoPath,
 				PATTokenEncrypted: encryptedPAT,
 			}
-			db.Create(repo)
+			if err := db.Create(repo).Error; err != nil {
+				return fmt.Errorf("failed to store repository: %w", err)
+			}
 		}
 	}
 
-	// Return success
-	w.Header().Set("Content-Type", "application/json")
-	_ = json.NewEncoder(w).Encode(map[string]string{
-		"success":  "true",
-		"token":    token.AccessToken,
-		"username": user.Username,
-	})
+	return nil
 }
 
-// HandleMCP handles MCP protocol requests
+// HandleMCP serves the streamable-HTTP MCP transport for the authenticated user
 func (h *HTTPServer) HandleMCP(w http.ResponseWriter, r *http.Request) {
 	db := h.mcpServer.dbMgr.SystemDB()
 
@@ -158,14 +255,74 @@ func (h *HTTPServer) HandleMCP(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Register tools for this user
-	if err := h.mcpServer.RegisterToolsForUser(userID, repo.RepoPath); err != nil {
+	transport, done, err := h.userTransport(userID, repo.RepoPath)
+	if err != nil {
 		http.Error(w, "Failed to register tools", http.StatusInternalServerError)
 		return
 	}
+	defer done() // The transport is released only once no request is using it
 
-	// Forward to MCP server
-	// Note: This is simplified - actual implementation would use mcp-go HTTP transport
+	if r.Method == http.MethodPost && (rejectUnpermittedRequest(w, r) || h.answerSubscription(w, r, userID)) {
+		return
+	}
+
+	transport.ServeHTTP(w, r)
+
+	if r.Method == http.MethodDelete {
+		h.mcpServer.subscriptions.drop(r.Header.Get(mcpserver.HeaderKeySessionID))
+	}
+}
+
+// answerSubscription answers resources/subscribe and resources/unsubscribe
+// for a live session of the user, which mcp-go's transport would reject.
+// The request body is restored for the transport when it is not answered.
+func (h *HTTPServer) answerSubscription(w http.ResponseWriter, r *http.Request, userID uint) bool {
+	sessionID := r.Header.Get(mcpserver.HeaderKeySessionID)
+	if sessionID == "" {
+		return false
+	}
+	if terminated, err := h.sessions.managerFor(userID).Validate(sessionID); err != nil || terminated {
+		return false
+	}
+
+	body, err := io.ReadAll(r.Body)
+	r.Body = io.NopCloser(bytes.NewReader(body))
+	if err != nil {
+		return false
+	}
+
+	response, ok := h.mcpServer.subscriptions.handle(userID, sessionID, body)
+	if !ok {
+		return false
+	}
 	w.Header().Set("Content-Type", "application/json")
-	_ = json.NewEncoder(w).Encode(map[string]string{"status": "ok"})
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to write subscription response: %v\n", err)
+	}
+	return true
+}
+
+// userTransport returns the user's streamable-HTTP transport, creating it on
+// first use, and the function to call once the request has been served
+func (h *HTTPServer) userTransport(userID uint, repoPath string) (*mcpserver.StreamableHTTPServer, func(), error) {
+	return h.transports.getOrCreate(userID, func() (*mcpserver.StreamableHTTPServer, func(), error) {
+		userServer, toolCtx, err := h.mcpServer.NewUserServer(userID, repoPath)
+		if err != nil {
+			return nil, nil, err
+		}
+		release := func() {
+			if err := toolCtx.CloseUserDB(); err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: failed to close database for user %d: %v\n", userID, err)
+			}
+			h.sessions.forgetUser(userID)
+		}
+		return mcpserver.NewStreamableHTTPServer(userServer,
+			mcpserver.WithSessionIdManager(h.sessions.managerFor(userID)),
+		), release, nil
+	})
+}
+
+// Close releases the per-user tool contexts of every cached transport
+func (h *HTTPServer) Close() {
+	h.transports.close()
 }
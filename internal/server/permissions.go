@@ -0,0 +1,118 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/tejzpr/medha-mcp/internal/auth"
+)
+
+// toolScopes maps each tool to the API token scope that allows calling it
+var toolScopes = map[string]string{
+	"medha_recall":   auth.ScopeMemoriesRead,
+	"medha_history":  auth.ScopeMemoriesRead,
+	"medha_graph":    auth.ScopeMemoriesRead,
+	"medha_remember": auth.ScopeMemoriesWrite,
+	"medha_connect":  auth.ScopeMemoriesWrite,
+	"medha_forget":   auth.ScopeMemoriesWrite,
+	"medha_restore":  auth.ScopeMemoriesWrite,
+	"medha_lock":     auth.ScopeMemoriesWrite,
+	"medha_sync":     auth.ScopeSync,
+}
+
+// ToolNames returns the names of all tools, sorted
+func ToolNames() []string {
+	names := make([]string, 0, len(toolScopes))
+	for name := range toolScopes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// permissionServerOptions enforce the permissions of API tokens on every
+// tool call and tool listing
+func permissionServerOptions() []server.ServerOption {
+	return []server.ServerOption{
+		server.WithToolHandlerMiddleware(requireToolPermission),
+		server.WithToolFilter(filterPermittedTools),
+	}
+}
+
+// requireToolPermission rejects tool calls the request's API token does not allow
+func requireToolPermission(next server.ToolHandlerFunc) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		name := request.Params.Name
+		if !auth.GetPermissionsFromContext(ctx).AllowsTool(name, toolScopes[name]) {
+			return mcp.NewToolResultError(fmt.Sprintf("This API token is not allowed to call %s", name)), nil
+		}
+		return next(ctx, request)
+	}
+}
+
+// filterPermittedTools hides tools the request's API token does not allow
+func filterPermittedTools(ctx context.Context, tools []mcp.Tool) []mcp.Tool {
+	permissions := auth.GetPermissionsFromContext(ctx)
+	if permissions == nil {
+		return tools
+	}
+	permitted := make([]mcp.Tool, 0, len(tools))
+	for _, tool := range tools {
+		if permissions.AllowsTool(tool.Name, toolScopes[tool.Name]) {
+			permitted = append(permitted, tool)
+		}
+	}
+	return permitted
+}
+
+// memoryReadMethods are the MCP methods, other than tool calls, that return
+// memories and so need the memories:read scope
+var memoryReadMethods = map[string]bool{
+	string(mcp.MethodResourcesList): true,
+	string(mcp.MethodResourcesRead): true,
+	methodResourcesSubscribe:        true,
+	string(mcp.MethodPromptsGet):    true,
+}
+
+// rejectUnpermittedRequest answers a JSON-RPC request for a method the
+// request's API token does not allow with an error. The request body is
+// restored for the transport when it is not answered.
+func rejectUnpermittedRequest(w http.ResponseWriter, r *http.Request) bool {
+	if auth.GetPermissionsFromContext(r.Context()).HasScope(auth.ScopeMemoriesRead) {
+		return false
+	}
+
+	body, err := io.ReadAll(r.Body)
+	r.Body = io.NopCloser(bytes.NewReader(body))
+	if err != nil {
+		return false
+	}
+
+	var request struct {
+		ID     mcp.RequestId `json:"id"`
+		Method string        `json:"method"`
+	}
+	if err := json.Unmarshal(body, &request); err != nil || !memoryReadMethods[request.Method] {
+		return false
+	}
+
+	response := mcp.NewJSONRPCError(request.ID, mcp.INVALID_REQUEST,
+		fmt.Sprintf("this API token needs the %s scope for %s", auth.ScopeMemoriesRead, request.Method), nil)
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to write permission error: %v\n", err)
+	}
+	return true
+}
@@ -0,0 +1,264 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package server
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/tejzpr/medha-mcp/internal/tools"
+)
+
+// stdioSessionID is the ID of mcp-go's single stdio session
+const stdioSessionID = "stdio"
+
+// resourceURIPrefix is shared by every resource Medha serves
+const resourceURIPrefix = "medha://"
+
+// Subscription methods, which mcp-go does not route
+const (
+	methodResourcesSubscribe   = "resources/subscribe"
+	methodResourcesUnsubscribe = "resources/unsubscribe"
+)
+
+// resourceSubscription is the set of resource URIs one session subscribed to
+type resourceSubscription struct {
+	userID uint
+	uris   map[string]bool
+}
+
+// resourceSubscriptions records resource subscriptions by session ID.
+// mcp-go advertises the subscribe capability but does not route
+// resources/subscribe, so the transports answer those requests here.
+type resourceSubscriptions struct {
+	mu       sync.Mutex
+	sessions map[string]*resourceSubscription
+}
+
+// newResourceSubscriptions creates an empty subscription registry
+func newResourceSubscriptions() *resourceSubscriptions {
+	return &resourceSubscriptions{sessions: make(map[string]*resourceSubscription)}
+}
+
+// hooks drops a session's subscriptions when mcp-go unregisters it
+func (r *resourceSubscriptions) hooks() *server.Hooks {
+	hooks := &server.Hooks{}
+	hooks.AddOnUnregisterSession(func(ctx context.Context, session server.ClientSession) {
+		r.drop(session.SessionID())
+	})
+	return hooks
+}
+
+// handle answers a resources/subscribe or resources/unsubscribe request.
+// Any other message is left for mcp-go and reported as not handled.
+func (r *resourceSubscriptions) handle(userID uint, sessionID string, raw []byte) (mcp.JSONRPCMessage, bool) {
+	var request struct {
+		ID     mcp.RequestId `json:"id"`
+		Method string        `json:"method"`
+		Params struct {
+			URI string `json:"uri"`
+		} `json:"params"`
+	}
+	if err := json.Unmarshal(raw, &request); err != nil {
+		return nil, false
+	}
+	if request.Method != methodResourcesSubscribe && request.Method != methodResourcesUnsubscribe {
+		return nil, false
+	}
+
+	if !strings.HasPrefix(request.Params.URI, resourceURIPrefix) {
+		return mcp.NewJSONRPCError(request.ID, mcp.INVALID_PARAMS,
+			fmt.Sprintf("unknown resource URI: %q", request.Params.URI), nil), true
+	}
+
+	if request.Method == methodResourcesSubscribe {
+		r.subscribe(userID, sessionID, request.Params.URI)
+	} else {
+		r.unsubscribe(sessionID, request.Params.URI)
+	}
+	return mcp.NewJSONRPCResultResponse(request.ID, mcp.EmptyResult{}), true
+}
+
+// subscribe records that a session wants updates to a resource
+func (r *resourceSubscriptions) subscribe(userID uint, sessionID, uri string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	sub, ok := r.sessions[sessionID]
+	if !ok {
+		sub = &resourceSubscription{userID: userID, uris: make(map[string]bool)}
+		r.sessions[sessionID] = sub
+	}
+	sub.uris[uri] = true
+}
+
+// unsubscribe stops a session's updates to a resource
+func (r *resourceSubscriptions) unsubscribe(sessionID, uri string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if sub, ok := r.sessions[sessionID]; ok {
+		delete(sub.uris, uri)
+		if len(sub.uris) == 0 {
+			delete(r.sessions, sessionID)
+		}
+	}
+}
+
+// drop removes every subscription of a session
+func (r *resourceSubscriptions) drop(sessionID string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.sessions, sessionID)
+}
+
+// subscribed returns the URIs a session subscribed to
+func (r *resourceSubscriptions) subscribed(sessionID string) []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var uris []string
+	if sub, ok := r.sessions[sessionID]; ok {
+		for uri := range sub.uris {
+			uris = append(uris, uri)
+		}
+	}
+	return uris
+}
+
+// notify sends resources/updated to the user's sessions subscribed to any of uris.
+// Sessions the server no longer knows about are dropped.
+func (r *resourceSubscriptions) notify(mcpServer *server.MCPServer, userID uint, uris []string) {
+	type delivery struct{ sessionID, uri string }
+	var deliveries []delivery
+
+	r.mu.Lock()
+	for sessionID, sub := range r.sessions {
+		if sub.userID != userID {
+			continue
+		}
+		for _, uri := range uris {
+			if sub.uris[uri] {
+				deliveries = append(deliveries, delivery{sessionID, uri})
+			}
+		}
+	}
+	r.mu.Unlock()
+
+	for _, d := range deliveries {
+		err := mcpServer.SendNotificationToSpecificClient(d.sessionID, mcp.MethodNotificationResourceUpdated, map[string]any{"uri": d.uri})
+		if errors.Is(err, server.ErrSessionNotFound) {
+			r.drop(d.sessionID)
+		} else if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to notify session %s of %s: %v\n", d.sessionID, d.uri, err)
+		}
+	}
+}
+
+// registerResources exposes a user's memories as MCP resources and keeps them
+// current as tools write memories
+func (s *MCPServer) registerResources(mcpServer *server.MCPServer, toolCtx *tools.ToolContext, userID uint) error {
+	memoryHandler := tools.MemoryResourceHandler(toolCtx)
+
+	resources, err := toolCtx.MemoryResources()
+	if err != nil {
+		return fmt.Errorf("failed to list memory resources: %w", err)
+	}
+	serverResources := make([]server.ServerResource, len(resources))
+	for i, resource := range resources {
+		serverResources[i] = server.ServerResource{Resource: resource, Handler: memoryHandler}
+	}
+	mcpServer.AddResources(serverResources...)
+
+	// Templates also serve memories written outside this server, such as by a sync
+	mcpServer.AddResourceTemplate(tools.NewMemoryResourceTemplate(), memoryHandler)
+	mcpServer.AddResourceTemplate(tools.NewFolderResourceTemplate(), tools.FolderResourceHandler(toolCtx))
+	mcpServer.AddResourceTemplate(tools.NewTagResourceTemplate(), tools.TagResourceHandler(toolCtx))
+
+	toolCtx.OnMemoryChange = func(change tools.MemoryChange) {
+		if change.Action == "archived" {
+			mcpServer.DeleteResources(tools.MemoryURI(change.Slug))
+		} else if resource, ok := toolCtx.MemoryResource(change.Slug); ok {
+			mcpServer.AddResource(resource, memoryHandler)
+		}
+		s.subscriptions.notify(mcpServer, userID, change.URIs)
+	}
+	return nil
+}
+
+// ServeStdio serves the shared server over stdin and stdout until EOF or a
+// termination signal
+func (s *MCPServer) ServeStdio() error {
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGTERM, syscall.SIGINT)
+	defer cancel()
+	return s.ServeStdioWith(ctx, os.Stdin, os.Stdout)
+}
+
+// ServeStdioWith serves the shared server over the given streams, answering
+// resource subscriptions before messages reach mcp-go
+func (s *MCPServer) ServeStdioWith(ctx context.Context, stdin io.Reader, stdout io.Writer) error {
+	out := &syncWriter{w: stdout}
+	in, pipe := io.Pipe()
+
+	go func() {
+		reader := bufio.NewReader(stdin)
+		for {
+			line, err := reader.ReadBytes('\n')
+			if len(line) > 0 {
+				if response, ok := s.subscriptions.handle(s.toolsUserID, stdioSessionID, line); ok {
+					if err := out.writeMessage(response); err != nil {
+						fmt.Fprintf(os.Stderr, "Warning: failed to write subscription response: %v\n", err)
+					}
+				} else if _, err := pipe.Write(line); err != nil {
+					return
+				}
+			}
+			if err != nil {
+				if err == io.EOF {
+					err = nil
+				}
+				pipe.CloseWithError(err)
+				return
+			}
+		}
+	}()
+
+	return server.NewStdioServer(s.mcpServer).Listen(ctx, in, out)
+}
+
+// syncWriter serialises writes so responses written outside mcp-go never
+// interleave with its own. mcp-go writes each message with a single Write.
+type syncWriter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// Write writes p while holding the lock
+func (w *syncWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.w.Write(p)
+}
+
+// writeMessage writes a JSON-RPC message as one line
+func (w *syncWriter) writeMessage(message mcp.JSONRPCMessage) error {
+	data, err := json.Marshal(message)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(data, '\n'))
+	return err
+}
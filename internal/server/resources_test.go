@@ -0,0 +1,62 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package server
+
+import (
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResourceSubscriptions_Handle(t *testing.T) {
+	subs := newResourceSubscriptions()
+
+	response, ok := subs.handle(1, "s1", []byte(`{"jsonrpc":"2.0","id":7,"method":"resources/subscribe","params":{"uri":"medha://memory/a"}}`))
+	require.True(t, ok)
+	result, isResult := response.(mcp.JSONRPCResponse)
+	require.True(t, isResult)
+	assert.Equal(t, mcp.NewRequestId(int64(7)), result.ID)
+	assert.Equal(t, []string{"medha://memory/a"}, subs.subscribed("s1"))
+
+	_, ok = subs.handle(1, "s1", []byte(`{"jsonrpc":"2.0","id":8,"method":"resources/unsubscribe","params":{"uri":"medha://memory/a"}}`))
+	require.True(t, ok)
+	assert.Empty(t, subs.subscribed("s1"))
+}
+
+func TestResourceSubscriptions_PassesOtherMessages(t *testing.T) {
+	subs := newResourceSubscriptions()
+
+	for _, raw := range []string{
+		`{"jsonrpc":"2.0","id":1,"method":"resources/read","params":{"uri":"medha://memory/a"}}`,
+		`{"jsonrpc":"2.0","method":"notifications/initialized"}`,
+		`not json`,
+	} {
+		_, ok := subs.handle(1, "s1", []byte(raw))
+		assert.False(t, ok, raw)
+	}
+}
+
+func TestResourceSubscriptions_RejectsForeignURIs(t *testing.T) {
+	subs := newResourceSubscriptions()
+
+	response, ok := subs.handle(1, "s1", []byte(`{"jsonrpc":"2.0","id":1,"method":"resources/subscribe","params":{"uri":"file:///etc/passwd"}}`))
+	require.True(t, ok)
+	_, isError := response.(mcp.JSONRPCError)
+	assert.True(t, isError)
+	assert.Empty(t, subs.subscribed("s1"))
+}
+
+func TestResourceSubscriptions_Drop(t *testing.T) {
+	subs := newResourceSubscriptions()
+	subs.subscribe(1, "s1", "medha://memory/a")
+	subs.subscribe(1, "s1", "medha://tag/b")
+	subs.subscribe(2, "s2", "medha://memory/a")
+
+	subs.drop("s1")
+	assert.Empty(t, subs.subscribed("s1"))
+	assert.Equal(t, []string{"medha://memory/a"}, subs.subscribed("s2"))
+}
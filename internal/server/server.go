@@ -7,15 +7,23 @@ package server
 import (
 	"fmt"
 	"os"
+	"sync"
 
 	"github.com/mark3labs/mcp-go/server"
 	"github.com/tejzpr/medha-mcp/internal/auth"
 	"github.com/tejzpr/medha-mcp/internal/config"
 	"github.com/tejzpr/medha-mcp/internal/database"
 	"github.com/tejzpr/medha-mcp/internal/embeddings"
+	"github.com/tejzpr/medha-mcp/internal/ranking"
 	"github.com/tejzpr/medha-mcp/internal/tools"
 )
 
+// OpenAI defaults, also used to detect unset values for other providers
+const (
+	defaultOpenAIBaseURL = "https://api.openai.com/v1"
+	defaultOpenAIModel   = "text-embedding-3-small"
+)
+
 // MCPServer wraps the mcp-go server with our configuration
 type MCPServer struct {
 	mcpServer        *server.MCPServer
@@ -23,20 +31,25 @@ type MCPServer struct {
 	dbMgr            *database.Manager
 	tokenManager     *auth.TokenManager
 	encryptionKey    []byte
-	embeddingService *embeddings.Service // Optional embedding service for semantic search
+	embeddingFactory *embeddings.Factory // Optional; creates per-user embedding services for semantic search
+	subscriptions    *resourceSubscriptions
+	spaces           *tools.SpaceContexts // Team space contexts shared by every user's tools
+
+	// User whose tools are registered on the shared mcpServer (stdio mode).
+	// Re-registering for another user would silently reroute every caller.
+	registerMu   sync.Mutex
+	toolsUserID  uint
+	toolsHasUser bool
 }
 
 // NewMCPServer creates a new MCP server instance
 func NewMCPServer(cfg *config.Config, dbMgr *database.Manager, encryptionKey []byte) (*MCPServer, error) {
 	// Create MCP server
-	mcpServer := server.NewMCPServer(
-		"Medha",
-		"1.0.0",
-		server.WithToolCapabilities(true),
-	)
+	subscriptions := newResourceSubscriptions()
+	mcpServer := newMCPGoServer(server.WithHooks(subscriptions.hooks()))
 
 	// Create token manager
-	tokenManager := auth.NewTokenManager(dbMgr.SystemDB(), cfg.Security.TokenTTL)
+	tokenManager := auth.NewTokenManager(dbMgr.SystemDB(), cfg.Security.TokenTTL, encryptionKey)
 
 	srv := &MCPServer{
 		mcpServer:     mcpServer,
@@ -44,106 +57,240 @@ func NewMCPServer(cfg *config.Config, dbMgr *database.Manager, encryptionKey []b
 		dbMgr:         dbMgr,
 		tokenManager:  tokenManager,
 		encryptionKey: encryptionKey,
+		subscriptions: subscriptions,
 	}
 
 	// Initialize embedding service if enabled
 	if cfg.Embeddings.Enabled {
-		embSvc, err := srv.initEmbeddingService()
+		factory, err := srv.initEmbeddingFactory()
 		if err != nil {
 			// Log warning but don't fail - embeddings are optional
 			fmt.Fprintf(os.Stderr, "Warning: Failed to initialize embedding service: %v\n", err)
 		} else {
-			srv.embeddingService = embSvc
+			srv.embeddingFactory = factory
 		}
 	}
+	srv.spaces = tools.NewSpaceContexts(dbMgr, srv.embeddingFactory, rankingWeights(cfg.Ranking))
 
 	return srv, nil
 }
 
-// initEmbeddingService initializes the embedding service based on config
-func (s *MCPServer) initEmbeddingService() (*embeddings.Service, error) {
+// Close closes the team space contexts shared by the users' tools
+func (s *MCPServer) Close() {
+	s.spaces.Close()
+}
+
+// initEmbeddingFactory initializes the embedding client based on config.
+// The provider is probed once so an unreachable or misconfigured model is
+// reported at startup and the real vector size is used for storage.
+// Vectors are stored per user; see registerTools.
+func (s *MCPServer) initEmbeddingFactory() (*embeddings.Factory, error) {
 	cfg := s.config.Embeddings
 
-	// Get API key from environment
-	apiKey := os.Getenv(cfg.APIKeyEnv)
-	if apiKey == "" && cfg.Provider == config.EmbeddingProviderOpenAI {
-		// Try default env var
-		apiKey = os.Getenv("OPENAI_API_KEY")
+	client, model, err := newEmbeddingClient(cfg)
+	if err != nil {
+		return nil, err
 	}
 
-	if apiKey == "" {
-		return nil, fmt.Errorf("embedding API key not found (set %s or OPENAI_API_KEY)", cfg.APIKeyEnv)
+	dimensions, err := embeddings.ProbeDimensions(client)
+	if err != nil {
+		return nil, fmt.Errorf("%s embedding provider unavailable: %w", client.GetModelInfo().Provider, err)
 	}
-
-	// Set defaults
-	dimensions := cfg.Dimensions
-	if dimensions == 0 {
-		dimensions = embeddings.DefaultEmbeddingDimensions
+	if cfg.Dimensions > 0 && cfg.Dimensions != dimensions {
+		fmt.Fprintf(os.Stderr, "Warning: embeddings.dimensions is %d but model %s returns %d; using %d\n",
+			cfg.Dimensions, model, dimensions, dimensions)
 	}
 
-	model := cfg.Model
-	if model == "" {
-		model = "text-embedding-3-small"
+	return embeddings.NewFactory(client, model, dimensions), nil
+}
+
+// newEmbeddingClient creates the embedding client for the configured provider
+// and returns it with the effective model name
+func newEmbeddingClient(cfg config.EmbeddingConfig) (embeddings.Client, string, error) {
+	// Get API key from environment
+	apiKey := ""
+	if cfg.APIKeyEnv != "" {
+		apiKey = os.Getenv(cfg.APIKeyEnv)
 	}
 
-	// Create embedding client based on provider
-	var client embeddings.Client
 	switch cfg.Provider {
 	case config.EmbeddingProviderOpenAI, "":
+		if apiKey == "" {
+			// Try default env var
+			apiKey = os.Getenv("OPENAI_API_KEY")
+		}
+		if apiKey == "" {
+			return nil, "", fmt.Errorf("embedding API key not found (set %s or OPENAI_API_KEY)", cfg.APIKeyEnv)
+		}
 		baseURL := cfg.BaseURL
 		if baseURL == "" {
-			baseURL = "https://api.openai.com/v1"
+			baseURL = defaultOpenAIBaseURL
+		}
+		model := embeddingModelOrDefault(cfg.Model, defaultOpenAIModel)
+		dimensions := cfg.Dimensions
+		if dimensions == 0 {
+			dimensions = embeddings.DefaultEmbeddingDimensions
+		}
+		return embeddings.NewOpenAIClient(baseURL, apiKey, model, dimensions), model, nil
+
+	case config.EmbeddingProviderAzure:
+		if apiKey == "" {
+			return nil, "", fmt.Errorf("embedding API key not found (set %s)", cfg.APIKeyEnv)
+		}
+		if cfg.BaseURL == "" || cfg.BaseURL == defaultOpenAIBaseURL {
+			return nil, "", fmt.Errorf("embeddings.base_url must be the Azure OpenAI deployment URL")
+		}
+		model := embeddingModelOrDefault(cfg.Model, defaultOpenAIModel)
+		return embeddings.NewAzureOpenAIClient(cfg.BaseURL, apiKey, cfg.APIVersion, model, cfg.Dimensions), model, nil
+
+	case config.EmbeddingProviderOllama:
+		baseURL := cfg.BaseURL
+		if baseURL == defaultOpenAIBaseURL {
+			baseURL = ""
 		}
-		client = embeddings.NewOpenAIClient(baseURL, apiKey, model, dimensions)
+		model := cfg.Model
+		if model == "" || model == defaultOpenAIModel {
+			model = embeddings.DefaultOllamaModel
+		}
+		return embeddings.NewOllamaClient(baseURL, model), model, nil
+
+	case config.EmbeddingProviderLocal:
+		if cfg.BaseURL == "" || cfg.BaseURL == defaultOpenAIBaseURL {
+			return nil, "", fmt.Errorf("embeddings.base_url must point at the local embedding server (e.g. http://localhost:8081/v1)")
+		}
+		return embeddings.NewLocalClient(cfg.BaseURL, cfg.Model), cfg.Model, nil
+
 	default:
-		return nil, fmt.Errorf("unsupported embedding provider: %s", cfg.Provider)
+		return nil, "", fmt.Errorf("unsupported embedding provider: %s", cfg.Provider)
+	}
+}
+
+// embeddingModelOrDefault returns model, or fallback when model is empty
+func embeddingModelOrDefault(model, fallback string) string {
+	if model == "" {
+		return fallback
 	}
+	return model
+}
+
+// rankingWeights converts the ranking config into fusion weights
+func rankingWeights(cfg config.RankingConfig) ranking.Weights {
+	return ranking.Weights{
+		K:           cfg.K,
+		Keyword:     cfg.KeywordWeight,
+		Semantic:    cfg.SemanticWeight,
+		Tag:         cfg.TagWeight,
+		Association: cfg.AssociationWeight,
+	}
+}
 
-	// Create embedding service
-	svc := embeddings.NewService(s.dbMgr.SystemDB(), client, model, "v1", dimensions)
-	return svc, nil
+// newMCPGoServer creates an mcp-go server with Medha's capabilities, enforcing
+// the permissions of API tokens
+func newMCPGoServer(opts ...server.ServerOption) *server.MCPServer {
+	options := append([]server.ServerOption{
+		server.WithToolCapabilities(true),
+		server.WithResourceCapabilities(true, true),
+		server.WithPromptCapabilities(false),
+	}, permissionServerOptions()...)
+	return server.NewMCPServer("Medha", "1.0.0", append(options, opts...)...)
 }
 
-// RegisterToolsForUser registers all MCP tools for a specific user
-// Uses v2 architecture with per-user databases
+// RegisterToolsForUser registers all MCP tools for a specific user on the shared server
+// Uses v2 architecture with per-user databases. The shared server serves exactly one
+// user (stdio mode); multi-user transports must use NewUserServer instead.
 func (s *MCPServer) RegisterToolsForUser(userID uint, repoPath string) error {
+	s.registerMu.Lock()
+	defer s.registerMu.Unlock()
+
+	if s.toolsHasUser && s.toolsUserID != userID {
+		return fmt.Errorf("tools already registered for user %d; use NewUserServer for additional users", s.toolsUserID)
+	}
+
+	if _, err := s.registerTools(s.mcpServer, userID, repoPath); err != nil {
+		return err
+	}
+
+	s.toolsUserID = userID
+	s.toolsHasUser = true
+	return nil
+}
+
+// NewUserServer creates a dedicated mcp-go server holding only this user's tools.
+// Used by HTTP mode so that concurrent users never share tool handlers. The
+// returned tool context is closed once the server is no longer used.
+func (s *MCPServer) NewUserServer(userID uint, repoPath string) (*server.MCPServer, *tools.ToolContext, error) {
+	mcpServer := newMCPGoServer(server.WithHooks(s.subscriptions.hooks()))
+	toolCtx, err := s.registerTools(mcpServer, userID, repoPath)
+	if err != nil {
+		return nil, nil, err
+	}
+	return mcpServer, toolCtx, nil
+}
+
+// registerTools registers all MCP tools for a user on the given mcp-go server
+// and returns the tool context they share
+func (s *MCPServer) registerTools(mcpServer *server.MCPServer, userID uint, repoPath string) (*tools.ToolContext, error) {
 	// Create v2 tool context with database manager
 	toolCtx, err := tools.NewToolContextWithManager(s.dbMgr, repoPath)
 	if err != nil {
-		return fmt.Errorf("failed to create tool context: %w", err)
+		return nil, fmt.Errorf("failed to create tool context: %w", err)
 	}
+	toolCtx.RankingWeights = rankingWeights(s.config.Ranking)
+	toolCtx.Spaces = s.spaces
 
-	// Set embedding service if available
-	if s.embeddingService != nil {
-		toolCtx.SetEmbeddingService(s.embeddingService)
+	// Store embeddings in the user's own database if available
+	if s.embeddingFactory != nil {
+		if err := toolCtx.EnableEmbeddings(s.embeddingFactory); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: Semantic search disabled for user %d: %v\n", userID, err)
+		} else if imported, err := toolCtx.ImportLegacyEmbeddings(); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: Failed to import legacy embeddings for user %d: %v\n", userID, err)
+		} else if imported > 0 {
+			fmt.Fprintf(os.Stderr, "Imported %d legacy embeddings for user %d\n", imported, userID)
+		}
 	}
 
-	// Register human-aligned tools (6 core + 1 sync)
+	// Register human-aligned tools (7 core + lock + sync)
 	// These tools express intent rather than implementation, making them
 	// easier for LLMs to use correctly.
 
 	// medha_recall: Smart retrieval - "What do I know about X?"
-	s.mcpServer.AddTool(tools.NewRecallTool(), tools.RecallHandler(toolCtx, userID))
+	mcpServer.AddTool(tools.NewRecallTool(), tools.RecallHandler(toolCtx, userID))
 
 	// medha_remember: Store/update information - "Store this for later"
-	s.mcpServer.AddTool(tools.NewRememberTool(), tools.RememberHandler(toolCtx, userID))
+	mcpServer.AddTool(tools.NewRememberTool(), tools.RememberHandler(toolCtx, userID))
 
 	// medha_history: Temporal queries - "When did I learn about X?"
-	s.mcpServer.AddTool(tools.NewHistoryTool(), tools.HistoryHandler(toolCtx, userID))
+	mcpServer.AddTool(tools.NewHistoryTool(), tools.HistoryHandler(toolCtx, userID))
 
 	// medha_connect: Link/unlink memories - "These are related"
-	s.mcpServer.AddTool(tools.NewConnectTool(), tools.ConnectHandler(toolCtx, userID))
+	mcpServer.AddTool(tools.NewConnectTool(), tools.ConnectHandler(toolCtx, userID))
+
+	// medha_graph: Walk the knowledge graph - "What is connected to X?"
+	mcpServer.AddTool(tools.NewGraphTool(), tools.GraphHandler(toolCtx, userID))
 
 	// medha_forget: Archive memories - "No longer relevant"
-	s.mcpServer.AddTool(tools.NewForgetTool(), tools.ForgetHandler(toolCtx, userID))
+	mcpServer.AddTool(tools.NewForgetTool(), tools.ForgetHandler(toolCtx, userID))
 
 	// medha_restore: Undelete memories - "Bring back that archived memory"
-	s.mcpServer.AddTool(tools.NewRestoreTool(), tools.RestoreHandler(toolCtx, userID))
+	mcpServer.AddTool(tools.NewRestoreTool(), tools.RestoreHandler(toolCtx, userID))
+
+	// medha_lock: Hold a memory across a long edit - "I'm rewriting this, hands off"
+	mcpServer.AddTool(tools.NewLockTool(), tools.LockHandler(toolCtx, userID))
 
 	// medha_sync: Git synchronization (kept for explicit sync operations)
-	s.mcpServer.AddTool(tools.NewSyncTool(), tools.SyncHandler(toolCtx, userID, s.encryptionKey))
+	mcpServer.AddTool(tools.NewSyncTool(), tools.SyncHandler(toolCtx, userID, s.encryptionKey))
 
-	return nil
+	// Prompts: ready-made workflows fed by the same recall and history code paths
+	mcpServer.AddPrompt(tools.NewSummarizePrompt(), tools.SummarizePromptHandler(toolCtx, userID))
+	mcpServer.AddPrompt(tools.NewRecordDecisionPrompt(), tools.RecordDecisionPromptHandler(toolCtx, userID))
+	mcpServer.AddPrompt(tools.NewStandupPrompt(), tools.StandupPromptHandler(toolCtx, userID))
+	mcpServer.AddPrompt(tools.NewReviewStalePrompt(), tools.ReviewStalePromptHandler(toolCtx, userID))
+
+	// Memories as resources: medha://memory/{slug}, plus folder and tag listings
+	if err := s.registerResources(mcpServer, toolCtx, userID); err != nil {
+		return nil, err
+	}
+	return toolCtx, nil
 }
 
 // GetMCPServer returns the underlying MCP server
@@ -161,7 +308,13 @@ func (s *MCPServer) GetDBManager() *database.Manager {
 	return s.dbMgr
 }
 
+// GetEmbeddingFactory returns the factory for per-user embedding services,
+// or nil when semantic search is disabled
+func (s *MCPServer) GetEmbeddingFactory() *embeddings.Factory {
+	return s.embeddingFactory
+}
+
 // HasEmbeddings returns true if embedding service is available
 func (s *MCPServer) HasEmbeddings() bool {
-	return s.embeddingService != nil
+	return s.embeddingFactory != nil
 }
@@ -0,0 +1,158 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package server
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// sessionIDPrefix marks MCP session IDs issued by Medha
+const sessionIDPrefix = "medha-session-"
+
+// Session lifetimes. Clients rarely end their sessions, so idle ones expire;
+// terminated ones are remembered a while to answer late requests.
+const (
+	sessionIdleTimeout   = 24 * time.Hour
+	terminatedSessionTTL = time.Hour
+	sessionSweepInterval = time.Minute
+)
+
+// sessionEntry is a session's owner and when it was last used or terminated
+type sessionEntry struct {
+	userID     uint
+	lastSeen   time.Time
+	terminated bool
+}
+
+// sessionRegistry records which user owns each MCP session ID.
+// It lives outside the per-user server cache so that a user's sessions stay
+// valid while their transport is rebuilt; they are forgotten once the user's
+// server is evicted and released, or once they expire.
+type sessionRegistry struct {
+	mu        sync.Mutex
+	sessions  map[string]*sessionEntry
+	lastSweep time.Time
+	now       func() time.Time // Replaced in tests
+}
+
+// newSessionRegistry creates an empty session registry
+func newSessionRegistry() *sessionRegistry {
+	return &sessionRegistry{
+		sessions: make(map[string]*sessionEntry),
+		now:      time.Now,
+	}
+}
+
+// managerFor returns a session ID manager that only accepts sessions owned by userID
+func (r *sessionRegistry) managerFor(userID uint) *userSessionManager {
+	return &userSessionManager{registry: r, userID: userID}
+}
+
+// forgetUser drops every session of a user
+func (r *sessionRegistry) forgetUser(userID uint) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for sessionID, entry := range r.sessions {
+		if entry.userID == userID {
+			delete(r.sessions, sessionID)
+		}
+	}
+}
+
+// len returns the number of sessions remembered
+func (r *sessionRegistry) len() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.sessions)
+}
+
+// sweep drops idle and long-terminated sessions, at most once per sweep
+// interval. The registry lock must be held.
+func (r *sessionRegistry) sweep(now time.Time) {
+	if now.Sub(r.lastSweep) < sessionSweepInterval {
+		return
+	}
+	r.lastSweep = now
+
+	for sessionID, entry := range r.sessions {
+		ttl := sessionIdleTimeout
+		if entry.terminated {
+			ttl = terminatedSessionTTL
+		}
+		if now.Sub(entry.lastSeen) > ttl {
+			delete(r.sessions, sessionID)
+		}
+	}
+}
+
+// userSessionManager implements mcp-go's SessionIdManager for a single user
+type userSessionManager struct {
+	registry *sessionRegistry
+	userID   uint
+}
+
+// Generate issues a new session ID owned by the manager's user
+func (m *userSessionManager) Generate() string {
+	buf := make([]byte, 16)
+	_, _ = rand.Read(buf)
+	sessionID := sessionIDPrefix + hex.EncodeToString(buf)
+
+	m.registry.mu.Lock()
+	defer m.registry.mu.Unlock()
+	now := m.registry.now()
+	m.registry.sweep(now)
+	m.registry.sessions[sessionID] = &sessionEntry{userID: m.userID, lastSeen: now}
+	return sessionID
+}
+
+// Validate accepts only live sessions that belong to the manager's user.
+// Expired and forgotten sessions count as terminated, so clients are told to
+// start a new one.
+func (m *userSessionManager) Validate(sessionID string) (isTerminated bool, err error) {
+	if !strings.HasPrefix(sessionID, sessionIDPrefix) {
+		return false, fmt.Errorf("invalid session id: %s", sessionID)
+	}
+
+	m.registry.mu.Lock()
+	defer m.registry.mu.Unlock()
+	now := m.registry.now()
+	m.registry.sweep(now)
+
+	entry, ok := m.registry.sessions[sessionID]
+	if !ok {
+		return true, nil
+	}
+	if entry.userID != m.userID {
+		return false, fmt.Errorf("session not found: %s", sessionID)
+	}
+	if entry.terminated {
+		return true, nil
+	}
+	entry.lastSeen = now
+	return false, nil
+}
+
+// Terminate ends a session owned by the manager's user
+func (m *userSessionManager) Terminate(sessionID string) (isNotAllowed bool, err error) {
+	m.registry.mu.Lock()
+	defer m.registry.mu.Unlock()
+
+	entry, ok := m.registry.sessions[sessionID]
+	if !ok || entry.terminated {
+		return false, nil
+	}
+	if entry.userID != m.userID {
+		return true, nil
+	}
+
+	entry.terminated = true
+	entry.lastSeen = m.registry.now()
+	return false, nil
+}
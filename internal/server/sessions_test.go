@@ -0,0 +1,131 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package server
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSessionRegistry_BindsSessionToUser(t *testing.T) {
+	registry := newSessionRegistry()
+	alice := registry.managerFor(1)
+	bob := registry.managerFor(2)
+
+	sessionID := alice.Generate()
+
+	terminated, err := alice.Validate(sessionID)
+	assert.NoError(t, err)
+	assert.False(t, terminated)
+
+	_, err = bob.Validate(sessionID)
+	assert.Error(t, err)
+}
+
+func TestSessionRegistry_SurvivesNewManager(t *testing.T) {
+	registry := newSessionRegistry()
+	sessionID := registry.managerFor(1).Generate()
+
+	// A rebuilt transport gets a fresh manager for the same user
+	_, err := registry.managerFor(1).Validate(sessionID)
+	assert.NoError(t, err)
+}
+
+func TestSessionRegistry_Terminate(t *testing.T) {
+	registry := newSessionRegistry()
+	alice := registry.managerFor(1)
+	bob := registry.managerFor(2)
+
+	sessionID := alice.Generate()
+
+	notAllowed, err := bob.Terminate(sessionID)
+	assert.NoError(t, err)
+	assert.True(t, notAllowed)
+
+	notAllowed, err = alice.Terminate(sessionID)
+	assert.NoError(t, err)
+	assert.False(t, notAllowed)
+
+	terminated, err := alice.Validate(sessionID)
+	assert.NoError(t, err)
+	assert.True(t, terminated)
+}
+
+func TestSessionRegistry_RejectsForeignIDs(t *testing.T) {
+	registry := newSessionRegistry()
+	_, err := registry.managerFor(1).Validate("mcp-session-not-ours")
+	assert.Error(t, err)
+}
+
+func TestSessionRegistry_ExpiresIdleSessions(t *testing.T) {
+	registry := newSessionRegistry()
+	now := time.Now()
+	registry.now = func() time.Time { return now }
+	alice := registry.managerFor(1)
+
+	idle := alice.Generate()
+	now = now.Add(sessionIdleTimeout / 2)
+	used := alice.Generate()
+	now = now.Add(sessionIdleTimeout / 2)
+
+	// Using a session keeps it alive
+	terminated, err := alice.Validate(used)
+	assert.NoError(t, err)
+	assert.False(t, terminated)
+
+	now = now.Add(sessionIdleTimeout/2 + time.Minute)
+	terminated, err = alice.Validate(idle)
+	assert.NoError(t, err)
+	assert.True(t, terminated)
+	assert.Equal(t, 1, registry.len())
+
+	terminated, err = alice.Validate(used)
+	assert.NoError(t, err)
+	assert.False(t, terminated)
+}
+
+func TestSessionRegistry_ForgetsTerminatedSessions(t *testing.T) {
+	registry := newSessionRegistry()
+	now := time.Now()
+	registry.now = func() time.Time { return now }
+	alice := registry.managerFor(1)
+
+	sessionID := alice.Generate()
+	_, err := alice.Terminate(sessionID)
+	assert.NoError(t, err)
+
+	now = now.Add(terminatedSessionTTL / 2)
+	terminated, err := alice.Validate(sessionID)
+	assert.NoError(t, err)
+	assert.True(t, terminated)
+	assert.Equal(t, 1, registry.len())
+
+	now = now.Add(terminatedSessionTTL)
+	alice.Generate()
+	assert.Equal(t, 1, registry.len())
+}
+
+func TestSessionRegistry_ForgetUser(t *testing.T) {
+	registry := newSessionRegistry()
+	alice := registry.managerFor(1)
+	bob := registry.managerFor(2)
+
+	aliceSession := alice.Generate()
+	bobSession := bob.Generate()
+
+	registry.forgetUser(1)
+	assert.Equal(t, 1, registry.len())
+
+	// A forgotten session has ended; the client starts a new one
+	terminated, err := alice.Validate(aliceSession)
+	assert.NoError(t, err)
+	assert.True(t, terminated)
+
+	terminated, err = bob.Validate(bobSession)
+	assert.NoError(t, err)
+	assert.False(t, terminated)
+}
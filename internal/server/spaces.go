@@ -0,0 +1,152 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	"github.com/tejzpr/medha-mcp/internal/auth"
+	"github.com/tejzpr/medha-mcp/internal/spaces"
+)
+
+// createSpaceRequest is the body of POST /api/spaces
+type createSpaceRequest struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	RepoURL     string `json:"repo_url"`  // Optional remote to clone and sync with
+	PATToken    string `json:"pat_token"` // Token for the remote
+}
+
+// setSpaceMemberRequest is the body of PUT /api/spaces/{name}/members
+type setSpaceMemberRequest struct {
+	Username string `json:"username"`
+	Role     string `json:"role"`
+}
+
+// HandleSpaces lists (GET) the authenticated user's spaces and creates (POST)
+// a space with the user as its admin
+func (h *HTTPServer) HandleSpaces(w http.ResponseWriter, r *http.Request) {
+	userID, ok := auth.GetUserIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	db := h.mcpServer.dbMgr.SystemDB()
+
+	switch r.Method {
+	case http.MethodGet:
+		memberships, err := spaces.ForUser(db, userID)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		writeJSON(w, http.StatusOK, memberships)
+
+	case http.MethodPost:
+		var req createSpaceRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request body: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		homeDir, _ := os.UserHomeDir()
+		membership, err := spaces.Create(db, userID, spaces.CreateConfig{
+			StorePath:     filepath.Join(homeDir, ".medha", "store"),
+			Name:          req.Name,
+			Description:   req.Description,
+			RepoURL:       req.RepoURL,
+			PAT:           req.PATToken,
+			EncryptionKey: h.encryptionKey,
+		})
+		if err != nil {
+			http.Error(w, err.Error(), spaceErrorStatus(err))
+			return
+		}
+		writeJSON(w, http.StatusCreated, membership)
+
+	default:
+		w.Header().Set("Allow", "GET, POST")
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// HandleSpaceMembers lists (GET) a space's members, for any member, and adds
+// a member or changes their role (PUT), for admins
+func (h *HTTPServer) HandleSpaceMembers(w http.ResponseWriter, r *http.Request) {
+	userID, ok := auth.GetUserIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	db := h.mcpServer.dbMgr.SystemDB()
+	name := r.PathValue("name")
+
+	switch r.Method {
+	case http.MethodGet:
+		members, err := spaces.Members(db, userID, name)
+		if err != nil {
+			http.Error(w, err.Error(), spaceErrorStatus(err))
+			return
+		}
+		writeJSON(w, http.StatusOK, members)
+
+	case http.MethodPut:
+		var req setSpaceMemberRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request body: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		member, err := spaces.SetMember(db, userID, name, req.Username, req.Role)
+		if err != nil {
+			http.Error(w, err.Error(), spaceErrorStatus(err))
+			return
+		}
+		writeJSON(w, http.StatusOK, member)
+
+	default:
+		w.Header().Set("Allow", "GET, PUT")
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// HandleRemoveSpaceMember removes (DELETE) a member from a space: admins may
+// remove anyone, and members may remove themselves
+func (h *HTTPServer) HandleRemoveSpaceMember(w http.ResponseWriter, r *http.Request) {
+	userID, ok := auth.GetUserIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	if r.Method != http.MethodDelete {
+		w.Header().Set("Allow", "DELETE")
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	db := h.mcpServer.dbMgr.SystemDB()
+	if err := spaces.RemoveMember(db, userID, r.PathValue("name"), r.PathValue("username")); err != nil {
+		http.Error(w, err.Error(), spaceErrorStatus(err))
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// spaceErrorStatus returns the HTTP status for a failed space operation
+func spaceErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, spaces.ErrNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, spaces.ErrForbidden):
+		return http.StatusForbidden
+	case errors.Is(err, spaces.ErrInvalid):
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
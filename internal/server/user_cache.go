@@ -0,0 +1,228 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package server
+
+import (
+	"container/list"
+	"sync"
+
+	mcpserver "github.com/mark3labs/mcp-go/server"
+)
+
+// DefaultMaxUserServers is the per-user server cache size used when none is configured
+const DefaultMaxUserServers = 100
+
+// userServerEntry is a cached per-user MCP transport
+type userServerEntry struct {
+	userID    uint
+	ready     chan struct{} // Closed once create has returned
+	transport *mcpserver.StreamableHTTPServer
+	release   func() // Frees what the transport's tools hold open
+	err       error
+	active    int           // Requests using the transport, guarded by the cache lock
+	evicted   bool          // Out of the cache; released once no request is active
+	released  chan struct{} // Closed once an evicted entry is released
+}
+
+// userServerCreator builds a user's transport and the function that frees
+// its resources once the transport is evicted
+type userServerCreator func() (*mcpserver.StreamableHTTPServer, func(), error)
+
+// userServerCache is an LRU cache of per-user MCP transports.
+// Each entry wraps a dedicated mcp-go server whose tools are bound to one
+// user's ToolContext, so evicting an entry never affects other users.
+type userServerCache struct {
+	mu       sync.Mutex
+	capacity int
+	order    *list.List // Front = most recently used
+	entries  map[uint]*list.Element
+	draining map[uint]*userServerEntry // Evicted users whose requests are still running
+	closing  map[uint]chan struct{}    // Evicted users whose release is running
+}
+
+// newUserServerCache creates a cache holding at most capacity users
+func newUserServerCache(capacity int) *userServerCache {
+	if capacity <= 0 {
+		capacity = DefaultMaxUserServers
+	}
+	return &userServerCache{
+		capacity: capacity,
+		order:    list.New(),
+		entries:  make(map[uint]*list.Element),
+		draining: make(map[uint]*userServerEntry),
+		closing:  make(map[uint]chan struct{}),
+	}
+}
+
+// getOrCreate returns the user's transport, creating it with create on a miss,
+// and a done function to call once the request using it has been served.
+// Creation runs outside the cache lock, once per user however many requests
+// wait for it. The least recently used user is evicted when the cache is
+// full, and released once none of its requests are running.
+func (c *userServerCache) getOrCreate(userID uint, create userServerCreator) (*mcpserver.StreamableHTTPServer, func(), error) {
+	c.mu.Lock()
+	if elem, ok := c.entries[userID]; ok {
+		c.order.MoveToFront(elem)
+		entry := elem.Value.(*userServerEntry)
+		entry.active++
+		c.mu.Unlock()
+		return c.await(entry)
+	}
+
+	// An evicted user still serving requests comes back rather than
+	// opening their database a second time
+	if entry, ok := c.draining[userID]; ok {
+		delete(c.draining, userID)
+		entry.evicted = false
+		entry.active++
+		c.entries[userID] = c.order.PushFront(entry)
+		evicted := c.evict()
+		c.mu.Unlock()
+
+		c.releaseAll(evicted)
+		return c.await(entry)
+	}
+
+	entry := &userServerEntry{userID: userID, ready: make(chan struct{}), active: 1}
+	c.entries[userID] = c.order.PushFront(entry)
+	released := c.closing[userID]
+	evicted := c.evict()
+	c.mu.Unlock()
+
+	// The user's previous transport shares its database connection
+	if released != nil {
+		<-released
+	}
+	entry.transport, entry.release, entry.err = create()
+	close(entry.ready)
+	if entry.err != nil {
+		// Let the next request try again
+		c.mu.Lock()
+		if elem, ok := c.entries[userID]; ok && elem.Value == entry {
+			c.order.Remove(elem)
+			delete(c.entries, userID)
+		}
+		if c.draining[userID] == entry {
+			delete(c.draining, userID)
+		}
+		c.mu.Unlock()
+	}
+
+	c.releaseAll(evicted)
+	return c.await(entry)
+}
+
+// await waits for the entry to be created and returns its transport and done
+// function; a failed creation is done at once
+func (c *userServerCache) await(entry *userServerEntry) (*mcpserver.StreamableHTTPServer, func(), error) {
+	<-entry.ready
+	if entry.err != nil {
+		c.done(entry)
+		return nil, nil, entry.err
+	}
+	var once sync.Once
+	return entry.transport, func() { once.Do(func() { c.done(entry) }) }, nil
+}
+
+// done ends a request using the entry, releasing it if it was evicted and
+// this was its last request
+func (c *userServerCache) done(entry *userServerEntry) {
+	c.mu.Lock()
+	entry.active--
+	release := entry.evicted && entry.active == 0 && c.draining[entry.userID] == entry
+	if release {
+		delete(c.draining, entry.userID)
+		c.startRelease(entry)
+	}
+	c.mu.Unlock()
+
+	if release {
+		c.releaseAll([]*userServerEntry{entry})
+	}
+}
+
+// evict removes the least recently used entries past capacity and returns
+// those to release now; entries still serving requests are left draining.
+// The cache lock must be held.
+func (c *userServerCache) evict() []*userServerEntry {
+	var evicted []*userServerEntry
+	for c.order.Len() > c.capacity {
+		oldest := c.order.Back().Value.(*userServerEntry)
+		c.order.Remove(c.order.Back())
+		delete(c.entries, oldest.userID)
+		oldest.evicted = true
+		if oldest.active > 0 {
+			c.draining[oldest.userID] = oldest
+			continue
+		}
+		c.startRelease(oldest)
+		evicted = append(evicted, oldest)
+	}
+	return evicted
+}
+
+// startRelease marks the entry's user as closing so that a new transport for
+// them waits for the release. The cache lock must be held.
+func (c *userServerCache) startRelease(entry *userServerEntry) {
+	entry.released = make(chan struct{})
+	c.closing[entry.userID] = entry.released
+}
+
+// releaseAll releases evicted entries; the cache lock must not be held
+func (c *userServerCache) releaseAll(entries []*userServerEntry) {
+	for _, entry := range entries {
+		entry.close()
+		c.mu.Lock()
+		if c.closing[entry.userID] == entry.released {
+			delete(c.closing, entry.userID)
+		}
+		c.mu.Unlock()
+		close(entry.released)
+	}
+}
+
+// close releases everything held by the cached transports, including those
+// still serving requests
+func (c *userServerCache) close() {
+	c.mu.Lock()
+	entries := make([]*userServerEntry, 0, c.order.Len()+len(c.draining))
+	for elem := c.order.Front(); elem != nil; elem = elem.Next() {
+		entries = append(entries, elem.Value.(*userServerEntry))
+	}
+	for _, entry := range c.draining {
+		entries = append(entries, entry)
+	}
+	c.order.Init()
+	c.entries = make(map[uint]*list.Element)
+	c.draining = make(map[uint]*userServerEntry)
+	c.mu.Unlock()
+
+	for _, entry := range entries {
+		entry.close()
+	}
+}
+
+// close waits for the entry to be created and releases it
+func (e *userServerEntry) close() {
+	<-e.ready
+	if e.err == nil && e.release != nil {
+		e.release()
+	}
+}
+
+// len returns the number of cached users
+func (c *userServerCache) len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len()
+}
+
+// contains reports whether the user currently has a cached transport
+func (c *userServerCache) contains(userID uint) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	_, ok := c.entries[userID]
+	return ok
+}
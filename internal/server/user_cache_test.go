@@ -0,0 +1,172 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package server
+
+import (
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	mcpserver "github.com/mark3labs/mcp-go/server"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestTransport() (*mcpserver.StreamableHTTPServer, func(), error) {
+	return mcpserver.NewStreamableHTTPServer(newMCPGoServer()), nil, nil
+}
+
+func TestUserServerCache_ReusesTransport(t *testing.T) {
+	cache := newUserServerCache(2)
+
+	first, _, err := cache.getOrCreate(1, newTestTransport)
+	require.NoError(t, err)
+
+	second, _, err := cache.getOrCreate(1, func() (*mcpserver.StreamableHTTPServer, func(), error) {
+		t.Fatal("create called for cached user")
+		return nil, nil, nil
+	})
+	require.NoError(t, err)
+
+	assert.Same(t, first, second)
+	assert.Equal(t, 1, cache.len())
+}
+
+func TestUserServerCache_SeparatesUsers(t *testing.T) {
+	cache := newUserServerCache(2)
+
+	a, _, err := cache.getOrCreate(1, newTestTransport)
+	require.NoError(t, err)
+	b, _, err := cache.getOrCreate(2, newTestTransport)
+	require.NoError(t, err)
+
+	assert.NotSame(t, a, b)
+}
+
+func TestUserServerCache_EvictsLeastRecentlyUsed(t *testing.T) {
+	cache := newUserServerCache(2)
+
+	_, _, _ = cache.getOrCreate(1, newTestTransport)
+	_, _, _ = cache.getOrCreate(2, newTestTransport)
+	// Touch user 1 so user 2 becomes least recently used
+	_, _, _ = cache.getOrCreate(1, newTestTransport)
+	_, _, _ = cache.getOrCreate(3, newTestTransport)
+
+	assert.Equal(t, 2, cache.len())
+	assert.True(t, cache.contains(1))
+	assert.False(t, cache.contains(2))
+	assert.True(t, cache.contains(3))
+}
+
+func TestUserServerCache_ReleasesEvictedUsers(t *testing.T) {
+	cache := newUserServerCache(1)
+	released := map[uint]int{}
+	creator := func(userID uint) userServerCreator {
+		return func() (*mcpserver.StreamableHTTPServer, func(), error) {
+			transport, _, err := newTestTransport()
+			return transport, func() { released[userID]++ }, err
+		}
+	}
+
+	_, done, err := cache.getOrCreate(1, creator(1))
+	require.NoError(t, err)
+	done()
+	_, _, err = cache.getOrCreate(2, creator(2))
+	require.NoError(t, err)
+	assert.Equal(t, map[uint]int{1: 1}, released)
+
+	cache.close()
+	assert.Equal(t, map[uint]int{1: 1, 2: 1}, released)
+	assert.Equal(t, 0, cache.len())
+}
+
+func TestUserServerCache_KeepsEvictedUsersUntilTheirRequestsEnd(t *testing.T) {
+	cache := newUserServerCache(1)
+	var released atomic.Int32
+	create := func() (*mcpserver.StreamableHTTPServer, func(), error) {
+		transport, _, err := newTestTransport()
+		return transport, func() { released.Add(1) }, err
+	}
+
+	// User 1 is evicted while a request is in flight
+	first, done, err := cache.getOrCreate(1, create)
+	require.NoError(t, err)
+	_, doneOther, err := cache.getOrCreate(2, newTestTransport)
+	require.NoError(t, err)
+	doneOther()
+	assert.False(t, cache.contains(1))
+	assert.Equal(t, int32(0), released.Load(), "released under a running request")
+
+	// Returning meanwhile takes the same transport back instead of a new one
+	again, doneAgain, err := cache.getOrCreate(1, func() (*mcpserver.StreamableHTTPServer, func(), error) {
+		t.Fatal("create called for a user still serving requests")
+		return nil, nil, nil
+	})
+	require.NoError(t, err)
+	assert.Same(t, first, again)
+
+	// Evicted again, it is released once both requests end
+	_, doneOther, err = cache.getOrCreate(2, newTestTransport)
+	require.NoError(t, err)
+	doneOther()
+	done()
+	done() // Done twice counts once
+	assert.Equal(t, int32(0), released.Load())
+	doneAgain()
+	assert.Equal(t, int32(1), released.Load())
+
+	_, doneNew, err := cache.getOrCreate(1, create)
+	require.NoError(t, err)
+	doneNew()
+	cache.close()
+	assert.Equal(t, int32(2), released.Load())
+}
+
+func TestUserServerCache_CreatesOutsideTheLock(t *testing.T) {
+	cache := newUserServerCache(2)
+	unblock := make(chan struct{})
+	var creates atomic.Int32
+	slow := func() (*mcpserver.StreamableHTTPServer, func(), error) {
+		creates.Add(1)
+		<-unblock
+		return newTestTransport()
+	}
+
+	// Two requests for a user being created share one creation
+	results := make(chan *mcpserver.StreamableHTTPServer, 2)
+	for i := 0; i < 2; i++ {
+		go func() {
+			transport, _, _ := cache.getOrCreate(1, slow)
+			results <- transport
+		}()
+	}
+	require.Eventually(t, func() bool { return creates.Load() == 1 && cache.contains(1) }, time.Second, time.Millisecond)
+
+	// Other users are not held up meanwhile
+	_, _, err := cache.getOrCreate(2, newTestTransport)
+	require.NoError(t, err)
+
+	close(unblock)
+	first, second := <-results, <-results
+	assert.NotNil(t, first)
+	assert.Same(t, first, second)
+	assert.Equal(t, int32(1), creates.Load())
+}
+
+func TestUserServerCache_CreateError(t *testing.T) {
+	cache := newUserServerCache(2)
+
+	_, _, err := cache.getOrCreate(1, func() (*mcpserver.StreamableHTTPServer, func(), error) {
+		return nil, nil, errors.New("boom")
+	})
+	assert.Error(t, err)
+	assert.False(t, cache.contains(1))
+}
+
+func TestUserServerCache_DefaultCapacity(t *testing.T) {
+	cache := newUserServerCache(0)
+	assert.Equal(t, DefaultMaxUserServers, cache.capacity)
+}
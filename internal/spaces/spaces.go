@@ -0,0 +1,328 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+// Package spaces manages team memory spaces: repositories shared by a group
+// of users, each a reader, writer or admin of the space.
+package spaces
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/tejzpr/medha-mcp/internal/crypto"
+	"github.com/tejzpr/medha-mcp/internal/database"
+	"github.com/tejzpr/medha-mcp/internal/git"
+	"gorm.io/gorm"
+)
+
+// Names with a meaning of their own in the tools' space parameter
+const (
+	Personal = "personal" // The user's own repository
+	All      = "all"      // The user's own repository and every space they can read
+)
+
+// Errors a space operation is refused with, for errors.Is
+var (
+	ErrNotFound  = errors.New("not found")
+	ErrForbidden = errors.New("forbidden")
+	ErrInvalid   = errors.New("invalid request")
+)
+
+// validName is what space names look like: they name a directory and are
+// typed into tool calls
+var validName = regexp.MustCompile(`^[a-z0-9][a-z0-9-]{0,62}$`)
+
+// spaceError is a refusal carrying a message for the user
+type spaceError struct {
+	kind    error
+	message string
+}
+
+func (e *spaceError) Error() string { return e.message }
+
+func (e *spaceError) Unwrap() error { return e.kind }
+
+// refuse returns a refusal of the given kind
+func refuse(kind error, format string, args ...interface{}) error {
+	return &spaceError{kind: kind, message: fmt.Sprintf(format, args...)}
+}
+
+// Membership is a space with the role a user has in it
+type Membership struct {
+	database.MedhaSpace
+	Role string `json:"role"`
+}
+
+// Member is a user's role in a space
+type Member struct {
+	UserID   uint      `json:"user_id"`
+	Username string    `json:"username"`
+	Role     string    `json:"role"`
+	AddedAt  time.Time `json:"added_at"`
+}
+
+// CreateConfig describes a new space
+type CreateConfig struct {
+	StorePath     string // Base path for repositories (e.g., ~/.medha/store); spaces go in its spaces folder
+	Name          string
+	Description   string
+	RepoURL       string // Optional: remote to clone and sync with
+	PAT           string // Optional: token for the remote, stored encrypted
+	EncryptionKey []byte
+}
+
+// Create creates a space with its own repository, making the creator its admin
+func Create(db *gorm.DB, creatorID uint, cfg CreateConfig) (*Membership, error) {
+	name := strings.TrimSpace(cfg.Name)
+	if err := ValidateName(name); err != nil {
+		return nil, err
+	}
+
+	var existing int64
+	if err := db.Model(&database.MedhaSpace{}).Where("name = ?", name).Count(&existing).Error; err != nil {
+		return nil, fmt.Errorf("failed to check space name: %w", err)
+	}
+	if existing > 0 {
+		return nil, refuse(ErrInvalid, "a space named %q already exists", name)
+	}
+
+	encryptedPAT := ""
+	if cfg.PAT != "" {
+		var err error
+		if encryptedPAT, err = crypto.EncryptPAT(cfg.PAT, cfg.EncryptionKey); err != nil {
+			return nil, fmt.Errorf("failed to encrypt PAT: %w", err)
+		}
+	}
+
+	result, err := git.SetupUserRepository(&git.SetupConfig{
+		BaseStorePath: filepath.Join(cfg.StorePath, "spaces"),
+		Username:      name,
+		RepoURL:       cfg.RepoURL,
+		PAT:           cfg.PAT,
+		LocalOnly:     cfg.RepoURL == "" || cfg.PAT == "",
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to set up space repository: %w", err)
+	}
+
+	space := database.MedhaSpace{
+		Name:              name,
+		Description:       strings.TrimSpace(cfg.Description),
+		RepoURL:           cfg.RepoURL,
+		RepoPath:          result.RepoPath,
+		PATTokenEncrypted: encryptedPAT,
+		CreatedBy:         creatorID,
+	}
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&space).Error; err != nil {
+			return fmt.Errorf("failed to store space: %w", err)
+		}
+		admin := database.MedhaSpaceMember{SpaceID: space.ID, UserID: creatorID, Role: database.SpaceRoleAdmin}
+		if err := tx.Create(&admin).Error; err != nil {
+			return fmt.Errorf("failed to add space admin: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		if removeErr := os.RemoveAll(result.RepoPath); removeErr != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to remove repository of space %s: %v\n", name, removeErr)
+		}
+		return nil, err
+	}
+
+	return &Membership{MedhaSpace: space, Role: database.SpaceRoleAdmin}, nil
+}
+
+// ValidateName checks a name can be given to a new space
+func ValidateName(name string) error {
+	if !validName.MatchString(name) {
+		return refuse(ErrInvalid, "invalid space name %q: use up to 63 lowercase letters, digits and dashes, starting with a letter or digit", name)
+	}
+	if name == Personal || name == All {
+		return refuse(ErrInvalid, "space name %q is reserved", name)
+	}
+	return nil
+}
+
+// ForUser returns the spaces a user is a member of, by name
+func ForUser(db *gorm.DB, userID uint) ([]Membership, error) {
+	var members []database.MedhaSpaceMember
+	if err := db.Preload("Space").Where("user_id = ?", userID).Find(&members).Error; err != nil {
+		return nil, fmt.Errorf("failed to list spaces: %w", err)
+	}
+
+	memberships := make([]Membership, len(members))
+	for i, m := range members {
+		memberships[i] = Membership{MedhaSpace: m.Space, Role: m.Role}
+	}
+	sort.Slice(memberships, func(i, j int) bool {
+		return memberships[i].Name < memberships[j].Name
+	})
+	return memberships, nil
+}
+
+// Resolve returns a space a user is a member of, checking their role grants
+// at least needed. Spaces the user is not a member of are reported as not
+// found, so their names don't leak.
+func Resolve(db *gorm.DB, userID uint, name, needed string) (*Membership, error) {
+	var space database.MedhaSpace
+	if err := db.Where("name = ?", name).First(&space).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, notMember(name)
+		}
+		return nil, fmt.Errorf("failed to look up space: %w", err)
+	}
+
+	var member database.MedhaSpaceMember
+	if err := db.Where("space_id = ? AND user_id = ?", space.ID, userID).First(&member).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, notMember(name)
+		}
+		return nil, fmt.Errorf("failed to look up space membership: %w", err)
+	}
+
+	if !database.SpaceRoleAllows(member.Role, needed) {
+		return nil, refuse(ErrForbidden, "your role in space %q is %s; this needs %s", name, member.Role, needed)
+	}
+	return &Membership{MedhaSpace: space, Role: member.Role}, nil
+}
+
+// Members lists the members of a space, for any of its members
+func Members(db *gorm.DB, actorID uint, name string) ([]Member, error) {
+	membership, err := Resolve(db, actorID, name, database.SpaceRoleReader)
+	if err != nil {
+		return nil, err
+	}
+
+	var members []database.MedhaSpaceMember
+	if err := db.Preload("User").Where("space_id = ?", membership.ID).Order("id").Find(&members).Error; err != nil {
+		return nil, fmt.Errorf("failed to list space members: %w", err)
+	}
+
+	result := make([]Member, len(members))
+	for i, m := range members {
+		result[i] = Member{UserID: m.UserID, Username: m.User.Username, Role: m.Role, AddedAt: m.CreatedAt}
+	}
+	return result, nil
+}
+
+// SetMember adds a user to a space or changes their role. Only admins may,
+// and the last admin cannot step down.
+func SetMember(db *gorm.DB, actorID uint, name, username, role string) (*Member, error) {
+	if !database.IsValidSpaceRole(role) {
+		return nil, refuse(ErrInvalid, "invalid role %q (valid roles: %s)", role, strings.Join(database.ValidSpaceRoles(), ", "))
+	}
+	membership, err := Resolve(db, actorID, name, database.SpaceRoleAdmin)
+	if err != nil {
+		return nil, err
+	}
+	user, err := findUser(db, username)
+	if err != nil {
+		return nil, err
+	}
+
+	var member database.MedhaSpaceMember
+	err = db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Where("space_id = ? AND user_id = ?", membership.ID, user.ID).First(&member).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			member = database.MedhaSpaceMember{SpaceID: membership.ID, UserID: user.ID, Role: role}
+			if err := tx.Create(&member).Error; err != nil {
+				return fmt.Errorf("failed to add space member: %w", err)
+			}
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to look up space membership: %w", err)
+		}
+
+		if member.Role == database.SpaceRoleAdmin && role != database.SpaceRoleAdmin {
+			if err := requireAnotherAdmin(tx, membership.ID, name); err != nil {
+				return err
+			}
+		}
+		member.Role = role
+		if err := tx.Model(&member).Update("role", role).Error; err != nil {
+			return fmt.Errorf("failed to update space member: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &Member{UserID: user.ID, Username: user.Username, Role: member.Role, AddedAt: member.CreatedAt}, nil
+}
+
+// RemoveMember removes a user from a space. Admins may remove anyone and
+// members may leave; the last admin cannot.
+func RemoveMember(db *gorm.DB, actorID uint, name, username string) error {
+	membership, err := Resolve(db, actorID, name, database.SpaceRoleReader)
+	if err != nil {
+		return err
+	}
+	user, err := findUser(db, username)
+	if err != nil {
+		return err
+	}
+	if user.ID != actorID && membership.Role != database.SpaceRoleAdmin {
+		return refuse(ErrForbidden, "only admins of space %q can remove other members", name)
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		var member database.MedhaSpaceMember
+		if err := tx.Where("space_id = ? AND user_id = ?", membership.ID, user.ID).First(&member).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return refuse(ErrNotFound, "%s is not a member of space %q", username, name)
+			}
+			return fmt.Errorf("failed to look up space membership: %w", err)
+		}
+
+		if member.Role == database.SpaceRoleAdmin {
+			if err := requireAnotherAdmin(tx, membership.ID, name); err != nil {
+				return err
+			}
+		}
+		if err := tx.Delete(&member).Error; err != nil {
+			return fmt.Errorf("failed to remove space member: %w", err)
+		}
+		return nil
+	})
+}
+
+// requireAnotherAdmin refuses to take away an admin when they are the last
+func requireAnotherAdmin(tx *gorm.DB, spaceID uint, name string) error {
+	var admins int64
+	if err := tx.Model(&database.MedhaSpaceMember{}).
+		Where("space_id = ? AND role = ?", spaceID, database.SpaceRoleAdmin).
+		Count(&admins).Error; err != nil {
+		return fmt.Errorf("failed to count space admins: %w", err)
+	}
+	if admins <= 1 {
+		return refuse(ErrInvalid, "space %q needs at least one admin; make another member admin first", name)
+	}
+	return nil
+}
+
+// findUser returns the user with a username
+func findUser(db *gorm.DB, username string) (*database.MedhaUser, error) {
+	var user database.MedhaUser
+	if err := db.Where("username = ?", username).First(&user).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, refuse(ErrNotFound, "no user named %q", username)
+		}
+		return nil, fmt.Errorf("failed to look up user: %w", err)
+	}
+	return &user, nil
+}
+
+// notMember reports a space that doesn't exist or the user can't see
+func notMember(name string) error {
+	return refuse(ErrNotFound, "space %q not found, or you are not a member", name)
+}
@@ -0,0 +1,167 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package spaces
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tejzpr/medha-mcp/internal/database"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// setupSpacesTest returns a system database with users alice, bob and carol
+// and a store path for space repositories
+func setupSpacesTest(t *testing.T) (*gorm.DB, string, map[string]uint) {
+	tempDir := t.TempDir()
+	db, err := database.Connect(&database.Config{
+		Type:       "sqlite",
+		SQLitePath: filepath.Join(tempDir, "system.db"),
+		LogLevel:   logger.Silent,
+	})
+	require.NoError(t, err)
+	t.Cleanup(func() { database.Close(db) })
+	require.NoError(t, database.MigrateSystemDB(db))
+
+	users := make(map[string]uint)
+	for _, name := range []string{"alice", "bob", "carol"} {
+		user := &database.MedhaUser{Username: name}
+		require.NoError(t, db.Create(user).Error)
+		users[name] = user.ID
+	}
+	return db, filepath.Join(tempDir, "store"), users
+}
+
+func TestCreate(t *testing.T) {
+	db, storePath, users := setupSpacesTest(t)
+
+	space, err := Create(db, users["alice"], CreateConfig{StorePath: storePath, Name: "platform", Description: " Architecture decisions "})
+	require.NoError(t, err)
+	assert.Equal(t, "platform", space.Name)
+	assert.Equal(t, "Architecture decisions", space.Description)
+	assert.Equal(t, database.SpaceRoleAdmin, space.Role)
+	assert.Equal(t, filepath.Join(storePath, "spaces", "medha-platform"), space.RepoPath)
+
+	_, err = os.Stat(filepath.Join(space.RepoPath, ".git"))
+	assert.NoError(t, err, "space repository should be initialized")
+
+	memberships, err := ForUser(db, users["alice"])
+	require.NoError(t, err)
+	require.Len(t, memberships, 1)
+	assert.Equal(t, "platform", memberships[0].Name)
+	assert.Equal(t, database.SpaceRoleAdmin, memberships[0].Role)
+
+	_, err = Create(db, users["bob"], CreateConfig{StorePath: storePath, Name: "platform"})
+	assert.ErrorIs(t, err, ErrInvalid)
+}
+
+func TestCreate_InvalidNames(t *testing.T) {
+	db, storePath, users := setupSpacesTest(t)
+
+	for _, name := range []string{"", "Platform", "-team", "a/b", Personal, All} {
+		_, err := Create(db, users["alice"], CreateConfig{StorePath: storePath, Name: name})
+		assert.ErrorIs(t, err, ErrInvalid, "name %q", name)
+	}
+}
+
+func TestResolve(t *testing.T) {
+	db, storePath, users := setupSpacesTest(t)
+
+	_, err := Create(db, users["alice"], CreateConfig{StorePath: storePath, Name: "platform"})
+	require.NoError(t, err)
+	_, err = SetMember(db, users["alice"], "platform", "bob", database.SpaceRoleReader)
+	require.NoError(t, err)
+
+	membership, err := Resolve(db, users["bob"], "platform", database.SpaceRoleReader)
+	require.NoError(t, err)
+	assert.Equal(t, database.SpaceRoleReader, membership.Role)
+
+	_, err = Resolve(db, users["bob"], "platform", database.SpaceRoleWriter)
+	assert.ErrorIs(t, err, ErrForbidden)
+
+	// Non-members can't tell a space exists
+	_, notMemberErr := Resolve(db, users["carol"], "platform", database.SpaceRoleReader)
+	assert.ErrorIs(t, notMemberErr, ErrNotFound)
+	_, missingErr := Resolve(db, users["carol"], "missing", database.SpaceRoleReader)
+	assert.ErrorIs(t, missingErr, ErrNotFound)
+	assert.Equal(t, notMember("platform").Error(), notMemberErr.Error())
+}
+
+func TestSetMember(t *testing.T) {
+	db, storePath, users := setupSpacesTest(t)
+
+	_, err := Create(db, users["alice"], CreateConfig{StorePath: storePath, Name: "platform"})
+	require.NoError(t, err)
+
+	member, err := SetMember(db, users["alice"], "platform", "bob", database.SpaceRoleWriter)
+	require.NoError(t, err)
+	assert.Equal(t, "bob", member.Username)
+	assert.Equal(t, database.SpaceRoleWriter, member.Role)
+
+	// Only admins manage members
+	_, err = SetMember(db, users["bob"], "platform", "carol", database.SpaceRoleReader)
+	assert.ErrorIs(t, err, ErrForbidden)
+
+	_, err = SetMember(db, users["alice"], "platform", "nobody", database.SpaceRoleReader)
+	assert.ErrorIs(t, err, ErrNotFound)
+	_, err = SetMember(db, users["alice"], "platform", "carol", "owner")
+	assert.ErrorIs(t, err, ErrInvalid)
+
+	// Changing a role keeps one membership
+	_, err = SetMember(db, users["alice"], "platform", "bob", database.SpaceRoleAdmin)
+	require.NoError(t, err)
+	members, err := Members(db, users["bob"], "platform")
+	require.NoError(t, err)
+	require.Len(t, members, 2)
+	assert.Equal(t, "alice", members[0].Username)
+	assert.Equal(t, database.SpaceRoleAdmin, members[1].Role)
+}
+
+func TestLastAdminStays(t *testing.T) {
+	db, storePath, users := setupSpacesTest(t)
+
+	_, err := Create(db, users["alice"], CreateConfig{StorePath: storePath, Name: "platform"})
+	require.NoError(t, err)
+	_, err = SetMember(db, users["alice"], "platform", "bob", database.SpaceRoleReader)
+	require.NoError(t, err)
+
+	_, err = SetMember(db, users["alice"], "platform", "alice", database.SpaceRoleWriter)
+	assert.ErrorIs(t, err, ErrInvalid)
+	assert.ErrorIs(t, RemoveMember(db, users["alice"], "platform", "alice"), ErrInvalid)
+
+	// With a second admin, the first may leave
+	_, err = SetMember(db, users["alice"], "platform", "bob", database.SpaceRoleAdmin)
+	require.NoError(t, err)
+	require.NoError(t, RemoveMember(db, users["alice"], "platform", "alice"))
+
+	memberships, err := ForUser(db, users["alice"])
+	require.NoError(t, err)
+	assert.Empty(t, memberships)
+}
+
+func TestRemoveMember(t *testing.T) {
+	db, storePath, users := setupSpacesTest(t)
+
+	_, err := Create(db, users["alice"], CreateConfig{StorePath: storePath, Name: "platform"})
+	require.NoError(t, err)
+	_, err = SetMember(db, users["alice"], "platform", "bob", database.SpaceRoleWriter)
+	require.NoError(t, err)
+	_, err = SetMember(db, users["alice"], "platform", "carol", database.SpaceRoleReader)
+	require.NoError(t, err)
+
+	// Members can leave but not remove others
+	assert.ErrorIs(t, RemoveMember(db, users["carol"], "platform", "bob"), ErrForbidden)
+	require.NoError(t, RemoveMember(db, users["carol"], "platform", "carol"))
+
+	require.NoError(t, RemoveMember(db, users["alice"], "platform", "bob"))
+	assert.ErrorIs(t, RemoveMember(db, users["alice"], "platform", "bob"), ErrNotFound)
+
+	_, err = Resolve(db, users["bob"], "platform", database.SpaceRoleReader)
+	assert.ErrorIs(t, err, ErrNotFound)
+}
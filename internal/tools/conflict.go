@@ -0,0 +1,131 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package tools
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/tejzpr/medha-mcp/internal/database"
+	"github.com/tejzpr/medha-mcp/internal/git"
+	"github.com/tejzpr/medha-mcp/internal/memory"
+	"github.com/tejzpr/medha-mcp/internal/merge"
+)
+
+// maxMergeBaseHistory bounds how many of a memory's commits are searched for
+// the version an agent last read
+const maxMergeBaseHistory = 200
+
+// VersionConflict describes an update rejected because the memory changed
+// after the version the agent read
+type VersionConflict struct {
+	ExpectedVersion int64  `json:"expected_version"`
+	CurrentVersion  int64  `json:"current_version"`
+	CurrentTitle    string `json:"current_title"`
+	CurrentContent  string `json:"current_content"`
+	BaseVersion     int64  `json:"base_version,omitempty"`    // Version the merge started from, when found in history
+	MergedContent   string `json:"merged_content,omitempty"`  // Proposed content merged into the current content
+	MergeConflicts  bool   `json:"merge_conflicts,omitempty"` // Whether merged_content has conflict markers to resolve
+}
+
+// newVersionConflict describes a memory as stored now and, when the version
+// the agent read is in git history, merges the proposed content into it
+func (tc *ToolContext) newVersionConflict(dbMem *database.UserMemory, expectedVersion int64, proposed string) VersionConflict {
+	conflict := VersionConflict{
+		ExpectedVersion: expectedVersion,
+		CurrentVersion:  dbMem.Version,
+		CurrentTitle:    dbMem.Title,
+	}
+
+	markdownContent, err := os.ReadFile(dbMem.FilePath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to read %s for a version conflict: %v\n", dbMem.Slug, err)
+		return conflict
+	}
+	current, err := memory.ParseMarkdown(string(markdownContent))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to parse %s for a version conflict: %v\n", dbMem.Slug, err)
+		return conflict
+	}
+	conflict.CurrentContent = current.Content
+
+	if base, baseVersion, ok := tc.contentAtVersion(dbMem.FilePath, expectedVersion); ok {
+		conflict.BaseVersion = baseVersion
+		conflict.MergedContent, conflict.MergeConflicts = merge.ThreeWayMerge(base, current.Content, strings.TrimSpace(proposed))
+	}
+	return conflict
+}
+
+// contentAtVersion returns a memory's content from its latest commit written
+// at or below a version, going by the version in the file's frontmatter
+func (tc *ToolContext) contentAtVersion(filePath string, version int64) (string, int64, bool) {
+	if version <= 0 {
+		return "", 0, false
+	}
+	gitRepo, err := git.OpenRepository(tc.RepoPath)
+	if err != nil {
+		return "", 0, false
+	}
+	commits, err := gitRepo.GetFileHistory(filePath, maxMergeBaseHistory)
+	if err != nil {
+		return "", 0, false
+	}
+
+	for _, commit := range commits {
+		data, err := gitRepo.GetFileAtRevision(filePath, commit.Hash)
+		if err != nil {
+			continue
+		}
+		mem, err := memory.ParseMarkdown(string(data))
+		if err != nil {
+			continue
+		}
+		if mem.Version > 0 && mem.Version <= version {
+			return mem.Content, mem.Version, true
+		}
+	}
+	return "", 0, false
+}
+
+// versionConflictResult rejects an update with the memory's current state and
+// a merge suggestion, as structured content in either format
+func versionConflictResult(format, slug string, conflict VersionConflict) *mcp.CallToolResult {
+	message := fmt.Sprintf("Memory '%s' was changed by another agent: you read version %d, it is now version %d. Nothing was written.",
+		slug, conflict.ExpectedVersion, conflict.CurrentVersion)
+	if conflict.ExpectedVersion == 0 {
+		message = fmt.Sprintf("Memory '%s' already exists (version %d). Nothing was written.", slug, conflict.CurrentVersion)
+	}
+	out := MemoryChangeOutput{Action: "conflict", Message: message, Conflict: &conflict}
+
+	var result *mcp.CallToolResult
+	if format == FormatJSON {
+		result = mcp.NewToolResultStructuredOnly(out)
+	} else {
+		result = mcp.NewToolResultStructured(out, formatVersionConflict(message, conflict))
+	}
+	result.IsError = true
+	return result
+}
+
+// formatVersionConflict describes a conflict as markdown
+func formatVersionConflict(message string, conflict VersionConflict) string {
+	var sb strings.Builder
+	sb.WriteString(message + "\n\n")
+	sb.WriteString(fmt.Sprintf("## Current content (version %d)\n\n%s\n\n", conflict.CurrentVersion, conflict.CurrentContent))
+
+	switch {
+	case conflict.MergedContent == "":
+		sb.WriteString("No merge suggestion: the version you read is not in this memory's history.\n\n")
+	case conflict.MergeConflicts:
+		sb.WriteString(fmt.Sprintf("## Suggested merge (from version %d, resolve the conflict markers)\n\n%s\n\n", conflict.BaseVersion, conflict.MergedContent))
+	default:
+		sb.WriteString(fmt.Sprintf("## Suggested merge (from version %d, merged cleanly)\n\n%s\n\n", conflict.BaseVersion, conflict.MergedContent))
+	}
+
+	sb.WriteString(fmt.Sprintf("Review it, then call medha_remember again with expected_version %d.", conflict.CurrentVersion))
+	return sb.String()
+}
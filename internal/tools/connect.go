@@ -6,6 +6,7 @@ package tools
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
 	"time"
@@ -18,6 +19,16 @@ import (
 	"gorm.io/gorm"
 )
 
+// ConnectOutput is the structured output of medha_connect
+type ConnectOutput struct {
+	Action       string  `json:"action"` // connected or disconnected
+	From         string  `json:"from"`
+	To           string  `json:"to"`
+	Relationship string  `json:"relationship,omitempty"`
+	Strength     float64 `json:"strength,omitempty"`
+	Message      string  `json:"message"`
+}
+
 // NewConnectTool creates the medha_connect tool definition
 func NewConnectTool() mcp.Tool {
 	return mcp.NewTool("medha_connect",
@@ -39,6 +50,9 @@ func NewConnectTool() mcp.Tool {
 		mcp.WithNumber("strength",
 			mcp.Description("Relationship importance from 0.0 (weak) to 1.0 (strong). Default: 0.5"),
 		),
+		withSpace(),
+		withFormat(),
+		mcp.WithOutputSchema[ConnectOutput](),
 	)
 }
 
@@ -59,6 +73,16 @@ func ConnectHandler(ctx *ToolContext, userID uint) func(context.Context, mcp.Cal
 		disconnect := request.GetBool("disconnect", false)
 		relationship := request.GetString("relationship", "related")
 		strength := request.GetFloat("strength", 0.5)
+		format, err := requestFormat(request)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+
+		// Work in the requested team space, if any
+		ctx, err := ctx.forSpace(userID, request, database.SpaceRoleWriter)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
 
 		// Validate UserDB is available
 		if ctx.UserDB == nil {
@@ -71,6 +95,13 @@ func ConnectHandler(ctx *ToolContext, userID uint) func(context.Context, mcp.Cal
 			return mcp.NewToolResultError(fmt.Sprintf("invalid relationship type: '%s'. Valid: related, references, follows, supersedes, part_of", relationship)), nil
 		}
 
+		// Superseding and disconnecting rewrite the target's file
+		release, err := ctx.lockMemory(c, toSlug)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		defer release()
+
 		// Get source memory from UserDB
 		var fromMem database.UserMemory
 		if err := ctx.UserDB.Where("slug = ?", fromSlug).First(&fromMem).Error; err != nil {
@@ -89,11 +120,23 @@ func ConnectHandler(ctx *ToolContext, userID uint) func(context.Context, mcp.Cal
 			return mcp.NewToolResultError(fmt.Sprintf("database error: %v", err)), nil
 		}
 
+		var result *mcp.CallToolResult
+		out := ConnectOutput{From: fromMem.Slug, To: toMem.Slug}
 		if disconnect {
-			return handleDisconnectV2(ctx, &fromMem, &toMem)
+			out.Action = "disconnected"
+			result, err = handleDisconnectV2(ctx, &fromMem, &toMem)
+		} else {
+			out.Action = "connected"
+			out.Relationship = assocType
+			out.Strength = strength
+			result, err = handleConnectV2(ctx, &fromMem, &toMem, assocType, strength)
+		}
+		if err != nil || result.IsError {
+			return result, err
 		}
 
-		return handleConnectV2(ctx, &fromMem, &toMem, assocType, strength)
+		out.Message = resultText(result)
+		return formatResult(format, out, out.Message), nil
 	}
 }
 
@@ -153,6 +196,7 @@ func handleConnectV2(ctx *ToolContext, fromMem, toMem *database.UserMemory, asso
 		// Update frontmatter with superseded_by
 		mem.SupersededBy = fromMem.Slug
 		mem.Updated = time.Now()
+		mem.Version = originalVersion + 1
 
 		// Generate updated markdown
 		markdown, err := mem.ToMarkdown()
@@ -160,29 +204,24 @@ func handleConnectV2(ctx *ToolContext, fromMem, toMem *database.UserMemory, asso
 			return mcp.NewToolResultError(fmt.Sprintf("failed to generate updated markdown: %v", err)), nil
 		}
 
-		// Write updated file
-		if err := os.WriteFile(toMem.FilePath, []byte(markdown), 0644); err != nil {
-			return mcp.NewToolResultError(fmt.Sprintf("failed to write updated file: %v", err)), nil
-		}
-
-		// Git commit
-		gitRepo, err := git.OpenRepository(ctx.RepoPath)
-		if err == nil {
-			msgFormat := git.CommitMessageFormats{}
-			_ = gitRepo.CommitFile(toMem.FilePath, msgFormat.SupersedeMemory(toMem.Slug, fromMem.Slug))
-		}
-
-		// Update database with optimistic locking
+		// Write, commit and update database with optimistic locking as one unit
 		now := time.Now()
-		err = locking.RetryWithBackoff(locking.MaxRetries, locking.RetryDelay, func() error {
-			return locking.UpdateWithVersion(ctx.UserDB, "memories", toMem.Slug, originalVersion, map[string]interface{}{
-				"superseded_by": fromMem.Slug,
-				"updated_at":    now,
-			})
+		msgFormat := git.CommitMessageFormats{}
+		err = ctx.applyMemoryWrite(memoryWrite{
+			path:    toMem.FilePath,
+			content: []byte(markdown),
+			message: msgFormat.SupersedeMemory(toMem.Slug, fromMem.Slug),
+			update: func(tx *gorm.DB) error {
+				return locking.UpdateWithVersion(tx, "memories", toMem.Slug, originalVersion, map[string]interface{}{
+					"superseded_by": fromMem.Slug,
+					"updated_at":    now,
+				})
+			},
 		})
 
 		if err != nil {
-			if _, ok := err.(*locking.ConflictError); ok {
+			var conflict *locking.ConflictError
+			if errors.As(err, &conflict) {
 				return mcp.NewToolResultError("Target memory was modified by another agent. Please retry."), nil
 			}
 			return mcp.NewToolResultError(fmt.Sprintf("failed to update database: %v", err)), nil
@@ -192,6 +231,8 @@ func handleConnectV2(ctx *ToolContext, fromMem, toMem *database.UserMemory, asso
 	}
 
 	// Git commit
+	unlock := git.LockRepository(ctx.RepoPath)
+	defer unlock()
 	gitRepo, err := git.OpenRepository(ctx.RepoPath)
 	if err == nil {
 		msgFormat := git.CommitMessageFormats{}
@@ -218,43 +259,53 @@ func handleDisconnectV2(ctx *ToolContext, fromMem, toMem *database.UserMemory) (
 		// Capture version for optimistic locking
 		originalVersion := toMem.Version
 
-		// Update the markdown file to remove superseded_by from frontmatter
-		markdownContent, err := os.ReadFile(toMem.FilePath)
-		if err == nil {
-			mem, err := memory.ParseMarkdown(string(markdownContent))
-			if err == nil {
-				// Clear superseded_by from frontmatter
-				mem.SupersededBy = ""
-				mem.Updated = time.Now()
-
-				// Generate updated markdown
-				markdown, err := mem.ToMarkdown()
-				if err == nil {
-					_ = os.WriteFile(toMem.FilePath, []byte(markdown), 0644)
-
-					// Git commit
-					gitRepo, err := git.OpenRepository(ctx.RepoPath)
-					if err == nil {
-						msgFormat := git.CommitMessageFormats{}
-						_ = gitRepo.CommitFile(toMem.FilePath, msgFormat.ClearSuperseded(toMem.Slug))
-					}
-				}
-			}
-		}
-
-		// Update database with optimistic locking
 		now := time.Now()
-		_ = locking.RetryWithBackoff(locking.MaxRetries, locking.RetryDelay, func() error {
-			return locking.UpdateWithVersion(ctx.UserDB, "memories", toMem.Slug, originalVersion, map[string]interface{}{
+		clearSuperseded := func(tx *gorm.DB) error {
+			return locking.UpdateWithVersion(tx, "memories", toMem.Slug, originalVersion, map[string]interface{}{
 				"superseded_by": nil,
 				"updated_at":    now,
 			})
-		})
+		}
+
+		// Remove superseded_by from the frontmatter, committing the file and
+		// updating the database as one unit. A file that cannot be rewritten
+		// still has the database cleared.
+		markdown, err := clearSupersededMarkdown(toMem.FilePath, now, originalVersion+1)
+		if err == nil {
+			msgFormat := git.CommitMessageFormats{}
+			err = ctx.applyMemoryWrite(memoryWrite{
+				path:    toMem.FilePath,
+				content: []byte(markdown),
+				message: msgFormat.ClearSuperseded(toMem.Slug),
+				update:  clearSuperseded,
+			})
+		} else {
+			err = ctx.UserDB.Transaction(clearSuperseded)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to clear superseded_by on %s: %v\n", toMem.Slug, err)
+		}
 	}
 
 	return mcp.NewToolResultText(fmt.Sprintf("Disconnected: '%s' and '%s'", fromMem.Slug, toMem.Slug)), nil
 }
 
+// clearSupersededMarkdown returns a memory file's markdown without superseded_by
+func clearSupersededMarkdown(filePath string, updated time.Time, version int64) (string, error) {
+	markdownContent, err := os.ReadFile(filePath)
+	if err != nil {
+		return "", err
+	}
+	mem, err := memory.ParseMarkdown(string(markdownContent))
+	if err != nil {
+		return "", err
+	}
+	mem.SupersededBy = ""
+	mem.Updated = updated
+	mem.Version = version
+	return mem.ToMarkdown()
+}
+
 // mapRelationshipType maps user-friendly names to internal constants
 func mapRelationshipType(relationship string) string {
 	switch relationship {
@@ -0,0 +1,72 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package tools
+
+import (
+	"fmt"
+
+	"github.com/tejzpr/medha-mcp/internal/database"
+	"github.com/tejzpr/medha-mcp/internal/embeddings"
+	"github.com/tejzpr/medha-mcp/internal/memory"
+)
+
+// memoryEmbeddingText returns the text that is embedded for a memory
+func memoryEmbeddingText(mem *memory.Memory) string {
+	return mem.Title + "\n\n" + mem.Content
+}
+
+// embeddingContentForSlug loads the embedding text of a live memory by slug
+func (tc *ToolContext) embeddingContentForSlug(slug string) (string, bool) {
+	mem, err := tc.GetUserMemoryBySlug(slug)
+	if err != nil {
+		return "", false
+	}
+	content := loadMemoryContent(mem.FilePath)
+	if content == nil {
+		return "", false
+	}
+	return memoryEmbeddingText(content), true
+}
+
+// queueEmbedding schedules a written memory for background embedding.
+// It is a no-op when semantic search is disabled.
+func (tc *ToolContext) queueEmbedding(slug string, mem *memory.Memory) {
+	if tc.embeddingIndexer == nil || mem == nil {
+		return
+	}
+	tc.embeddingIndexer.Enqueue(slug, memoryEmbeddingText(mem))
+}
+
+// WaitForEmbeddings blocks until queued background embeddings are stored
+func (tc *ToolContext) WaitForEmbeddings() {
+	if tc.embeddingIndexer != nil {
+		tc.embeddingIndexer.Wait()
+	}
+}
+
+// ReindexEmbeddings embeds every live memory whose vector is missing or stale.
+// Memories are sent to the provider in chunks of batchSize; see Service.Reindex.
+func (tc *ToolContext) ReindexEmbeddings(batchSize int, progress embeddings.ReindexProgress) (*embeddings.ReindexResult, error) {
+	svc := tc.currentEmbeddingService()
+	if svc == nil || tc.UserDB == nil {
+		return nil, fmt.Errorf("semantic search is not enabled")
+	}
+
+	var dbMems []database.UserMemory
+	if err := tc.UserDB.Order("slug").Find(&dbMems).Error; err != nil {
+		return nil, fmt.Errorf("failed to list memories: %w", err)
+	}
+
+	contents := make([]embeddings.MemoryContent, 0, len(dbMems))
+	for _, dbMem := range dbMems {
+		mem := loadMemoryContent(dbMem.FilePath)
+		if mem == nil {
+			continue
+		}
+		contents = append(contents, embeddings.MemoryContent{Slug: dbMem.Slug, Content: memoryEmbeddingText(mem)})
+	}
+
+	return svc.Reindex(contents, batchSize, progress)
+}
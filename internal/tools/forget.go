@@ -6,9 +6,8 @@ package tools
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"os"
-	"path/filepath"
 	"time"
 
 	"github.com/mark3labs/mcp-go/mcp"
@@ -27,6 +26,9 @@ func NewForgetTool() mcp.Tool {
 			mcp.Required(),
 			mcp.Description("Memory to archive"),
 		),
+		withSpace(),
+		withFormat(),
+		mcp.WithOutputSchema[MemoryChangeOutput](),
 	)
 }
 
@@ -38,12 +40,29 @@ func ForgetHandler(ctx *ToolContext, userID uint) func(context.Context, mcp.Call
 		if err != nil {
 			return mcp.NewToolResultError(err.Error()), nil
 		}
+		format, err := requestFormat(request)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+
+		// Work in the requested team space, if any
+		ctx, err := ctx.forSpace(userID, request, database.SpaceRoleWriter)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
 
 		// Validate UserDB is available
 		if ctx.UserDB == nil {
 			return mcp.NewToolResultError("per-user database not available"), nil
 		}
 
+		// Hold the memory's write lock until it is archived
+		release, err := ctx.lockMemory(c, slug)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		defer release()
+
 		// Get memory from UserDB
 		var mem database.UserMemory
 		if err := ctx.UserDB.Where("slug = ?", slug).First(&mem).Error; err != nil {
@@ -61,28 +80,13 @@ func ForgetHandler(ctx *ToolContext, userID uint) func(context.Context, mcp.Call
 			return mcp.NewToolResultError(fmt.Sprintf("memory '%s' is already archived", slug)), nil
 		}
 
+		changed := ctx.watchMemory(slug)
+
 		// Get organizer and determine archive path
 		organizer := memory.NewOrganizer(ctx.RepoPath)
 		archivePath := organizer.GetArchivePath(slug)
 
-		// Move file to archive
-		if err := os.MkdirAll(filepath.Dir(archivePath), 0755); err != nil {
-			return mcp.NewToolResultError(fmt.Sprintf("failed to create archive dir: %v", err)), nil
-		}
-
-		oldFilePath := mem.FilePath
-		if err := os.Rename(mem.FilePath, archivePath); err != nil {
-			return mcp.NewToolResultError(fmt.Sprintf("failed to move to archive: %v", err)), nil
-		}
-
-		// Git commit
-		gitRepo, err := git.OpenRepository(ctx.RepoPath)
-		if err == nil {
-			msgFormat := git.CommitMessageFormats{}
-			_ = gitRepo.CommitAll(msgFormat.ArchiveMemory(slug))
-		}
-
-		// Update database with optimistic locking (update file path and soft delete)
+		// Move file to archive, commit and soft delete with optimistic locking as one unit
 		now := time.Now()
 		updates := map[string]interface{}{
 			"file_path":  archivePath,
@@ -90,19 +94,28 @@ func ForgetHandler(ctx *ToolContext, userID uint) func(context.Context, mcp.Call
 			"updated_at": now,
 		}
 
-		err = locking.RetryWithBackoff(locking.MaxRetries, locking.RetryDelay, func() error {
-			return locking.UpdateWithVersion(ctx.UserDB, "memories", slug, originalVersion, updates)
+		msgFormat := git.CommitMessageFormats{}
+		err = ctx.applyMemoryWrite(memoryWrite{
+			path:    mem.FilePath,
+			moveTo:  archivePath,
+			message: msgFormat.ArchiveMemory(slug),
+			update: func(tx *gorm.DB) error {
+				return locking.UpdateWithVersion(tx, "memories", slug, originalVersion, updates)
+			},
 		})
 
 		if err != nil {
-			// Restore file to original location on conflict
-			_ = os.Rename(archivePath, oldFilePath)
-			if _, ok := err.(*locking.ConflictError); ok {
+			var conflict *locking.ConflictError
+			if errors.As(err, &conflict) {
 				return mcp.NewToolResultError("Memory was modified by another agent. Please retry."), nil
 			}
 			return mcp.NewToolResultError(fmt.Sprintf("failed to archive: %v", err)), nil
 		}
 
-		return mcp.NewToolResultText(fmt.Sprintf("Memory '%s' archived (can be restored later)", slug)), nil
+		// Archived memories are no longer searchable
+		ctx.removeFromSearch(slug)
+		changed("archived")
+
+		return ctx.memoryChangeResult(format, "archived", slug, fmt.Sprintf("Memory '%s' archived (can be restored later)", slug)), nil
 	}
 }
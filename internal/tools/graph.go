@@ -0,0 +1,166 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/tejzpr/medha-mcp/internal/database"
+	"github.com/tejzpr/medha-mcp/internal/graph"
+)
+
+// NewGraphTool creates the medha_graph tool definition
+func NewGraphTool() mcp.Tool {
+	return mcp.NewTool("medha_graph",
+		mcp.WithDescription("Explore the knowledge graph around a memory. Walks connections N hops out from a starting memory and returns every linked memory and connection in one call. Use this instead of repeated recalls when you need the surrounding context of a topic."),
+		mcp.WithString("slug",
+			mcp.Required(),
+			mcp.Description("Memory to start from"),
+		),
+		mcp.WithNumber("max_hops",
+			mcp.Description("How many connections away to walk (1-5). Default: 2"),
+		),
+		mcp.WithString("direction",
+			mcp.Description("Which connections to follow: 'outgoing', 'incoming', or 'both'. Default: 'both'"),
+		),
+		mcp.WithArray("relationships",
+			mcp.Description("Only follow these connection types: 'related', 'references', 'follows', 'precedes', 'supersedes', 'part_of', 'project', 'person'"),
+			mcp.WithStringItems(),
+		),
+		mcp.WithNumber("min_strength",
+			mcp.Description("Only follow connections at least this strong (0.0 to 1.0). Default: 0"),
+		),
+		mcp.WithBoolean("depth_first",
+			mcp.Description("Walk depth-first instead of breadth-first"),
+		),
+		withSpace(),
+		withFormat(),
+		mcp.WithOutputSchema[graph.SlugGraph](),
+	)
+}
+
+// GraphHandler handles the medha_graph tool
+// Uses v2 architecture: traverses the slug-based associations in UserDB
+func GraphHandler(ctx *ToolContext, userID uint) func(context.Context, mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	return func(c context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		slug, err := request.RequireString("slug")
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+
+		maxHops := int(request.GetFloat("max_hops", 2.0))
+		direction := request.GetString("direction", graph.DirectionBoth)
+		relationships := request.GetStringSlice("relationships", []string{})
+		minStrength := request.GetFloat("min_strength", 0.0)
+		depthFirst := request.GetBool("depth_first", false)
+		format, err := requestFormat(request)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+
+		// Work in the requested team space, if any
+		ctx, err := ctx.forSpace(userID, request, database.SpaceRoleReader)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+
+		// Validate UserDB is available
+		if ctx.UserDB == nil {
+			return mcp.NewToolResultError("per-user database not available"), nil
+		}
+
+		if maxHops < 1 || maxHops > graph.MaxTraversalHops {
+			return mcp.NewToolResultError(fmt.Sprintf("max_hops must be between 1 and %d", graph.MaxTraversalHops)), nil
+		}
+
+		// Map simplified relationship names to internal constants
+		var relFilter []string
+		for _, rel := range relationships {
+			assocType := mapRelationshipType(rel)
+			if assocType == "" {
+				return mcp.NewToolResultError(fmt.Sprintf("invalid relationship type: '%s'. Valid: related, references, follows, precedes, supersedes, part_of, project, person", rel)), nil
+			}
+			relFilter = append(relFilter, assocType)
+		}
+
+		mgr := graph.NewManager(ctx.UserDB)
+		g, err := mgr.TraverseUserGraph(slug, graph.TraversalOptions{
+			MaxHops:       maxHops,
+			Direction:     direction,
+			Relationships: relFilter,
+			MinStrength:   minStrength,
+			BreadthFirst:  !depthFirst,
+		})
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+
+		if format == FormatJSON {
+			return mcp.NewToolResultStructuredOnly(g), nil
+		}
+
+		graphJSON, err := json.MarshalIndent(g, "", "  ")
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("failed to encode graph: %v", err)), nil
+		}
+
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				mcp.NewTextContent(formatGraphOutline(g)),
+				mcp.NewTextContent(string(graphJSON)),
+			},
+			StructuredContent: g,
+		}, nil
+	}
+}
+
+// formatGraphOutline renders a traversal as an indented outline rooted at the start memory
+func formatGraphOutline(g *graph.SlugGraph) string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("# Graph from '%s'\n\n", g.Start))
+	sb.WriteString(fmt.Sprintf("%d memories, %d connections\n\n", len(g.Nodes), len(g.Edges)))
+
+	if len(g.Nodes) > 0 {
+		root := g.Nodes[0]
+		sb.WriteString(fmt.Sprintf("- **%s** (`%s`)\n", root.Title, root.Slug))
+		writeGraphChildren(&sb, g, root.Slug, 1)
+	}
+
+	if len(g.Edges) == 0 {
+		sb.WriteString("\nNo connections found.\n")
+	}
+
+	return sb.String()
+}
+
+// writeGraphChildren writes the subtree of nodes reached from parent
+func writeGraphChildren(sb *strings.Builder, g *graph.SlugGraph, parent string, indent int) {
+	for _, child := range g.Children(parent) {
+		sb.WriteString(strings.Repeat("  ", indent))
+		sb.WriteString(fmt.Sprintf("- %s **%s** (`%s`)", graphArrow(child), child.Title, child.Slug))
+		if child.Superseded {
+			sb.WriteString(" ⚠️ superseded")
+		}
+		sb.WriteString("\n")
+		writeGraphChildren(sb, g, child.Slug, indent+1)
+	}
+}
+
+// graphArrow renders the edge a node was reached by, pointing the way the
+// association does
+func graphArrow(n graph.SlugNode) string {
+	switch {
+	case n.Incoming:
+		return "<-" + n.Relationship + "-"
+	case database.IsDirectionalType(n.Relationship):
+		return "-" + n.Relationship + "->"
+	default:
+		return "-" + n.Relationship + "-"
+	}
+}
@@ -16,6 +16,40 @@ import (
 	"gorm.io/gorm"
 )
 
+// HistoryOutput is the structured output of medha_history
+type HistoryOutput struct {
+	Scope    string          `json:"scope"` // memory, topic or recent
+	Topic    string          `json:"topic,omitempty"`
+	Memory   *MemoryInfo     `json:"memory,omitempty"`   // The memory, for a single memory's history
+	Memories []MemoryHistory `json:"memories,omitempty"` // Memories matching the topic
+	Commits  []HistoryCommit `json:"commits,omitempty"`  // A memory's commits, or recent activity
+}
+
+// MemoryHistory is a memory matching a history topic with its recent commits
+type MemoryHistory struct {
+	Memory  MemoryInfo      `json:"memory"`
+	Commits []HistoryCommit `json:"commits"`
+}
+
+// HistoryCommit is a commit in HistoryOutput
+type HistoryCommit struct {
+	Hash      string    `json:"hash"`
+	Timestamp time.Time `json:"timestamp"`
+	Message   string    `json:"message"`
+	Files     []string  `json:"files,omitempty"`
+	Changes   []string  `json:"changes,omitempty"` // Diff hunks since the previous commit, with show_changes
+}
+
+// newHistoryCommit converts a git commit for output
+func newHistoryCommit(commit git.CommitInfo) HistoryCommit {
+	return HistoryCommit{
+		Hash:      commit.Hash,
+		Timestamp: commit.Timestamp,
+		Message:   commit.Message,
+		Files:     commit.Files,
+	}
+}
+
 // NewHistoryTool creates the medha_history tool definition
 func NewHistoryTool() mcp.Tool {
 	return mcp.NewTool("medha_history",
@@ -35,6 +69,9 @@ func NewHistoryTool() mcp.Tool {
 		mcp.WithNumber("limit",
 			mcp.Description("Maximum entries to return. Default: 10"),
 		),
+		withSpace(),
+		withFormat(),
+		mcp.WithOutputSchema[HistoryOutput](),
 	)
 }
 
@@ -47,15 +84,25 @@ func HistoryHandler(ctx *ToolContext, userID uint) func(context.Context, mcp.Cal
 		showChanges := request.GetBool("show_changes", false)
 		sinceStr := request.GetString("since", "")
 		limit := int(request.GetFloat("limit", 10.0))
+		format, err := requestFormat(request)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+
+		// Work in the requested team space, if any
+		ctx, err := ctx.forSpace(userID, request, database.SpaceRoleReader)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
 
 		// Validate UserDB is available
 		if ctx.UserDB == nil {
 			return mcp.NewToolResultError("per-user database not available"), nil
 		}
 
-		// Get user's repo from system DB
-		var repo database.MedhaGitRepo
-		if err := ctx.DB.Where("user_id = ?", userID).First(&repo).Error; err != nil {
+		// Get the user's or the space's repo
+		repo, err := ctx.repository(userID)
+		if err != nil {
 			return mcp.NewToolResultError(fmt.Sprintf("failed to get user repository: %v", err)), nil
 		}
 
@@ -68,52 +115,74 @@ func HistoryHandler(ctx *ToolContext, userID uint) func(context.Context, mcp.Cal
 			return mcp.NewToolResultError(fmt.Sprintf("failed to open git repository: %v", err)), nil
 		}
 
-		var output strings.Builder
+		var history *HistoryOutput
+		var output string
 
 		if slug != "" {
 			// History for specific memory
-			result, err := getMemoryHistoryV2(ctx, gitRepo, slug, sinceTime, showChanges, limit)
+			history, err = getMemoryHistoryV2(ctx, gitRepo, slug, sinceTime, showChanges, limit)
 			if err != nil {
 				return mcp.NewToolResultError(err.Error()), nil
 			}
-			output.WriteString(result)
+			output = formatMemoryHistory(history)
 		} else if topic != "" {
 			// Search for memories matching topic and show combined history
-			result, err := getTopicHistoryV2(ctx, gitRepo, topic, sinceTime, showChanges, limit, repo.RepoPath)
+			history, err = getTopicHistoryV2(ctx, gitRepo, topic, sinceTime)
 			if err != nil {
 				return mcp.NewToolResultError(err.Error()), nil
 			}
-			output.WriteString(result)
+			output = formatTopicHistory(history)
 		} else {
 			// Show recent activity across all memories
-			result, err := getRecentActivity(gitRepo, sinceTime, limit, repo.RepoPath)
+			history, err = getRecentActivity(gitRepo, sinceTime, limit)
 			if err != nil {
 				return mcp.NewToolResultError(err.Error()), nil
 			}
-			output.WriteString(result)
+			output = formatRecentActivity(history)
 		}
 
-		return mcp.NewToolResultText(output.String()), nil
+		return formatResult(format, history, output), nil
 	}
 }
 
 // getMemoryHistoryV2 returns history for a specific memory (v2 architecture)
-func getMemoryHistoryV2(ctx *ToolContext, gitRepo *git.Repository, slug string, since time.Time, showChanges bool, limit int) (string, error) {
+func getMemoryHistoryV2(ctx *ToolContext, gitRepo *git.Repository, slug string, since time.Time, showChanges bool, limit int) (*HistoryOutput, error) {
 	// Get memory from UserDB
 	var mem database.UserMemory
 	if err := ctx.UserDB.Unscoped().Where("slug = ?", slug).First(&mem).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
-			return "", fmt.Errorf("memory not found: %s", slug)
+			return nil, fmt.Errorf("memory not found: %s", slug)
 		}
-		return "", fmt.Errorf("database error: %v", err)
+		return nil, fmt.Errorf("database error: %v", err)
 	}
 
 	// Get commit history for this file
 	commits, err := gitRepo.SearchCommits("", mem.FilePath, since, time.Time{}, limit)
 	if err != nil {
-		return "", fmt.Errorf("failed to get history: %v", err)
+		return nil, fmt.Errorf("failed to get history: %v", err)
+	}
+
+	info := newMemoryInfo(&mem)
+	history := &HistoryOutput{Scope: "memory", Memory: &info}
+	for i, commit := range commits {
+		hc := newHistoryCommit(commit)
+		if showChanges && i < len(commits)-1 {
+			// Diff between this commit and the next (older) one
+			diff, err := gitRepo.GetFileDiff(mem.FilePath, commits[i+1].Hash, commit.Hash)
+			if err == nil && diff != nil {
+				hc.Changes = diff.Hunks
+			}
+		}
+		history.Commits = append(history.Commits, hc)
 	}
 
+	return history, nil
+}
+
+// formatMemoryHistory renders a single memory's history
+func formatMemoryHistory(history *HistoryOutput) string {
+	mem := history.Memory
+
 	var sb strings.Builder
 	sb.WriteString(fmt.Sprintf("# History for '%s'\n\n", mem.Title))
 	sb.WriteString(fmt.Sprintf("**Slug**: `%s`\n", mem.Slug))
@@ -122,99 +191,122 @@ func getMemoryHistoryV2(ctx *ToolContext, gitRepo *git.Repository, slug string,
 	sb.WriteString(fmt.Sprintf("**Access Count**: %d\n", mem.AccessCount))
 	sb.WriteString(fmt.Sprintf("**Version**: %d\n\n", mem.Version))
 
-	if mem.DeletedAt.Valid {
+	if mem.Archived {
 		sb.WriteString("⚠️ **Status**: Archived\n\n")
 	}
-	if mem.SupersededBy != nil {
-		sb.WriteString(fmt.Sprintf("⚠️ **Superseded by**: `%s`\n\n", *mem.SupersededBy))
+	if mem.SupersededBy != "" {
+		sb.WriteString(fmt.Sprintf("⚠️ **Superseded by**: `%s`\n\n", mem.SupersededBy))
 	}
 
 	sb.WriteString("## Commit History\n\n")
 
-	if len(commits) == 0 {
+	if len(history.Commits) == 0 {
 		sb.WriteString("No commits found in the specified time range.\n")
-		return sb.String(), nil
+		return sb.String()
 	}
 
-	for i, commit := range commits {
+	for i, commit := range history.Commits {
 		sb.WriteString(fmt.Sprintf("### %d. %s\n", i+1, commit.Timestamp.Format("2006-01-02 15:04")))
 		sb.WriteString(fmt.Sprintf("**Commit**: `%s`\n", commit.Hash[:8]))
 		sb.WriteString(fmt.Sprintf("**Message**: %s\n", commit.Message))
 
-		if showChanges && i < len(commits)-1 {
-			// Show diff between this commit and the next (older) one
-			diff, err := gitRepo.GetFileDiff(mem.FilePath, commits[i+1].Hash, commit.Hash)
-			if err == nil && diff != nil {
-				sb.WriteString("\n**Changes**:\n```diff\n")
-				for _, hunk := range diff.Hunks {
-					sb.WriteString(hunk + "\n")
-				}
-				sb.WriteString("```\n")
+		if commit.Changes != nil {
+			sb.WriteString("\n**Changes**:\n```diff\n")
+			for _, hunk := range commit.Changes {
+				sb.WriteString(hunk + "\n")
 			}
+			sb.WriteString("```\n")
 		}
 		sb.WriteString("\n")
 	}
 
-	return sb.String(), nil
+	return sb.String()
 }
 
 // getTopicHistoryV2 returns history for memories matching a topic (v2 architecture)
-func getTopicHistoryV2(ctx *ToolContext, gitRepo *git.Repository, topic string, since time.Time, showChanges bool, limit int, repoPath string) (string, error) {
+func getTopicHistoryV2(ctx *ToolContext, gitRepo *git.Repository, topic string, since time.Time) (*HistoryOutput, error) {
 	// Find memories matching topic from UserDB
 	var memories []database.UserMemory
 	ctx.UserDB.Where("title LIKE ?", "%"+topic+"%").Find(&memories)
 
-	if len(memories) == 0 {
-		return fmt.Sprintf("No memories found matching topic: '%s'", topic), nil
+	history := &HistoryOutput{Scope: "topic", Topic: topic}
+	for i := range memories {
+		mh := MemoryHistory{Memory: newMemoryInfo(&memories[i]), Commits: []HistoryCommit{}}
+
+		// Get recent commits for this memory
+		commits, err := gitRepo.SearchCommits("", memories[i].FilePath, since, time.Time{}, 3)
+		if err == nil {
+			for _, commit := range commits {
+				mh.Commits = append(mh.Commits, newHistoryCommit(commit))
+			}
+		}
+		history.Memories = append(history.Memories, mh)
+	}
+
+	return history, nil
+}
+
+// formatTopicHistory renders the history of memories matching a topic
+func formatTopicHistory(history *HistoryOutput) string {
+	if len(history.Memories) == 0 {
+		return fmt.Sprintf("No memories found matching topic: '%s'", history.Topic)
 	}
 
 	var sb strings.Builder
-	sb.WriteString(fmt.Sprintf("# History for topic: '%s'\n\n", topic))
-	sb.WriteString(fmt.Sprintf("Found %d related memories:\n\n", len(memories)))
+	sb.WriteString(fmt.Sprintf("# History for topic: '%s'\n\n", history.Topic))
+	sb.WriteString(fmt.Sprintf("Found %d related memories:\n\n", len(history.Memories)))
 
-	for _, mem := range memories {
+	for _, mh := range history.Memories {
+		mem := mh.Memory
 		sb.WriteString(fmt.Sprintf("## %s (`%s`)\n", mem.Title, mem.Slug))
-		sb.WriteString(fmt.Sprintf("Created: %s | Updated: %s | Version: %d\n\n", 
+		sb.WriteString(fmt.Sprintf("Created: %s | Updated: %s | Version: %d\n\n",
 			mem.CreatedAt.Format("2006-01-02"),
 			mem.UpdatedAt.Format("2006-01-02"),
 			mem.Version))
 
-		// Get recent commits for this memory
-		commits, err := gitRepo.SearchCommits("", mem.FilePath, since, time.Time{}, 3)
-		if err != nil || len(commits) == 0 {
+		if len(mh.Commits) == 0 {
 			sb.WriteString("No recent changes.\n\n")
 			continue
 		}
 
 		sb.WriteString("Recent changes:\n")
-		for _, commit := range commits {
-			sb.WriteString(fmt.Sprintf("- %s: %s\n", 
+		for _, commit := range mh.Commits {
+			sb.WriteString(fmt.Sprintf("- %s: %s\n",
 				commit.Timestamp.Format("2006-01-02"),
 				commit.Message))
 		}
 		sb.WriteString("\n")
 	}
 
-	return sb.String(), nil
+	return sb.String()
 }
 
 // getRecentActivity returns recent activity across all memories
-func getRecentActivity(gitRepo *git.Repository, since time.Time, limit int, repoPath string) (string, error) {
+func getRecentActivity(gitRepo *git.Repository, since time.Time, limit int) (*HistoryOutput, error) {
 	// Get recent commits
 	commits, err := gitRepo.SearchCommits("", "", since, time.Time{}, limit)
 	if err != nil {
-		return "", fmt.Errorf("failed to get recent activity: %v", err)
+		return nil, fmt.Errorf("failed to get recent activity: %v", err)
 	}
 
+	history := &HistoryOutput{Scope: "recent"}
+	for _, commit := range commits {
+		history.Commits = append(history.Commits, newHistoryCommit(commit))
+	}
+	return history, nil
+}
+
+// formatRecentActivity renders recent activity across all memories
+func formatRecentActivity(history *HistoryOutput) string {
 	var sb strings.Builder
 	sb.WriteString("# Recent Activity\n\n")
 
-	if len(commits) == 0 {
+	if len(history.Commits) == 0 {
 		sb.WriteString("No recent activity found.\n")
-		return sb.String(), nil
+		return sb.String()
 	}
 
-	for _, commit := range commits {
+	for _, commit := range history.Commits {
 		sb.WriteString(fmt.Sprintf("## %s\n", commit.Timestamp.Format("2006-01-02 15:04")))
 		sb.WriteString(fmt.Sprintf("**%s**\n", commit.Message))
 		if len(commit.Files) > 0 {
@@ -223,7 +315,7 @@ func getRecentActivity(gitRepo *git.Repository, since time.Time, limit int, repo
 		sb.WriteString("\n")
 	}
 
-	return sb.String(), nil
+	return sb.String()
 }
 
 // parseSinceTime parses a since string into a time.Time
@@ -0,0 +1,318 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package tools
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/tejzpr/medha-mcp/internal/database"
+	"github.com/tejzpr/medha-mcp/internal/locking"
+	"gorm.io/gorm"
+)
+
+// Lock timing
+const (
+	writeLockWait = 2 * time.Second       // How long a write waits for another agent's write to finish
+	writeLockPoll = 50 * time.Millisecond // How often a waiting write retries
+	maxLockTTL    = time.Hour             // Longest lock medha_lock will take
+)
+
+// Lock actions for medha_lock
+const (
+	LockActionAcquire = "acquire"
+	LockActionExtend  = "extend"
+	LockActionRelease = "release"
+	LockActionStatus  = "status"
+)
+
+// stdioSessionID is the ID mcp-go gives every stdio session
+const stdioSessionID = "stdio"
+
+// LockOutput is the structured output of medha_lock
+type LockOutput struct {
+	Slug      string     `json:"slug"`
+	Action    string     `json:"action"` // acquire, extend, release or status
+	Locked    bool       `json:"locked"`
+	LockedBy  string     `json:"locked_by,omitempty"`
+	Mine      bool       `json:"mine"` // Whether the calling agent holds the lock
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	Message   string     `json:"message"`
+}
+
+// NewLockTool creates the medha_lock tool definition
+func NewLockTool() mcp.Tool {
+	return mcp.NewTool("medha_lock",
+		mcp.WithDescription("Lock a memory for a long edit so other agents cannot change it meanwhile. Every write already takes a short lock; use this when you will read, think and then write. Locks expire after their TTL unless extended."),
+		mcp.WithString("slug",
+			mcp.Required(),
+			mcp.Description("Memory to lock"),
+		),
+		mcp.WithString("action",
+			mcp.Description("acquire (default), extend, release or status"),
+			mcp.Enum(LockActionAcquire, LockActionExtend, LockActionRelease, LockActionStatus),
+		),
+		mcp.WithString("ttl",
+			mcp.Description("How long the lock lasts for acquire and extend, e.g. '10m'. Default: 5m, at most 1h"),
+		),
+		withSpace(),
+		withFormat(),
+		mcp.WithOutputSchema[LockOutput](),
+	)
+}
+
+// LockHandler handles the medha_lock tool
+func LockHandler(ctx *ToolContext, userID uint) func(context.Context, mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	return func(c context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		slug, err := request.RequireString("slug")
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		action := request.GetString("action", LockActionAcquire)
+		ttl := locking.DefaultLockTTL
+		if raw := request.GetString("ttl", ""); raw != "" {
+			ttl, err = time.ParseDuration(raw)
+			if err != nil || ttl <= 0 || ttl > maxLockTTL {
+				return mcp.NewToolResultError(fmt.Sprintf("invalid ttl '%s': use a duration up to %s, e.g. '10m'", raw, maxLockTTL)), nil
+			}
+		}
+		format, err := requestFormat(request)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+
+		// Work in the requested team space, if any
+		ctx, err := ctx.forSpace(userID, request, database.SpaceRoleWriter)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+
+		// Validate UserDB is available
+		if ctx.UserDB == nil {
+			return mcp.NewToolResultError("per-user database not available"), nil
+		}
+
+		// Archived memories can be locked too, ahead of a restore
+		var mem database.UserMemory
+		if err := ctx.UserDB.Unscoped().Where(querySlugEquals, slug).First(&mem).Error; err != nil {
+			if err == gorm.ErrRecordNotFound {
+				return mcp.NewToolResultError(fmt.Sprintf("memory not found: %s", slug)), nil
+			}
+			return mcp.NewToolResultError(fmt.Sprintf("database error: %v", err)), nil
+		}
+
+		agent := agentID(c)
+		locker := ctx.locker().WithTTL(ttl)
+		current, err := locker.Current(slug)
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("failed to read lock: %v", err)), nil
+		}
+		heldByOther := current != nil && current.LockedBy != agent
+
+		var message string
+		switch action {
+		case LockActionAcquire, LockActionExtend:
+			if heldByOther {
+				return mcp.NewToolResultError(lockedMessage(slug, current)), nil
+			}
+			if current == nil && action == LockActionExtend {
+				return mcp.NewToolResultError(fmt.Sprintf("you do not hold a lock on '%s'; acquire it first", slug)), nil
+			}
+			if current == nil {
+				acquired, err := locker.Acquire(slug, agent)
+				if err != nil {
+					return mcp.NewToolResultError(fmt.Sprintf("failed to acquire lock: %v", err)), nil
+				}
+				if !acquired {
+					return mcp.NewToolResultError(fmt.Sprintf("memory '%s' was locked by another agent; try again", slug)), nil
+				}
+				message = fmt.Sprintf("Locked '%s' for %s", slug, ttl)
+			} else {
+				if err := locker.Extend(slug, agent); err != nil {
+					return mcp.NewToolResultError(fmt.Sprintf("failed to extend lock: %v", err)), nil
+				}
+				message = fmt.Sprintf("Extended lock on '%s' by %s", slug, ttl)
+			}
+		case LockActionRelease:
+			if heldByOther {
+				return mcp.NewToolResultError(fmt.Sprintf("%s; only its holder can release it", lockedMessage(slug, current))), nil
+			}
+			if current == nil {
+				message = fmt.Sprintf("'%s' is not locked", slug)
+				break
+			}
+			if err := locker.Release(slug, agent); err != nil {
+				return mcp.NewToolResultError(fmt.Sprintf("failed to release lock: %v", err)), nil
+			}
+			message = fmt.Sprintf("Released lock on '%s'", slug)
+		case LockActionStatus:
+			switch {
+			case current == nil:
+				message = fmt.Sprintf("'%s' is not locked", slug)
+			case heldByOther:
+				message = lockedMessage(slug, current)
+			default:
+				message = fmt.Sprintf("You hold the lock on '%s' until %s", slug, current.ExpiresAt.Format(time.RFC3339))
+			}
+		default:
+			return mcp.NewToolResultError(fmt.Sprintf("invalid action '%s': use acquire, extend, release or status", action)), nil
+		}
+
+		out := LockOutput{Slug: slug, Action: action, Message: message}
+		if lock, err := locker.Current(slug); err == nil && lock != nil {
+			out.Locked = true
+			out.LockedBy = lock.LockedBy
+			out.Mine = lock.LockedBy == agent
+			out.ExpiresAt = &lock.ExpiresAt
+		}
+		return formatResult(format, out, message), nil
+	}
+}
+
+// agentID identifies the agent behind a tool call: the MCP client's name and
+// session, or this process when the call has no session
+func agentID(c context.Context) string {
+	session := server.ClientSessionFromContext(c)
+	if session == nil {
+		return fmt.Sprintf("medha@local:%d", os.Getpid())
+	}
+
+	name := "mcp-client"
+	if withInfo, ok := session.(server.SessionWithClientInfo); ok && withInfo.GetClientInfo().Name != "" {
+		name = withInfo.GetClientInfo().Name
+	}
+	id := session.SessionID()
+	if id == stdioSessionID {
+		// Every stdio session shares one ID; tell processes apart
+		id = fmt.Sprintf("stdio:%d", os.Getpid())
+	}
+	return name + "@" + id
+}
+
+// locker returns a locker over the current per-user database
+func (tc *ToolContext) locker() *locking.Locker {
+	return locking.NewLocker(tc.UserDB)
+}
+
+// writeMutex serializes the writes to one memory made by this process
+type writeMutex struct {
+	held chan struct{} // Holds a token while a write runs
+	refs int           // Writes holding or waiting for it, guarded by writeMutexesMu
+}
+
+// writeMutexes holds a writeMutex per repository and slug while it is in use
+var (
+	writeMutexesMu sync.Mutex
+	writeMutexes   = make(map[string]*writeMutex)
+)
+
+// lockWrite takes the process-wide write mutex for a memory, waiting until
+// the deadline, and returns the function that releases it. The memory lock
+// alone lets one agent run two writes to a memory at once, since both hold it.
+func lockWrite(repoPath, slug string, deadline time.Time) (unlock func(), ok bool) {
+	key := filepath.Clean(repoPath) + "\x00" + slug
+
+	writeMutexesMu.Lock()
+	m, found := writeMutexes[key]
+	if !found {
+		m = &writeMutex{held: make(chan struct{}, 1)}
+		writeMutexes[key] = m
+	}
+	m.refs++
+	writeMutexesMu.Unlock()
+
+	done := func() {
+		writeMutexesMu.Lock()
+		m.refs--
+		if m.refs == 0 {
+			delete(writeMutexes, key)
+		}
+		writeMutexesMu.Unlock()
+	}
+
+	timer := time.NewTimer(time.Until(deadline))
+	defer timer.Stop()
+	select {
+	case m.held <- struct{}{}:
+		return func() {
+			<-m.held
+			done()
+		}, true
+	case <-timer.C:
+		done()
+		return nil, false
+	}
+}
+
+// lockMemory takes the write lock on a memory for the calling agent and
+// returns its release. It waits briefly for another write to finish.
+// A lock the agent already holds through medha_lock is extended and kept.
+func (tc *ToolContext) lockMemory(c context.Context, slug string) (func(), error) {
+	agent := agentID(c)
+	locker := tc.locker()
+
+	deadline := time.Now().Add(writeLockWait)
+	unlock, ok := lockWrite(tc.RepoPath, slug, deadline)
+	if !ok {
+		return nil, &locking.LockError{Slug: slug, LockedBy: agent, Message: fmt.Sprintf("memory '%s' is being written; try again", slug)}
+	}
+
+	current, err := locker.Current(slug)
+	if err != nil {
+		unlock()
+		return nil, fmt.Errorf("failed to read lock on '%s': %w", slug, err)
+	}
+	if current != nil && current.LockedBy == agent {
+		// Keep the lock live for the write without cutting a longer one short.
+		// It may lapse before the extend; take it afresh then.
+		if time.Until(current.ExpiresAt) >= locking.DefaultLockTTL || locker.Extend(slug, agent) == nil {
+			return unlock, nil
+		}
+	}
+
+	for {
+		acquired, err := locker.Acquire(slug, agent)
+		if err != nil {
+			unlock()
+			return nil, fmt.Errorf("failed to lock '%s': %w", slug, err)
+		}
+		if acquired {
+			return func() {
+				if err := locker.Release(slug, agent); err != nil {
+					fmt.Fprintf(os.Stderr, "Warning: failed to release lock on %s: %v\n", slug, err)
+				}
+				unlock()
+			}, nil
+		}
+		if time.Now().After(deadline) {
+			unlock()
+			current, _ := locker.Current(slug)
+			return nil, &locking.LockError{Slug: slug, LockedBy: lockHolder(current), Message: lockedMessage(slug, current)}
+		}
+		time.Sleep(writeLockPoll)
+	}
+}
+
+// lockedMessage explains that another agent holds a memory's lock
+func lockedMessage(slug string, lock *locking.MemoryLock) string {
+	if lock == nil {
+		return fmt.Sprintf("memory '%s' is locked by another agent; try again", slug)
+	}
+	return fmt.Sprintf("memory '%s' is locked by %s until %s; try again later", slug, lock.LockedBy, lock.ExpiresAt.Format(time.RFC3339))
+}
+
+// lockHolder returns who holds a lock, if anyone
+func lockHolder(lock *locking.MemoryLock) string {
+	if lock == nil {
+		return ""
+	}
+	return lock.LockedBy
+}
@@ -0,0 +1,112 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package tools
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/tejzpr/medha-mcp/internal/database"
+)
+
+// Tool output formats
+const (
+	FormatText = "text" // Markdown prose for LLMs (default)
+	FormatJSON = "json" // MCP structured content matching the tool's output schema
+)
+
+// withFormat adds the format parameter shared by every tool
+func withFormat() mcp.ToolOption {
+	return mcp.WithString("format",
+		mcp.Description("Output format: 'text' (default) or 'json' for structured content matching the tool's output schema"),
+		mcp.Enum(FormatText, FormatJSON),
+	)
+}
+
+// requestFormat returns the requested output format
+func requestFormat(request mcp.CallToolRequest) (string, error) {
+	switch format := request.GetString("format", FormatText); format {
+	case FormatText, FormatJSON:
+		return format, nil
+	default:
+		return "", fmt.Errorf("invalid format '%s': use 'text' or 'json'", format)
+	}
+}
+
+// formatResult returns structured content matching the tool's output schema,
+// with the text block holding its JSON for JSON requests and text otherwise
+func formatResult(format string, structured interface{}, text string) *mcp.CallToolResult {
+	if format == FormatJSON {
+		return mcp.NewToolResultStructuredOnly(structured)
+	}
+	return mcp.NewToolResultStructured(structured, text)
+}
+
+// resultText returns the text of a tool result
+func resultText(result *mcp.CallToolResult) string {
+	for _, content := range result.Content {
+		if text, ok := content.(mcp.TextContent); ok {
+			return text.Text
+		}
+	}
+	return ""
+}
+
+// MemoryInfo describes a memory in structured output
+type MemoryInfo struct {
+	Slug           string     `json:"slug"`
+	Title          string     `json:"title"`
+	FilePath       string     `json:"file_path"`
+	Version        int64      `json:"version"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+	LastAccessedAt *time.Time `json:"last_accessed_at,omitempty"`
+	AccessCount    int        `json:"access_count"`
+	SupersededBy   string     `json:"superseded_by,omitempty"`
+	Archived       bool       `json:"archived,omitempty"`
+}
+
+// newMemoryInfo describes a per-user memory row
+func newMemoryInfo(mem *database.UserMemory) MemoryInfo {
+	info := MemoryInfo{
+		Slug:        mem.Slug,
+		Title:       mem.Title,
+		FilePath:    mem.FilePath,
+		Version:     mem.Version,
+		CreatedAt:   mem.CreatedAt,
+		UpdatedAt:   mem.UpdatedAt,
+		AccessCount: mem.AccessCount,
+		Archived:    mem.DeletedAt.Valid,
+	}
+	if !mem.LastAccessedAt.IsZero() {
+		accessed := mem.LastAccessedAt
+		info.LastAccessedAt = &accessed
+	}
+	if mem.SupersededBy != nil {
+		info.SupersededBy = *mem.SupersededBy
+	}
+	return info
+}
+
+// MemoryChangeOutput is the structured output of tools that write a memory
+type MemoryChangeOutput struct {
+	Action   string           `json:"action"` // created, updated, archived, restored or conflict
+	Message  string           `json:"message"`
+	Memory   *MemoryInfo      `json:"memory,omitempty"`
+	Conflict *VersionConflict `json:"conflict,omitempty"` // Why an expected_version update was rejected
+}
+
+// memoryChangeResult reports a successful write in the requested format,
+// describing the memory as it is now stored
+func (tc *ToolContext) memoryChangeResult(format, action, slug, message string) *mcp.CallToolResult {
+	out := MemoryChangeOutput{Action: action, Message: message}
+	var mem database.UserMemory
+	if err := tc.UserDB.Unscoped().Where(querySlugEquals, slug).First(&mem).Error; err == nil {
+		info := newMemoryInfo(&mem)
+		out.Memory = &info
+	}
+	return formatResult(format, out, message)
+}
@@ -0,0 +1,254 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package tools
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// Prompt argument defaults
+const (
+	defaultStandupSince   = "1d"
+	defaultStaleOlderThan = "90d"
+	defaultStaleLimit     = 20
+	summarizeRecallLimit  = 10
+	decisionRecallLimit   = 5
+	standupHistoryLimit   = 50
+)
+
+// toolHandler is the signature shared by every tool handler
+type toolHandler func(context.Context, mcp.CallToolRequest) (*mcp.CallToolResult, error)
+
+// NewSummarizePrompt creates the summarize prompt definition
+func NewSummarizePrompt() mcp.Prompt {
+	return mcp.NewPrompt("summarize",
+		mcp.WithPromptDescription("Summarize what I know about a topic from stored memories and how that knowledge evolved"),
+		mcp.WithArgument("topic",
+			mcp.ArgumentDescription("What to summarize, e.g. 'authentication' or 'billing service'"),
+			mcp.RequiredArgument(),
+		),
+	)
+}
+
+// NewRecordDecisionPrompt creates the record_decision prompt definition
+func NewRecordDecisionPrompt() mcp.Prompt {
+	return mcp.NewPrompt("record_decision",
+		mcp.WithPromptDescription("Record an architecture decision (ADR), linked to related memories and superseding earlier decisions"),
+		mcp.WithArgument("title",
+			mcp.ArgumentDescription("The decision, e.g. 'Use PostgreSQL for the event store'"),
+			mcp.RequiredArgument(),
+		),
+		mcp.WithArgument("context",
+			mcp.ArgumentDescription("Background, constraints and alternatives considered"),
+		),
+	)
+}
+
+// NewStandupPrompt creates the standup prompt definition
+func NewStandupPrompt() mcp.Prompt {
+	return mcp.NewPrompt("standup",
+		mcp.WithPromptDescription("Draft a daily standup from recent memory activity"),
+		mcp.WithArgument("since",
+			mcp.ArgumentDescription("How far back to look, as medha_history's since (e.g. '1d', '3d', '1w'). Default: 1d"),
+		),
+	)
+}
+
+// NewReviewStalePrompt creates the review_stale prompt definition
+func NewReviewStalePrompt() mcp.Prompt {
+	return mcp.NewPrompt("review_stale",
+		mcp.WithPromptDescription("Review memories that have not been updated for a while and decide what to keep, update, supersede or archive"),
+		mcp.WithArgument("older_than",
+			mcp.ArgumentDescription("Memories not updated within this age or since this date (e.g. '90d', '6m', '2025-01-01'). Default: 90d"),
+		),
+		mcp.WithArgument("limit",
+			mcp.ArgumentDescription("Maximum memories to review. Default: 20"),
+		),
+	)
+}
+
+// SummarizePromptHandler handles the summarize prompt
+func SummarizePromptHandler(ctx *ToolContext, userID uint) func(context.Context, mcp.GetPromptRequest) (*mcp.GetPromptResult, error) {
+	return func(c context.Context, request mcp.GetPromptRequest) (*mcp.GetPromptResult, error) {
+		topic := strings.TrimSpace(request.Params.Arguments["topic"])
+		if topic == "" {
+			return nil, fmt.Errorf("topic is required")
+		}
+
+		memories, err := promptToolText(c, RecallHandler(ctx, userID), map[string]interface{}{
+			"topic": topic,
+			"limit": float64(summarizeRecallLimit),
+		})
+		if err != nil {
+			return nil, err
+		}
+		timeline, err := promptToolText(c, HistoryHandler(ctx, userID), map[string]interface{}{"topic": topic})
+		if err != nil {
+			return nil, err
+		}
+
+		text := fmt.Sprintf(`Summarize what I know about "%s" using only the memories below.
+
+- Lead with the current state of knowledge, then key decisions and open questions.
+- Prefer memories that are not superseded; mention superseded ones only to explain what changed.
+- Cite memory slugs in backticks so I can open them.
+- If the memories do not cover the topic, say so rather than guessing.
+
+## Memories (medha_recall)
+
+%s
+
+## Timeline (medha_history)
+
+%s`, topic, memories, timeline)
+
+		return mcp.NewGetPromptResult(fmt.Sprintf("Summary of what is known about %s", topic),
+			[]mcp.PromptMessage{mcp.NewPromptMessage(mcp.RoleUser, mcp.NewTextContent(text))}), nil
+	}
+}
+
+// RecordDecisionPromptHandler handles the record_decision prompt
+func RecordDecisionPromptHandler(ctx *ToolContext, userID uint) func(context.Context, mcp.GetPromptRequest) (*mcp.GetPromptResult, error) {
+	return func(c context.Context, request mcp.GetPromptRequest) (*mcp.GetPromptResult, error) {
+		title := strings.TrimSpace(request.Params.Arguments["title"])
+		if title == "" {
+			return nil, fmt.Errorf("title is required")
+		}
+		background := strings.TrimSpace(request.Params.Arguments["context"])
+		if background == "" {
+			background = "(not given - ask me for the context and the alternatives considered before storing)"
+		}
+
+		related, err := promptToolText(c, RecallHandler(ctx, userID), map[string]interface{}{
+			"topic": title,
+			"limit": float64(decisionRecallLimit),
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		text := fmt.Sprintf(`Record this architecture decision as an ADR memory.
+
+**Decision**: %s
+**Context**: %s
+
+Write the ADR in markdown with the sections Status, Context, Decision, Consequences and Alternatives Considered, then store it with medha_remember:
+
+- title: "ADR: %s"
+- tags: ["adr", "decision"] plus the main subject area
+- connections: link related memories below ("references", or "part_of" for the project it belongs to)
+- replaces: the slug of an earlier decision this one overturns, if any
+
+Show me the draft before storing it.
+
+## Related memories (medha_recall)
+
+%s`, title, background, title, related)
+
+		return mcp.NewGetPromptResult(fmt.Sprintf("Record the decision: %s", title),
+			[]mcp.PromptMessage{mcp.NewPromptMessage(mcp.RoleUser, mcp.NewTextContent(text))}), nil
+	}
+}
+
+// StandupPromptHandler handles the standup prompt
+func StandupPromptHandler(ctx *ToolContext, userID uint) func(context.Context, mcp.GetPromptRequest) (*mcp.GetPromptResult, error) {
+	return func(c context.Context, request mcp.GetPromptRequest) (*mcp.GetPromptResult, error) {
+		since := strings.TrimSpace(request.Params.Arguments["since"])
+		if since == "" {
+			since = defaultStandupSince
+		}
+
+		activity, err := promptToolText(c, HistoryHandler(ctx, userID), map[string]interface{}{
+			"since": since,
+			"limit": float64(standupHistoryLimit),
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		text := fmt.Sprintf(`Draft my daily standup from the memory activity below (since %s).
+
+Use three short sections:
+- **Done**: what I learned, decided or recorded
+- **Next**: follow-ups implied by that work
+- **Blockers**: open questions or conflicts, if any
+
+Group related commits, cite memory slugs in backticks and use medha_recall for details a commit message leaves out. If there was no activity, say so.
+
+## Recent activity (medha_history)
+
+%s`, since, activity)
+
+		return mcp.NewGetPromptResult(fmt.Sprintf("Standup from activity since %s", since),
+			[]mcp.PromptMessage{mcp.NewPromptMessage(mcp.RoleUser, mcp.NewTextContent(text))}), nil
+	}
+}
+
+// ReviewStalePromptHandler handles the review_stale prompt
+func ReviewStalePromptHandler(ctx *ToolContext, userID uint) func(context.Context, mcp.GetPromptRequest) (*mcp.GetPromptResult, error) {
+	return func(c context.Context, request mcp.GetPromptRequest) (*mcp.GetPromptResult, error) {
+		olderThan := strings.TrimSpace(request.Params.Arguments["older_than"])
+		if olderThan == "" {
+			olderThan = defaultStaleOlderThan
+		}
+		if _, _, err := parseFilterTime(olderThan, time.Now()); err != nil {
+			return nil, fmt.Errorf("invalid older_than: %v", err)
+		}
+
+		limit := defaultStaleLimit
+		if raw := strings.TrimSpace(request.Params.Arguments["limit"]); raw != "" {
+			n, err := strconv.Atoi(raw)
+			if err != nil || n <= 0 {
+				return nil, fmt.Errorf("invalid limit %q: use a positive number", raw)
+			}
+			limit = n
+		}
+
+		stale, err := promptToolText(c, RecallHandler(ctx, userID), map[string]interface{}{
+			"filter": "updated:<" + olderThan,
+			"limit":  float64(limit),
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		text := fmt.Sprintf(`Review these memories, none of which has been updated recently (filter: updated:<%s).
+
+For each one, recommend exactly one action with a one-line reason:
+- **Keep**: still accurate
+- **Update**: mostly right; say what to change (medha_remember with its slug)
+- **Supersede**: replaced by newer knowledge (medha_remember with replaces)
+- **Archive**: no longer relevant (medha_forget)
+
+Present the recommendations as a table and wait for my confirmation before changing anything.
+
+## Stale memories (medha_recall)
+
+%s`, olderThan, stale)
+
+		return mcp.NewGetPromptResult(fmt.Sprintf("Review of memories last updated before %s", olderThan),
+			[]mcp.PromptMessage{mcp.NewPromptMessage(mcp.RoleUser, mcp.NewTextContent(text))}), nil
+	}
+}
+
+// promptToolText runs a tool handler for a prompt and returns its text output
+func promptToolText(c context.Context, handler toolHandler, args map[string]interface{}) (string, error) {
+	request := mcp.CallToolRequest{}
+	request.Params.Arguments = args
+	result, err := handler(c, request)
+	if err != nil {
+		return "", err
+	}
+	if result.IsError {
+		return "", fmt.Errorf("%s", resultText(result))
+	}
+	return resultText(result), nil
+}
@@ -7,8 +7,9 @@ package tools
 import (
 	"context"
 	"fmt"
+	"math"
 	"os"
-	"sort"
+	"path/filepath"
 	"strings"
 	"time"
 
@@ -17,6 +18,8 @@ import (
 	"github.com/tejzpr/medha-mcp/internal/embeddings"
 	"github.com/tejzpr/medha-mcp/internal/git"
 	"github.com/tejzpr/medha-mcp/internal/memory"
+	"github.com/tejzpr/medha-mcp/internal/ranking"
+	"gorm.io/gorm"
 )
 
 // loadMemoryContent reads and parses a memory file
@@ -37,7 +40,30 @@ type RecallResult struct {
 	Memory      *database.UserMemory
 	Content     *memory.Memory
 	Score       float64
-	MatchSource string // "title", "content", "tag", "grep", "association", "semantic"
+	MatchSource string          // "list", "exact", "grep", or the ranking signals that matched, e.g. "keyword+semantic"
+	Ranking     *ranking.Result // How a topic search ranked the memory; nil for other searches
+	Space       string          // Space the memory is in, when the recall named a space
+
+	source *ToolContext // Context of the repository the memory is in
+}
+
+// RecallOutput is the structured output of medha_recall
+type RecallOutput struct {
+	Count      int          `json:"count"`
+	Results    []RecallItem `json:"results"`
+	NextCursor string       `json:"next_cursor,omitempty"` // Pass as cursor for the next page
+}
+
+// RecallItem is one memory in RecallOutput
+type RecallItem struct {
+	MemoryInfo
+	Space       string              `json:"space,omitempty"`
+	Score       float64             `json:"score"`
+	MatchSource string              `json:"match_source"`
+	Tags        []string            `json:"tags,omitempty"`
+	Annotations []memory.Annotation `json:"annotations,omitempty"`
+	Content     string              `json:"content"`
+	Ranking     *ranking.Result     `json:"ranking,omitempty"`
 }
 
 // NewRecallTool creates the medha_recall tool definition
@@ -45,7 +71,7 @@ func NewRecallTool() mcp.Tool {
 	return mcp.NewTool("medha_recall",
 		mcp.WithDescription("Find and retrieve information from memory. This is the primary tool for getting information - use it whenever you need to know something. It searches everything: titles, content, tags, associations. Returns full content, ranked by relevance."),
 		mcp.WithString("topic",
-			mcp.Description("What you want to know about. Can be a question, keywords, or topic. Examples: 'authentication approach', 'what did we decide about caching', 'TODO items'"),
+			mcp.Description("What you want to know about. Can be a question, keywords, or topic. Examples: 'authentication approach', 'what did we decide about caching', 'TODO items'. Also supports \"exact phrases\", prefix* matches and AND/OR/NOT with parentheses"),
 		),
 		mcp.WithString("exact",
 			mcp.Description("Search for exact text (when topic search doesn't find something you know exists)"),
@@ -56,6 +82,9 @@ func NewRecallTool() mcp.Tool {
 		mcp.WithString("path",
 			mcp.Description("Limit to a folder. Example: 'projects/alpha'"),
 		),
+		mcp.WithString("filter",
+			mcp.Description("Structured filter, alone or with topic/exact. Fields: tag:NAME (tag:auth*), created:/updated:/accessed: with >, >=, <, <= or A..B on YYYY-MM-DD, today, yesterday or ages like 7d/2w/1m; accesses:>5; is:superseded, is:archived; rel:TYPE->SLUG (links to SLUG), rel:TYPE<-SLUG (links from SLUG); path:TEXT. Combine with AND (implicit), OR, NOT/-, parentheses. Example: 'tag:auth updated:>2026-01-01 -tag:draft rel:part_of->project-x'"),
+		),
 		mcp.WithBoolean("include_superseded",
 			mcp.Description("Include memories that have been superseded (default: false)"),
 		),
@@ -63,8 +92,23 @@ func NewRecallTool() mcp.Tool {
 			mcp.Description("Include archived memories (default: false)"),
 		),
 		mcp.WithNumber("limit",
-			mcp.Description("Max results. Default: 10"),
+			mcp.Description("Max results per page. Default: 10"),
 		),
+		mcp.WithString("order",
+			mcp.Description("Result order: 'relevance' (default for topic/exact), 'updated' (default for listing), 'created', 'accessed' or 'title'"),
+			mcp.Enum(RecallOrderRelevance, RecallOrderUpdated, RecallOrderCreated, RecallOrderAccessed, RecallOrderTitle),
+		),
+		mcp.WithString("cursor",
+			mcp.Description("next_cursor from a previous call, to get the next page. Repeat the other parameters unchanged"),
+		),
+		mcp.WithBoolean("explain",
+			mcp.Description("Show how each signal (keyword, semantic, tag, association) contributed to a topic result's rank"),
+		),
+		mcp.WithString("space",
+			mcp.Description("Team space to search, by name, or 'all' for your own memories and every team space you belong to, each result labelled with its space. Omit for your own memories"),
+		),
+		withFormat(),
+		mcp.WithOutputSchema[RecallOutput](),
 	)
 }
 
@@ -76,387 +120,517 @@ func RecallHandler(ctx *ToolContext, userID uint) func(context.Context, mcp.Call
 		exact := request.GetString("exact", "")
 		listAll := request.GetBool("list_all", false)
 		pathFilter := request.GetString("path", "")
+		filterExpr := request.GetString("filter", "")
 		includeSuperseded := request.GetBool("include_superseded", false)
 		includeArchived := request.GetBool("include_archived", false)
 		limit := int(request.GetFloat("limit", 10.0))
+		explain := request.GetBool("explain", false)
+		orderParam := request.GetString("order", "")
+		cursorToken := request.GetString("cursor", "")
+		space := request.GetString("space", "")
+		format, err := requestFormat(request)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+
+		// The repositories to search: the user's own, a space or both
+		sources, err := ctx.recallSources(userID, request)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
 
 		// Validate UserDB is available
-		if ctx.UserDB == nil {
-			return mcp.NewToolResultError("per-user database not available"), nil
+		for _, source := range sources {
+			if source.UserDB == nil {
+				return mcp.NewToolResultError("per-user database not available"), nil
+			}
 		}
 
-		// Get user's repo from system DB
-		var repo database.MedhaGitRepo
-		if err := ctx.DB.Where("user_id = ?", userID).First(&repo).Error; err != nil {
-			return mcp.NewToolResultError(fmt.Sprintf("failed to get user repository: %v", err)), nil
+		filter, err := parseMemoryFilter(filterExpr, time.Now())
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("invalid filter: %v", err)), nil
+		}
+		scope := recallScope{
+			includeSuperseded: includeSuperseded,
+			includeArchived:   includeArchived,
+			filter:            filter,
+		}
+		if filter != nil {
+			// Asking for superseded or archived memories implies including them
+			scope.includeSuperseded = scope.includeSuperseded || filter.superseded
+			scope.includeArchived = scope.includeArchived || filter.archived
 		}
 
-		var results []RecallResult
+		if limit <= 0 {
+			limit = 10
+		}
 
-		if listAll {
-			// List all memories
-			results = listAllMemoriesV2(ctx, pathFilter, includeSuperseded, includeArchived)
-		} else if exact != "" {
-			// Exact text search using git grep
-			results = searchExactV2(ctx, exact, pathFilter, repo.RepoPath)
-		} else if topic != "" {
-			// Topic-based search (combines multiple strategies)
-			results = searchByTopicV2(ctx, topic, pathFilter, includeSuperseded, repo.RepoPath)
-		} else {
-			return mcp.NewToolResultError("please provide 'topic', 'exact', or set 'list_all' to true"), nil
+		// Listings page in the database; searches rank first, then page
+		listing := listAll || (exact == "" && topic == "" && filter != nil)
+		order, err := parseRecallOrder(orderParam, !listing)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		fingerprint := recallFingerprint(listing, topic, exact, filterExpr, pathFilter,
+			scope.includeSuperseded, scope.includeArchived, order, space)
+		after, err := decodeRecallCursor(cursorToken, fingerprint)
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("invalid cursor: %v", err)), nil
 		}
 
-		// Sort by score (descending)
-		sort.Slice(results, func(i, j int) bool {
-			return results[i].Score > results[j].Score
-		})
+		if !listing && exact == "" && topic == "" {
+			return mcp.NewToolResultError("please provide 'topic', 'exact', 'filter', or set 'list_all' to true"), nil
+		}
 
-		// Apply limit
-		if len(results) > limit {
-			results = results[:limit]
+		var results []RecallResult
+		var next *recallCursor
+
+		if listing {
+			// List memories, or only those matching the filter
+			var err error
+			results, next, err = listSourcesPage(sources, space != "", pathFilter, scope, order, fingerprint, after, limit)
+			if err != nil {
+				return mcp.NewToolResultError(fmt.Sprintf("listing failed: %v", err)), nil
+			}
+		} else {
+			for _, source := range sources {
+				var found []RecallResult
+				if exact != "" {
+					// Exact text search, narrowed by the full-text index
+					repo, err := source.repository(userID)
+					if err != nil {
+						return mcp.NewToolResultError(fmt.Sprintf("failed to get user repository: %v", err)), nil
+					}
+					found, err = searchExactV2(source, exact, pathFilter, repo.RepoPath, scope)
+					if err != nil {
+						return mcp.NewToolResultError(fmt.Sprintf("search failed: %v", err)), nil
+					}
+				} else {
+					// Topic-based search (fuses multiple ranking signals)
+					found, err = searchByTopicV2(source, topic, pathFilter, scope)
+					if err != nil {
+						return mcp.NewToolResultError(fmt.Sprintf("search failed: %v", err)), nil
+					}
+				}
+				results = append(results, fromSource(found, source, space != "")...)
+			}
+			results, next = pageRecallResults(results, order, fingerprint, after, limit)
+		}
+
+		// Load contents of the returned memories and update access statistics
+		for i := range results {
+			if results[i].Content == nil {
+				results[i].Content = loadMemoryContent(results[i].Memory.FilePath)
+			}
+			updateAccessStatsV2(results[i].source, results[i].Memory)
 		}
 
-		// Update access statistics
-		for _, r := range results {
-			updateAccessStatsV2(ctx, r.Memory)
+		out := newRecallOutput(results, encodeRecallCursor(next))
+		if format == FormatJSON {
+			return mcp.NewToolResultStructuredOnly(out), nil
 		}
 
 		// Format output
-		output := formatRecallResultsV2(results)
+		output := formatRecallResultsV2(results, explain)
+		if next != nil {
+			output += fmt.Sprintf("**next_cursor**: `%s`\n\nMore results available: call again with this `cursor` and the same parameters.\n", encodeRecallCursor(next))
+		}
 
 		if len(results) == 0 {
-			if topic != "" {
-				return mcp.NewToolResultText(fmt.Sprintf("No memories found for topic: '%s'\n\nTry using 'exact' for literal text search, or 'list_all' to see what's stored.", topic)), nil
+			switch {
+			case after != nil:
+				output = "No more memories."
+			case topic != "":
+				output = fmt.Sprintf("No memories found for topic: '%s'\n\nTry using 'exact' for literal text search, or 'list_all' to see what's stored.", topic)
+			default:
+				output = "No memories found."
 			}
-			return mcp.NewToolResultText("No memories found."), nil
 		}
 
-		return mcp.NewToolResultText(output), nil
+		return mcp.NewToolResultStructured(out, output), nil
 	}
 }
 
-// listAllMemoriesV2 returns all memories for browsing (v2 architecture)
-func listAllMemoriesV2(ctx *ToolContext, pathFilter string, includeSuperseded, includeArchived bool) []RecallResult {
-	query := ctx.UserDB.Model(&database.UserMemory{})
-
-	if !includeSuperseded {
-		query = query.Where("superseded_by IS NULL")
+// fromSource marks results as found in a source, labelling them with its
+// space when label is set
+func fromSource(results []RecallResult, source *ToolContext, label bool) []RecallResult {
+	for i := range results {
+		results[i].source = source
+		if label {
+			results[i].Space = source.spaceName()
+		}
 	}
+	return results
+}
+
+// recallScope selects which memories a recall searches
+type recallScope struct {
+	includeSuperseded bool
+	includeArchived   bool
+	filter            *memoryFilter // Optional structured filter
+}
 
-	if includeArchived {
+// query returns a memories query restricted to the scope
+func (s recallScope) query(db *gorm.DB) *gorm.DB {
+	query := db.Model(&database.UserMemory{})
+	if s.includeArchived {
 		query = query.Unscoped()
 	}
+	if !s.includeSuperseded {
+		query = query.Where("superseded_by IS NULL")
+	}
+	if s.filter != nil {
+		query = s.filter.apply(query)
+	}
+	return query
+}
 
-	var memories []database.UserMemory
-	query.Order("updated_at DESC").Find(&memories)
-
-	var results []RecallResult
-	for i := range memories {
-		mem := &memories[i]
-		if pathFilter != "" && !strings.Contains(mem.FilePath, pathFilter) {
-			continue
+// Slugs loaded per query, well under SQLite's bound parameter limit
+const slugBatchSize = 500
+
+// memories returns the memories in scope among slugs and under the path
+// filter, keyed by slug
+func (s recallScope) memories(db *gorm.DB, slugs []string, pathFilter string) (map[string]*database.UserMemory, error) {
+	bySlug := make(map[string]*database.UserMemory, len(slugs))
+	for start := 0; start < len(slugs); start += slugBatchSize {
+		query := s.query(db).Where("slug IN ?", slugs[start:min(start+slugBatchSize, len(slugs))])
+		if pathFilter != "" {
+			query = query.Where("file_path LIKE ?", "%"+pathFilter+"%")
 		}
 
-		content := loadMemoryContent(mem.FilePath)
-		results = append(results, RecallResult{
-			Memory:      mem,
-			Content:     content,
-			Score:       calculateRecencyScoreV2(mem),
-			MatchSource: "list",
-		})
+		var memories []database.UserMemory
+		if err := query.Find(&memories).Error; err != nil {
+			return nil, fmt.Errorf("failed to load memories: %w", err)
+		}
+		for i := range memories {
+			bySlug[memories[i].Slug] = &memories[i]
+		}
 	}
-
-	return results
+	return bySlug, nil
 }
 
-// searchByTopicV2 performs multi-strategy topic search (v2 architecture)
-// Includes semantic search when embeddings are available
-func searchByTopicV2(ctx *ToolContext, topic string, pathFilter string, includeSuperseded bool, repoPath string) []RecallResult {
-	resultMap := make(map[string]*RecallResult) // Keyed by slug
+// Semantic matches below this similarity are treated as noise
+const minSemanticSimilarity = 0.3
 
-	// Strategy 1: Title match
-	searchTitleV2(ctx, topic, resultMap, includeSuperseded)
+// Full-text matches considered for the keyword signal
+const keywordCandidateLimit = 200
 
-	// Strategy 2: Tag match
-	searchTagsV2(ctx, topic, resultMap, includeSuperseded)
-
-	// Strategy 3: Content search (file-based)
-	searchContentV2(ctx, topic, resultMap, repoPath, includeSuperseded)
-
-	// Strategy 4: Semantic search (when embeddings are available)
-	if ctx.HasEmbeddings() {
-		searchSemanticV2(ctx, topic, resultMap, includeSuperseded)
+// searchByTopicV2 ranks memories for a topic by fusing keyword (BM25 from the
+// full-text index), semantic, tag and association signals with reciprocal
+// rank fusion. Only memories a signal matched are loaded, and contents are
+// loaded later, only for the results returned.
+func searchByTopicV2(ctx *ToolContext, topic string, pathFilter string, scope recallScope) ([]RecallResult, error) {
+	keywordHits, err := keywordHitsV2(ctx, topic)
+	if err != nil {
+		return nil, err
+	}
+	tagHits, err := tagHitsV2(ctx, topic)
+	if err != nil {
+		return nil, err
+	}
+	var semanticHits []ranking.Hit
+	if svc := ctx.currentEmbeddingService(); svc != nil && svc.IsEnabled() {
+		semanticHits = semanticHitsV2(svc, topic)
 	}
 
-	// Strategy 5: Associated memories expansion
-	expandAssociationsV2(ctx, resultMap)
-
-	// Apply path filter and convert to slice
-	var results []RecallResult
-	for _, r := range resultMap {
-		if pathFilter != "" && !strings.Contains(r.Memory.FilePath, pathFilter) {
-			continue
-		}
-		results = append(results, *r)
+	// Matches outside the scope or the path filter are dropped here
+	candidates := make(map[string]*RecallResult)
+	if err := addCandidates(ctx, scope, pathFilter, candidates, keywordHits, tagHits, semanticHits); err != nil {
+		return nil, err
+	}
+	if len(candidates) == 0 {
+		return nil, nil
 	}
 
-	return results
-}
+	fusion := ranking.NewFusion(ctx.RankingWeights)
+	fusion.Add(ranking.SignalKeyword, candidateHits(keywordHits, candidates))
+	fusion.Add(ranking.SignalTag, candidateHits(tagHits, candidates))
+	fusion.Add(ranking.SignalSemantic, candidateHits(semanticHits, candidates))
 
-// searchSemanticV2 performs semantic search using embeddings (v2 architecture)
-func searchSemanticV2(ctx *ToolContext, topic string, resultMap map[string]*RecallResult, includeSuperseded bool) {
-	if ctx.EmbeddingService == nil || !ctx.EmbeddingService.IsEnabled() {
-		return
+	// Association proximity depends on how well the linking memory ranked
+	associationHits, err := associationHitsV2(ctx, fusion.Results())
+	if err != nil {
+		return nil, err
 	}
-
-	// Get vector search from embedding service
-	vecSearch := ctx.EmbeddingService.GetVectorSearch()
-	if vecSearch == nil {
-		return
+	if err := addCandidates(ctx, scope, pathFilter, candidates, associationHits); err != nil {
+		return nil, err
 	}
+	fusion.Add(ranking.SignalAssociation, candidateHits(associationHits, candidates))
 
-	// Create semantic search instance
-	semanticSearch := embeddings.NewSemanticSearch(ctx.EmbeddingService, vecSearch)
-
-	// Perform semantic search
-	searchResults, err := semanticSearch.Search(topic, 20)
-	if err != nil || searchResults == nil {
-		return
+	fused := fusion.Results()
+	results := make([]RecallResult, 0, len(fused))
+	for i := range fused {
+		r := candidates[fused[i].Slug]
+		r.Score = fused[i].Score
+		r.MatchSource = strings.Join(fused[i].Signals(), "+")
+		r.Ranking = &fused[i]
+		results = append(results, *r)
 	}
 
-	// Add results to resultMap
-	for _, sr := range searchResults {
-		// Skip if similarity is too low
-		if sr.Similarity < 0.3 {
-			continue
-		}
+	return results, nil
+}
 
-		if _, exists := resultMap[sr.Slug]; exists {
-			// Boost existing results that also have semantic match
-			resultMap[sr.Slug].Score += float64(sr.Similarity) * 5.0
-			if resultMap[sr.Slug].MatchSource != "semantic" {
-				resultMap[sr.Slug].MatchSource += "+semantic"
-			}
-		} else {
-			// Find memory in database
-			var mem database.UserMemory
-			if err := ctx.UserDB.Where("slug = ?", sr.Slug).First(&mem).Error; err != nil {
-				continue
+// addCandidates loads the memories hit by any signal that are in scope and
+// not yet candidates
+func addCandidates(ctx *ToolContext, scope recallScope, pathFilter string, candidates map[string]*RecallResult, signals ...[]ranking.Hit) error {
+	seen := make(map[string]bool)
+	var slugs []string
+	for _, hits := range signals {
+		for _, h := range hits {
+			if _, ok := candidates[h.Slug]; !ok && !seen[h.Slug] {
+				seen[h.Slug] = true
+				slugs = append(slugs, h.Slug)
 			}
+		}
+	}
+	if len(slugs) == 0 {
+		return nil
+	}
 
-			// Skip superseded if not included
-			if !includeSuperseded && mem.SupersededBy != nil {
-				continue
-			}
+	memories, err := scope.memories(ctx.UserDB, slugs, pathFilter)
+	if err != nil {
+		return err
+	}
+	for slug, mem := range memories {
+		candidates[slug] = &RecallResult{Memory: mem}
+	}
+	return nil
+}
 
-			content := loadMemoryContent(mem.FilePath)
-			resultMap[sr.Slug] = &RecallResult{
-				Memory:      &mem,
-				Content:     content,
-				Score:       float64(sr.Similarity) * 8.0, // Semantic score
-				MatchSource: "semantic",
-			}
+// candidateHits keeps the hits on candidates
+func candidateHits(hits []ranking.Hit, candidates map[string]*RecallResult) []ranking.Hit {
+	kept := make([]ranking.Hit, 0, len(hits))
+	for _, h := range hits {
+		if _, ok := candidates[h.Slug]; ok {
+			kept = append(kept, h)
 		}
 	}
+	return kept
 }
 
-// searchTitleV2 searches by title match (v2 architecture)
-func searchTitleV2(ctx *ToolContext, topic string, resultMap map[string]*RecallResult, includeSuperseded bool) {
-	query := ctx.UserDB.Where("title LIKE ?", "%"+topic+"%")
-	if !includeSuperseded {
-		query = query.Where("superseded_by IS NULL")
+// keywordHitsV2 queries the full-text index for the topic
+func keywordHitsV2(ctx *ToolContext, topic string) ([]ranking.Hit, error) {
+	if err := ctx.ensureSearchIndex(); err != nil {
+		return nil, err
 	}
 
-	var memories []database.UserMemory
-	query.Find(&memories)
+	ftsHits, err := database.SearchFTS(ctx.UserDB, topic, keywordCandidateLimit)
+	if err != nil {
+		return nil, err
+	}
 
-	for i := range memories {
-		mem := &memories[i]
-		if _, exists := resultMap[mem.Slug]; !exists {
-			content := loadMemoryContent(mem.FilePath)
-			resultMap[mem.Slug] = &RecallResult{
-				Memory:      mem,
-				Content:     content,
-				Score:       10.0 + calculateRecencyScoreV2(mem), // High base score for title match
-				MatchSource: "title",
-			}
-		} else {
-			resultMap[mem.Slug].Score += 5.0 // Boost if matched multiple ways
-		}
+	hits := make([]ranking.Hit, 0, len(ftsHits))
+	for _, h := range ftsHits {
+		hits = append(hits, ranking.Hit{Slug: h.Slug, Score: h.Score})
 	}
+	return hits, nil
 }
 
-// searchTagsV2 searches by tag match (v2 architecture)
-func searchTagsV2(ctx *ToolContext, topic string, resultMap map[string]*RecallResult, includeSuperseded bool) {
-	// Find memory slugs with matching tags
-	var memorySlugs []string
-	ctx.UserDB.Model(&database.UserMemoryTag{}).
-		Where("tag_name LIKE ?", "%"+topic+"%").
-		Distinct("memory_slug").
-		Pluck("memory_slug", &memorySlugs)
-
-	if len(memorySlugs) == 0 {
-		return
+// tagHitsV2 scores memories by how well their tags match the topic.
+// A tag containing the whole topic counts double a tag matching one word.
+func tagHitsV2(ctx *ToolContext, topic string) ([]ranking.Hit, error) {
+	phrase := strings.ToLower(strings.TrimSpace(topic))
+	words := make(map[string]bool)
+	for _, word := range ranking.Tokenize(topic) {
+		words[word] = true
 	}
-
-	query := ctx.UserDB.Where("slug IN ?", memorySlugs)
-	if !includeSuperseded {
-		query = query.Where("superseded_by IS NULL")
+	if phrase == "" && len(words) == 0 {
+		return nil, nil
 	}
 
-	var memories []database.UserMemory
-	query.Find(&memories)
+	// Narrow to tags containing the topic or one of its words
+	var conditions []string
+	var args []interface{}
+	if phrase != "" {
+		conditions = append(conditions, `LOWER(tag_name) LIKE ? ESCAPE '\'`)
+		args = append(args, "%"+escapeLike(phrase)+"%")
+	}
+	for word := range words {
+		conditions = append(conditions, `LOWER(tag_name) LIKE ? ESCAPE '\'`)
+		args = append(args, "%"+escapeLike(word)+"%")
+	}
+	var tags []database.UserMemoryTag
+	if err := ctx.UserDB.Where(strings.Join(conditions, " OR "), args...).Find(&tags).Error; err != nil {
+		return nil, fmt.Errorf("failed to search tags: %w", err)
+	}
 
-	for i := range memories {
-		mem := &memories[i]
-		if _, exists := resultMap[mem.Slug]; !exists {
-			content := loadMemoryContent(mem.FilePath)
-			resultMap[mem.Slug] = &RecallResult{
-				Memory:      mem,
-				Content:     content,
-				Score:       8.0 + calculateRecencyScoreV2(mem),
-				MatchSource: "tag",
+	scores := make(map[string]float64)
+	for _, tag := range tags {
+		name := strings.ToLower(tag.TagName)
+		if phrase != "" && strings.Contains(name, phrase) {
+			scores[tag.MemorySlug] += 2
+			continue
+		}
+		for _, word := range ranking.Tokenize(name) {
+			if words[word] {
+				scores[tag.MemorySlug]++
 			}
-		} else {
-			resultMap[mem.Slug].Score += 4.0
 		}
 	}
+
+	hits := make([]ranking.Hit, 0, len(scores))
+	for slug, score := range scores {
+		hits = append(hits, ranking.Hit{Slug: slug, Score: score})
+	}
+	return hits, nil
 }
 
-// searchContentV2 searches file content (v2 architecture)
-func searchContentV2(ctx *ToolContext, topic string, resultMap map[string]*RecallResult, repoPath string, includeSuperseded bool) {
-	// Use git grep for content search
-	gitRepo, err := git.OpenRepository(repoPath)
-	if err != nil {
-		return
+// semanticHitsV2 returns memories similar to the topic by embedding
+func semanticHitsV2(svc *embeddings.Service, topic string) []ranking.Hit {
+	vecSearch := svc.GetVectorSearch()
+	if vecSearch == nil {
+		return nil
 	}
 
-	grepResults, err := gitRepo.Grep(topic, "")
+	semanticSearch := embeddings.NewSemanticSearch(svc, vecSearch)
+	searchResults, err := semanticSearch.Search(topic, 20)
 	if err != nil {
-		return
+		return nil
 	}
 
-	// Map file paths to memory slugs
-	query := ctx.UserDB.Model(&database.UserMemory{})
-	if !includeSuperseded {
-		query = query.Where("superseded_by IS NULL")
+	var hits []ranking.Hit
+	for _, sr := range searchResults {
+		if sr.Similarity < minSemanticSimilarity {
+			continue
+		}
+		hits = append(hits, ranking.Hit{Slug: sr.Slug, Score: float64(sr.Similarity)})
+	}
+	return hits
+}
+
+// associationHitsV2 scores memories linked from matched memories (1 hop).
+// A link is worth its strength divided by the linking memory's rank, so
+// neighbours of the best matches rank highest.
+func associationHitsV2(ctx *ToolContext, matched []ranking.Result) ([]ranking.Hit, error) {
+	if len(matched) == 0 {
+		return nil, nil
 	}
 
-	var memories []database.UserMemory
-	query.Find(&memories)
+	seedRank := make(map[string]int, len(matched))
+	seeds := make([]string, len(matched))
+	for i, r := range matched {
+		seedRank[r.Slug] = i + 1
+		seeds[i] = r.Slug
+	}
 
-	fileToMem := make(map[string]*database.UserMemory)
-	for i := range memories {
-		relPath := strings.TrimPrefix(memories[i].FilePath, repoPath+"/")
-		fileToMem[relPath] = &memories[i]
+	var associations []database.UserMemoryAssociation
+	for start := 0; start < len(seeds); start += slugBatchSize {
+		var batch []database.UserMemoryAssociation
+		if err := ctx.UserDB.Where("source_slug IN ?", seeds[start:min(start+slugBatchSize, len(seeds))]).Find(&batch).Error; err != nil {
+			return nil, fmt.Errorf("failed to get associations: %w", err)
+		}
+		associations = append(associations, batch...)
 	}
 
-	for _, gr := range grepResults {
-		mem, exists := fileToMem[gr.FilePath]
-		if !exists {
+	var hits []ranking.Hit
+	for _, assoc := range associations {
+		if assoc.TargetSlug == assoc.SourceSlug {
 			continue
 		}
 
-		if _, exists := resultMap[mem.Slug]; !exists {
-			content := loadMemoryContent(mem.FilePath)
-			resultMap[mem.Slug] = &RecallResult{
-				Memory:      mem,
-				Content:     content,
-				Score:       6.0 + calculateRecencyScoreV2(mem),
-				MatchSource: "content",
-			}
-		} else {
-			resultMap[mem.Slug].Score += 3.0
+		strength := assoc.Strength
+		if strength <= 0 {
+			strength = 0.5
 		}
+		hits = append(hits, ranking.Hit{
+			Slug:  assoc.TargetSlug,
+			Score: strength / float64(seedRank[assoc.SourceSlug]),
+		})
 	}
+	return hits, nil
 }
 
-// expandAssociationsV2 adds associated memories to results (v2 architecture)
-func expandAssociationsV2(ctx *ToolContext, resultMap map[string]*RecallResult) {
-	if len(resultMap) == 0 {
-		return
+// searchExactV2 finds memories containing the exact text. The full-text
+// index narrows the search to memories containing its words; their files
+// are then checked for the literal text.
+func searchExactV2(ctx *ToolContext, exact, pathFilter, repoPath string, scope recallScope) ([]RecallResult, error) {
+	if len(ranking.Tokenize(exact)) == 0 {
+		// Punctuation only: nothing the index can narrow down
+		return grepExactV2(ctx, exact, pathFilter, repoPath, scope)
 	}
 
-	// Get slugs of current results
-	var currentSlugs []string
-	for slug := range resultMap {
-		currentSlugs = append(currentSlugs, slug)
+	if err := ctx.ensureSearchIndex(); err != nil {
+		return nil, err
 	}
 
-	// Get associated memories (1 hop) using slug-based associations
-	var associations []database.UserMemoryAssociation
-	ctx.UserDB.Where("source_slug IN ?", currentSlugs).Find(&associations)
-
-	for _, assoc := range associations {
-		if _, exists := resultMap[assoc.TargetSlug]; exists {
-			continue // Skip already in results
-		}
+	phrase := `"` + strings.ReplaceAll(exact, `"`, " ") + `"`
+	ftsHits, err := database.SearchFTS(ctx.UserDB, phrase, math.MaxInt32)
+	if err != nil {
+		return nil, err
+	}
+	slugs := make([]string, len(ftsHits))
+	for i, hit := range ftsHits {
+		slugs[i] = hit.Slug
+	}
+	inScope, err := scope.memories(ctx.UserDB, slugs, pathFilter)
+	if err != nil {
+		return nil, err
+	}
 
-		var mem database.UserMemory
-		if err := ctx.UserDB.Where("slug = ?", assoc.TargetSlug).First(&mem).Error; err != nil {
+	var results []RecallResult
+	for _, hit := range ftsHits {
+		mem, ok := inScope[hit.Slug]
+		if !ok {
 			continue
 		}
 
-		// Only add if not superseded
-		if mem.SupersededBy != nil {
+		raw, err := os.ReadFile(mem.FilePath)
+		if err != nil || !strings.Contains(string(raw), exact) {
 			continue
 		}
 
-		content := loadMemoryContent(mem.FilePath)
-		resultMap[mem.Slug] = &RecallResult{
-			Memory:      &mem,
-			Content:     content,
-			Score:       3.0 + calculateRecencyScoreV2(&mem), // Lower score for associations
-			MatchSource: "association",
-		}
+		parsed, _ := memory.ParseMarkdown(string(raw))
+		results = append(results, RecallResult{
+			Memory:      mem,
+			Content:     parsed,
+			Score:       10.0 + calculateRecencyScoreV2(mem),
+			MatchSource: "exact",
+		})
 	}
+	return results, nil
 }
 
-// searchExactV2 uses git grep for exact text search (v2 architecture)
-func searchExactV2(ctx *ToolContext, exact, pathFilter, repoPath string) []RecallResult {
+// grepExactV2 uses git grep for exact text search among the memories in scope (v2 architecture)
+func grepExactV2(ctx *ToolContext, exact, pathFilter, repoPath string, scope recallScope) ([]RecallResult, error) {
 	gitRepo, err := git.OpenRepository(repoPath)
 	if err != nil {
-		return nil
+		return nil, nil
 	}
 
 	grepResults, err := gitRepo.Grep(exact, pathFilter)
 	if err != nil {
-		return nil
+		return nil, nil
 	}
 
-	// Build file path to memory mapping
-	var memories []database.UserMemory
-	ctx.UserDB.Find(&memories)
-
-	fileToMem := make(map[string]*database.UserMemory)
-	for i := range memories {
-		relPath := strings.TrimPrefix(memories[i].FilePath, repoPath+"/")
-		fileToMem[relPath] = &memories[i]
-	}
-
-	resultMap := make(map[string]*RecallResult) // Keyed by slug
+	// Load the memories stored in the matching files
+	var filePaths []string
+	seen := make(map[string]bool)
 	for _, gr := range grepResults {
-		mem, exists := fileToMem[gr.FilePath]
-		if !exists {
-			continue
+		if !seen[gr.FilePath] {
+			seen[gr.FilePath] = true
+			filePaths = append(filePaths, filepath.Join(repoPath, gr.FilePath))
 		}
-
-		if _, exists := resultMap[mem.Slug]; !exists {
-			content := loadMemoryContent(mem.FilePath)
-			resultMap[mem.Slug] = &RecallResult{
-				Memory:      mem,
-				Content:     content,
-				Score:       10.0 + calculateRecencyScoreV2(mem),
-				MatchSource: "grep",
-			}
+	}
+	var memories []database.UserMemory
+	for start := 0; start < len(filePaths); start += slugBatchSize {
+		var batch []database.UserMemory
+		if err := scope.query(ctx.UserDB).Where("file_path IN ?", filePaths[start:min(start+slugBatchSize, len(filePaths))]).Find(&batch).Error; err != nil {
+			return nil, fmt.Errorf("failed to load memories: %w", err)
 		}
+		memories = append(memories, batch...)
 	}
 
-	var results []RecallResult
-	for _, r := range resultMap {
-		results = append(results, *r)
+	results := make([]RecallResult, 0, len(memories))
+	for i := range memories {
+		mem := &memories[i]
+		results = append(results, RecallResult{
+			Memory:      mem,
+			Content:     loadMemoryContent(mem.FilePath),
+			Score:       10.0 + calculateRecencyScoreV2(mem),
+			MatchSource: "grep",
+		})
 	}
-	return results
+	return results, nil
 }
 
 // calculateRecencyScoreV2 calculates a recency-based score boost (v2 architecture)
@@ -472,25 +646,55 @@ func calculateRecencyScoreV2(mem *database.UserMemory) float64 {
 	return 0.5 // Older
 }
 
-// updateAccessStatsV2 updates access statistics for a memory (v2 architecture)
+// updateAccessStatsV2 updates access statistics for a memory (v2 architecture).
+// Reading a memory doesn't change its updated_at.
 func updateAccessStatsV2(ctx *ToolContext, mem *database.UserMemory) {
-	ctx.UserDB.Model(mem).Updates(map[string]interface{}{
+	ctx.UserDB.Model(mem).UpdateColumns(map[string]interface{}{
 		"last_accessed_at": time.Now(),
 		"access_count":     mem.AccessCount + 1,
 	})
 }
 
+// newRecallOutput builds the structured output for a page of results
+func newRecallOutput(results []RecallResult, nextCursor string) RecallOutput {
+	out := RecallOutput{Count: len(results), Results: make([]RecallItem, len(results)), NextCursor: nextCursor}
+	for i, r := range results {
+		item := RecallItem{
+			MemoryInfo:  newMemoryInfo(r.Memory),
+			Score:       r.Score,
+			Space:       r.Space,
+			MatchSource: r.MatchSource,
+			Ranking:     r.Ranking,
+		}
+		if r.Content != nil {
+			item.Tags = r.Content.Tags
+			item.Annotations = r.Content.Annotations
+			item.Content = r.Content.Content
+		}
+		out.Results[i] = item
+	}
+	return out
+}
+
 // formatRecallResultsV2 formats results for output (v2 architecture)
-func formatRecallResultsV2(results []RecallResult) string {
+func formatRecallResultsV2(results []RecallResult, explain bool) string {
 	var sb strings.Builder
 	sb.WriteString(fmt.Sprintf("Found %d memories:\n\n", len(results)))
 
 	for i, r := range results {
 		sb.WriteString(fmt.Sprintf("## %d. %s\n", i+1, r.Memory.Title))
-		sb.WriteString(fmt.Sprintf("**Slug**: `%s` | **Match**: %s | **Updated**: %s\n\n",
+		if r.Space != "" {
+			sb.WriteString(fmt.Sprintf("**Space**: %s | ", r.Space))
+		}
+		sb.WriteString(fmt.Sprintf("**Slug**: `%s` | **Match**: %s | **Updated**: %s | **Version**: %d\n\n",
 			r.Memory.Slug,
 			r.MatchSource,
-			r.Memory.UpdatedAt.Format("2006-01-02")))
+			r.Memory.UpdatedAt.Format("2006-01-02"),
+			r.Memory.Version))
+
+		if explain && r.Ranking != nil {
+			sb.WriteString(fmt.Sprintf("**Rank**: %s\n\n", r.Ranking.Explain()))
+		}
 
 		// Show superseded warning
 		if r.Memory.SupersededBy != nil {
@@ -0,0 +1,406 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package tools
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/tejzpr/medha-mcp/internal/database"
+	"gorm.io/gorm"
+)
+
+// memoryFilter is a compiled medha_recall filter expression.
+//
+// Grammar (terms are ANDed unless joined by OR):
+//
+//	expr      := and { "OR" and }
+//	and       := unary { ["AND"] unary }
+//	unary     := ("-" | "NOT") unary | "(" expr ")" | field ":" value
+//
+// Fields:
+//
+//	tag:auth  tag:auth*                  tag name (case-insensitive, * for prefix)
+//	created:>2026-01-01  updated:<=7d    date, "today", "yesterday" or N[hdwm] ago
+//	accessed:2026-03-01..2026-03-31      inclusive range
+//	accesses:>5                          access count
+//	is:superseded  is:archived           state (default recall hides both)
+//	rel:part_of->project-x               links from the memory to project-x
+//	rel:<-project-x                      links from project-x, any relationship
+//	path:projects/alpha                  file path contains
+type memoryFilter struct {
+	where      string
+	args       []interface{}
+	archived   bool // Mentions is:archived, so archived memories must be searched
+	superseded bool // Mentions is:superseded, so superseded memories must be searched
+}
+
+// apply restricts a memories query to the filter
+func (f *memoryFilter) apply(query *gorm.DB) *gorm.DB {
+	return query.Where("("+f.where+")", f.args...)
+}
+
+// parseMemoryFilter compiles a filter expression; an empty expression yields nil
+func parseMemoryFilter(expr string, now time.Time) (*memoryFilter, error) {
+	tokens, err := tokenizeFilter(expr)
+	if err != nil {
+		return nil, err
+	}
+	if len(tokens) == 0 {
+		return nil, nil
+	}
+
+	p := &filterParser{tokens: tokens, now: now, filter: &memoryFilter{}}
+	where, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.tokens) {
+		return nil, fmt.Errorf("unexpected %q", p.tokens[p.pos])
+	}
+
+	p.filter.where = where
+	return p.filter, nil
+}
+
+// tokenizeFilter splits a filter into parentheses, "-" and terms.
+// Values may be quoted: tag:"open question".
+func tokenizeFilter(expr string) ([]string, error) {
+	var tokens []string
+	for i := 0; i < len(expr); {
+		c := expr[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c == '(' || c == ')':
+			tokens = append(tokens, string(c))
+			i++
+		case c == '-' && i+1 < len(expr) && expr[i+1] != ' ':
+			tokens = append(tokens, "-")
+			i++
+		default:
+			var sb strings.Builder
+			for i < len(expr) && !strings.ContainsRune(" \t\n\r()", rune(expr[i])) {
+				if expr[i] == '"' {
+					end := strings.IndexByte(expr[i+1:], '"')
+					if end < 0 {
+						return nil, fmt.Errorf("unterminated quote in filter")
+					}
+					sb.WriteString(expr[i+1 : i+1+end])
+					i += end + 2
+					continue
+				}
+				sb.WriteByte(expr[i])
+				i++
+			}
+			tokens = append(tokens, sb.String())
+		}
+	}
+	return tokens, nil
+}
+
+// filterParser is a recursive descent parser producing SQL
+type filterParser struct {
+	tokens []string
+	pos    int
+	now    time.Time
+	filter *memoryFilter
+}
+
+// peek returns the next token, or "" at the end
+func (p *filterParser) peek() string {
+	if p.pos < len(p.tokens) {
+		return p.tokens[p.pos]
+	}
+	return ""
+}
+
+func (p *filterParser) parseOr() (string, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return "", err
+	}
+	for p.peek() == "OR" {
+		p.pos++
+		right, err := p.parseAnd()
+		if err != nil {
+			return "", err
+		}
+		left = fmt.Sprintf("(%s OR %s)", left, right)
+	}
+	return left, nil
+}
+
+func (p *filterParser) parseAnd() (string, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return "", err
+	}
+	for {
+		next := p.peek()
+		if next == "" || next == "OR" || next == ")" {
+			return left, nil
+		}
+		if next == "AND" {
+			p.pos++
+		}
+		right, err := p.parseUnary()
+		if err != nil {
+			return "", err
+		}
+		left = fmt.Sprintf("(%s AND %s)", left, right)
+	}
+}
+
+func (p *filterParser) parseUnary() (string, error) {
+	tok := p.peek()
+	p.pos++
+
+	switch tok {
+	case "":
+		return "", fmt.Errorf("filter ends unexpectedly")
+	case "-", "NOT":
+		inner, err := p.parseUnary()
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("(NOT %s)", inner), nil
+	case "(":
+		inner, err := p.parseOr()
+		if err != nil {
+			return "", err
+		}
+		if p.peek() != ")" {
+			return "", fmt.Errorf("missing closing parenthesis")
+		}
+		p.pos++
+		return inner, nil
+	case ")", "AND", "OR":
+		return "", fmt.Errorf("unexpected %q", tok)
+	}
+
+	return p.parsePredicate(tok)
+}
+
+// parsePredicate compiles a single field:value term
+func (p *filterParser) parsePredicate(term string) (string, error) {
+	field, value, ok := strings.Cut(term, ":")
+	if !ok || value == "" {
+		return "", fmt.Errorf("%q is not a filter; use field:value (put free text in 'topic')", term)
+	}
+
+	switch strings.ToLower(field) {
+	case "tag":
+		return p.add(tagPredicate(value))
+	case "created":
+		return p.add(datePredicate("created_at", value, p.now))
+	case "updated":
+		return p.add(datePredicate("updated_at", value, p.now))
+	case "accessed":
+		return p.add(datePredicate("last_accessed_at", value, p.now))
+	case "accesses":
+		return p.add(countPredicate("access_count", value))
+	case "is":
+		return p.statePredicate(value)
+	case "rel":
+		return p.add(relPredicate(value))
+	case "path":
+		return p.add("file_path LIKE ?", []interface{}{"%" + value + "%"}, nil)
+	}
+	return "", fmt.Errorf("unknown filter field %q (use tag, created, updated, accessed, accesses, is, rel or path)", field)
+}
+
+// add records a predicate's arguments and returns its SQL
+func (p *filterParser) add(sql string, args []interface{}, err error) (string, error) {
+	if err != nil {
+		return "", err
+	}
+	p.filter.args = append(p.filter.args, args...)
+	return sql, nil
+}
+
+// statePredicate compiles is:superseded and is:archived
+func (p *filterParser) statePredicate(value string) (string, error) {
+	switch strings.ToLower(value) {
+	case "superseded":
+		p.filter.superseded = true
+		return "superseded_by IS NOT NULL", nil
+	case "archived":
+		p.filter.archived = true
+		return "deleted_at IS NOT NULL", nil
+	}
+	return "", fmt.Errorf("unknown state is:%s (use is:superseded or is:archived)", value)
+}
+
+// tagPredicate matches memories carrying a tag
+func tagPredicate(value string) (string, []interface{}, error) {
+	name := strings.ToLower(value)
+	if prefix, ok := strings.CutSuffix(name, "*"); ok {
+		return `slug IN (SELECT memory_slug FROM memory_tags WHERE LOWER(tag_name) LIKE ? ESCAPE '\')`,
+			[]interface{}{escapeLike(prefix) + "%"}, nil
+	}
+	return "slug IN (SELECT memory_slug FROM memory_tags WHERE LOWER(tag_name) = ?)", []interface{}{name}, nil
+}
+
+// escapeLike escapes LIKE wildcards for use with ESCAPE '\'
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
+}
+
+// relPredicate matches memories linked to or from a memory:
+// rel:TYPE->slug (memory links to slug) or rel:TYPE<-slug (slug links to memory)
+func relPredicate(value string) (string, []interface{}, error) {
+	relType, slug, outgoing := strings.Cut(value, "->")
+	if !outgoing {
+		var incoming bool
+		relType, slug, incoming = strings.Cut(value, "<-")
+		if !incoming {
+			return "", nil, fmt.Errorf("rel:%s needs a direction: rel:TYPE->slug or rel:TYPE<-slug", value)
+		}
+	}
+	if slug == "" {
+		return "", nil, fmt.Errorf("rel:%s is missing a memory slug", value)
+	}
+
+	self, other := "source_slug", "target_slug"
+	if !outgoing {
+		self, other = other, self
+	}
+
+	if relType == "" || relType == "*" {
+		sql := fmt.Sprintf("slug IN (SELECT %s FROM associations WHERE %s = ?)", self, other)
+		return sql, []interface{}{slug}, nil
+	}
+
+	mapped, err := filterRelationshipType(relType)
+	if err != nil {
+		return "", nil, err
+	}
+	sql := fmt.Sprintf("slug IN (SELECT %s FROM associations WHERE %s = ? AND relationship = ?)", self, other)
+	return sql, []interface{}{slug, mapped}, nil
+}
+
+// filterRelationshipType resolves a relationship name, rejecting unknown ones
+// rather than falling back to related_to as medha_remember does
+func filterRelationshipType(name string) (string, error) {
+	name = strings.ToLower(name)
+	mapped := mapRelationshipTypeForRemember(name)
+	if mapped == database.AssociationTypeRelatedTo && name != "related" && name != database.AssociationTypeRelatedTo {
+		return "", fmt.Errorf("unknown relationship %q", name)
+	}
+	return mapped, nil
+}
+
+// splitComparison separates a leading comparison operator from its operand
+func splitComparison(value string) (op, operand string) {
+	for _, candidate := range []string{">=", "<=", ">", "<", "="} {
+		if rest, ok := strings.CutPrefix(value, candidate); ok {
+			return candidate, rest
+		}
+	}
+	return "=", value
+}
+
+// countPredicate compiles comparisons and ranges on an integer column
+func countPredicate(column, value string) (string, []interface{}, error) {
+	if lo, hi, ok := strings.Cut(value, ".."); ok {
+		from, err1 := strconv.Atoi(lo)
+		to, err2 := strconv.Atoi(hi)
+		if err1 != nil || err2 != nil {
+			return "", nil, fmt.Errorf("invalid range %q", value)
+		}
+		return fmt.Sprintf("%s BETWEEN ? AND ?", column), []interface{}{from, to}, nil
+	}
+
+	op, operand := splitComparison(value)
+	n, err := strconv.Atoi(operand)
+	if err != nil {
+		return "", nil, fmt.Errorf("invalid number %q", operand)
+	}
+	return fmt.Sprintf("%s %s ?", column, op), []interface{}{n}, nil
+}
+
+// datePredicate compiles comparisons and ranges on a timestamp column.
+// Dates cover a whole day, so updated:>2026-01-01 starts on January 2nd.
+func datePredicate(column, value string, now time.Time) (string, []interface{}, error) {
+	col := fmt.Sprintf("julianday(%s)", column)
+
+	if lo, hi, ok := strings.Cut(value, ".."); ok {
+		fromStart, _, err := parseFilterTime(lo, now)
+		if err != nil {
+			return "", nil, err
+		}
+		_, toEnd, err := parseFilterTime(hi, now)
+		if err != nil {
+			return "", nil, err
+		}
+		return fmt.Sprintf("%s >= julianday(?) AND %s < julianday(?)", col, col), []interface{}{fromStart, toEnd}, nil
+	}
+
+	op, operand := splitComparison(value)
+	start, end, err := parseFilterTime(operand, now)
+	if err != nil {
+		return "", nil, err
+	}
+
+	switch op {
+	case ">":
+		return col + " >= julianday(?)", []interface{}{end}, nil
+	case ">=":
+		return col + " >= julianday(?)", []interface{}{start}, nil
+	case "<":
+		return col + " < julianday(?)", []interface{}{start}, nil
+	case "<=":
+		return col + " < julianday(?)", []interface{}{end}, nil
+	}
+	return fmt.Sprintf("%s >= julianday(?) AND %s < julianday(?)", col, col), []interface{}{start, end}, nil
+}
+
+// parseFilterTime parses a date, timestamp, "today", "yesterday" or a
+// relative age such as 36h, 7d, 2w or 1m (as medha_history's since). It returns the half-open interval
+// the value covers: a whole day for dates, an instant otherwise.
+func parseFilterTime(value string, now time.Time) (start, end time.Time, err error) {
+	day := func(t time.Time) (time.Time, time.Time, error) {
+		start := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+		return start, start.AddDate(0, 0, 1), nil
+	}
+	instant := func(t time.Time) (time.Time, time.Time, error) {
+		return t, t.Add(time.Nanosecond), nil
+	}
+
+	switch strings.ToLower(value) {
+	case "today":
+		return day(now)
+	case "yesterday":
+		return day(now.AddDate(0, 0, -1))
+	}
+
+	if t, err := time.ParseInLocation("2006-01-02", value, now.Location()); err == nil {
+		return day(t)
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return instant(t)
+	}
+
+	if len(value) >= 2 {
+		n, err := strconv.Atoi(value[:len(value)-1])
+		if err == nil && n >= 0 {
+			switch value[len(value)-1] {
+			case 'h':
+				return instant(now.Add(-time.Duration(n) * time.Hour))
+			case 'd':
+				return instant(now.AddDate(0, 0, -n))
+			case 'w':
+				return instant(now.AddDate(0, 0, -7*n))
+			case 'm':
+				return instant(now.AddDate(0, -n, 0))
+			}
+		}
+	}
+
+	return time.Time{}, time.Time{}, fmt.Errorf("invalid date %q (use YYYY-MM-DD, RFC 3339, today, yesterday or an age like 7d, 2w, 1m)", value)
+}
@@ -0,0 +1,272 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package tools
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/tejzpr/medha-mcp/internal/database"
+)
+
+// Recall result orderings. Ties are broken by slug, then space, so pages are
+// stable.
+const (
+	RecallOrderRelevance = "relevance" // Score, then most recently updated (topic and exact searches)
+	RecallOrderUpdated   = "updated"   // Most recently updated first
+	RecallOrderCreated   = "created"   // Most recently created first
+	RecallOrderAccessed  = "accessed"  // Most recently accessed first
+	RecallOrderTitle     = "title"     // Title A-Z
+)
+
+// recallOrderColumns maps time orderings to memory columns
+var recallOrderColumns = map[string]string{
+	RecallOrderUpdated:  "updated_at",
+	RecallOrderCreated:  "created_at",
+	RecallOrderAccessed: "last_accessed_at",
+}
+
+// parseRecallOrder validates an ordering. Searches default to relevance;
+// listings, which have no relevance, default to updated.
+func parseRecallOrder(order string, ranked bool) (string, error) {
+	switch order {
+	case "":
+		if ranked {
+			return RecallOrderRelevance, nil
+		}
+		return RecallOrderUpdated, nil
+	case RecallOrderRelevance:
+		if !ranked {
+			return RecallOrderUpdated, nil
+		}
+		return order, nil
+	case RecallOrderUpdated, RecallOrderCreated, RecallOrderAccessed, RecallOrderTitle:
+		return order, nil
+	}
+	return "", fmt.Errorf("unknown order %q (use relevance, updated, created, accessed or title)", order)
+}
+
+// recallCursor is the position after the last result of a page. It is
+// handed to clients as an opaque token.
+type recallCursor struct {
+	Query string    `json:"q"` // Fingerprint of the query the cursor belongs to
+	Score float64   `json:"s,omitempty"`
+	Time  time.Time `json:"t"`
+	Title string    `json:"n,omitempty"`
+	Slug  string    `json:"k"`
+	Space string    `json:"sp,omitempty"`
+}
+
+// recallFingerprint identifies a query so that cursors can't be replayed
+// against a different one
+func recallFingerprint(parts ...interface{}) string {
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%q", parts)))
+	return hex.EncodeToString(sum[:8])
+}
+
+// encodeRecallCursor returns the opaque token for a cursor
+func encodeRecallCursor(c *recallCursor) string {
+	if c == nil {
+		return ""
+	}
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// decodeRecallCursor parses a token, checking it belongs to the query.
+// An empty token yields nil (the first page).
+func decodeRecallCursor(token, fingerprint string) (*recallCursor, error) {
+	if token == "" {
+		return nil, nil
+	}
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, fmt.Errorf("malformed cursor")
+	}
+	var c recallCursor
+	if err := json.Unmarshal(data, &c); err != nil || c.Slug == "" {
+		return nil, fmt.Errorf("malformed cursor")
+	}
+	if c.Query != fingerprint {
+		return nil, fmt.Errorf("cursor belongs to a different query; repeat the original parameters with it")
+	}
+	return &c, nil
+}
+
+// recallSortKey returns the cursor that sorts at a result's position
+func recallSortKey(r *RecallResult, order, fingerprint string) *recallCursor {
+	c := &recallCursor{Query: fingerprint, Slug: r.Memory.Slug, Space: r.Space}
+	switch order {
+	case RecallOrderRelevance:
+		c.Score = r.Score
+		c.Time = r.Memory.UpdatedAt
+	case RecallOrderCreated:
+		c.Time = r.Memory.CreatedAt
+	case RecallOrderAccessed:
+		c.Time = r.Memory.LastAccessedAt
+	case RecallOrderTitle:
+		c.Title = r.Memory.Title
+	default:
+		c.Time = r.Memory.UpdatedAt
+	}
+	return c
+}
+
+// recallKeyBefore reports whether key a sorts before key b
+func recallKeyBefore(a, b *recallCursor, order string) bool {
+	switch order {
+	case RecallOrderRelevance:
+		if a.Score != b.Score {
+			return a.Score > b.Score
+		}
+		if !a.Time.Equal(b.Time) {
+			return a.Time.After(b.Time)
+		}
+	case RecallOrderTitle:
+		if at, bt := strings.ToLower(a.Title), strings.ToLower(b.Title); at != bt {
+			return at < bt
+		}
+	default:
+		if !a.Time.Equal(b.Time) {
+			return a.Time.After(b.Time)
+		}
+	}
+	if a.Slug != b.Slug {
+		return a.Slug < b.Slug
+	}
+	return a.Space < b.Space
+}
+
+// pageRecallResults sorts search results and returns the page after the
+// cursor, with the cursor for the next page (nil on the last page)
+func pageRecallResults(results []RecallResult, order, fingerprint string, after *recallCursor, limit int) ([]RecallResult, *recallCursor) {
+	keys := make([]*recallCursor, len(results))
+	for i := range results {
+		keys[i] = recallSortKey(&results[i], order, fingerprint)
+	}
+	sort.Sort(recallResultSorter{results, keys, order})
+
+	start := 0
+	if after != nil {
+		start = sort.Search(len(keys), func(i int) bool {
+			return recallKeyBefore(after, keys[i], order)
+		})
+	}
+
+	end := start + limit
+	if end >= len(results) {
+		return results[start:], nil
+	}
+	return results[start:end], keys[end-1]
+}
+
+// recallResultSorter sorts results together with their sort keys
+type recallResultSorter struct {
+	results []RecallResult
+	keys    []*recallCursor
+	order   string
+}
+
+func (s recallResultSorter) Len() int { return len(s.results) }
+
+func (s recallResultSorter) Less(i, j int) bool {
+	return recallKeyBefore(s.keys[i], s.keys[j], s.order)
+}
+
+func (s recallResultSorter) Swap(i, j int) {
+	s.results[i], s.results[j] = s.results[j], s.results[i]
+	s.keys[i], s.keys[j] = s.keys[j], s.keys[i]
+}
+
+// listSourcesPage returns one page of memories in scope across sources. Each
+// source lists its own page, and the pages are merged, so no repository is
+// ever fully loaded.
+func listSourcesPage(sources []*ToolContext, label bool, pathFilter string, scope recallScope, order, fingerprint string, after *recallCursor, limit int) ([]RecallResult, *recallCursor, error) {
+	if len(sources) == 1 {
+		return listMemoriesPage(sources[0], label, pathFilter, scope, order, fingerprint, after, limit)
+	}
+
+	var merged []RecallResult
+	more := false
+	for _, source := range sources {
+		results, next, err := listMemoriesPage(source, label, pathFilter, scope, order, fingerprint, after, limit)
+		if err != nil {
+			return nil, nil, err
+		}
+		merged = append(merged, results...)
+		more = more || next != nil
+	}
+
+	results, next := pageRecallResults(merged, order, fingerprint, nil, limit)
+	if next == nil && more {
+		// A source has more than the page holds, though the merge filled it exactly
+		next = recallSortKey(&results[len(results)-1], order, fingerprint)
+	}
+	return results, next, nil
+}
+
+// listMemoriesPage returns one page of memories in scope, ordered and
+// paginated in the database so large repositories are never fully loaded
+func listMemoriesPage(ctx *ToolContext, label bool, pathFilter string, scope recallScope, order, fingerprint string, after *recallCursor, limit int) ([]RecallResult, *recallCursor, error) {
+	query := scope.query(ctx.UserDB)
+	if pathFilter != "" {
+		query = query.Where("file_path LIKE ?", "%"+pathFilter+"%")
+	}
+
+	space := ""
+	if label {
+		space = ctx.spaceName()
+	}
+	// Past the cursor's slug, or from it in a space that sorts after the cursor's
+	slugAfter := "slug > ?"
+	if after != nil && space > after.Space {
+		slugAfter = "slug >= ?"
+	}
+
+	if order == RecallOrderTitle {
+		if after != nil {
+			query = query.Where("(LOWER(title) > LOWER(?) OR (LOWER(title) = LOWER(?) AND "+slugAfter+"))", after.Title, after.Title, after.Slug)
+		}
+		query = query.Order("LOWER(title) ASC")
+	} else {
+		// julianday() compares instants whatever time zone they were stored in
+		col := fmt.Sprintf("julianday(%s)", recallOrderColumns[order])
+		if after != nil {
+			query = query.Where(fmt.Sprintf("(%s < julianday(?) OR (%s = julianday(?) AND %s))", col, col, slugAfter),
+				after.Time, after.Time, after.Slug)
+		}
+		query = query.Order(col + " DESC")
+	}
+
+	var memories []database.UserMemory
+	if err := query.Order("slug ASC").Limit(limit + 1).Find(&memories).Error; err != nil {
+		return nil, nil, fmt.Errorf("failed to list memories: %w", err)
+	}
+
+	hasMore := len(memories) > limit
+	if hasMore {
+		memories = memories[:limit]
+	}
+
+	results := make([]RecallResult, len(memories))
+	for i := range memories {
+		results[i] = RecallResult{
+			Memory:      &memories[i],
+			Score:       calculateRecencyScoreV2(&memories[i]),
+			MatchSource: "list",
+		}
+	}
+	fromSource(results, ctx, label)
+	if !hasMore {
+		return results, nil, nil
+	}
+	return results, recallSortKey(&results[len(results)-1], order, fingerprint), nil
+}
@@ -8,6 +8,7 @@ import (
 	"context"
 	"crypto/sha256"
 	"encoding/hex"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -19,6 +20,7 @@ import (
 	"github.com/tejzpr/medha-mcp/internal/locking"
 	"github.com/tejzpr/medha-mcp/internal/memory"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // NewRememberTool creates the medha_remember tool definition
@@ -49,6 +51,10 @@ func NewRememberTool() mcp.Tool {
 		mcp.WithString("note",
 			mcp.Description("Add a note/annotation to the memory. Can be combined with content updates."),
 		),
+		mcp.WithNumber("expected_version",
+			mcp.Description("Version you read (from medha_recall). If the memory has changed since, nothing is written and the current content is returned with a merge suggestion. 0 means create only."),
+			mcp.Min(0),
+		),
 		mcp.WithArray("connections",
 			mcp.Description("Link to related memories. Array of objects: [{\"to\": \"slug\", \"relationship\": \"related|part_of|references|person\"}]"),
 			mcp.Items(map[string]any{
@@ -61,6 +67,9 @@ func NewRememberTool() mcp.Tool {
 				"required": []string{"to"},
 			}),
 		),
+		withSpace(),
+		withFormat(),
+		mcp.WithOutputSchema[MemoryChangeOutput](),
 	)
 }
 
@@ -93,15 +102,28 @@ func RememberHandler(ctx *ToolContext, userID uint) func(context.Context, mcp.Ca
 		pathFolder := request.GetString("path", "")
 		note := request.GetString("note", "")
 		connections := parseConnections(request)
+		expectedVersion, hasExpectedVersion := optionalVersion(request)
+		if expectedVersion < 0 {
+			return mcp.NewToolResultError("expected_version cannot be negative"), nil
+		}
+		format, err := requestFormat(request)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+
+		// Work in the requested team space, if any
+		ctx, err := ctx.forSpace(userID, request, database.SpaceRoleWriter)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
 
 		// Validate UserDB is available
 		if ctx.UserDB == nil {
 			return mcp.NewToolResultError("per-user database not available"), nil
 		}
 
-		// Get user's repo from system DB
-		var repo database.MedhaGitRepo
-		err = ctx.DB.Where("user_id = ?", userID).First(&repo).Error
+		// Get the user's or the space's repo
+		repo, err := ctx.repository(userID)
 		if err != nil {
 			return mcp.NewToolResultError(fmt.Sprintf("failed to get user repository: %v", err)), nil
 		}
@@ -112,30 +134,50 @@ func RememberHandler(ctx *ToolContext, userID uint) func(context.Context, mcp.Ca
 			return mcp.NewToolResultError("title cannot be empty after sanitization"), nil
 		}
 
+		// Hold the memory's write lock until the change is stored
+		lockSlug := slug
+		if lockSlug == "" {
+			lockSlug = memory.GenerateSlug(title)
+		}
+		release, err := ctx.lockMemory(c, lockSlug)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		defer release()
+
 		// Determine if this is an update or create
 		if slug != "" {
 			// Try to find existing memory in UserDB
 			var existingMem database.UserMemory
 			err = ctx.UserDB.Where("slug = ?", slug).First(&existingMem).Error
 			if err == nil {
-				// Memory exists - update it
+				// Memory exists - update it unless it changed since the caller read it
+				if hasExpectedVersion && expectedVersion != existingMem.Version {
+					return versionConflictResult(format, slug, ctx.newVersionConflict(&existingMem, expectedVersion, content)), nil
+				}
+				changed := ctx.watchMemory(slug)
 				result, updateErr := handleUpdateV2(ctx, &existingMem, title, content, tags, repo.RepoPath)
-				if updateErr != nil {
+				if updateErr != nil || result.IsError {
 					return result, updateErr
 				}
+				message := resultText(result)
 				// If note provided, also add annotation
 				if note != "" {
 					annotationResult, _ := handleAnnotationV2(ctx, slug, note, repo.RepoPath)
 					// Append annotation result to update result
 					if annotationResult != nil {
-						return mcp.NewToolResultText(result.Content[0].(mcp.TextContent).Text + "\n" + annotationResult.Content[0].(mcp.TextContent).Text), nil
+						message += "\n" + resultText(annotationResult)
 					}
 				}
-				return result, nil
+				changed("updated")
+				return ctx.memoryChangeResult(format, "updated", slug, message), nil
 			} else if err != gorm.ErrRecordNotFound {
 				return mcp.NewToolResultError(fmt.Sprintf("database error: %v", err)), nil
 			}
 			// Memory doesn't exist - validate custom slug
+			if hasExpectedVersion && expectedVersion > 0 {
+				return mcp.NewToolResultError(fmt.Sprintf("memory not found: %s (expected version %d)", slug, expectedVersion)), nil
+			}
 			if err := memory.ValidateSlug(slug); err != nil {
 				return mcp.NewToolResultError(fmt.Sprintf("invalid slug: %v", err)), nil
 			}
@@ -155,6 +197,7 @@ func RememberHandler(ctx *ToolContext, userID uint) func(context.Context, mcp.Ca
 		}
 
 		// Create new memory
+		changed := ctx.watchMemory(slug)
 		result, err := handleCreateV2(ctx, slug, title, content, tags, pathFolder, repo.RepoPath)
 		if err != nil {
 			return mcp.NewToolResultError(err.Error()), nil
@@ -162,12 +205,14 @@ func RememberHandler(ctx *ToolContext, userID uint) func(context.Context, mcp.Ca
 
 		// Handle supersession if specified
 		if replaces != "" {
-			err = handleSupersessionV2(ctx, slug, replaces, repo.RepoPath)
+			replacedChanged := ctx.watchMemory(replaces)
+			err = handleSupersessionV2(c, ctx, slug, replaces, repo.RepoPath)
 			if err != nil {
 				// Log but don't fail - memory was created successfully
 				result = result + fmt.Sprintf("\n\nWarning: Failed to mark '%s' as superseded: %v", replaces, err)
 			} else {
 				result = result + fmt.Sprintf("\n\nSupersedes: '%s' (marked as outdated)", replaces)
+				replacedChanged("updated")
 			}
 		}
 
@@ -179,10 +224,19 @@ func RememberHandler(ctx *ToolContext, userID uint) func(context.Context, mcp.Ca
 			}
 		}
 
-		return mcp.NewToolResultText(result), nil
+		changed("created")
+		return ctx.memoryChangeResult(format, "created", slug, result), nil
 	}
 }
 
+// optionalVersion returns the expected_version argument, if given
+func optionalVersion(request mcp.CallToolRequest) (int64, bool) {
+	if _, ok := request.GetArguments()["expected_version"]; !ok {
+		return 0, false
+	}
+	return int64(request.GetInt("expected_version", 0)), true
+}
+
 // handleCreateV2 creates a new memory (v2 architecture)
 func handleCreateV2(ctx *ToolContext, slug, title, content string, tags []string, pathFolder, repoPath string) (string, error) {
 	now := time.Now()
@@ -194,6 +248,7 @@ func handleCreateV2(ctx *ToolContext, slug, title, content string, tags []string
 		Tags:         tags,
 		Created:      now,
 		Updated:      now,
+		Version:      1,
 		Associations: []memory.Association{},
 		Content:      content,
 	}
@@ -215,31 +270,10 @@ func handleCreateV2(ctx *ToolContext, slug, title, content string, tags []string
 		filePath = organizer.GetMemoryPath(slug, tags, "", now)
 	}
 
-	// Ensure directory exists
-	if err := os.MkdirAll(filepath.Dir(filePath), 0755); err != nil {
-		return "", fmt.Errorf("failed to create directory: %w", err)
-	}
-
-	// Write file
-	if err := os.WriteFile(filePath, []byte(markdown), 0644); err != nil {
-		return "", fmt.Errorf("failed to write file: %w", err)
-	}
-
-	// Git commit
-	gitRepo, err := git.OpenRepository(repoPath)
-	if err != nil {
-		return "", fmt.Errorf("failed to open git repo: %w", err)
-	}
-
-	msgFormat := git.CommitMessageFormats{}
-	if err := gitRepo.CommitFile(filePath, msgFormat.CreateMemory(slug)); err != nil {
-		return "", fmt.Errorf("failed to commit: %w", err)
-	}
-
 	// Calculate content hash for embedding staleness detection
 	contentHash := computeContentHash(content)
 
-	// Store in UserDB (v2 architecture)
+	// Write, commit and store in UserDB (v2 architecture) as one unit
 	dbMem := &database.UserMemory{
 		Slug:           slug,
 		Title:          title,
@@ -249,12 +283,25 @@ func handleCreateV2(ctx *ToolContext, slug, title, content string, tags []string
 		ContentHash:    contentHash,
 		Version:        1,
 	}
-	if err := ctx.UserDB.Create(dbMem).Error; err != nil {
-		return "", fmt.Errorf("failed to store memory: %w", err)
+	msgFormat := git.CommitMessageFormats{}
+	err = ctx.applyMemoryWrite(memoryWrite{
+		path:    filePath,
+		content: []byte(markdown),
+		message: msgFormat.CreateMemory(slug),
+		update: func(tx *gorm.DB) error {
+			if err := tx.Create(dbMem).Error; err != nil {
+				return fmt.Errorf("failed to store memory: %w", err)
+			}
+			return storeTagsV2(tx, slug, tags)
+		},
+	})
+	if err != nil {
+		return "", err
 	}
 
-	// Store tags in UserDB
-	storeTagsV2(ctx, slug, tags)
+	// Index for full-text search and embed in the background for semantic search
+	ctx.indexForSearch(slug, mem)
+	ctx.queueEmbedding(slug, mem)
 
 	return fmt.Sprintf("Memory created: %s\nSlug: %s\nPath: %s", title, slug, filePath), nil
 }
@@ -289,12 +336,11 @@ func handleUpdateV2(ctx *ToolContext, dbMem *database.UserMemory, title, content
 	}
 	mem.Content = content
 	mem.Updated = time.Now()
+	mem.Version = originalVersion + 1
 
 	// Update tags if provided
 	if len(tags) > 0 {
 		mem.Tags = tags
-		ctx.UserDB.Where("memory_slug = ?", dbMem.Slug).Delete(&database.UserMemoryTag{})
-		storeTagsV2(ctx, dbMem.Slug, tags)
 	}
 
 	// Generate updated markdown
@@ -303,23 +349,7 @@ func handleUpdateV2(ctx *ToolContext, dbMem *database.UserMemory, title, content
 		return mcp.NewToolResultError(fmt.Sprintf("failed to generate markdown: %v", err)), nil
 	}
 
-	// Write file
-	if err := os.WriteFile(dbMem.FilePath, []byte(markdown), 0644); err != nil {
-		return mcp.NewToolResultError(fmt.Sprintf("failed to write file: %v", err)), nil
-	}
-
-	// Git commit
-	gitRepo, err := git.OpenRepository(repoPath)
-	if err != nil {
-		return mcp.NewToolResultError(fmt.Sprintf("failed to open git repo: %v", err)), nil
-	}
-
-	msgFormat := git.CommitMessageFormats{}
-	if err := gitRepo.CommitFile(dbMem.FilePath, msgFormat.UpdateMemory(dbMem.Slug)); err != nil {
-		return mcp.NewToolResultError(fmt.Sprintf("failed to commit: %v", err)), nil
-	}
-
-	// Update database with optimistic locking
+	// Write, commit and update the database with optimistic locking as one unit
 	now := time.Now()
 	contentHash := computeContentHash(content)
 	updates := map[string]interface{}{
@@ -328,17 +358,38 @@ func handleUpdateV2(ctx *ToolContext, dbMem *database.UserMemory, title, content
 		"content_hash": contentHash,
 	}
 
-	err = locking.RetryWithBackoff(locking.MaxRetries, locking.RetryDelay, func() error {
-		return locking.UpdateWithVersion(ctx.UserDB, "memories", dbMem.Slug, originalVersion, updates)
+	msgFormat := git.CommitMessageFormats{}
+	err = ctx.applyMemoryWrite(memoryWrite{
+		path:    dbMem.FilePath,
+		content: []byte(markdown),
+		message: msgFormat.UpdateMemory(dbMem.Slug),
+		update: func(tx *gorm.DB) error {
+			if err := locking.UpdateWithVersion(tx, "memories", dbMem.Slug, originalVersion, updates); err != nil {
+				return err
+			}
+			if len(tags) == 0 {
+				return nil
+			}
+			if err := tx.Where("memory_slug = ?", dbMem.Slug).Delete(&database.UserMemoryTag{}).Error; err != nil {
+				return err
+			}
+			return storeTagsV2(tx, dbMem.Slug, tags)
+		},
 	})
 
 	if err != nil {
-		if _, ok := err.(*locking.ConflictError); ok {
+		var conflict *locking.ConflictError
+		if errors.As(err, &conflict) {
 			return mcp.NewToolResultError("Memory was modified by another agent. Please recall and retry."), nil
 		}
-		return mcp.NewToolResultError(fmt.Sprintf("failed to update database: %v", err)), nil
+		return mcp.NewToolResultError(fmt.Sprintf("failed to update memory: %v", err)), nil
 	}
 
+	// Refresh the full-text index and re-embed in the background;
+	// unchanged content is skipped by its hash
+	ctx.indexForSearch(dbMem.Slug, mem)
+	ctx.queueEmbedding(dbMem.Slug, mem)
+
 	return mcp.NewToolResultText(fmt.Sprintf("Memory updated: %s", dbMem.Slug)), nil
 }
 
@@ -381,6 +432,7 @@ func handleAnnotationV2(ctx *ToolContext, slug, note, repoPath string) (*mcp.Cal
 		CreatedAt: time.Now(),
 	})
 	mem.Updated = time.Now()
+	mem.Version = dbMem.Version // Annotations do not bump the version
 
 	// Generate updated markdown
 	markdown, err := mem.ToMarkdown()
@@ -388,36 +440,43 @@ func handleAnnotationV2(ctx *ToolContext, slug, note, repoPath string) (*mcp.Cal
 		return mcp.NewToolResultError(fmt.Sprintf("failed to generate markdown: %v", err)), nil
 	}
 
-	// Write file
-	if err := os.WriteFile(dbMem.FilePath, []byte(markdown), 0644); err != nil {
-		return mcp.NewToolResultError(fmt.Sprintf("failed to write file: %v", err)), nil
-	}
-
-	// Git commit
-	gitRepo, err := git.OpenRepository(repoPath)
-	if err != nil {
-		return mcp.NewToolResultError(fmt.Sprintf("failed to open git repo: %v", err)), nil
-	}
-
-	msgFormat := git.CommitMessageFormats{}
-	if err := gitRepo.CommitFile(dbMem.FilePath, msgFormat.AddAnnotation(slug, annotationType)); err != nil {
-		return mcp.NewToolResultError(fmt.Sprintf("failed to commit: %v", err)), nil
-	}
-
-	// Store annotation in UserDB
+	// Write, commit and store the annotation in UserDB as one unit
 	annotation := &database.UserAnnotation{
 		MemorySlug: slug,
 		Type:       annotationType,
 		Content:    note,
 		CreatedAt:  time.Now(),
 	}
-	ctx.UserDB.Create(annotation)
+	msgFormat := git.CommitMessageFormats{}
+	err = ctx.applyMemoryWrite(memoryWrite{
+		path:    dbMem.FilePath,
+		content: []byte(markdown),
+		message: msgFormat.AddAnnotation(slug, annotationType),
+		update: func(tx *gorm.DB) error {
+			return tx.Create(annotation).Error
+		},
+	})
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to add annotation: %v", err)), nil
+	}
+
+	// Annotations are searchable
+	ctx.indexForSearch(slug, mem)
 
 	return mcp.NewToolResultText(fmt.Sprintf("Annotation added to '%s' (type: %s)", slug, annotationType)), nil
 }
 
 // handleSupersessionV2 marks an old memory as superseded by a new one (v2 architecture)
-func handleSupersessionV2(ctx *ToolContext, newSlug, oldSlug, repoPath string) error {
+func handleSupersessionV2(c context.Context, ctx *ToolContext, newSlug, oldSlug, repoPath string) error {
+	if oldSlug == newSlug {
+		return fmt.Errorf("a memory cannot supersede itself")
+	}
+	release, err := ctx.lockMemory(c, oldSlug)
+	if err != nil {
+		return err
+	}
+	defer release()
+
 	// Get old memory from UserDB
 	var oldMem database.UserMemory
 	if err := ctx.UserDB.Where("slug = ?", oldSlug).First(&oldMem).Error; err != nil {
@@ -444,6 +503,7 @@ func handleSupersessionV2(ctx *ToolContext, newSlug, oldSlug, repoPath string) e
 	// Update frontmatter with superseded_by
 	mem.SupersededBy = newSlug
 	mem.Updated = time.Now()
+	mem.Version = oldMem.Version + 1
 
 	// Generate updated markdown
 	markdown, err := mem.ToMarkdown()
@@ -451,55 +511,51 @@ func handleSupersessionV2(ctx *ToolContext, newSlug, oldSlug, repoPath string) e
 		return fmt.Errorf("failed to generate updated markdown: %w", err)
 	}
 
-	// Write updated file
-	if err := os.WriteFile(oldMem.FilePath, []byte(markdown), 0644); err != nil {
-		return fmt.Errorf("failed to write updated file: %w", err)
-	}
-
-	// Mark old memory as superseded in UserDB
-	oldMem.SupersededBy = &newSlug
-	if err := ctx.UserDB.Save(&oldMem).Error; err != nil {
-		return fmt.Errorf("failed to update old memory: %w", err)
-	}
-
-	// Create association in UserDB (slug-based)
+	// Write, commit, mark old memory as superseded and link the two in UserDB as one unit
 	association := &database.UserMemoryAssociation{
 		SourceSlug:      newSlug,
 		TargetSlug:      oldSlug,
 		AssociationType: database.AssociationTypeSupersedes,
 		Strength:        1.0,
 	}
-	if err := ctx.UserDB.Create(association).Error; err != nil {
-		// Not a critical error - memory is still marked as superseded
-		return nil
-	}
-
-	// Git commit
-	gitRepo, err := git.OpenRepository(repoPath)
-	if err == nil {
-		msgFormat := git.CommitMessageFormats{}
-		_ = gitRepo.CommitFile(oldMem.FilePath, msgFormat.SupersedeMemory(oldSlug, newSlug))
-	}
-
-	return nil
+	msgFormat := git.CommitMessageFormats{}
+	return ctx.applyMemoryWrite(memoryWrite{
+		path:    oldMem.FilePath,
+		content: []byte(markdown),
+		message: msgFormat.SupersedeMemory(oldSlug, newSlug),
+		update: func(tx *gorm.DB) error {
+			if err := locking.UpdateWithVersion(tx, "memories", oldSlug, oldMem.Version, map[string]interface{}{
+				"superseded_by": newSlug,
+				"updated_at":    mem.Updated,
+			}); err != nil {
+				return fmt.Errorf("failed to update old memory: %w", err)
+			}
+			return tx.Create(association).Error
+		},
+	})
 }
 
 // storeTagsV2 stores tags for a memory (v2 architecture using slugs)
-func storeTagsV2(ctx *ToolContext, memorySlug string, tags []string) {
+func storeTagsV2(db *gorm.DB, memorySlug string, tags []string) error {
 	for _, tagName := range tags {
 		// Create tag if not exists
 		var tag database.UserTag
-		ctx.UserDB.Where("name = ?", tagName).FirstOrCreate(&tag, database.UserTag{
+		if err := db.Where("name = ?", tagName).FirstOrCreate(&tag, database.UserTag{
 			Name: tagName,
-		})
+		}).Error; err != nil {
+			return fmt.Errorf("failed to store tag %s: %w", tagName, err)
+		}
 
 		// Create memory-tag relationship
 		memTag := &database.UserMemoryTag{
 			MemorySlug: memorySlug,
 			TagName:    tagName,
 		}
-		ctx.UserDB.Create(memTag)
+		if err := db.Clauses(clause.OnConflict{DoNothing: true}).Create(memTag).Error; err != nil {
+			return fmt.Errorf("failed to tag memory with %s: %w", tagName, err)
+		}
 	}
+	return nil
 }
 
 // containsWord checks if text contains any of the given words (case-insensitive)
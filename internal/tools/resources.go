@@ -0,0 +1,272 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/tejzpr/medha-mcp/internal/database"
+	"gorm.io/gorm"
+)
+
+// Resource URI prefixes
+const (
+	MemoryURIPrefix = "medha://memory/"
+	FolderURIPrefix = "medha://folder/"
+	TagURIPrefix    = "medha://tag/"
+)
+
+// Resource MIME types
+const (
+	mimeMarkdown = "text/markdown"
+	mimeJSON     = "application/json"
+)
+
+// MemoryURI returns the resource URI of a memory
+func MemoryURI(slug string) string {
+	return MemoryURIPrefix + slug
+}
+
+// FolderURI returns the resource URI of a folder, relative to the repository root
+func FolderURI(folder string) string {
+	return FolderURIPrefix + filepath.ToSlash(folder)
+}
+
+// TagURI returns the resource URI of a tag
+func TagURI(tag string) string {
+	return TagURIPrefix + url.PathEscape(strings.ToLower(tag))
+}
+
+// MemoryChange reports a write to a memory so its resources can be refreshed
+type MemoryChange struct {
+	Slug   string
+	Action string   // created, updated, archived or restored
+	URIs   []string // Memory, folder and tag resources whose contents changed
+}
+
+// MemoryResourceList is the JSON body of folder and tag resources
+type MemoryResourceList struct {
+	URI      string               `json:"uri"`
+	Memories []MemoryResourceItem `json:"memories"`
+}
+
+// MemoryResourceItem is a memory listed by a folder or tag resource
+type MemoryResourceItem struct {
+	URI string `json:"uri"`
+	MemoryInfo
+}
+
+// NewMemoryResourceTemplate creates the template for reading any memory by slug
+func NewMemoryResourceTemplate() mcp.ResourceTemplate {
+	return mcp.NewResourceTemplate(MemoryURIPrefix+"{slug}", "Memory",
+		mcp.WithTemplateDescription("A memory's markdown, including its frontmatter and annotations"),
+		mcp.WithTemplateMIMEType(mimeMarkdown),
+	)
+}
+
+// NewFolderResourceTemplate creates the template listing the memories in a folder
+func NewFolderResourceTemplate() mcp.ResourceTemplate {
+	return mcp.NewResourceTemplate(FolderURIPrefix+"{+path}", "Memory folder",
+		mcp.WithTemplateDescription("Live memories stored under a folder of the memory repository, most recently updated first"),
+		mcp.WithTemplateMIMEType(mimeJSON),
+	)
+}
+
+// NewTagResourceTemplate creates the template listing the memories with a tag
+func NewTagResourceTemplate() mcp.ResourceTemplate {
+	return mcp.NewResourceTemplate(TagURIPrefix+"{tag}", "Memory tag",
+		mcp.WithTemplateDescription("Live memories carrying a tag, most recently updated first"),
+		mcp.WithTemplateMIMEType(mimeJSON),
+	)
+}
+
+// NewMemoryResource describes a memory as a concrete resource
+func NewMemoryResource(mem *database.UserMemory) mcp.Resource {
+	return mcp.NewResource(MemoryURI(mem.Slug), mem.Title,
+		mcp.WithResourceDescription(fmt.Sprintf("Memory '%s'", mem.Slug)),
+		mcp.WithMIMEType(mimeMarkdown),
+	)
+}
+
+// MemoryResources describes every live memory as a resource
+func (tc *ToolContext) MemoryResources() ([]mcp.Resource, error) {
+	if tc.UserDB == nil {
+		return nil, fmt.Errorf("per-user database not available")
+	}
+
+	var memories []database.UserMemory
+	if err := tc.UserDB.Order("slug").Find(&memories).Error; err != nil {
+		return nil, err
+	}
+
+	resources := make([]mcp.Resource, len(memories))
+	for i := range memories {
+		resources[i] = NewMemoryResource(&memories[i])
+	}
+	return resources, nil
+}
+
+// MemoryResource describes a live memory as a resource
+func (tc *ToolContext) MemoryResource(slug string) (mcp.Resource, bool) {
+	var mem database.UserMemory
+	if tc.UserDB == nil || tc.UserDB.Where(querySlugEquals, slug).First(&mem).Error != nil {
+		return mcp.Resource{}, false
+	}
+	return NewMemoryResource(&mem), true
+}
+
+// MemoryResourceHandler reads a memory's markdown
+func MemoryResourceHandler(ctx *ToolContext) func(context.Context, mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+	return func(c context.Context, request mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+		slug, err := resourceName(request.Params.URI, MemoryURIPrefix)
+		if err != nil {
+			return nil, err
+		}
+		if ctx.UserDB == nil {
+			return nil, fmt.Errorf("per-user database not available")
+		}
+
+		var mem database.UserMemory
+		if err := ctx.UserDB.Where(querySlugEquals, slug).First(&mem).Error; err != nil {
+			if err == gorm.ErrRecordNotFound {
+				return nil, fmt.Errorf("memory not found: %s", slug)
+			}
+			return nil, fmt.Errorf("database error: %v", err)
+		}
+
+		content, err := os.ReadFile(mem.FilePath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read memory '%s': %v", slug, err)
+		}
+
+		return []mcp.ResourceContents{mcp.TextResourceContents{
+			URI:      request.Params.URI,
+			MIMEType: mimeMarkdown,
+			Text:     string(content),
+		}}, nil
+	}
+}
+
+// FolderResourceHandler lists the live memories under a folder
+func FolderResourceHandler(ctx *ToolContext) func(context.Context, mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+	return func(c context.Context, request mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+		folder, err := resourceName(request.Params.URI, FolderURIPrefix)
+		if err != nil {
+			return nil, err
+		}
+		folder = path.Clean(strings.Trim(folder, "/"))
+		if folder == "." || folder == ".." || strings.HasPrefix(folder, "../") {
+			return nil, fmt.Errorf("invalid folder: %s", folder)
+		}
+		if ctx.UserDB == nil {
+			return nil, fmt.Errorf("per-user database not available")
+		}
+
+		dir := filepath.Join(ctx.RepoPath, filepath.FromSlash(folder)) + string(filepath.Separator)
+		query := ctx.UserDB.Where(`file_path LIKE ? ESCAPE '\'`, escapeLike(dir)+"%")
+		return memoryListContents(request.Params.URI, query)
+	}
+}
+
+// TagResourceHandler lists the live memories carrying a tag
+func TagResourceHandler(ctx *ToolContext) func(context.Context, mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+	return func(c context.Context, request mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+		tag, err := resourceName(request.Params.URI, TagURIPrefix)
+		if err != nil {
+			return nil, err
+		}
+		if ctx.UserDB == nil {
+			return nil, fmt.Errorf("per-user database not available")
+		}
+
+		query := ctx.UserDB.Where("slug IN (SELECT memory_slug FROM memory_tags WHERE LOWER(tag_name) = ?)", strings.ToLower(tag))
+		return memoryListContents(request.Params.URI, query)
+	}
+}
+
+// resourceName returns the unescaped part of a URI after its prefix
+func resourceName(uri, prefix string) (string, error) {
+	name, ok := strings.CutPrefix(uri, prefix)
+	if !ok || name == "" {
+		return "", fmt.Errorf("invalid resource URI: %s", uri)
+	}
+	unescaped, err := url.PathUnescape(name)
+	if err != nil {
+		return "", fmt.Errorf("invalid resource URI %s: %v", uri, err)
+	}
+	return unescaped, nil
+}
+
+// memoryListContents renders the memories matched by query as a JSON resource
+func memoryListContents(uri string, query *gorm.DB) ([]mcp.ResourceContents, error) {
+	var memories []database.UserMemory
+	if err := query.Order("updated_at DESC, slug ASC").Find(&memories).Error; err != nil {
+		return nil, fmt.Errorf("database error: %v", err)
+	}
+
+	list := MemoryResourceList{URI: uri, Memories: make([]MemoryResourceItem, len(memories))}
+	for i := range memories {
+		list.Memories[i] = MemoryResourceItem{URI: MemoryURI(memories[i].Slug), MemoryInfo: newMemoryInfo(&memories[i])}
+	}
+
+	data, err := json.Marshal(list)
+	if err != nil {
+		return nil, err
+	}
+	return []mcp.ResourceContents{mcp.TextResourceContents{URI: uri, MIMEType: mimeJSON, Text: string(data)}}, nil
+}
+
+// memoryResourceURIs returns the resources showing a memory: the memory
+// itself, every folder above its file and its tags
+func (tc *ToolContext) memoryResourceURIs(slug string) []string {
+	var mem database.UserMemory
+	if tc.UserDB == nil || tc.UserDB.Unscoped().Where(querySlugEquals, slug).First(&mem).Error != nil {
+		return nil
+	}
+
+	uris := []string{MemoryURI(slug)}
+	if rel, err := filepath.Rel(tc.RepoPath, filepath.Dir(mem.FilePath)); err == nil && !strings.HasPrefix(rel, "..") {
+		for dir := filepath.ToSlash(rel); dir != "." && dir != "/"; dir = path.Dir(dir) {
+			uris = append(uris, FolderURI(dir))
+		}
+	}
+
+	var tags []string
+	tc.UserDB.Model(&database.UserMemoryTag{}).Where("memory_slug = ?", slug).Pluck("tag_name", &tags)
+	for _, tag := range tags {
+		uris = append(uris, TagURI(tag))
+	}
+	return uris
+}
+
+// watchMemory snapshots the resources showing a memory before a write and
+// returns a function that reports the change once it succeeds. Resources the
+// memory leaves, such as its old folder or removed tags, are included.
+func (tc *ToolContext) watchMemory(slug string) func(action string) {
+	if tc.OnMemoryChange == nil {
+		return func(string) {}
+	}
+
+	before := tc.memoryResourceURIs(slug)
+	return func(action string) {
+		seen := make(map[string]bool)
+		var uris []string
+		for _, uri := range append(before, tc.memoryResourceURIs(slug)...) {
+			if !seen[uri] {
+				seen[uri] = true
+				uris = append(uris, uri)
+			}
+		}
+		tc.OnMemoryChange(MemoryChange{Slug: slug, Action: action, URIs: uris})
+	}
+}
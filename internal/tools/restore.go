@@ -6,9 +6,9 @@ package tools
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
-	"path/filepath"
 	"time"
 
 	"github.com/mark3labs/mcp-go/mcp"
@@ -27,6 +27,9 @@ func NewRestoreTool() mcp.Tool {
 			mcp.Required(),
 			mcp.Description("Slug of the archived memory to restore"),
 		),
+		withSpace(),
+		withFormat(),
+		mcp.WithOutputSchema[MemoryChangeOutput](),
 	)
 }
 
@@ -38,12 +41,29 @@ func RestoreHandler(ctx *ToolContext, userID uint) func(context.Context, mcp.Cal
 		if err != nil {
 			return mcp.NewToolResultError(err.Error()), nil
 		}
+		format, err := requestFormat(request)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+
+		// Work in the requested team space, if any
+		ctx, err := ctx.forSpace(userID, request, database.SpaceRoleWriter)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
 
 		// Validate UserDB is available
 		if ctx.UserDB == nil {
 			return mcp.NewToolResultError("per-user database not available"), nil
 		}
 
+		// Hold the memory's write lock until it is restored
+		release, err := ctx.lockMemory(c, slug)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		defer release()
+
 		// Get memory including soft-deleted ones from UserDB
 		var mem database.UserMemory
 		if err := ctx.UserDB.Unscoped().Where("slug = ?", slug).First(&mem).Error; err != nil {
@@ -61,6 +81,8 @@ func RestoreHandler(ctx *ToolContext, userID uint) func(context.Context, mcp.Cal
 			return mcp.NewToolResultError(fmt.Sprintf("memory '%s' is not archived", slug)), nil
 		}
 
+		changed := ctx.watchMemory(slug)
+
 		// Read current memory content to get tags for path determination
 		var memContent *memory.Memory
 		if content, err := os.ReadFile(mem.FilePath); err == nil {
@@ -77,43 +99,35 @@ func RestoreHandler(ctx *ToolContext, userID uint) func(context.Context, mcp.Cal
 			newFilePath = organizer.GetMemoryPath(slug, []string{}, "", time.Now())
 		}
 
-		// Ensure directory exists
-		if err := os.MkdirAll(filepath.Dir(newFilePath), 0755); err != nil {
-			return mcp.NewToolResultError(fmt.Sprintf("failed to create directory: %v", err)), nil
-		}
-
-		// Move file from archive
-		oldFilePath := mem.FilePath
-		if err := os.Rename(mem.FilePath, newFilePath); err != nil {
-			return mcp.NewToolResultError(fmt.Sprintf("failed to restore file: %v", err)), nil
-		}
-
-		// Git commit
-		gitRepo, err := git.OpenRepository(ctx.RepoPath)
-		if err == nil {
-			msgFormat := git.CommitMessageFormats{}
-			_ = gitRepo.CommitAll(msgFormat.RestoreMemory(slug))
-		}
-
-		// Update database with optimistic locking (restore from soft delete)
+		// Move file from archive, commit and undo the soft delete with optimistic locking as one unit
 		now := time.Now()
-		err = locking.RetryWithBackoff(locking.MaxRetries, locking.RetryDelay, func() error {
-			return locking.UpdateWithVersionUnscoped(ctx.UserDB, "memories", slug, originalVersion, map[string]interface{}{
-				"file_path":  newFilePath,
-				"deleted_at": nil, // Remove soft delete
-				"updated_at": now,
-			})
+		msgFormat := git.CommitMessageFormats{}
+		err = ctx.applyMemoryWrite(memoryWrite{
+			path:    mem.FilePath,
+			moveTo:  newFilePath,
+			message: msgFormat.RestoreMemory(slug),
+			update: func(tx *gorm.DB) error {
+				return locking.UpdateWithVersionUnscoped(tx, "memories", slug, originalVersion, map[string]interface{}{
+					"file_path":  newFilePath,
+					"deleted_at": nil, // Remove soft delete
+					"updated_at": now,
+				})
+			},
 		})
 
 		if err != nil {
-			// Restore file to archive on conflict
-			_ = os.Rename(newFilePath, oldFilePath)
-			if _, ok := err.(*locking.ConflictError); ok {
+			var conflict *locking.ConflictError
+			if errors.As(err, &conflict) {
 				return mcp.NewToolResultError("Memory was modified by another agent. Please retry."), nil
 			}
 			return mcp.NewToolResultError(fmt.Sprintf("failed to restore in database: %v", err)), nil
 		}
 
-		return mcp.NewToolResultText(fmt.Sprintf("Memory '%s' restored to: %s", slug, newFilePath)), nil
+		// Make the restored memory searchable again
+		ctx.indexForSearch(slug, memContent)
+		ctx.queueEmbedding(slug, memContent)
+		changed("restored")
+
+		return ctx.memoryChangeResult(format, "restored", slug, fmt.Sprintf("Memory '%s' restored to: %s", slug, newFilePath)), nil
 	}
 }
@@ -0,0 +1,52 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package tools
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/tejzpr/medha-mcp/internal/database"
+	"github.com/tejzpr/medha-mcp/internal/memory"
+)
+
+// indexForSearch adds or refreshes a memory in the full-text index.
+// Failures are logged; the memory is picked up by the next backfill.
+func (tc *ToolContext) indexForSearch(slug string, mem *memory.Memory) {
+	if tc.UserDB == nil || mem == nil {
+		return
+	}
+	if err := database.IndexMemoryFTS(tc.UserDB, database.MemoryFTSDocument(slug, mem)); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to index memory %s for search: %v\n", slug, err)
+	}
+}
+
+// removeFromSearch drops a memory from the full-text index
+func (tc *ToolContext) removeFromSearch(slug string) {
+	if tc.UserDB == nil {
+		return
+	}
+	if err := database.DeleteMemoryFTS(tc.UserDB, slug); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to remove memory %s from search: %v\n", slug, err)
+	}
+}
+
+// ensureSearchIndex indexes live memories missing from the full-text index,
+// such as those written before it existed or brought in by a pull
+func (tc *ToolContext) ensureSearchIndex() error {
+	slugs, err := database.UnindexedMemorySlugs(tc.UserDB)
+	if err != nil {
+		return fmt.Errorf("failed to check search index: %w", err)
+	}
+
+	for _, slug := range slugs {
+		dbMem, err := tc.GetUserMemoryBySlug(slug)
+		if err != nil {
+			continue
+		}
+		tc.indexForSearch(slug, loadMemoryContent(dbMem.FilePath))
+	}
+	return nil
+}
@@ -0,0 +1,168 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package tools
+
+import (
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/tejzpr/medha-mcp/internal/database"
+	"github.com/tejzpr/medha-mcp/internal/embeddings"
+	"github.com/tejzpr/medha-mcp/internal/ranking"
+	"github.com/tejzpr/medha-mcp/internal/spaces"
+)
+
+// withSpace adds the space parameter shared by every tool
+func withSpace() mcp.ToolOption {
+	return mcp.WithString("space",
+		mcp.Description("Team space to work in, by name. Omit (or use 'personal') for your own memories"),
+	)
+}
+
+// forSpace returns the context for the request's space, checking the user's
+// role in it grants needed. Without a space it returns tc itself. Membership
+// is checked on every call, so removed members lose access at once.
+func (tc *ToolContext) forSpace(userID uint, request mcp.CallToolRequest, needed string) (*ToolContext, error) {
+	name := request.GetString("space", "")
+	switch name {
+	case "", spaces.Personal:
+		return tc, nil
+	case spaces.All:
+		return nil, fmt.Errorf("space '%s' is only supported by medha_recall", spaces.All)
+	}
+
+	membership, err := spaces.Resolve(tc.SystemDB, userID, name, needed)
+	if err != nil {
+		return nil, err
+	}
+	return tc.spaceContext(&membership.MedhaSpace)
+}
+
+// SpaceContexts holds one context per team space, opened on first use and
+// shared by every member's tools, so a space has one database connection and
+// one embedding indexer. Writes to its repository are serialised by
+// git.LockRepository like any other.
+type SpaceContexts struct {
+	dbMgr            *database.Manager
+	embeddingFactory *embeddings.Factory // Optional; enables semantic search in spaces
+	rankingWeights   ranking.Weights
+
+	mu       sync.Mutex
+	contexts map[uint]*ToolContext // By space ID
+}
+
+// NewSpaceContexts creates an empty set of space contexts
+func NewSpaceContexts(mgr *database.Manager, factory *embeddings.Factory, weights ranking.Weights) *SpaceContexts {
+	return &SpaceContexts{
+		dbMgr:            mgr,
+		embeddingFactory: factory,
+		rankingWeights:   weights,
+		contexts:         make(map[uint]*ToolContext),
+	}
+}
+
+// open returns the context of a team space, opening it on first use
+func (s *SpaceContexts) open(space *database.MedhaSpace) (*ToolContext, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if sc, ok := s.contexts[space.ID]; ok {
+		return sc, nil
+	}
+
+	sc, err := NewToolContextWithManager(s.dbMgr, space.RepoPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open space '%s': %w", space.Name, err)
+	}
+	sc.Space = space
+	sc.RankingWeights = s.rankingWeights
+	if s.embeddingFactory != nil {
+		if err := sc.EnableEmbeddings(s.embeddingFactory); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: Semantic search disabled for space %s: %v\n", space.Name, err)
+		}
+	}
+	s.contexts[space.ID] = sc
+	return sc, nil
+}
+
+// Close closes every open space context
+func (s *SpaceContexts) Close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for id, sc := range s.contexts {
+		if err := sc.CloseUserDB(); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to close space %s: %v\n", sc.Space.Name, err)
+		}
+		delete(s.contexts, id)
+	}
+}
+
+// spaceContext returns the shared context of a team space
+func (tc *ToolContext) spaceContext(space *database.MedhaSpace) (*ToolContext, error) {
+	if tc.Spaces == nil {
+		return nil, fmt.Errorf("team spaces are not available")
+	}
+	return tc.Spaces.open(space)
+}
+
+// spaceName labels the memories of the context: the space's name, or
+// 'personal' for the user's own
+func (tc *ToolContext) spaceName() string {
+	if tc.Space == nil {
+		return spaces.Personal
+	}
+	return tc.Space.Name
+}
+
+// repository returns the repository the context works in: the user's own as
+// recorded in the system database, or the space's in the same shape
+func (tc *ToolContext) repository(userID uint) (*database.MedhaGitRepo, error) {
+	if tc.Space != nil {
+		return &database.MedhaGitRepo{
+			RepoName:          "medha-" + tc.Space.Name,
+			RepoURL:           tc.Space.RepoURL,
+			RepoPath:          tc.Space.RepoPath,
+			PATTokenEncrypted: tc.Space.PATTokenEncrypted,
+		}, nil
+	}
+	var repo database.MedhaGitRepo
+	if err := tc.DB.Where("user_id = ?", userID).First(&repo).Error; err != nil {
+		return nil, err
+	}
+	return &repo, nil
+}
+
+// recallSources returns the contexts a recall searches: the user's own for
+// no space, every space they can read as well for 'all', or one space
+func (tc *ToolContext) recallSources(userID uint, request mcp.CallToolRequest) ([]*ToolContext, error) {
+	if request.GetString("space", "") != spaces.All {
+		sc, err := tc.forSpace(userID, request, database.SpaceRoleReader)
+		if err != nil {
+			return nil, err
+		}
+		return []*ToolContext{sc}, nil
+	}
+
+	memberships, err := spaces.ForUser(tc.SystemDB, userID)
+	if err != nil {
+		return nil, err
+	}
+	sources := []*ToolContext{tc}
+	for i := range memberships {
+		if !database.SpaceRoleAllows(memberships[i].Role, database.SpaceRoleReader) {
+			continue
+		}
+		sc, err := tc.spaceContext(&memberships[i].MedhaSpace)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: skipping space %s in recall: %v\n", memberships[i].Name, err)
+			continue
+		}
+		sources = append(sources, sc)
+	}
+	return sources, nil
+}
@@ -7,20 +7,48 @@ package tools
 import (
 	"context"
 	"fmt"
+	"os"
+	"strings"
 	"time"
 
 	"github.com/mark3labs/mcp-go/mcp"
 	"github.com/tejzpr/medha-mcp/internal/crypto"
 	"github.com/tejzpr/medha-mcp/internal/database"
 	"github.com/tejzpr/medha-mcp/internal/git"
+	"github.com/tejzpr/medha-mcp/internal/merge"
 	"github.com/tejzpr/medha-mcp/internal/rebuild"
 )
 
+// SyncOutput is the structured output of medha_sync
+type SyncOutput struct {
+	Successful        bool             `json:"successful"`
+	LastSync          time.Time        `json:"last_sync"`
+	ConflictFiles     []string         `json:"conflict_files,omitempty"`
+	ConflictsResolved bool             `json:"conflicts_resolved,omitempty"`
+	ConflictMemories  []string         `json:"conflict_memories,omitempty"` // Conflict memories written for review
+	Note              string           `json:"note,omitempty"`
+	Index             *SyncIndexOutput `json:"index,omitempty"`
+	IndexError        string           `json:"index_error,omitempty"`
+	Message           string           `json:"message"`
+}
+
+// SyncIndexOutput summarises the reindex of files a sync pulled
+type SyncIndexOutput struct {
+	MemoriesProcessed   int `json:"memories_processed"`
+	MemoriesCreated     int `json:"memories_created"`
+	MemoriesUpdated     int `json:"memories_updated"`
+	MemoriesRemoved     int `json:"memories_removed"`
+	AssociationsCreated int `json:"associations_created"`
+}
+
 // NewSyncTool creates the medha_sync tool definition
 func NewSyncTool() mcp.Tool {
 	return mcp.NewTool("medha_sync",
 		mcp.WithDescription("Manually trigger git push/pull sync"),
 		mcp.WithBoolean("force", mcp.Description("Force last-write-wins for conflicts")),
+		withSpace(),
+		withFormat(),
+		mcp.WithOutputSchema[SyncOutput](),
 	)
 }
 
@@ -28,10 +56,20 @@ func NewSyncTool() mcp.Tool {
 func SyncHandler(ctx *ToolContext, userID uint, encryptionKey []byte) func(context.Context, mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 	return func(c context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 		force := request.GetBool("force", false)
+		format, err := requestFormat(request)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+
+		// Work in the requested team space, if any
+		ctx, err := ctx.forSpace(userID, request, database.SpaceRoleWriter)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
 
-		// Get user's repo
-		var repo database.MedhaGitRepo
-		if err := ctx.DB.Where("user_id = ?", userID).First(&repo).Error; err != nil {
+		// Get the user's or the space's repo
+		repo, err := ctx.repository(userID)
+		if err != nil {
 			return mcp.NewToolResultError("repository not found"), nil
 		}
 
@@ -52,8 +90,30 @@ func SyncHandler(ctx *ToolContext, userID uint, encryptionKey []byte) func(conte
 			return mcp.NewToolResultError(fmt.Sprintf("failed to open repository: %v", err)), nil
 		}
 
-		// Perform sync
-		status, err := gitRepo.Sync(pat, force)
+		// The per-user database is not in git and stays open; sync carries its
+		// access stats and the pulled files are reindexed into it
+		var index *rebuild.Result
+		var indexErr error
+		opts := git.SyncV2Options{
+			PAT:                pat,
+			ForceLastWriteWins: force,
+		}
+		if ctx.UserDB != nil {
+			opts.IncludePerUserDB = true
+			opts.OnBeforeSync = func() error {
+				if err := database.ExportAccessStats(ctx.UserDB, repo.RepoPath); err != nil {
+					fmt.Fprintf(os.Stderr, "Warning: failed to export access stats: %v\n", err)
+				}
+				return nil
+			}
+			opts.OnAfterPull = func([]string) error {
+				index, indexErr = rebuild.IncrementalRebuildUserIndex(ctx.UserDB, repo.RepoPath)
+				return indexErr
+			}
+		}
+		unlock := git.LockRepository(repo.RepoPath)
+		status, err := gitRepo.SyncV2(opts)
+		unlock()
 		if err != nil {
 			return mcp.NewToolResultError(fmt.Sprintf("sync failed: %v", err)), nil
 		}
@@ -63,26 +123,49 @@ func SyncHandler(ctx *ToolContext, userID uint, encryptionKey []byte) func(conte
 			status.LastSync.Format(time.RFC3339),
 			status.SyncSuccessful)
 
+		out := SyncOutput{
+			Successful: status.SyncSuccessful,
+			LastSync:   status.LastSync,
+			Note:       status.Error,
+		}
+
 		if status.HasConflicts {
 			result += fmt.Sprintf("- Conflicts: %d (resolved: %v)\n", len(status.ConflictFiles), force)
+			out.ConflictFiles = status.ConflictFiles
+			out.ConflictsResolved = force
+		}
+		if len(status.ConflictMemories) > 0 {
+			result += fmt.Sprintf("- Overlapping memory edits to review: %s (recall them with filter 'tag:%s')\n",
+				strings.Join(status.ConflictMemories, ", "), merge.ConflictTag)
+			out.ConflictMemories = status.ConflictMemories
 		}
 		if status.Error != "" {
 			result += fmt.Sprintf("- Note: %s\n", status.Error)
 		}
 
-		// Auto-rebuild database index after sync to incorporate pulled changes
-		if status.SyncSuccessful {
-			rebuildResult, rebuildErr := rebuild.RebuildIndex(ctx.DB, userID, repo.ID, repo.RepoPath, rebuild.Options{Force: true})
-			if rebuildErr != nil {
-				result += fmt.Sprintf("\n- Index rebuild warning: %v\n", rebuildErr)
-			} else {
-				result += fmt.Sprintf("\n- Index rebuilt: %d memories processed, %d created, %d associations\n",
-					rebuildResult.MemoriesProcessed,
-					rebuildResult.MemoriesCreated,
-					rebuildResult.AssociationsCreated)
+		// Report the reindex of pulled files
+		if indexErr != nil {
+			out.IndexError = indexErr.Error()
+		} else if index != nil {
+			out.Index = &SyncIndexOutput{
+				MemoriesProcessed:   index.MemoriesProcessed,
+				MemoriesCreated:     index.MemoriesCreated,
+				MemoriesUpdated:     index.MemoriesUpdated,
+				MemoriesRemoved:     index.MemoriesRemoved,
+				AssociationsCreated: index.AssociationsCreated,
+			}
+			result += fmt.Sprintf("\n- Index updated: %d memory files reindexed, %d memories created, %d updated, %d removed, %d associations\n",
+				index.MemoriesProcessed,
+				index.MemoriesCreated,
+				index.MemoriesUpdated,
+				index.MemoriesRemoved,
+				index.AssociationsCreated)
+			for _, indexErr := range index.Errors {
+				fmt.Fprintf(os.Stderr, "Warning: reindex: %s\n", indexErr)
 			}
 		}
 
-		return mcp.NewToolResultText(result), nil
+		out.Message = result
+		return formatResult(format, out, result), nil
 	}
 }
@@ -5,10 +5,14 @@
 package tools
 
 import (
+	"fmt"
+	"sync"
+
 	"github.com/tejzpr/medha-mcp/internal/database"
 	"github.com/tejzpr/medha-mcp/internal/embeddings"
 	"github.com/tejzpr/medha-mcp/internal/git"
 	"github.com/tejzpr/medha-mcp/internal/memory"
+	"github.com/tejzpr/medha-mcp/internal/ranking"
 	"gorm.io/gorm"
 )
 
@@ -23,6 +27,10 @@ const (
 // - UserDB: Per-user database in .medha/medha.db inside git repo
 // - DB: Kept for backward compatibility, points to SystemDB
 // - EmbeddingService: Optional embedding service for semantic search
+// - RankingWeights: Signal weights for medha_recall (zero value uses defaults)
+// - OnMemoryChange: Optional callback told when a tool writes a memory
+// - Space: The team space the context works in; nil for the user's own repository
+// - Spaces: The contexts of team spaces, shared with other users' tools
 type ToolContext struct {
 	DB               *gorm.DB            // Backward compatibility - points to SystemDB
 	SystemDB         *gorm.DB            // Global database for users, auth, repos
@@ -30,6 +38,13 @@ type ToolContext struct {
 	RepoPath         string
 	DBMgr            *database.Manager   // Database manager for handling connections
 	EmbeddingService *embeddings.Service // Optional embedding service for semantic search
+	RankingWeights   ranking.Weights     // Reciprocal rank fusion weights for medha_recall
+	OnMemoryChange   func(MemoryChange)  // Notifies resource subscribers after remember, forget and restore
+	Space            *database.MedhaSpace // Team space of a context opened by forSpace
+	Spaces           *SpaceContexts       // Team space contexts shared by all users; nil disables spaces
+
+	embeddingIndexer *embeddings.Indexer // Embeds memories in the background as they are written
+	embeddingMu      sync.RWMutex        // Guards EmbeddingService against the background indexer
 }
 
 // NewToolContext creates a new tool context (v1 style - for testing without UserDB)
@@ -67,14 +82,61 @@ func NewToolContextWithEmbeddings(mgr *database.Manager, repoPath string, embedd
 	return ctx, nil
 }
 
+// EnableEmbeddings creates an embedding service that stores vectors in the
+// user's own database, so they travel with the repository
+func (tc *ToolContext) EnableEmbeddings(factory *embeddings.Factory) error {
+	if tc.DBMgr == nil {
+		return fmt.Errorf("embeddings require a database manager")
+	}
+
+	userDB, err := tc.DBMgr.GetUserDBWithVec(tc.RepoPath, factory.Dimensions())
+	if err != nil {
+		return fmt.Errorf("failed to open user database with vector support: %w", err)
+	}
+
+	svc, err := factory.NewUserService(userDB)
+	if err != nil {
+		return err
+	}
+
+	tc.UserDB = userDB
+	tc.embeddingMu.Lock()
+	tc.EmbeddingService = svc
+	tc.embeddingMu.Unlock()
+	if tc.embeddingIndexer == nil {
+		tc.embeddingIndexer = embeddings.NewIndexer(tc.currentEmbeddingService)
+	}
+	return nil
+}
+
+// ImportLegacyEmbeddings moves this user's vectors out of the system database,
+// where older releases stored them keyed only by slug
+func (tc *ToolContext) ImportLegacyEmbeddings() (int, error) {
+	svc := tc.currentEmbeddingService()
+	if svc == nil || tc.UserDB == nil {
+		return 0, nil
+	}
+	return svc.ImportLegacyEmbeddings(tc.SystemDB, tc.embeddingContentForSlug)
+}
+
 // SetEmbeddingService sets the embedding service for the tool context
 func (tc *ToolContext) SetEmbeddingService(svc *embeddings.Service) {
+	tc.embeddingMu.Lock()
+	defer tc.embeddingMu.Unlock()
 	tc.EmbeddingService = svc
 }
 
+// currentEmbeddingService returns the embedding service in use
+func (tc *ToolContext) currentEmbeddingService() *embeddings.Service {
+	tc.embeddingMu.RLock()
+	defer tc.embeddingMu.RUnlock()
+	return tc.EmbeddingService
+}
+
 // HasEmbeddings returns true if embedding service is available and enabled
 func (tc *ToolContext) HasEmbeddings() bool {
-	return tc.EmbeddingService != nil && tc.EmbeddingService.IsEnabled()
+	svc := tc.currentEmbeddingService()
+	return svc != nil && svc.IsEnabled()
 }
 
 // GetRepository opens the git repository for operations
@@ -102,9 +164,16 @@ func (tc *ToolContext) HasUserDB() bool {
 	return tc.UserDB != nil
 }
 
-// CloseUserDB closes the per-user database connection
-// This should be called before git sync operations
+// CloseUserDB stops the background indexer and closes the per-user database
+// connection once the context is no longer used
 func (tc *ToolContext) CloseUserDB() error {
+	// Let queued embeddings finish before their connection goes away
+	if tc.embeddingIndexer != nil {
+		tc.embeddingIndexer.Close()
+	}
+	// A scheduled sync may be reindexing through the same connection
+	unlock := git.LockRepository(tc.RepoPath)
+	defer unlock()
 	if tc.DBMgr != nil {
 		return tc.DBMgr.CloseUserDB(tc.RepoPath)
 	}
@@ -117,16 +186,3 @@ func (tc *ToolContext) CloseUserDB() error {
 	}
 	return nil
 }
-
-// ReopenUserDB reopens the per-user database connection
-// This should be called after git sync operations
-func (tc *ToolContext) ReopenUserDB() error {
-	if tc.DBMgr != nil {
-		userDB, err := tc.DBMgr.ReopenUserDB(tc.RepoPath)
-		if err != nil {
-			return err
-		}
-		tc.UserDB = userDB
-	}
-	return nil
-}
@@ -0,0 +1,99 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package tools
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/tejzpr/medha-mcp/internal/git"
+	"gorm.io/gorm"
+)
+
+// memoryWrite is one change to a memory file, applied to the working tree,
+// git and the per-user database as a unit. Take the memory's lock first.
+type memoryWrite struct {
+	path    string                  // Memory file to write, or to move
+	content []byte                  // New file content; unused when moving
+	moveTo  string                  // Destination when archiving or restoring
+	message string                  // Commit message
+	update  func(tx *gorm.DB) error // Database change, run in one transaction
+}
+
+// applyMemoryWrite writes the file, commits it and updates the database. If
+// any step fails, the file and HEAD are put back and the transaction rolls
+// back, so the error is returned with nothing changed.
+func (tc *ToolContext) applyMemoryWrite(w memoryWrite) error {
+	// Writers to other memories share the index and HEAD
+	unlock := git.LockRepository(tc.RepoPath)
+	defer unlock()
+
+	gitRepo, err := git.OpenRepository(tc.RepoPath)
+	if err != nil {
+		return fmt.Errorf("failed to open git repo: %w", err)
+	}
+	head, err := gitRepo.HeadHash()
+	if err != nil {
+		return err
+	}
+
+	previous, readErr := os.ReadFile(w.path)
+	existed := readErr == nil
+	if !existed && !os.IsNotExist(readErr) {
+		return fmt.Errorf("failed to read file: %w", readErr)
+	}
+
+	// Write or move the file
+	target := w.path
+	if w.moveTo != "" {
+		target = w.moveTo
+	}
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+	if w.moveTo != "" {
+		if err := os.Rename(w.path, w.moveTo); err != nil {
+			return fmt.Errorf("failed to move file: %w", err)
+		}
+	} else if err := os.WriteFile(w.path, w.content, 0644); err != nil {
+		return fmt.Errorf("failed to write file: %w", err)
+	}
+
+	rollback := func() {
+		var err error
+		switch {
+		case w.moveTo != "":
+			err = os.Rename(w.moveTo, w.path)
+		case existed:
+			err = os.WriteFile(w.path, previous, 0644)
+		default:
+			err = os.Remove(w.path)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to restore %s: %v\n", w.path, err)
+		}
+		if err := gitRepo.ResetHead(head); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to reset git after a failed write: %v\n", err)
+		}
+	}
+
+	// Moves stage the removal as well as the new file
+	if w.moveTo != "" {
+		err = gitRepo.CommitFiles([]string{w.path, w.moveTo}, w.message)
+	} else {
+		err = gitRepo.CommitFile(w.path, w.message)
+	}
+	if err != nil {
+		rollback()
+		return fmt.Errorf("failed to commit: %w", err)
+	}
+
+	if err := tc.UserDB.Transaction(w.update); err != nil {
+		rollback()
+		return err
+	}
+	return nil
+}
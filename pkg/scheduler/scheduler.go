@@ -11,21 +11,24 @@ import (
 	"github.com/tejzpr/medha-mcp/internal/crypto"
 	"github.com/tejzpr/medha-mcp/internal/database"
 	"github.com/tejzpr/medha-mcp/internal/git"
+	"github.com/tejzpr/medha-mcp/internal/rebuild"
 	"gorm.io/gorm"
 )
 
 // Scheduler handles periodic git sync operations
 type Scheduler struct {
 	db            *gorm.DB
+	dbMgr         *database.Manager
 	interval      time.Duration
 	encryptionKey []byte
 	stopChan      chan bool
 }
 
 // NewScheduler creates a new scheduler
-func NewScheduler(db *gorm.DB, intervalMinutes int, encryptionKey []byte) *Scheduler {
+func NewScheduler(dbMgr *database.Manager, intervalMinutes int, encryptionKey []byte) *Scheduler {
 	return &Scheduler{
-		db:            db,
+		db:            dbMgr.SystemDB(),
+		dbMgr:         dbMgr,
 		interval:      time.Duration(intervalMinutes) * time.Minute,
 		encryptionKey: encryptionKey,
 		stopChan:      make(chan bool),
@@ -53,7 +56,7 @@ func (s *Scheduler) Stop() {
 	s.stopChan <- true
 }
 
-// syncAllRepositories syncs all user repositories
+// syncAllRepositories syncs all user and team space repositories
 func (s *Scheduler) syncAllRepositories() {
 	var repos []database.MedhaGitRepo
 	if err := s.db.Where("pat_token_encrypted != ''").Find(&repos).Error; err != nil {
@@ -62,27 +65,68 @@ func (s *Scheduler) syncAllRepositories() {
 	}
 
 	for _, repo := range repos {
-		if err := s.syncRepository(&repo); err != nil {
+		if err := s.syncRepository(repo.RepoName, repo.RepoPath, repo.PATTokenEncrypted); err != nil {
 			log.Printf("Failed to sync repo %s: %v", repo.RepoName, err)
 		}
 	}
+
+	var spaces []database.MedhaSpace
+	if err := s.db.Where("pat_token_encrypted != ''").Find(&spaces).Error; err != nil {
+		log.Printf("Failed to fetch spaces: %v", err)
+		return
+	}
+
+	for _, space := range spaces {
+		if err := s.syncRepository("space "+space.Name, space.RepoPath, space.PATTokenEncrypted); err != nil {
+			log.Printf("Failed to sync space %s: %v", space.Name, err)
+		}
+	}
 }
 
 // syncRepository syncs a single repository
-func (s *Scheduler) syncRepository(repo *database.MedhaGitRepo) error {
+func (s *Scheduler) syncRepository(name, repoPath, encryptedPAT string) error {
+	// Hold off tool writes, and the closing of the database, until done
+	unlock := git.LockRepository(repoPath)
+	defer unlock()
+
 	// Decrypt PAT
-	pat, err := crypto.DecryptPAT(repo.PATTokenEncrypted, s.encryptionKey)
+	pat, err := crypto.DecryptPAT(encryptedPAT, s.encryptionKey)
 	if err != nil {
 		return err
 	}
 
 	// Open repository
-	gitRepo, err := git.OpenRepository(repo.RepoPath)
+	gitRepo, err := git.OpenRepository(repoPath)
+	if err != nil {
+		return err
+	}
+
+	// The per-user database is not in git and stays open for the tools
+	// sharing it; sync carries its access stats and reindexes pulled files
+	userDB, err := s.dbMgr.GetUserDB(repoPath)
 	if err != nil {
 		return err
 	}
 
 	// Sync with last-write-wins
-	_, err = gitRepo.Sync(pat, true)
+	_, err = gitRepo.SyncV2(git.SyncV2Options{
+		PAT:                pat,
+		ForceLastWriteWins: true,
+		IncludePerUserDB:   true,
+		OnBeforeSync: func() error {
+			return database.ExportAccessStats(userDB, repoPath)
+		},
+		OnAfterPull: func([]string) error {
+			result, err := rebuild.IncrementalRebuildUserIndex(userDB, repoPath)
+			if err != nil {
+				log.Printf("Failed to reindex %s: %v", name, err)
+				return err
+			}
+			for _, reindexErr := range result.Errors {
+				log.Printf("Reindex of %s: %s", name, reindexErr)
+			}
+			return nil
+		},
+	})
 	return err
 }
@@ -41,7 +41,7 @@ func TestLocalAuthFlow(t *testing.T) {
 	t.Log("✓ Database initialized")
 
 	// 2. Local authentication (uses whoami)
-	tokenManager := auth.NewTokenManager(db, 24)
+	tokenManager := auth.NewTokenManager(db, 24, make([]byte, 32))
 	localAuth := auth.NewLocalAuthenticator(tokenManager)
 
 	user, token, err := localAuth.Authenticate(db)
@@ -0,0 +1,217 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package integration
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/client"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tejzpr/medha-mcp/internal/database"
+)
+
+// apiTokenRequest sends an authenticated request to the API token endpoints
+func apiTokenRequest(t *testing.T, method, url, token string, body interface{}) *http.Response {
+	var payload bytes.Buffer
+	if body != nil {
+		require.NoError(t, json.NewEncoder(&payload).Encode(body))
+	}
+	req, err := http.NewRequest(method, url, &payload)
+	require.NoError(t, err)
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	t.Cleanup(func() { resp.Body.Close() })
+	return resp
+}
+
+// createAPIToken creates an API token over HTTP with a login token
+func createAPIToken(t *testing.T, baseURL, loginToken string, body map[string]interface{}) database.MedhaAPIToken {
+	resp := apiTokenRequest(t, http.MethodPost, baseURL+"/api/tokens", loginToken, body)
+	require.Equal(t, http.StatusCreated, resp.StatusCode)
+
+	var token database.MedhaAPIToken
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&token))
+	require.NotEmpty(t, token.Token)
+	return token
+}
+
+// listedToolNames returns the names of the tools a client can see
+func listedToolNames(t *testing.T, c *client.Client) []string {
+	tools, err := c.ListTools(context.Background(), mcp.ListToolsRequest{})
+	require.NoError(t, err)
+	var names []string
+	for _, tool := range tools.Tools {
+		names = append(names, tool.Name)
+	}
+	return names
+}
+
+// TestAPIToken_ReadOnlyScope verifies a memories:read token can recall but
+// cannot change memories or sync
+func TestAPIToken_ReadOnlyScope(t *testing.T) {
+	ts, dbMgr, tokenManager := setupHTTPServer(t)
+	alice := createHTTPTestUser(t, dbMgr, tokenManager, "alice")
+
+	owner := newHTTPMCPClient(t, ts.URL, alice)
+	text, isErr := callHTTPTool(t, owner, "medha_remember", map[string]interface{}{
+		"title":   "Deploy Runbook",
+		"content": "Deploys go out on Tuesdays",
+		"slug":    "deploy-runbook",
+	})
+	require.False(t, isErr, text)
+
+	apiToken := createAPIToken(t, ts.URL, alice.Token, map[string]interface{}{
+		"name":   "ci-bot",
+		"scopes": []string{"memories:read"},
+	})
+	bot := newHTTPMCPClient(t, ts.URL, &httpTestUser{User: alice.User, Token: apiToken.Token})
+
+	assert.ElementsMatch(t, []string{"medha_recall", "medha_history", "medha_graph"}, listedToolNames(t, bot))
+
+	text, isErr = callHTTPTool(t, bot, "medha_recall", map[string]interface{}{"topic": "Deploy Runbook"})
+	require.False(t, isErr, text)
+	assert.Contains(t, text, "deploy-runbook")
+
+	for _, tool := range []string{"medha_forget", "medha_remember", "medha_sync"} {
+		text, isErr = callHTTPTool(t, bot, tool, map[string]interface{}{"slug": "deploy-runbook", "title": "x", "content": "x"})
+		assert.True(t, isErr, tool)
+		assert.Contains(t, text, "not allowed to call "+tool)
+	}
+
+	// The memory was not archived
+	text, isErr = callHTTPTool(t, owner, "medha_recall", map[string]interface{}{"topic": "Deploy Runbook"})
+	require.False(t, isErr, text)
+	assert.Contains(t, text, "deploy-runbook")
+
+	// Resources are readable with memories:read
+	req := mcp.ReadResourceRequest{}
+	req.Params.URI = "medha://memory/deploy-runbook"
+	_, err := bot.ReadResource(context.Background(), req)
+	assert.NoError(t, err)
+}
+
+// TestAPIToken_ToolAllowList verifies a token limited to named tools can call
+// only those and cannot read memories through resources or prompts
+func TestAPIToken_ToolAllowList(t *testing.T) {
+	ts, dbMgr, tokenManager := setupHTTPServer(t)
+	alice := createHTTPTestUser(t, dbMgr, tokenManager, "alice")
+
+	owner := newHTTPMCPClient(t, ts.URL, alice)
+	text, isErr := callHTTPTool(t, owner, "medha_remember", map[string]interface{}{
+		"title":   "Secret Plan",
+		"content": "Only for the owner",
+		"slug":    "secret-plan",
+	})
+	require.False(t, isErr, text)
+
+	apiToken := createAPIToken(t, ts.URL, alice.Token, map[string]interface{}{
+		"name":  "writer",
+		"tools": []string{"medha_remember"},
+	})
+	bot := newHTTPMCPClient(t, ts.URL, &httpTestUser{User: alice.User, Token: apiToken.Token})
+
+	assert.Equal(t, []string{"medha_remember"}, listedToolNames(t, bot))
+
+	text, isErr = callHTTPTool(t, bot, "medha_remember", map[string]interface{}{
+		"title":   "Build Result",
+		"content": "Build 42 passed",
+		"slug":    "build-result",
+	})
+	require.False(t, isErr, text)
+
+	text, isErr = callHTTPTool(t, bot, "medha_recall", map[string]interface{}{"topic": "Secret Plan"})
+	assert.True(t, isErr)
+	assert.NotContains(t, text, "secret-plan")
+
+	req := mcp.ReadResourceRequest{}
+	req.Params.URI = "medha://memory/secret-plan"
+	_, err := bot.ReadResource(context.Background(), req)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "memories:read")
+
+	_, err = bot.ListResources(context.Background(), mcp.ListResourcesRequest{})
+	assert.Error(t, err)
+
+	promptReq := mcp.GetPromptRequest{}
+	promptReq.Params.Name = "summarize"
+	promptReq.Params.Arguments = map[string]string{"topic": "Secret Plan"}
+	_, err = bot.GetPrompt(context.Background(), promptReq)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "memories:read")
+}
+
+// TestAPIToken_Management verifies users list and revoke their tokens with a
+// login token, and that API tokens cannot manage tokens
+func TestAPIToken_Management(t *testing.T) {
+	ts, dbMgr, tokenManager := setupHTTPServer(t)
+	alice := createHTTPTestUser(t, dbMgr, tokenManager, "alice")
+	bob := createHTTPTestUser(t, dbMgr, tokenManager, "bob")
+
+	apiToken := createAPIToken(t, ts.URL, alice.Token, map[string]interface{}{
+		"name":            "laptop",
+		"scopes":          []string{"memories:read", "memories:write", "sync"},
+		"expires_in_days": 30,
+	})
+	require.NotNil(t, apiToken.ExpiresAt)
+
+	// Invalid requests are rejected
+	for _, body := range []map[string]interface{}{
+		{"name": "laptop", "scopes": []string{"sync"}},
+		{"name": "bad-scope", "scopes": []string{"admin"}},
+		{"name": "bad-tool", "tools": []string{"medha_drop_tables"}},
+		{"name": "nothing"},
+	} {
+		resp := apiTokenRequest(t, http.MethodPost, ts.URL+"/api/tokens", alice.Token, body)
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode, body["name"])
+	}
+
+	// API tokens cannot mint or list tokens, even with every scope
+	resp := apiTokenRequest(t, http.MethodPost, ts.URL+"/api/tokens", apiToken.Token, map[string]interface{}{
+		"name": "escalated", "scopes": []string{"sync"},
+	})
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+	resp = apiTokenRequest(t, http.MethodGet, ts.URL+"/api/tokens", apiToken.Token, nil)
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+
+	// Every tool belongs to a scope, so all scopes allow all tools. Using the
+	// token records when it was last used.
+	bot := newHTTPMCPClient(t, ts.URL, &httpTestUser{User: alice.User, Token: apiToken.Token})
+	assert.ElementsMatch(t, listedToolNames(t, newHTTPMCPClient(t, ts.URL, alice)), listedToolNames(t, bot))
+
+	resp = apiTokenRequest(t, http.MethodGet, ts.URL+"/api/tokens", alice.Token, nil)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	var listed []map[string]interface{}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&listed))
+	require.Len(t, listed, 1)
+	assert.Equal(t, "laptop", listed[0]["name"])
+	assert.NotEmpty(t, listed[0]["last_used_at"])
+	assert.NotContains(t, listed[0], "token")
+
+	// Other users cannot see or revoke it
+	resp = apiTokenRequest(t, http.MethodGet, ts.URL+"/api/tokens", bob.Token, nil)
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&listed))
+	assert.Empty(t, listed)
+	revokeURL := fmt.Sprintf("%s/api/tokens/%d", ts.URL, apiToken.ID)
+	resp = apiTokenRequest(t, http.MethodDelete, revokeURL, bob.Token, nil)
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+	resp = apiTokenRequest(t, http.MethodDelete, revokeURL, alice.Token, nil)
+	assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+
+	resp, err := http.Post(ts.URL+"/mcp?access_token="+apiToken.Token, "application/json", bytes.NewReader([]byte(`{"jsonrpc":"2.0","id":1,"method":"ping"}`)))
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+}
@@ -0,0 +1,299 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package integration
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tejzpr/medha-mcp/internal/database"
+	"github.com/tejzpr/medha-mcp/internal/embeddings"
+	"github.com/tejzpr/medha-mcp/internal/git"
+	"github.com/tejzpr/medha-mcp/internal/tools"
+)
+
+// testEmbeddingDimensions keeps test vectors small
+const testEmbeddingDimensions = 8
+
+// newTestEmbeddingFactory creates a factory whose vectors depend on the text length
+func newTestEmbeddingFactory() *embeddings.Factory {
+	embed := func(text string) ([]float32, error) {
+		v := make([]float32, testEmbeddingDimensions)
+		v[0] = float32(len(text))
+		v[1] = 1
+		return v, nil
+	}
+	client := &embeddings.MockClient{
+		EmbedFunc: embed,
+		EmbedBatchFunc: func(texts []string) ([][]float32, error) {
+			vectors := make([][]float32, len(texts))
+			for i, text := range texts {
+				vectors[i], _ = embed(text)
+			}
+			return vectors, nil
+		},
+		ModelInfo: embeddings.ModelInfo{Name: "mock-model", Version: "v1", Dimensions: testEmbeddingDimensions, Provider: "mock"},
+	}
+	return embeddings.NewFactory(client, "mock-model", testEmbeddingDimensions)
+}
+
+// embeddingUser is a user with their own repository and tool context
+type embeddingUser struct {
+	ID      uint
+	ToolCtx *tools.ToolContext
+}
+
+// newEmbeddingUser creates a user, their git repository and a tool context
+func newEmbeddingUser(t *testing.T, mgr *database.Manager, name string) *embeddingUser {
+	repoPath := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.MkdirAll(repoPath, 0755))
+	_, err := git.InitRepository(repoPath)
+	require.NoError(t, err)
+
+	user := &database.MedhaUser{Username: name}
+	require.NoError(t, mgr.SystemDB().Create(user).Error)
+	require.NoError(t, mgr.SystemDB().Create(&database.MedhaGitRepo{
+		UserID:   user.ID,
+		RepoUUID: name,
+		RepoName: "medha-" + name,
+		RepoPath: repoPath,
+	}).Error)
+
+	toolCtx, err := tools.NewToolContextWithManager(mgr, repoPath)
+	require.NoError(t, err)
+	return &embeddingUser{ID: user.ID, ToolCtx: toolCtx}
+}
+
+// rememberForEmbedding stores a memory through the remember tool
+func rememberForEmbedding(t *testing.T, u *embeddingUser, slug, title, content string) {
+	request := mcp.CallToolRequest{}
+	request.Params.Arguments = map[string]interface{}{
+		"title":   title,
+		"content": content,
+		"slug":    slug,
+	}
+	result, err := tools.RememberHandler(u.ToolCtx, u.ID)(context.Background(), request)
+	require.NoError(t, err)
+	require.False(t, result.IsError, getResultText(result))
+}
+
+// TestEmbeddings_StoredPerUser verifies vectors live in each user's own database
+func TestEmbeddings_StoredPerUser(t *testing.T) {
+	mgr, _, _, cleanup := setupPerUserTestContext(t)
+	defer cleanup()
+
+	factory := newTestEmbeddingFactory()
+	alice := newEmbeddingUser(t, mgr, "alice").ToolCtx
+	bob := newEmbeddingUser(t, mgr, "bob").ToolCtx
+	require.NoError(t, alice.EnableEmbeddings(factory))
+	require.NoError(t, bob.EnableEmbeddings(factory))
+
+	// Same slug, different content
+	_, err := alice.EmbeddingService.GetEmbedding("shared-plan", "alice roadmap")
+	require.NoError(t, err)
+	_, err = bob.EmbeddingService.GetEmbedding("shared-plan", "bob has much longer private notes")
+	require.NoError(t, err)
+
+	aliceEmb, err := alice.EmbeddingService.GetCachedEmbedding("shared-plan")
+	require.NoError(t, err)
+	bobEmb, err := bob.EmbeddingService.GetCachedEmbedding("shared-plan")
+	require.NoError(t, err)
+
+	assert.Equal(t, embeddings.CalculateContentHash("alice roadmap"), aliceEmb.ContentHash)
+	assert.Equal(t, embeddings.CalculateContentHash("bob has much longer private notes"), bobEmb.ContentHash)
+	assert.Equal(t, embeddings.ModelVersion("mock-model", testEmbeddingDimensions), aliceEmb.ModelVersion)
+
+	// Nothing is written to the shared system database
+	assert.False(t, mgr.SystemDB().Migrator().HasTable(&embeddings.Embedding{}))
+
+	// The vectors are in the repository's .medha/medha.db
+	var count int64
+	require.NoError(t, alice.UserDB.Model(&embeddings.Embedding{}).Count(&count).Error)
+	assert.Equal(t, int64(1), count)
+}
+
+// TestEmbeddings_ImportLegacyRows verifies system DB vectors are imported only for matching content
+func TestEmbeddings_ImportLegacyRows(t *testing.T) {
+	mgr, _, _, cleanup := setupPerUserTestContext(t)
+	defer cleanup()
+
+	aliceUser := newEmbeddingUser(t, mgr, "alice")
+	bobUser := newEmbeddingUser(t, mgr, "bob")
+	rememberForEmbedding(t, aliceUser, "shared-plan", "Shared Plan", "alice roadmap")
+	rememberForEmbedding(t, bobUser, "shared-plan", "Shared Plan", "bob notes")
+	alice, bob := aliceUser.ToolCtx, bobUser.ToolCtx
+
+	// Legacy rows written by older releases, keyed only by slug
+	aliceText := "Shared Plan\n\nalice roadmap"
+	require.NoError(t, embeddings.MigrateEmbeddings(mgr.SystemDB()))
+	require.NoError(t, mgr.SystemDB().Create(&embeddings.Embedding{
+		Slug:         "shared-plan",
+		ContentHash:  embeddings.CalculateContentHash(aliceText),
+		ModelName:    "mock-model",
+		ModelVersion: "v1",
+		Dimensions:   testEmbeddingDimensions,
+		Vector:       embeddings.Float32SliceToBlob(make([]float32, testEmbeddingDimensions)),
+		CreatedAt:    time.Now(),
+	}).Error)
+
+	factory := newTestEmbeddingFactory()
+	require.NoError(t, alice.EnableEmbeddings(factory))
+	require.NoError(t, bob.EnableEmbeddings(factory))
+
+	imported, err := alice.ImportLegacyEmbeddings()
+	require.NoError(t, err)
+	assert.Equal(t, 1, imported)
+
+	// Bob's content differs, so Alice's vector is never adopted
+	imported, err = bob.ImportLegacyEmbeddings()
+	require.NoError(t, err)
+	assert.Equal(t, 0, imported)
+
+	// Importing again is a no-op
+	imported, err = alice.ImportLegacyEmbeddings()
+	require.NoError(t, err)
+	assert.Equal(t, 0, imported)
+
+	stale, err := alice.EmbeddingService.IsStale("shared-plan", aliceText)
+	require.NoError(t, err)
+	assert.False(t, stale)
+
+	count, err := bob.EmbeddingService.CountEmbeddings()
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), count)
+}
+
+// TestEmbeddings_VecTableFollowsModelDimensions verifies switching models recreates the vec0 table
+func TestEmbeddings_VecTableFollowsModelDimensions(t *testing.T) {
+	mgr, repoPath, _, cleanup := setupPerUserTestContext(t)
+	defer cleanup()
+
+	db, err := mgr.GetUserDBWithVec(repoPath, 8)
+	require.NoError(t, err)
+	if !database.IsVecAvailable(db) {
+		t.Skip("sqlite-vec not available")
+	}
+
+	require.NoError(t, embeddings.MigrateVecEmbeddings(db, 8))
+	require.NoError(t, embeddings.InsertVecEmbedding(db, "note", make([]float32, 8)))
+
+	// A 4-dimension model cannot write into an 8-dimension table
+	require.NoError(t, embeddings.MigrateVecEmbeddings(db, 4))
+	require.NoError(t, embeddings.InsertVecEmbedding(db, "note", []float32{1, 0, 0, 0}))
+}
+
+// TestEmbeddings_IndexedOnWrite verifies remember, update and restore embed in the background
+func TestEmbeddings_IndexedOnWrite(t *testing.T) {
+	mgr, _, _, cleanup := setupPerUserTestContext(t)
+	defer cleanup()
+
+	u := newEmbeddingUser(t, mgr, "alice")
+	require.NoError(t, u.ToolCtx.EnableEmbeddings(newTestEmbeddingFactory()))
+	svc := u.ToolCtx.EmbeddingService
+
+	rememberForEmbedding(t, u, "plan", "Plan", "first draft")
+	u.ToolCtx.WaitForEmbeddings()
+
+	hash, err := svc.GetContentHash("plan")
+	require.NoError(t, err)
+	assert.Equal(t, embeddings.CalculateContentHash("Plan\n\nfirst draft"), hash)
+
+	// Updating the content recomputes the vector
+	rememberForEmbedding(t, u, "plan", "Plan", "second draft")
+	u.ToolCtx.WaitForEmbeddings()
+
+	hash, err = svc.GetContentHash("plan")
+	require.NoError(t, err)
+	assert.Equal(t, embeddings.CalculateContentHash("Plan\n\nsecond draft"), hash)
+
+	// Archive, drop the vector, and restore: the memory is embedded again
+	forget := mcp.CallToolRequest{}
+	forget.Params.Arguments = map[string]interface{}{"slug": "plan"}
+	result, err := tools.ForgetHandler(u.ToolCtx, u.ID)(context.Background(), forget)
+	require.NoError(t, err)
+	require.False(t, result.IsError, getResultText(result))
+	require.NoError(t, svc.DeleteEmbedding("plan"))
+
+	result, err = tools.RestoreHandler(u.ToolCtx, u.ID)(context.Background(), forget)
+	require.NoError(t, err)
+	require.False(t, result.IsError, getResultText(result))
+	u.ToolCtx.WaitForEmbeddings()
+
+	stale, err := svc.IsStale("plan", "Plan\n\nsecond draft")
+	require.NoError(t, err)
+	assert.False(t, stale)
+}
+
+// TestEmbeddings_ReindexBackfillsExistingMemories verifies memories written before embeddings were enabled are backfilled
+func TestEmbeddings_ReindexBackfillsExistingMemories(t *testing.T) {
+	mgr, _, _, cleanup := setupPerUserTestContext(t)
+	defer cleanup()
+
+	u := newEmbeddingUser(t, mgr, "alice")
+	rememberForEmbedding(t, u, "old-one", "Old One", "written before semantic search")
+	rememberForEmbedding(t, u, "old-two", "Old Two", "also written before")
+
+	require.NoError(t, u.ToolCtx.EnableEmbeddings(newTestEmbeddingFactory()))
+
+	var progress []int
+	result, err := u.ToolCtx.ReindexEmbeddings(1, func(done, total int) {
+		progress = append(progress, done)
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 2, result.Total)
+	assert.Equal(t, 2, result.Embedded)
+	assert.Equal(t, []int{1, 2}, progress)
+
+	// A second run has nothing to do
+	result, err = u.ToolCtx.ReindexEmbeddings(1, nil)
+	require.NoError(t, err)
+	assert.Equal(t, 0, result.Embedded)
+	assert.Equal(t, 2, result.Fresh)
+}
+
+// TestEmbeddings_ServiceSwapsDuringRecall verifies recall reads the embedding
+// service through its lock; run with -race
+func TestEmbeddings_ServiceSwapsDuringRecall(t *testing.T) {
+	mgr, _, _, cleanup := setupPerUserTestContext(t)
+	defer cleanup()
+
+	u := newEmbeddingUser(t, mgr, "alice")
+	require.NoError(t, u.ToolCtx.EnableEmbeddings(newTestEmbeddingFactory()))
+	svc := u.ToolCtx.EmbeddingService
+	rememberForEmbedding(t, u, "plan", "Plan", "first draft")
+
+	stop, done := make(chan struct{}), make(chan struct{})
+	go func() {
+		defer close(done)
+		for {
+			select {
+			case <-stop:
+				u.ToolCtx.SetEmbeddingService(svc)
+				return
+			default:
+				u.ToolCtx.SetEmbeddingService(nil)
+				u.ToolCtx.SetEmbeddingService(svc)
+			}
+		}
+	}()
+
+	recall := tools.RecallHandler(u.ToolCtx, u.ID)
+	for i := 0; i < 20; i++ {
+		request := mcp.CallToolRequest{}
+		request.Params.Arguments = map[string]interface{}{"topic": "draft"}
+		result, err := recall(context.Background(), request)
+		require.NoError(t, err)
+		assert.False(t, result.IsError, getResultText(result))
+	}
+	close(stop)
+	<-done
+	u.ToolCtx.WaitForEmbeddings()
+}
@@ -0,0 +1,120 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package integration
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tejzpr/medha-mcp/internal/tools"
+)
+
+// rememberConflict calls medha_remember expecting a rejected update and
+// returns its structured output
+func rememberConflict(t *testing.T, setup *testSetup, args map[string]interface{}) (tools.MemoryChangeOutput, string) {
+	request := mcp.CallToolRequest{}
+	request.Params.Arguments = args
+	result, err := tools.RememberHandler(setup.ToolCtx, setup.User.ID)(context.Background(), request)
+	require.NoError(t, err)
+	require.True(t, result.IsError, getResultText(result))
+	require.NotNil(t, result.StructuredContent)
+
+	data, err := json.Marshal(result.StructuredContent)
+	require.NoError(t, err)
+	var out tools.MemoryChangeOutput
+	require.NoError(t, json.Unmarshal(data, &out))
+	require.NotNil(t, out.Conflict)
+	assert.Equal(t, "conflict", out.Action)
+	return out, getResultText(result)
+}
+
+func TestExpectedVersion_RejectsStaleUpdateWithMerge(t *testing.T) {
+	setup := setupTestEnvironment(t)
+	defer setup.Cleanup()
+	rememberResource(t, setup, map[string]interface{}{
+		"slug": "cas-doc", "title": "CAS Doc", "content": "Line A\nLine B\nLine C",
+	})
+
+	// Both agents read version 1
+	var recall tools.RecallOutput
+	callJSON(t, tools.RecallHandler(setup.ToolCtx, setup.User.ID), map[string]interface{}{"exact": "cas-doc"}, &recall)
+	require.Len(t, recall.Results, 1)
+	read := recall.Results[0].Version
+	assert.Equal(t, int64(1), read)
+
+	text := recallText(t, setup, map[string]interface{}{"exact": "cas-doc"})
+	assert.Contains(t, text, "**Version**: 1")
+
+	// The first agent's update succeeds and records version 2 in the file
+	var out tools.MemoryChangeOutput
+	callJSON(t, tools.RememberHandler(setup.ToolCtx, setup.User.ID), map[string]interface{}{
+		"slug": "cas-doc", "title": "CAS Doc", "content": "Line A changed\nLine B\nLine C", "expected_version": float64(read),
+	}, &out)
+	require.NotNil(t, out.Memory)
+	assert.Equal(t, int64(2), out.Memory.Version)
+	file, err := os.ReadFile(out.Memory.FilePath)
+	require.NoError(t, err)
+	assert.Contains(t, string(file), "version: 2")
+
+	// The second agent's update from version 1 is rejected with a merge
+	conflict, text := rememberConflict(t, setup, map[string]interface{}{
+		"slug": "cas-doc", "title": "CAS Doc", "content": "Line A\nLine B\nLine C changed", "expected_version": float64(read),
+	})
+	assert.Equal(t, int64(1), conflict.Conflict.ExpectedVersion)
+	assert.Equal(t, int64(2), conflict.Conflict.CurrentVersion)
+	assert.Equal(t, "Line A changed\nLine B\nLine C", conflict.Conflict.CurrentContent)
+	assert.Equal(t, int64(1), conflict.Conflict.BaseVersion)
+	assert.Equal(t, "Line A changed\nLine B\nLine C changed", conflict.Conflict.MergedContent)
+	assert.False(t, conflict.Conflict.MergeConflicts)
+	assert.Contains(t, text, "## Suggested merge (from version 1, merged cleanly)")
+	assert.Contains(t, text, "expected_version 2")
+
+	mem, err := setup.ToolCtx.GetUserMemoryBySlug("cas-doc")
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), mem.Version, "nothing was written")
+
+	// Retrying with the current version stores the merge
+	callJSON(t, tools.RememberHandler(setup.ToolCtx, setup.User.ID), map[string]interface{}{
+		"slug": "cas-doc", "title": "CAS Doc", "content": conflict.Conflict.MergedContent, "expected_version": float64(2),
+	}, &out)
+	assert.Equal(t, int64(3), out.Memory.Version)
+}
+
+func TestExpectedVersion_CreateOnlyAndMissing(t *testing.T) {
+	setup := setupTestEnvironment(t)
+	defer setup.Cleanup()
+
+	var out tools.MemoryChangeOutput
+	callJSON(t, tools.RememberHandler(setup.ToolCtx, setup.User.ID), map[string]interface{}{
+		"slug": "cas-new", "title": "CAS New", "content": "First", "expected_version": float64(0),
+	}, &out)
+	assert.Equal(t, "created", out.Action)
+
+	// A second create-only call finds it already exists
+	conflict, _ := rememberConflict(t, setup, map[string]interface{}{
+		"slug": "cas-new", "title": "CAS New", "content": "Second", "expected_version": float64(0), "format": "json",
+	})
+	assert.Equal(t, int64(1), conflict.Conflict.CurrentVersion)
+	assert.Equal(t, "First", conflict.Conflict.CurrentContent)
+	assert.Empty(t, conflict.Conflict.MergedContent)
+	assert.Contains(t, conflict.Message, "already exists")
+
+	for _, args := range []map[string]interface{}{
+		{"slug": "cas-missing", "title": "CAS Missing", "content": "Body", "expected_version": float64(4)},
+		{"slug": "cas-new", "title": "CAS New", "content": "Body", "expected_version": float64(-1)},
+	} {
+		request := mcp.CallToolRequest{}
+		request.Params.Arguments = args
+		result, err := tools.RememberHandler(setup.ToolCtx, setup.User.ID)(context.Background(), request)
+		require.NoError(t, err)
+		assert.True(t, result.IsError, args)
+		assert.Nil(t, result.StructuredContent, args)
+	}
+}
@@ -0,0 +1,152 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package integration
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tejzpr/medha-mcp/internal/database"
+	"github.com/tejzpr/medha-mcp/internal/rebuild"
+	"github.com/tejzpr/medha-mcp/internal/tools"
+	"gorm.io/gorm"
+)
+
+// ftsSearchSlugs queries the full-text index directly
+func ftsSearchSlugs(t *testing.T, db *gorm.DB, query string) []string {
+	hits, err := database.SearchFTS(db, query, 50)
+	require.NoError(t, err)
+	slugs := make([]string, len(hits))
+	for i, h := range hits {
+		slugs[i] = h.Slug
+	}
+	return slugs
+}
+
+// callSlugTool runs a tool that takes a single slug argument
+func callSlugTool(t *testing.T, handler func(context.Context, mcp.CallToolRequest) (*mcp.CallToolResult, error), slug string) {
+	request := mcp.CallToolRequest{}
+	request.Params.Arguments = map[string]interface{}{"slug": slug}
+	result, err := handler(context.Background(), request)
+	require.NoError(t, err)
+	require.False(t, result.IsError, getResultText(result))
+}
+
+func TestFTS_SyncedByWriteTools(t *testing.T) {
+	setup := setupTestEnvironment(t)
+	defer setup.Cleanup()
+	db := setup.ToolCtx.UserDB
+	remember := tools.RememberHandler(setup.ToolCtx, setup.User.ID)
+
+	request := mcp.CallToolRequest{}
+	request.Params.Arguments = map[string]interface{}{
+		"slug": "deploy", "title": "Deploy process", "content": "Blue green rollout", "tags": []interface{}{"ops"},
+	}
+	_, err := remember(context.Background(), request)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"deploy"}, ftsSearchSlugs(t, db, "rollout"))
+	assert.Equal(t, []string{"deploy"}, ftsSearchSlugs(t, db, "ops"))
+
+	// Updates replace the indexed text
+	request.Params.Arguments = map[string]interface{}{"slug": "deploy", "title": "Deploy process", "content": "Canary releases"}
+	_, err = remember(context.Background(), request)
+	require.NoError(t, err)
+	assert.Empty(t, ftsSearchSlugs(t, db, "rollout"))
+	assert.Equal(t, []string{"deploy"}, ftsSearchSlugs(t, db, "canary"))
+
+	// Annotations are indexed
+	request.Params.Arguments = map[string]interface{}{
+		"slug": "deploy", "title": "Deploy process", "content": "Canary releases", "note": "Clarify: canaries run for an hour",
+	}
+	_, err = remember(context.Background(), request)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"deploy"}, ftsSearchSlugs(t, db, "hour"))
+
+	callSlugTool(t, tools.ForgetHandler(setup.ToolCtx, setup.User.ID), "deploy")
+	assert.Empty(t, ftsSearchSlugs(t, db, "canary"))
+
+	callSlugTool(t, tools.RestoreHandler(setup.ToolCtx, setup.User.ID), "deploy")
+	assert.Equal(t, []string{"deploy"}, ftsSearchSlugs(t, db, "canary"))
+}
+
+func TestFTS_RecallQuerySyntax(t *testing.T) {
+	setup := setupTestEnvironment(t)
+	defer setup.Cleanup()
+	remember := tools.RememberHandler(setup.ToolCtx, setup.User.ID)
+
+	for _, args := range []map[string]interface{}{
+		{"slug": "cache-policy", "title": "Cache policy", "content": "Entries expire after ten minutes"},
+		{"slug": "db-migration", "title": "Database migration", "content": "Move the cache tables last"},
+		{"slug": "standup", "title": "Standup notes", "content": "Discussed database indexes"},
+	} {
+		request := mcp.CallToolRequest{}
+		request.Params.Arguments = args
+		_, err := remember(context.Background(), request)
+		require.NoError(t, err)
+	}
+
+	titles := func(topic string) []string {
+		return recallTitles(recallText(t, setup, map[string]interface{}{"topic": topic}))
+	}
+
+	assert.Equal(t, []string{"Cache policy", "Database migration"}, titles("caching"), "stemmed match")
+	assert.Equal(t, []string{"Database migration"}, titles(`"cache tables"`))
+	assert.Equal(t, []string{"Database migration"}, titles("cache AND database"))
+	assert.Equal(t, []string{"Standup notes"}, titles("database NOT cache"))
+	assert.Equal(t, []string{"Database migration", "Standup notes"}, titles("migrat* OR index*"))
+
+	text := recallText(t, setup, map[string]interface{}{"exact": "ten minutes"})
+	assert.Equal(t, []string{"Cache policy"}, recallTitles(text))
+	assert.Contains(t, text, "**Match**: exact")
+
+	// Words in a different order are not an exact match
+	text = recallText(t, setup, map[string]interface{}{"exact": "minutes ten"})
+	assert.Empty(t, recallTitles(text))
+}
+
+func TestFTS_BackfillsUnindexedMemories(t *testing.T) {
+	setup := setupTestEnvironment(t)
+	defer setup.Cleanup()
+
+	request := mcp.CallToolRequest{}
+	request.Params.Arguments = map[string]interface{}{"slug": "legacy", "title": "Legacy note", "content": "Written before the index existed"}
+	_, err := tools.RememberHandler(setup.ToolCtx, setup.User.ID)(context.Background(), request)
+	require.NoError(t, err)
+
+	require.NoError(t, database.ClearUserFTS(setup.ToolCtx.UserDB))
+
+	titles := recallTitles(recallText(t, setup, map[string]interface{}{"topic": "existed"}))
+	assert.Equal(t, []string{"Legacy note"}, titles)
+}
+
+func TestFTS_RebuildUserIndex(t *testing.T) {
+	repoPath := filepath.Join(t.TempDir(), "repo")
+	require.NoError(t, os.MkdirAll(filepath.Join(repoPath, "archive"), 0755))
+	createTestMemoryFile(t, repoPath, "live", "Live memory", []string{"kept"})
+	createTestMemoryFileInDir(t, filepath.Join(repoPath, "archive"), "archived", "Archived memory", []string{"kept"})
+
+	userDB, err := database.OpenUserDB(repoPath)
+	require.NoError(t, err)
+	defer func() {
+		sqlDB, _ := userDB.DB()
+		sqlDB.Close()
+	}()
+
+	result, err := rebuild.RebuildUserIndex(userDB, repoPath, rebuild.Options{})
+	require.NoError(t, err)
+	assert.Empty(t, result.Errors)
+	assert.Equal(t, []string{"live"}, ftsSearchSlugs(t, userDB, "kept"), "archived memories are not indexed")
+
+	// A forced rebuild clears stale entries
+	require.NoError(t, database.IndexMemoryFTS(userDB, database.FTSDocument{Slug: "stale", Title: "kept"}))
+	_, err = rebuild.RebuildUserIndex(userDB, repoPath, rebuild.Options{Force: true})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"live"}, ftsSearchSlugs(t, userDB, "kept"))
+}
@@ -0,0 +1,252 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package integration
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tejzpr/medha-mcp/internal/database"
+	"github.com/tejzpr/medha-mcp/internal/graph"
+	"github.com/tejzpr/medha-mcp/internal/tools"
+)
+
+// setupGraphMemories creates a small chain of memories:
+// project-x <-part_of- design-doc -related- api-notes -references-> rfc (weak)
+func setupGraphMemories(t *testing.T, setup *testSetup) {
+	rememberHandler := tools.RememberHandler(setup.ToolCtx, setup.User.ID)
+	for _, slug := range []string{"project-x", "design-doc", "api-notes", "rfc"} {
+		request := mcp.CallToolRequest{}
+		request.Params.Arguments = map[string]interface{}{
+			"title":   "Memory " + slug,
+			"content": "Content for " + slug,
+			"slug":    slug,
+		}
+		result, err := rememberHandler(context.Background(), request)
+		require.NoError(t, err)
+		require.False(t, result.IsError, getResultText(result))
+	}
+
+	connectHandler := tools.ConnectHandler(setup.ToolCtx, setup.User.ID)
+	links := []map[string]interface{}{
+		{"from": "design-doc", "to": "project-x", "relationship": "part_of", "strength": 0.9},
+		{"from": "design-doc", "to": "api-notes", "relationship": "related", "strength": 0.7},
+		{"from": "api-notes", "to": "rfc", "relationship": "references", "strength": 0.2},
+	}
+	for _, args := range links {
+		request := mcp.CallToolRequest{}
+		request.Params.Arguments = args
+		result, err := connectHandler(context.Background(), request)
+		require.NoError(t, err)
+		require.False(t, result.IsError, getResultText(result))
+	}
+}
+
+// decodeGraphResult extracts the JSON graph from a medha_graph result
+func decodeGraphResult(t *testing.T, result *mcp.CallToolResult) *graph.SlugGraph {
+	require.Len(t, result.Content, 2)
+	text, ok := result.Content[1].(mcp.TextContent)
+	require.True(t, ok)
+
+	var g graph.SlugGraph
+	require.NoError(t, json.Unmarshal([]byte(text.Text), &g))
+	return &g
+}
+
+// graphSlugs returns the node slugs of a graph
+func graphSlugs(g *graph.SlugGraph) []string {
+	var slugs []string
+	for _, n := range g.Nodes {
+		slugs = append(slugs, n.Slug)
+	}
+	return slugs
+}
+
+// TestGraphIntegration tests medha_graph over the per-user associations table
+func TestGraphIntegration(t *testing.T) {
+	setup := setupTestEnvironment(t)
+	defer setup.Cleanup()
+	setupGraphMemories(t, setup)
+
+	handler := tools.GraphHandler(setup.ToolCtx, setup.User.ID)
+
+	t.Run("Walk both directions", func(t *testing.T) {
+		request := mcp.CallToolRequest{}
+		request.Params.Arguments = map[string]interface{}{
+			"slug":     "project-x",
+			"max_hops": 3,
+		}
+
+		result, err := handler(context.Background(), request)
+		require.NoError(t, err)
+		require.False(t, result.IsError, getResultText(result))
+
+		g := decodeGraphResult(t, result)
+		assert.Equal(t, "project-x", g.Start)
+		assert.ElementsMatch(t, []string{"project-x", "design-doc", "api-notes", "rfc"}, graphSlugs(g))
+		// Bidirectional related_to rows are collapsed into a single edge
+		assert.Len(t, g.Edges, 3)
+
+		outline := getResultText(result)
+		assert.Contains(t, outline, "# Graph from 'project-x'")
+		// design-doc is part of project-x, so the arrow points back at the root
+		assert.Contains(t, outline, "<-part_of- **Memory design-doc**")
+		assert.Contains(t, outline, "-related_to- **Memory api-notes**")
+	})
+
+	t.Run("Respects max hops", func(t *testing.T) {
+		request := mcp.CallToolRequest{}
+		request.Params.Arguments = map[string]interface{}{
+			"slug":     "project-x",
+			"max_hops": 1,
+		}
+
+		result, err := handler(context.Background(), request)
+		require.NoError(t, err)
+		g := decodeGraphResult(t, result)
+		assert.ElementsMatch(t, []string{"project-x", "design-doc"}, graphSlugs(g))
+	})
+
+	t.Run("Outgoing only", func(t *testing.T) {
+		request := mcp.CallToolRequest{}
+		request.Params.Arguments = map[string]interface{}{
+			"slug":      "project-x",
+			"direction": "outgoing",
+		}
+
+		result, err := handler(context.Background(), request)
+		require.NoError(t, err)
+		g := decodeGraphResult(t, result)
+		assert.Equal(t, []string{"project-x"}, graphSlugs(g))
+		assert.Empty(t, g.Edges)
+	})
+
+	t.Run("Relationship filter", func(t *testing.T) {
+		request := mcp.CallToolRequest{}
+		request.Params.Arguments = map[string]interface{}{
+			"slug":          "design-doc",
+			"max_hops":      3,
+			"relationships": []interface{}{"related", "references"},
+		}
+
+		result, err := handler(context.Background(), request)
+		require.NoError(t, err)
+		g := decodeGraphResult(t, result)
+		assert.ElementsMatch(t, []string{"design-doc", "api-notes", "rfc"}, graphSlugs(g))
+	})
+
+	t.Run("Minimum strength", func(t *testing.T) {
+		request := mcp.CallToolRequest{}
+		request.Params.Arguments = map[string]interface{}{
+			"slug":         "design-doc",
+			"max_hops":     3,
+			"min_strength": 0.5,
+			"depth_first":  true,
+		}
+
+		result, err := handler(context.Background(), request)
+		require.NoError(t, err)
+		g := decodeGraphResult(t, result)
+		assert.ElementsMatch(t, []string{"design-doc", "project-x", "api-notes"}, graphSlugs(g))
+	})
+
+	t.Run("Invalid relationship", func(t *testing.T) {
+		request := mcp.CallToolRequest{}
+		request.Params.Arguments = map[string]interface{}{
+			"slug":          "design-doc",
+			"relationships": []interface{}{"enemy_of"},
+		}
+
+		result, err := handler(context.Background(), request)
+		require.NoError(t, err)
+		assert.True(t, result.IsError)
+	})
+
+	t.Run("Unknown start", func(t *testing.T) {
+		request := mcp.CallToolRequest{}
+		request.Params.Arguments = map[string]interface{}{
+			"slug": "does-not-exist",
+		}
+
+		result, err := handler(context.Background(), request)
+		require.NoError(t, err)
+		assert.True(t, result.IsError)
+		assert.Contains(t, getResultText(result), "memory not found")
+	})
+}
+
+// TestGraphSkipsArchived verifies archived memories are not walked into
+func TestGraphSkipsArchived(t *testing.T) {
+	setup := setupTestEnvironment(t)
+	defer setup.Cleanup()
+	setupGraphMemories(t, setup)
+
+	// Archive the middle of the chain
+	setup.ToolCtx.UserDB.Where("slug = ?", "api-notes").Delete(&database.UserMemory{})
+
+	g, err := graph.NewManager(setup.ToolCtx.UserDB).TraverseUserGraph("design-doc", graph.TraversalOptions{
+		MaxHops:      3,
+		BreadthFirst: true,
+	})
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"design-doc", "project-x"}, graphSlugs(g))
+}
+
+// TestGraphDepthFirstFindsShorterPaths verifies a node first reached at the
+// hop limit is walked again when DFS later finds a shorter path to it
+func TestGraphDepthFirstFindsShorterPaths(t *testing.T) {
+	setup := setupTestEnvironment(t)
+	defer setup.Cleanup()
+
+	rememberHandler := tools.RememberHandler(setup.ToolCtx, setup.User.ID)
+	for _, slug := range []string{"start-node", "node-a", "node-b", "node-c", "node-x", "node-y"} {
+		request := mcp.CallToolRequest{}
+		request.Params.Arguments = map[string]interface{}{
+			"title":   "Memory " + slug,
+			"content": "Content for " + slug,
+			"slug":    slug,
+		}
+		result, err := rememberHandler(context.Background(), request)
+		require.NoError(t, err)
+		require.False(t, result.IsError, getResultText(result))
+	}
+
+	// The stronger start-node->node-a->node-c->node-x path is walked first and
+	// reaches node-x at the limit
+	connectHandler := tools.ConnectHandler(setup.ToolCtx, setup.User.ID)
+	for _, link := range []struct {
+		from, to string
+		strength float64
+	}{
+		{"start-node", "node-a", 0.9}, {"node-a", "node-c", 0.9}, {"node-c", "node-x", 0.9},
+		{"start-node", "node-b", 0.5}, {"node-b", "node-x", 0.5}, {"node-x", "node-y", 0.5},
+	} {
+		request := mcp.CallToolRequest{}
+		request.Params.Arguments = map[string]interface{}{
+			"from": link.from, "to": link.to, "relationship": "follows", "strength": link.strength,
+		}
+		result, err := connectHandler(context.Background(), request)
+		require.NoError(t, err)
+		require.False(t, result.IsError, getResultText(result))
+	}
+
+	g, err := graph.NewManager(setup.ToolCtx.UserDB).TraverseUserGraph("start-node", graph.TraversalOptions{
+		MaxHops:   3,
+		Direction: graph.DirectionOutgoing,
+	})
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"start-node", "node-a", "node-b", "node-c", "node-x", "node-y"}, graphSlugs(g))
+
+	depths := map[string]int{}
+	for _, n := range g.Nodes {
+		depths[n.Slug] = n.Depth
+	}
+	assert.Equal(t, 2, depths["node-x"])
+	assert.Equal(t, 3, depths["node-y"])
+}
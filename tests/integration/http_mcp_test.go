@@ -0,0 +1,278 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package integration
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/client"
+	"github.com/mark3labs/mcp-go/client/transport"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tejzpr/medha-mcp/internal/auth"
+	"github.com/tejzpr/medha-mcp/internal/config"
+	"github.com/tejzpr/medha-mcp/internal/database"
+	"github.com/tejzpr/medha-mcp/internal/git"
+	"github.com/tejzpr/medha-mcp/internal/server"
+	"gorm.io/gorm/logger"
+)
+
+// httpTestUser is a user with a repository and access token for HTTP mode tests
+type httpTestUser struct {
+	User  *database.MedhaUser
+	Token string
+}
+
+// setupHTTPServer starts an HTTP mode server backed by a fresh system DB
+func setupHTTPServer(t *testing.T) (*httptest.Server, *database.Manager, *auth.TokenManager) {
+	return setupHTTPServerWithCache(t, 0)
+}
+
+// setupHTTPServerWithCache starts an HTTP mode server with a bounded per-user server cache
+func setupHTTPServerWithCache(t *testing.T, maxUserServers int) (*httptest.Server, *database.Manager, *auth.TokenManager) {
+	tempDir := t.TempDir()
+
+	dbMgr, err := database.NewManager(&database.Config{
+		Type:       "sqlite",
+		SQLitePath: filepath.Join(tempDir, "system.db"),
+		LogLevel:   logger.Silent,
+	})
+	require.NoError(t, err)
+	t.Cleanup(func() { dbMgr.Close() })
+	require.NoError(t, database.Migrate(dbMgr.SystemDB()))
+
+	cfg := &config.Config{
+		Server:     config.ServerConfig{MaxUserServers: maxUserServers},
+		Security:   config.SecurityConfig{TokenTTL: 24},
+		Embeddings: config.EmbeddingConfig{Enabled: false},
+	}
+
+	encryptionKey := make([]byte, 32)
+	mcpServer, err := server.NewMCPServer(cfg, dbMgr, encryptionKey)
+	require.NoError(t, err)
+
+	localAuth := auth.NewLocalAuthenticator(mcpServer.GetTokenManager())
+	httpServer := server.NewHTTPServer(mcpServer, nil, nil, localAuth, "local", encryptionKey)
+	t.Cleanup(mcpServer.Close)
+	t.Cleanup(httpServer.Close)
+
+	mux := http.NewServeMux()
+	httpServer.RegisterRoutes(mux)
+
+	ts := httptest.NewServer(mux)
+	t.Cleanup(ts.Close)
+
+	return ts, dbMgr, mcpServer.GetTokenManager()
+}
+
+// createHTTPTestUser creates a user, their git repository and an access token
+func createHTTPTestUser(t *testing.T, dbMgr *database.Manager, tokenManager *auth.TokenManager, username string) *httpTestUser {
+	db := dbMgr.SystemDB()
+
+	user := &database.MedhaUser{Username: username}
+	require.NoError(t, db.Create(user).Error)
+
+	repoPath := filepath.Join(t.TempDir(), "medha-"+username)
+	require.NoError(t, os.MkdirAll(repoPath, 0755))
+	_, err := git.InitRepository(repoPath)
+	require.NoError(t, err)
+
+	require.NoError(t, db.Create(&database.MedhaGitRepo{
+		UserID:   user.ID,
+		RepoUUID: username,
+		RepoName: "medha-" + username,
+		RepoPath: repoPath,
+	}).Error)
+
+	token, err := tokenManager.GenerateToken(user.ID)
+	require.NoError(t, err)
+
+	return &httpTestUser{User: user, Token: token.AccessToken}
+}
+
+// newHTTPMCPClient connects an initialized MCP client to the server as the given user
+func newHTTPMCPClient(t *testing.T, baseURL string, u *httpTestUser) *client.Client {
+	c, err := client.NewStreamableHttpClient(baseURL+"/mcp",
+		transport.WithHTTPHeaders(map[string]string{"Authorization": "Bearer " + u.Token}),
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() { c.Close() })
+
+	require.NoError(t, c.Start(context.Background()))
+	initializeHTTPMCPClient(t, c)
+	return c
+}
+
+// initializeHTTPMCPClient starts a new MCP session on a client
+func initializeHTTPMCPClient(t *testing.T, c *client.Client) {
+	initReq := mcp.InitializeRequest{}
+	initReq.Params.ProtocolVersion = mcp.LATEST_PROTOCOL_VERSION
+	initReq.Params.ClientInfo = mcp.Implementation{Name: "medha-test", Version: "1.0.0"}
+	_, err := c.Initialize(context.Background(), initReq)
+	require.NoError(t, err)
+}
+
+// callHTTPTool calls a tool through an MCP client and returns the first text
+// content. Like any client, it starts a new session when told its session ended.
+func callHTTPTool(t *testing.T, c *client.Client, name string, args map[string]interface{}) (string, bool) {
+	req := mcp.CallToolRequest{}
+	req.Params.Name = name
+	req.Params.Arguments = args
+
+	result, err := c.CallTool(context.Background(), req)
+	if errors.Is(err, transport.ErrSessionTerminated) {
+		initializeHTTPMCPClient(t, c)
+		result, err = c.CallTool(context.Background(), req)
+	}
+	require.NoError(t, err)
+	return getResultText(result), result.IsError
+}
+
+// TestHTTPMCP_StreamableTransport verifies --http mode serves real MCP over streamable HTTP
+func TestHTTPMCP_StreamableTransport(t *testing.T) {
+	ts, dbMgr, tokenManager := setupHTTPServer(t)
+	alice := createHTTPTestUser(t, dbMgr, tokenManager, "alice")
+
+	c := newHTTPMCPClient(t, ts.URL, alice)
+
+	tools, err := c.ListTools(context.Background(), mcp.ListToolsRequest{})
+	require.NoError(t, err)
+
+	var names []string
+	for _, tool := range tools.Tools {
+		names = append(names, tool.Name)
+	}
+	assert.Contains(t, names, "medha_recall")
+	assert.Contains(t, names, "medha_remember")
+	assert.Contains(t, names, "medha_graph")
+
+	text, isErr := callHTTPTool(t, c, "medha_remember", map[string]interface{}{
+		"title":   "HTTP Memory",
+		"content": "Stored over streamable HTTP",
+		"slug":    "http-memory",
+	})
+	require.False(t, isErr, text)
+	assert.Contains(t, text, "Memory created")
+
+	text, isErr = callHTTPTool(t, c, "medha_recall", map[string]interface{}{"topic": "HTTP Memory"})
+	require.False(t, isErr, text)
+	assert.Contains(t, text, "http-memory")
+}
+
+// TestHTTPMCP_RequiresAuth verifies the MCP endpoint rejects unauthenticated requests
+func TestHTTPMCP_RequiresAuth(t *testing.T) {
+	ts, _, _ := setupHTTPServer(t)
+
+	resp, err := http.Post(ts.URL+"/mcp", "application/json", strings.NewReader(`{"jsonrpc":"2.0","id":1,"method":"ping"}`))
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+}
+
+// TestHTTPMCP_SessionBoundToUser verifies a session ID cannot be reused by another user
+func TestHTTPMCP_SessionBoundToUser(t *testing.T) {
+	ts, dbMgr, tokenManager := setupHTTPServer(t)
+	alice := createHTTPTestUser(t, dbMgr, tokenManager, "alice")
+	bob := createHTTPTestUser(t, dbMgr, tokenManager, "bob")
+
+	aliceClient := newHTTPMCPClient(t, ts.URL, alice)
+	sessionID := aliceClient.GetSessionId()
+	require.NotEmpty(t, sessionID)
+
+	body := []byte(`{"jsonrpc":"2.0","id":2,"method":"tools/list"}`)
+	req, err := http.NewRequest(http.MethodPost, ts.URL+"/mcp", bytes.NewReader(body))
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+bob.Token)
+	req.Header.Set("Mcp-Session-Id", sessionID)
+
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+}
+
+// TestHTTPMCP_UserIsolation verifies one user's tools can never return another user's memories
+func TestHTTPMCP_UserIsolation(t *testing.T) {
+	// A cache of one forces the per-user servers to be evicted and rebuilt between calls
+	for _, cacheSize := range []int{0, 1} {
+		ts, dbMgr, tokenManager := setupHTTPServerWithCache(t, cacheSize)
+		alice := createHTTPTestUser(t, dbMgr, tokenManager, "alice")
+		bob := createHTTPTestUser(t, dbMgr, tokenManager, "bob")
+
+		aliceClient := newHTTPMCPClient(t, ts.URL, alice)
+		bobClient := newHTTPMCPClient(t, ts.URL, bob)
+
+		// Same slug in both repositories, different content
+		text, isErr := callHTTPTool(t, aliceClient, "medha_remember", map[string]interface{}{
+			"title":   "Shared Plan",
+			"content": "alice-secret-roadmap",
+			"slug":    "shared-plan",
+		})
+		require.False(t, isErr, text)
+
+		text, isErr = callHTTPTool(t, bobClient, "medha_remember", map[string]interface{}{
+			"title":   "Shared Plan",
+			"content": "bob-private-notes",
+			"slug":    "shared-plan",
+		})
+		require.False(t, isErr, text)
+
+		// Interleave calls so a shared handler registry would route one user to the other's context
+		for i := 0; i < 3; i++ {
+			aliceText, isErr := callHTTPTool(t, aliceClient, "medha_recall", map[string]interface{}{"topic": "Shared Plan"})
+			require.False(t, isErr, aliceText)
+			assert.Contains(t, aliceText, "alice-secret-roadmap")
+			assert.NotContains(t, aliceText, "bob-private-notes")
+
+			bobText, isErr := callHTTPTool(t, bobClient, "medha_recall", map[string]interface{}{"list_all": true})
+			require.False(t, isErr, bobText)
+			assert.Contains(t, bobText, "bob-private-notes")
+			assert.NotContains(t, bobText, "alice-secret-roadmap")
+		}
+
+		// Alice's memory only exists in Alice's repository
+		text, isErr = callHTTPTool(t, bobClient, "medha_recall", map[string]interface{}{"exact": "alice-secret-roadmap"})
+		require.False(t, isErr, text)
+		assert.NotContains(t, text, "alice-secret-roadmap")
+	}
+}
+
+// TestRegisterToolsForUser_RejectsSecondUser verifies the shared stdio server cannot be rebound
+func TestRegisterToolsForUser_RejectsSecondUser(t *testing.T) {
+	_, dbMgr, tokenManager := setupHTTPServer(t)
+	alice := createHTTPTestUser(t, dbMgr, tokenManager, "alice")
+	bob := createHTTPTestUser(t, dbMgr, tokenManager, "bob")
+
+	cfg := &config.Config{
+		Security:   config.SecurityConfig{TokenTTL: 24},
+		Embeddings: config.EmbeddingConfig{Enabled: false},
+	}
+	mcpServer, err := server.NewMCPServer(cfg, dbMgr, make([]byte, 32))
+	require.NoError(t, err)
+
+	var aliceRepo, bobRepo database.MedhaGitRepo
+	require.NoError(t, dbMgr.SystemDB().Where("user_id = ?", alice.User.ID).First(&aliceRepo).Error)
+	require.NoError(t, dbMgr.SystemDB().Where("user_id = ?", bob.User.ID).First(&bobRepo).Error)
+
+	require.NoError(t, mcpServer.RegisterToolsForUser(alice.User.ID, aliceRepo.RepoPath))
+	// Re-registering for the same user is allowed
+	require.NoError(t, mcpServer.RegisterToolsForUser(alice.User.ID, aliceRepo.RepoPath))
+
+	err = mcpServer.RegisterToolsForUser(bob.User.ID, bobRepo.RepoPath)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "already registered")
+}
@@ -0,0 +1,174 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package integration
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tejzpr/medha-mcp/internal/graph"
+	"github.com/tejzpr/medha-mcp/internal/tools"
+)
+
+// callJSON runs a handler with format "json" and decodes its structured content
+func callJSON(t *testing.T, handler func(context.Context, mcp.CallToolRequest) (*mcp.CallToolResult, error), args map[string]interface{}, out interface{}) {
+	args["format"] = "json"
+	request := mcp.CallToolRequest{}
+	request.Params.Arguments = args
+	result, err := handler(context.Background(), request)
+	require.NoError(t, err)
+	require.False(t, result.IsError, getResultText(result))
+	require.NotNil(t, result.StructuredContent)
+
+	data, err := json.Marshal(result.StructuredContent)
+	require.NoError(t, err)
+	assert.JSONEq(t, string(data), getResultText(result), "text content mirrors the structured content")
+	require.NoError(t, json.Unmarshal(data, out))
+}
+
+func TestJSONOutput_ToolsDeclareSchemas(t *testing.T) {
+	for _, tool := range []mcp.Tool{
+		tools.NewRecallTool(),
+		tools.NewRememberTool(),
+		tools.NewHistoryTool(),
+		tools.NewConnectTool(),
+		tools.NewGraphTool(),
+		tools.NewForgetTool(),
+		tools.NewRestoreTool(),
+		tools.NewSyncTool(),
+	} {
+		assert.Contains(t, tool.InputSchema.Properties, "format", tool.Name)
+		assert.Equal(t, "object", tool.OutputSchema.Type, tool.Name)
+		assert.NotEmpty(t, tool.OutputSchema.Properties, tool.Name)
+	}
+
+	schema := tools.NewRecallTool().OutputSchema
+	results, ok := schema.Properties["results"].(map[string]interface{})
+	require.True(t, ok)
+	items, ok := results["items"].(map[string]interface{})
+	require.True(t, ok)
+	props, ok := items["properties"].(map[string]interface{})
+	require.True(t, ok)
+	for _, field := range []string{"slug", "score", "match_source", "file_path", "version", "updated_at"} {
+		assert.Contains(t, props, field)
+	}
+}
+
+func TestJSONOutput_WriteAndReadTools(t *testing.T) {
+	setup := setupTestEnvironment(t)
+	defer setup.Cleanup()
+
+	remember := tools.RememberHandler(setup.ToolCtx, setup.User.ID)
+	var created tools.MemoryChangeOutput
+	callJSON(t, remember, map[string]interface{}{
+		"slug": "json-cache", "title": "Cache Design", "content": "Use Redis for caching", "tags": []interface{}{"caching"},
+	}, &created)
+	assert.Equal(t, "created", created.Action)
+	require.NotNil(t, created.Memory)
+	assert.Equal(t, "json-cache", created.Memory.Slug)
+	assert.Equal(t, int64(1), created.Memory.Version)
+	assert.NotEmpty(t, created.Memory.FilePath)
+	assert.False(t, created.Memory.CreatedAt.IsZero())
+
+	var updated tools.MemoryChangeOutput
+	callJSON(t, remember, map[string]interface{}{
+		"slug": "json-cache", "title": "Cache Design", "content": "Use Redis with a 5 minute TTL", "note": "decided in review",
+	}, &updated)
+	assert.Equal(t, "updated", updated.Action)
+	assert.Equal(t, int64(2), updated.Memory.Version)
+	assert.Contains(t, updated.Message, "Annotation added")
+
+	callJSON(t, remember, map[string]interface{}{"slug": "json-redis", "title": "Redis Ops", "content": "Cluster runbook"}, &created)
+
+	var connected tools.ConnectOutput
+	callJSON(t, tools.ConnectHandler(setup.ToolCtx, setup.User.ID), map[string]interface{}{
+		"from": "json-cache", "to": "json-redis", "relationship": "references", "strength": 0.9,
+	}, &connected)
+	assert.Equal(t, tools.ConnectOutput{
+		Action: "connected", From: "json-cache", To: "json-redis", Relationship: "references", Strength: 0.9,
+		Message: connected.Message,
+	}, connected)
+
+	var g graph.SlugGraph
+	callJSON(t, tools.GraphHandler(setup.ToolCtx, setup.User.ID), map[string]interface{}{"slug": "json-cache"}, &g)
+	assert.Equal(t, "json-cache", g.Start)
+	assert.Len(t, g.Nodes, 2)
+
+	var recall tools.RecallOutput
+	callJSON(t, tools.RecallHandler(setup.ToolCtx, setup.User.ID), map[string]interface{}{"topic": "caching", "explain": true}, &recall)
+	require.NotEmpty(t, recall.Results)
+	top := recall.Results[0]
+	assert.Equal(t, recall.Count, len(recall.Results))
+	assert.Equal(t, "json-cache", top.Slug)
+	assert.Equal(t, int64(2), top.Version)
+	assert.Greater(t, top.Score, 0.0)
+	assert.Contains(t, top.MatchSource, "keyword")
+	assert.Equal(t, []string{"caching"}, top.Tags)
+	assert.Contains(t, top.Content, "5 minute TTL")
+	require.NotNil(t, top.Ranking)
+	assert.NotEmpty(t, top.Ranking.Contributions)
+	require.Len(t, top.Annotations, 1)
+
+	var page tools.RecallOutput
+	callJSON(t, tools.RecallHandler(setup.ToolCtx, setup.User.ID), map[string]interface{}{"list_all": true, "limit": 1}, &page)
+	assert.Len(t, page.Results, 1)
+	assert.NotEmpty(t, page.NextCursor)
+
+	var forgotten tools.MemoryChangeOutput
+	callJSON(t, tools.ForgetHandler(setup.ToolCtx, setup.User.ID), map[string]interface{}{"slug": "json-redis"}, &forgotten)
+	assert.Equal(t, "archived", forgotten.Action)
+	assert.True(t, forgotten.Memory.Archived)
+
+	var restored tools.MemoryChangeOutput
+	callJSON(t, tools.RestoreHandler(setup.ToolCtx, setup.User.ID), map[string]interface{}{"slug": "json-redis"}, &restored)
+	assert.Equal(t, "restored", restored.Action)
+	assert.False(t, restored.Memory.Archived)
+
+	var history tools.HistoryOutput
+	callJSON(t, tools.HistoryHandler(setup.ToolCtx, setup.User.ID), map[string]interface{}{"slug": "json-cache"}, &history)
+	assert.Equal(t, "memory", history.Scope)
+	require.NotNil(t, history.Memory)
+	assert.Equal(t, "json-cache", history.Memory.Slug)
+	assert.NotEmpty(t, history.Commits)
+}
+
+// TestJSONOutput_TextRemainsDefault verifies text is the default format and
+// that text results still carry the structured content their schema promises
+func TestJSONOutput_TextRemainsDefault(t *testing.T) {
+	setup := setupTestEnvironment(t)
+	defer setup.Cleanup()
+
+	for _, call := range []struct {
+		handler func(context.Context, mcp.CallToolRequest) (*mcp.CallToolResult, error)
+		args    map[string]interface{}
+	}{
+		{tools.RememberHandler(setup.ToolCtx, setup.User.ID), map[string]interface{}{"title": "Plain", "content": "Plain text output", "slug": "plain"}},
+		{tools.RecallHandler(setup.ToolCtx, setup.User.ID), map[string]interface{}{"list_all": true}},
+		{tools.RecallHandler(setup.ToolCtx, setup.User.ID), map[string]interface{}{"topic": "nothing matches this"}},
+		{tools.GraphHandler(setup.ToolCtx, setup.User.ID), map[string]interface{}{"slug": "plain"}},
+		{tools.LockHandler(setup.ToolCtx, setup.User.ID), map[string]interface{}{"slug": "plain", "action": "status"}},
+		{tools.HistoryHandler(setup.ToolCtx, setup.User.ID), map[string]interface{}{"slug": "plain"}},
+		{tools.ForgetHandler(setup.ToolCtx, setup.User.ID), map[string]interface{}{"slug": "plain"}},
+	} {
+		request := mcp.CallToolRequest{}
+		request.Params.Arguments = call.args
+		result, err := call.handler(context.Background(), request)
+		require.NoError(t, err)
+		require.False(t, result.IsError, getResultText(result))
+		assert.NotNil(t, result.StructuredContent, call.args)
+		assert.False(t, json.Valid([]byte(getResultText(result))), "text format is prose: %v", call.args)
+	}
+
+	request := mcp.CallToolRequest{}
+	request.Params.Arguments = map[string]interface{}{"list_all": true, "format": "yaml"}
+	result, err := tools.RecallHandler(setup.ToolCtx, setup.User.ID)(context.Background(), request)
+	require.NoError(t, err)
+	assert.True(t, result.IsError)
+	assert.Contains(t, getResultText(result), "invalid format")
+}
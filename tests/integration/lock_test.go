@@ -0,0 +1,335 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package integration
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/mark3labs/mcp-go/mcp"
+	mcpserver "github.com/mark3labs/mcp-go/server"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tejzpr/medha-mcp/internal/database"
+	"github.com/tejzpr/medha-mcp/internal/git"
+	"github.com/tejzpr/medha-mcp/internal/locking"
+	"github.com/tejzpr/medha-mcp/internal/tools"
+)
+
+// testAgentSession is an MCP session standing in for one agent
+type testAgentSession struct {
+	id string
+}
+
+func (s *testAgentSession) Initialize()                                         {}
+func (s *testAgentSession) Initialized() bool                                   { return true }
+func (s *testAgentSession) NotificationChannel() chan<- mcp.JSONRPCNotification { return nil }
+func (s *testAgentSession) SessionID() string                                   { return s.id }
+
+// agentContext returns a tool call context for the agent with a session ID
+func agentContext(sessionID string) context.Context {
+	return mcpserver.NewMCPServer("test", "1.0.0").WithContext(context.Background(), &testAgentSession{id: sessionID})
+}
+
+// callAs calls a tool handler as an agent and returns its text and error flag
+func callAs(t *testing.T, c context.Context, handler func(context.Context, mcp.CallToolRequest) (*mcp.CallToolResult, error), args map[string]interface{}) (string, bool) {
+	request := mcp.CallToolRequest{}
+	request.Params.Arguments = args
+	result, err := handler(c, request)
+	require.NoError(t, err)
+	return getResultText(result), result.IsError
+}
+
+// repoHead returns the test repository's HEAD commit
+func repoHead(t *testing.T, setup *testSetup) plumbing.Hash {
+	repo, err := git.OpenRepository(setup.RepoPath)
+	require.NoError(t, err)
+	head, err := repo.HeadHash()
+	require.NoError(t, err)
+	return head
+}
+
+func TestLock_BlocksOtherAgents(t *testing.T) {
+	setup := setupTestEnvironment(t)
+	defer setup.Cleanup()
+	rememberResource(t, setup, map[string]interface{}{"slug": "lock-doc", "title": "Lock Doc", "content": "Version one"})
+
+	alice, bob := agentContext("agent-a"), agentContext("agent-b")
+	lock := tools.LockHandler(setup.ToolCtx, setup.User.ID)
+	remember := tools.RememberHandler(setup.ToolCtx, setup.User.ID)
+
+	text, isErr := callAs(t, alice, lock, map[string]interface{}{"slug": "lock-doc", "ttl": "10m"})
+	require.False(t, isErr, text)
+	assert.Contains(t, text, "Locked 'lock-doc' for 10m")
+
+	// Another agent can neither write nor release the memory
+	text, isErr = callAs(t, bob, remember, map[string]interface{}{"slug": "lock-doc", "title": "Lock Doc", "content": "Bob's version"})
+	require.True(t, isErr)
+	assert.Contains(t, text, "locked by mcp-client@agent-a")
+	text, isErr = callAs(t, bob, lock, map[string]interface{}{"slug": "lock-doc", "action": "release"})
+	require.True(t, isErr)
+	assert.Contains(t, text, "only its holder can release it")
+
+	// The holder writes without losing the lock
+	text, isErr = callAs(t, alice, remember, map[string]interface{}{"slug": "lock-doc", "title": "Lock Doc", "content": "Alice's version"})
+	require.False(t, isErr, text)
+	text, isErr = callAs(t, alice, lock, map[string]interface{}{"slug": "lock-doc", "action": "status"})
+	require.False(t, isErr, text)
+	assert.Contains(t, text, "You hold the lock on 'lock-doc'")
+
+	text, isErr = callAs(t, alice, lock, map[string]interface{}{"slug": "lock-doc", "action": "release"})
+	require.False(t, isErr, text)
+	text, isErr = callAs(t, bob, remember, map[string]interface{}{"slug": "lock-doc", "title": "Lock Doc", "content": "Bob's version"})
+	require.False(t, isErr, text)
+
+	mem, err := setup.ToolCtx.GetUserMemoryBySlug("lock-doc")
+	require.NoError(t, err)
+	content, err := os.ReadFile(mem.FilePath)
+	require.NoError(t, err)
+	assert.Contains(t, string(content), "Bob's version")
+
+	// Write locks are released once the write is stored
+	text, isErr = callAs(t, alice, lock, map[string]interface{}{"slug": "lock-doc", "action": "status"})
+	require.False(t, isErr, text)
+	assert.Contains(t, text, "'lock-doc' is not locked")
+}
+
+func TestLock_Actions(t *testing.T) {
+	setup := setupTestEnvironment(t)
+	defer setup.Cleanup()
+	rememberResource(t, setup, map[string]interface{}{"slug": "lock-actions", "title": "Lock Actions", "content": "Content"})
+
+	alice := agentContext("agent-a")
+	lock := tools.LockHandler(setup.ToolCtx, setup.User.ID)
+
+	for _, args := range []map[string]interface{}{
+		{"slug": "lock-actions", "action": "extend"},
+		{"slug": "lock-actions", "ttl": "2h"},
+		{"slug": "lock-actions", "ttl": "soon"},
+		{"slug": "lock-actions", "action": "steal"},
+		{"slug": "missing-memory"},
+	} {
+		_, isErr := callAs(t, alice, lock, args)
+		assert.True(t, isErr, args)
+	}
+
+	var out tools.LockOutput
+	callJSON(t, lock, map[string]interface{}{"slug": "lock-actions"}, &out)
+	assert.True(t, out.Locked)
+	assert.True(t, out.Mine, "calls without a session share the process's agent")
+	require.NotNil(t, out.ExpiresAt)
+	acquiredUntil := *out.ExpiresAt
+
+	callJSON(t, lock, map[string]interface{}{"slug": "lock-actions", "action": "extend", "ttl": "30m"}, &out)
+	require.NotNil(t, out.ExpiresAt)
+	assert.True(t, out.ExpiresAt.After(acquiredUntil))
+
+	var released tools.LockOutput
+	callJSON(t, lock, map[string]interface{}{"slug": "lock-actions", "action": "release"}, &released)
+	assert.False(t, released.Locked)
+	assert.Nil(t, released.ExpiresAt)
+}
+
+func TestLock_FailedWriteRollsBack(t *testing.T) {
+	setup := setupTestEnvironment(t)
+	defer setup.Cleanup()
+	rememberResource(t, setup, map[string]interface{}{"slug": "rollback-doc", "title": "Rollback Doc", "content": "Original content"})
+
+	mem, err := setup.ToolCtx.GetUserMemoryBySlug("rollback-doc")
+	require.NoError(t, err)
+	original, err := os.ReadFile(mem.FilePath)
+	require.NoError(t, err)
+	head := repoHead(t, setup)
+
+	// Make every database write to memories fail after the file is committed
+	db := setup.ToolCtx.UserDB
+	require.NoError(t, db.Exec(`CREATE TRIGGER fail_memory_update BEFORE UPDATE ON memories BEGIN SELECT RAISE(ABORT, 'update refused'); END`).Error)
+	require.NoError(t, db.Exec(`CREATE TRIGGER fail_memory_insert BEFORE INSERT ON memories BEGIN SELECT RAISE(ABORT, 'insert refused'); END`).Error)
+
+	text, isErr := callAs(t, context.Background(), tools.RememberHandler(setup.ToolCtx, setup.User.ID),
+		map[string]interface{}{"slug": "rollback-doc", "title": "Rollback Doc", "content": "Changed content", "tags": []interface{}{"changed"}})
+	require.True(t, isErr)
+	assert.Contains(t, text, "update refused")
+
+	text, isErr = callAs(t, context.Background(), tools.ForgetHandler(setup.ToolCtx, setup.User.ID),
+		map[string]interface{}{"slug": "rollback-doc"})
+	require.True(t, isErr)
+	assert.Contains(t, text, "update refused")
+
+	text, isErr = callAs(t, context.Background(), tools.RememberHandler(setup.ToolCtx, setup.User.ID),
+		map[string]interface{}{"slug": "rollback-new", "title": "Rollback New", "content": "Never stored"})
+	require.True(t, isErr)
+	assert.Contains(t, text, "insert refused")
+
+	// The file, HEAD and database are as they were
+	current, err := os.ReadFile(mem.FilePath)
+	require.NoError(t, err)
+	assert.Equal(t, string(original), string(current))
+	assert.Equal(t, head, repoHead(t, setup))
+
+	repo, err := git.OpenRepository(setup.RepoPath)
+	require.NoError(t, err)
+	status, err := repo.Status()
+	require.NoError(t, err)
+	for path, fileStatus := range status {
+		if filepath.Ext(path) == ".md" {
+			t.Errorf("%s left %c%c in git", path, fileStatus.Staging, fileStatus.Worktree)
+		}
+	}
+
+	var tags []database.UserMemoryTag
+	require.NoError(t, db.Where("memory_slug = ?", "rollback-doc").Find(&tags).Error)
+	assert.Empty(t, tags, "tag changes roll back with the memory")
+	_, err = setup.ToolCtx.GetUserMemoryBySlug("rollback-new")
+	assert.Error(t, err)
+	matches, err := filepath.Glob(filepath.Join(setup.RepoPath, "*", "*", "rollback-new.md"))
+	require.NoError(t, err)
+	assert.Empty(t, matches, "the new memory's file is removed")
+}
+
+func TestLock_HTTPSessionsAreSeparateAgents(t *testing.T) {
+	ts, dbMgr, tokenManager := setupHTTPServer(t)
+	alice := createHTTPTestUser(t, dbMgr, tokenManager, "alice")
+
+	editor := newHTTPMCPClient(t, ts.URL, alice)
+	other := newHTTPMCPClient(t, ts.URL, alice)
+
+	text, isErr := callHTTPTool(t, editor, "medha_remember", map[string]interface{}{
+		"slug": "shared-doc", "title": "Shared Doc", "content": "First draft",
+	})
+	require.False(t, isErr, text)
+	text, isErr = callHTTPTool(t, editor, "medha_lock", map[string]interface{}{"slug": "shared-doc"})
+	require.False(t, isErr, text)
+
+	text, isErr = callHTTPTool(t, other, "medha_forget", map[string]interface{}{"slug": "shared-doc"})
+	require.True(t, isErr)
+	assert.Contains(t, text, "locked by medha-test@")
+
+	text, isErr = callHTTPTool(t, editor, "medha_remember", map[string]interface{}{
+		"slug": "shared-doc", "title": "Shared Doc", "content": "Second draft",
+	})
+	require.False(t, isErr, text)
+}
+
+func TestLock_ConcurrentWritersShareTheRepository(t *testing.T) {
+	setup := setupTestEnvironment(t)
+	defer setup.Cleanup()
+	for i := 0; i < 4; i++ {
+		rememberResource(t, setup, map[string]interface{}{"slug": fmt.Sprintf("archive-%d", i), "title": "Archive Me", "content": "Soon archived"})
+	}
+	repo, err := git.OpenRepository(setup.RepoPath)
+	require.NoError(t, err)
+	before, err := repo.GetCommitHistory(0)
+	require.NoError(t, err)
+
+	// Agents write and archive different memories at the same time
+	remember := tools.RememberHandler(setup.ToolCtx, setup.User.ID)
+	forget := tools.ForgetHandler(setup.ToolCtx, setup.User.ID)
+	var wg sync.WaitGroup
+	errs := make(chan string, 12)
+	call := func(handler func(context.Context, mcp.CallToolRequest) (*mcp.CallToolResult, error), agent string, args map[string]interface{}) {
+		defer wg.Done()
+		request := mcp.CallToolRequest{}
+		request.Params.Arguments = args
+		result, err := handler(agentContext(agent), request)
+		if err != nil {
+			errs <- fmt.Sprintf("%s: %v", agent, err)
+		} else if result.IsError {
+			errs <- fmt.Sprintf("%s: %s", agent, getResultText(result))
+		}
+	}
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go call(remember, fmt.Sprintf("writer-%d", i), map[string]interface{}{
+			"slug": fmt.Sprintf("concurrent-%d", i), "title": "Concurrent", "content": fmt.Sprintf("Written by writer %d", i),
+		})
+	}
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go call(forget, fmt.Sprintf("archiver-%d", i), map[string]interface{}{"slug": fmt.Sprintf("archive-%d", i)})
+	}
+	wg.Wait()
+	close(errs)
+	for msg := range errs {
+		t.Error(msg)
+	}
+
+	// Every change is its own commit and nothing is left behind
+	after, err := repo.GetCommitHistory(0)
+	require.NoError(t, err)
+	assert.Len(t, after, len(before)+12)
+	status, err := repo.Status()
+	require.NoError(t, err)
+	for path, fileStatus := range status {
+		if filepath.Ext(path) != ".md" {
+			continue
+		}
+		t.Errorf("%s left %c%c in git", path, fileStatus.Staging, fileStatus.Worktree)
+	}
+	var count int64
+	require.NoError(t, setup.ToolCtx.UserDB.Model(&database.UserMemory{}).Where("slug LIKE ?", "concurrent-%").Count(&count).Error)
+	assert.Equal(t, int64(8), count)
+}
+
+func TestLock_HolderWritesKeepTheLockLive(t *testing.T) {
+	setup := setupTestEnvironment(t)
+	defer setup.Cleanup()
+	rememberResource(t, setup, map[string]interface{}{"slug": "held-doc", "title": "Held Doc", "content": "Version one"})
+
+	alice := agentContext("agent-a")
+	lock := tools.LockHandler(setup.ToolCtx, setup.User.ID)
+	remember := tools.RememberHandler(setup.ToolCtx, setup.User.ID)
+	text, isErr := callAs(t, alice, lock, map[string]interface{}{"slug": "held-doc"})
+	require.False(t, isErr, text)
+
+	// A write made just before the lock lapses extends it
+	require.NoError(t, setup.ToolCtx.UserDB.Model(&locking.MemoryLock{}).
+		Where("slug = ?", "held-doc").Update("expires_at", time.Now().Add(time.Second)).Error)
+	text, isErr = callAs(t, alice, remember, map[string]interface{}{"slug": "held-doc", "title": "Held Doc", "content": "Version two"})
+	require.False(t, isErr, text)
+
+	current, err := locking.NewLocker(setup.ToolCtx.UserDB).Current("held-doc")
+	require.NoError(t, err)
+	require.NotNil(t, current)
+	assert.Equal(t, "mcp-client@agent-a", current.LockedBy)
+	assert.True(t, current.ExpiresAt.After(time.Now().Add(time.Minute)), "lock expires at %s", current.ExpiresAt)
+
+	// The holder's own writes run one at a time
+	var wg sync.WaitGroup
+	results := make(chan bool, 6)
+	for i := 0; i < 6; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			request := mcp.CallToolRequest{}
+			request.Params.Arguments = map[string]interface{}{
+				"slug": "held-doc", "title": "Held Doc", "content": fmt.Sprintf("Writer %d", i), "expected_version": 2,
+			}
+			result, err := remember(alice, request)
+			if assert.NoError(t, err) {
+				results <- !result.IsError
+			}
+		}(i)
+	}
+	wg.Wait()
+	close(results)
+
+	succeeded := 0
+	for ok := range results {
+		if ok {
+			succeeded++
+		}
+	}
+	assert.Equal(t, 1, succeeded, "only one write may update version 2")
+	mem, err := setup.ToolCtx.GetUserMemoryBySlug("held-doc")
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), mem.Version)
+}
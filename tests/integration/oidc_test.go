@@ -0,0 +1,352 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package integration
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tejzpr/medha-mcp/internal/auth"
+	"github.com/tejzpr/medha-mcp/internal/database"
+	"github.com/tejzpr/medha-mcp/internal/server"
+)
+
+const (
+	fakeClientID     = "medha"
+	fakeClientSecret = "s3cret"
+)
+
+// fakeIssuer is an in-process OpenID Connect provider that signs in one user
+type fakeIssuer struct {
+	server *httptest.Server
+	claims jwt.MapClaims // Claims for the user, merged into each ID token
+
+	mu     sync.Mutex
+	key    *rsa.PrivateKey
+	kid    string
+	codes  map[string]fakeAuthorization
+	tamper func(claims jwt.MapClaims) // Changes the next ID token's claims
+	signer *rsa.PrivateKey            // Signs the next ID token instead of key
+}
+
+// fakeAuthorization is an authorization code waiting to be redeemed
+type fakeAuthorization struct {
+	redirectURI string
+	challenge   string
+	nonce       string
+}
+
+// newFakeIssuer starts a fake issuer
+func newFakeIssuer(t *testing.T, claims jwt.MapClaims) *fakeIssuer {
+	f := &fakeIssuer{claims: claims, codes: make(map[string]fakeAuthorization)}
+	f.rotateKey(t)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 f.server.URL,
+			"authorization_endpoint": f.server.URL + "/authorize",
+			"token_endpoint":         f.server.URL + "/token",
+			"jwks_uri":               f.server.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", f.serveJWKS)
+	mux.HandleFunc("/authorize", f.serveAuthorize)
+	mux.HandleFunc("/token", f.serveToken)
+	f.server = httptest.NewServer(mux)
+	t.Cleanup(f.server.Close)
+	return f
+}
+
+// rotateKey replaces the issuer's signing key
+func (f *fakeIssuer) rotateKey(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.key = key
+	f.kid = base64.RawURLEncoding.EncodeToString(key.PublicKey.N.Bytes()[:8])
+}
+
+// serveJWKS publishes the current signing key
+func (f *fakeIssuer) serveJWKS(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{
+		"keys": []map[string]string{{
+			"kty": "RSA",
+			"use": "sig",
+			"alg": "RS256",
+			"kid": f.kid,
+			"n":   base64.RawURLEncoding.EncodeToString(f.key.PublicKey.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(f.key.PublicKey.E)).Bytes()),
+		}},
+	})
+}
+
+// serveAuthorize signs the user in at once and redirects back with a code
+func (f *fakeIssuer) serveAuthorize(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	if query.Get("client_id") != fakeClientID || query.Get("response_type") != "code" ||
+		query.Get("code_challenge_method") != "S256" || query.Get("code_challenge") == "" {
+		http.Error(w, "bad authorization request", http.StatusBadRequest)
+		return
+	}
+
+	code := rand.Text()
+	f.mu.Lock()
+	f.codes[code] = fakeAuthorization{
+		redirectURI: query.Get("redirect_uri"),
+		challenge:   query.Get("code_challenge"),
+		nonce:       query.Get("nonce"),
+	}
+	f.mu.Unlock()
+
+	callback, _ := url.Parse(query.Get("redirect_uri"))
+	values := url.Values{"code": {code}, "state": {query.Get("state")}}
+	callback.RawQuery = values.Encode()
+	http.Redirect(w, r, callback.String(), http.StatusFound)
+}
+
+// serveToken redeems a code once, checking the client and PKCE verifier
+func (f *fakeIssuer) serveToken(w http.ResponseWriter, r *http.Request) {
+	tokenError := func(code string) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		_ = json.NewEncoder(w).Encode(map[string]string{"error": code})
+	}
+
+	id, secret, ok := r.BasicAuth()
+	if !ok || id != fakeClientID || secret != fakeClientSecret {
+		tokenError("invalid_client")
+		return
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	authorization, ok := f.codes[r.FormValue("code")]
+	delete(f.codes, r.FormValue("code"))
+	verifier := sha256.Sum256([]byte(r.FormValue("code_verifier")))
+	if !ok || r.FormValue("grant_type") != "authorization_code" ||
+		r.FormValue("redirect_uri") != authorization.redirectURI ||
+		base64.RawURLEncoding.EncodeToString(verifier[:]) != authorization.challenge {
+		tokenError("invalid_grant")
+		return
+	}
+
+	claims := jwt.MapClaims{
+		"iss":   f.server.URL,
+		"aud":   fakeClientID,
+		"iat":   time.Now().Unix(),
+		"exp":   time.Now().Add(time.Hour).Unix(),
+		"nonce": authorization.nonce,
+	}
+	for name, value := range f.claims {
+		claims[name] = value
+	}
+	if f.tamper != nil {
+		f.tamper(claims)
+	}
+	signer := f.key
+	if f.signer != nil {
+		signer = f.signer
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = f.kid
+	idToken, err := token.SignedString(signer)
+	if err != nil {
+		tokenError("server_error")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]string{
+		"access_token": "issuer-access-token",
+		"token_type":   "Bearer",
+		"id_token":     idToken,
+	})
+}
+
+// setupOIDCServer starts an HTTP mode server with OIDC auth against a fake issuer
+func setupOIDCServer(t *testing.T, oidcCfg auth.OIDCConfig, claims jwt.MapClaims) (*httptest.Server, *database.Manager, *fakeIssuer) {
+	ts, mux, dbMgr, mcpServer := startIdentityTestServer(t, "oidc")
+	issuer := newFakeIssuer(t, claims)
+
+	oidcCfg.Issuer = issuer.server.URL
+	oidcCfg.ClientID = fakeClientID
+	oidcCfg.ClientSecret = fakeClientSecret
+	oidcCfg.RedirectURL = ts.URL + "/oidc/callback"
+	oidcAuth, err := auth.NewOIDCAuthenticator(&oidcCfg, mcpServer.GetTokenManager())
+	require.NoError(t, err)
+
+	httpServer := server.NewHTTPServer(mcpServer, nil, oidcAuth, nil, "oidc", make([]byte, 32))
+	httpServer.RegisterRoutes(mux)
+
+	return ts, dbMgr, issuer
+}
+
+// oidcLogin signs in through the issuer, delivering the callback to a
+// browser, and returns the callback response
+func oidcLogin(t *testing.T, start, callback *http.Client, baseURL string) *http.Response {
+	resp, err := start.Get(baseURL + "/oidc/login")
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusFound, resp.StatusCode)
+
+	resp, err = start.Get(resp.Header.Get("Location"))
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusFound, resp.StatusCode)
+
+	resp, err = callback.Get(resp.Header.Get("Location"))
+	require.NoError(t, err)
+	resp.Body.Close()
+	return resp
+}
+
+// TestOIDC_LoginProvisionsUserAndIssuesToken verifies an OIDC login creates
+// the user and repository and returns a token that works on /mcp
+func TestOIDC_LoginProvisionsUserAndIssuesToken(t *testing.T) {
+	ts, dbMgr, issuer := setupOIDCServer(t, auth.OIDCConfig{}, jwt.MapClaims{
+		"sub":                "00u1",
+		"preferred_username": "alice",
+		"email":              "alice@corp.example",
+	})
+
+	// The auth page detects OIDC without starting a login
+	req, err := http.NewRequest(http.MethodHead, ts.URL+"/oidc/login", nil)
+	require.NoError(t, err)
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusMethodNotAllowed, resp.StatusCode)
+
+	browser := newBrowser(t)
+	resp = oidcLogin(t, browser, browser, ts.URL)
+	require.Equal(t, http.StatusSeeOther, resp.StatusCode)
+
+	redirect, err := url.Parse(resp.Header.Get("Location"))
+	require.NoError(t, err)
+	assert.Equal(t, "/auth", redirect.Path)
+	assert.Equal(t, "alice", redirect.Query().Get("username"))
+	token := redirect.Query().Get("token")
+	require.NotEmpty(t, token)
+
+	db := dbMgr.SystemDB()
+	var user database.MedhaUser
+	require.NoError(t, db.Where("username = ?", "alice").First(&user).Error)
+	assert.Equal(t, "alice@corp.example", user.Email)
+
+	var repo database.MedhaGitRepo
+	require.NoError(t, db.Where("user_id = ?", user.ID).First(&repo).Error)
+	assert.DirExists(t, filepath.Join(repo.RepoPath, ".git"))
+
+	c := newHTTPMCPClient(t, ts.URL, &httpTestUser{User: &user, Token: token})
+	text, isErr := callHTTPTool(t, c, "medha_remember", map[string]interface{}{
+		"title":   "OIDC Memory",
+		"content": "Stored after an OIDC login",
+		"slug":    "oidc-memory",
+	})
+	require.False(t, isErr, text)
+
+	// A rotated signing key is fetched, and the same user signs in again
+	issuer.rotateKey(t)
+	resp = oidcLogin(t, browser, browser, ts.URL)
+	require.Equal(t, http.StatusSeeOther, resp.StatusCode)
+
+	var users int64
+	db.Model(&database.MedhaUser{}).Count(&users)
+	assert.Equal(t, int64(1), users)
+}
+
+// TestOIDC_ClaimMapping verifies the username and email claims are configurable
+func TestOIDC_ClaimMapping(t *testing.T) {
+	ts, dbMgr, _ := setupOIDCServer(t, auth.OIDCConfig{
+		UsernameClaim: "login",
+		EmailClaim:    "work_email",
+	}, jwt.MapClaims{
+		"sub":                "00u2",
+		"preferred_username": "ignored",
+		"login":              "bob",
+		"work_email":         "bob@corp.example",
+	})
+
+	browser := newBrowser(t)
+	resp := oidcLogin(t, browser, browser, ts.URL)
+	require.Equal(t, http.StatusSeeOther, resp.StatusCode)
+
+	var user database.MedhaUser
+	require.NoError(t, dbMgr.SystemDB().Where("username = ?", "bob").First(&user).Error)
+	assert.Equal(t, "bob@corp.example", user.Email)
+}
+
+// TestOIDC_RejectsInvalidIDTokens verifies ID tokens are checked against the
+// issuer's keys and the login they answer
+func TestOIDC_RejectsInvalidIDTokens(t *testing.T) {
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name   string
+		tamper func(claims jwt.MapClaims)
+		signer *rsa.PrivateKey
+	}{
+		{name: "wrong audience", tamper: func(c jwt.MapClaims) { c["aud"] = "someone-else" }},
+		{name: "wrong issuer", tamper: func(c jwt.MapClaims) { c["iss"] = "https://evil.example" }},
+		{name: "wrong nonce", tamper: func(c jwt.MapClaims) { c["nonce"] = "replayed" }},
+		{name: "expired", tamper: func(c jwt.MapClaims) { c["exp"] = time.Now().Add(-time.Minute).Unix() }},
+		{name: "no expiry", tamper: func(c jwt.MapClaims) { delete(c, "exp") }},
+		{name: "untrusted key", signer: otherKey},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ts, dbMgr, issuer := setupOIDCServer(t, auth.OIDCConfig{}, jwt.MapClaims{
+				"sub":                "00u3",
+				"preferred_username": "mallory",
+			})
+			issuer.tamper = tt.tamper
+			issuer.signer = tt.signer
+
+			browser := newBrowser(t)
+			resp := oidcLogin(t, browser, browser, ts.URL)
+			assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+
+			var users int64
+			dbMgr.SystemDB().Model(&database.MedhaUser{}).Count(&users)
+			assert.Equal(t, int64(0), users)
+		})
+	}
+}
+
+// TestOIDC_RejectsCallbackFromAnotherBrowser verifies a login can only be
+// completed by the browser that started it
+func TestOIDC_RejectsCallbackFromAnotherBrowser(t *testing.T) {
+	ts, dbMgr, _ := setupOIDCServer(t, auth.OIDCConfig{}, jwt.MapClaims{
+		"sub":                "00u4",
+		"preferred_username": "mallory",
+	})
+
+	resp := oidcLogin(t, newBrowser(t), newBrowser(t), ts.URL)
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+
+	var users int64
+	dbMgr.SystemDB().Model(&database.MedhaUser{}).Count(&users)
+	assert.Equal(t, int64(0), users)
+}
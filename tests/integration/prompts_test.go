@@ -0,0 +1,139 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package integration
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tejzpr/medha-mcp/internal/database"
+	"github.com/tejzpr/medha-mcp/internal/tools"
+)
+
+// promptText renders a prompt handler and returns its single message's text
+func promptText(t *testing.T, handler func(context.Context, mcp.GetPromptRequest) (*mcp.GetPromptResult, error), args map[string]string) string {
+	request := mcp.GetPromptRequest{}
+	request.Params.Arguments = args
+	result, err := handler(context.Background(), request)
+	require.NoError(t, err)
+	require.Len(t, result.Messages, 1)
+	assert.Equal(t, mcp.RoleUser, result.Messages[0].Role)
+	text, ok := result.Messages[0].Content.(mcp.TextContent)
+	require.True(t, ok)
+	return text.Text
+}
+
+// setupPromptMemories stores a few memories about caching and one about billing
+func setupPromptMemories(t *testing.T, setup *testSetup) {
+	for _, args := range []map[string]interface{}{
+		{"slug": "cache-redis", "title": "Redis Cache", "content": "We cache sessions in Redis with a 5 minute TTL", "tags": []interface{}{"caching"}},
+		{"slug": "cache-cdn", "title": "CDN Caching", "content": "Static assets are cached at the CDN edge", "tags": []interface{}{"caching"}},
+		{"slug": "billing-stripe", "title": "Billing Provider", "content": "Invoices are issued through Stripe"},
+	} {
+		rememberResource(t, setup, args)
+	}
+}
+
+func TestPrompts_Summarize(t *testing.T) {
+	setup := setupTestEnvironment(t)
+	defer setup.Cleanup()
+	setupPromptMemories(t, setup)
+
+	text := promptText(t, tools.SummarizePromptHandler(setup.ToolCtx, setup.User.ID), map[string]string{"topic": "caching"})
+	assert.Contains(t, text, `Summarize what I know about "caching"`)
+	assert.Contains(t, text, "cache-redis")
+	assert.Contains(t, text, "5 minute TTL")
+	assert.Contains(t, text, "## Timeline (medha_history)")
+	assert.NotContains(t, text, "billing-stripe")
+
+	request := mcp.GetPromptRequest{}
+	_, err := tools.SummarizePromptHandler(setup.ToolCtx, setup.User.ID)(context.Background(), request)
+	assert.Error(t, err, "topic is required")
+}
+
+func TestPrompts_RecordDecision(t *testing.T) {
+	setup := setupTestEnvironment(t)
+	defer setup.Cleanup()
+	setupPromptMemories(t, setup)
+
+	handler := tools.RecordDecisionPromptHandler(setup.ToolCtx, setup.User.ID)
+	text := promptText(t, handler, map[string]string{"title": "Move session caching to Redis Cluster", "context": "Single node is a SPOF"})
+	assert.Contains(t, text, `title: "ADR: Move session caching to Redis Cluster"`)
+	assert.Contains(t, text, "Single node is a SPOF")
+	assert.Contains(t, text, "cache-redis", "related memories are included")
+
+	text = promptText(t, handler, map[string]string{"title": "Adopt Stripe Billing"})
+	assert.Contains(t, text, "ask me for the context")
+}
+
+func TestPrompts_Standup(t *testing.T) {
+	setup := setupTestEnvironment(t)
+	defer setup.Cleanup()
+	setupPromptMemories(t, setup)
+
+	text := promptText(t, tools.StandupPromptHandler(setup.ToolCtx, setup.User.ID), nil)
+	assert.Contains(t, text, "since 1d")
+	assert.Contains(t, text, "**Done**")
+	assert.Contains(t, text, "billing-stripe")
+	assert.Contains(t, text, "cache-cdn")
+}
+
+func TestPrompts_ReviewStale(t *testing.T) {
+	setup := setupTestEnvironment(t)
+	defer setup.Cleanup()
+	setupPromptMemories(t, setup)
+
+	old := time.Now().AddDate(0, -6, 0)
+	require.NoError(t, setup.ToolCtx.UserDB.Model(&database.UserMemory{}).Where("slug = ?", "cache-cdn").
+		UpdateColumn("updated_at", old).Error)
+
+	handler := tools.ReviewStalePromptHandler(setup.ToolCtx, setup.User.ID)
+	text := promptText(t, handler, nil)
+	assert.Contains(t, text, "updated:<90d")
+	assert.Contains(t, text, "cache-cdn")
+	assert.NotContains(t, text, "cache-redis")
+	assert.NotContains(t, text, "billing-stripe")
+
+	for _, args := range []map[string]string{
+		{"older_than": "90d tag:x"},
+		{"older_than": "soon"},
+		{"limit": "-1"},
+	} {
+		request := mcp.GetPromptRequest{}
+		request.Params.Arguments = args
+		_, err := handler(context.Background(), request)
+		assert.Error(t, err, args)
+	}
+}
+
+func TestPrompts_ServedOverMCP(t *testing.T) {
+	setup := setupTestEnvironment(t)
+	defer setup.Cleanup()
+	setupPromptMemories(t, setup)
+
+	c := newStdioMCPClient(t, setup)
+
+	prompts, err := c.ListPrompts(context.Background(), mcp.ListPromptsRequest{})
+	require.NoError(t, err)
+	var names []string
+	for _, p := range prompts.Prompts {
+		names = append(names, p.Name)
+	}
+	assert.ElementsMatch(t, []string{"summarize", "record_decision", "standup", "review_stale"}, names)
+
+	request := mcp.GetPromptRequest{}
+	request.Params.Name = "summarize"
+	request.Params.Arguments = map[string]string{"topic": "billing"}
+	result, err := c.GetPrompt(context.Background(), request)
+	require.NoError(t, err)
+	require.Len(t, result.Messages, 1)
+	text, ok := result.Messages[0].Content.(mcp.TextContent)
+	require.True(t, ok)
+	assert.Contains(t, text.Text, "billing-stripe")
+}
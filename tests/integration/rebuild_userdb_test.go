@@ -13,8 +13,10 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"github.com/tejzpr/medha-mcp/internal/database"
+	"github.com/tejzpr/medha-mcp/internal/git"
 	"github.com/tejzpr/medha-mcp/internal/memory"
 	"github.com/tejzpr/medha-mcp/internal/rebuild"
+	"gorm.io/gorm"
 	"gorm.io/gorm/logger"
 )
 
@@ -354,6 +356,154 @@ func TestRebuildUserDB_FindByUsername(t *testing.T) {
 	assert.Equal(t, repoPath, foundRepo.RepoPath)
 }
 
+// TestRebuildUserDB_RegenerateAfterPull tests rebuilding the index git does
+// not carry from the memory files and the exported access stats
+func TestRebuildUserDB_RegenerateAfterPull(t *testing.T) {
+	repoPath := filepath.Join(t.TempDir(), "test-repo")
+	require.NoError(t, os.MkdirAll(repoPath, 0755))
+
+	mem := &memory.Memory{ID: "pulled", Title: "Pulled", Version: 4, Created: time.Now(), Updated: time.Now(), Content: "From another machine"}
+	markdown, err := mem.ToMarkdown()
+	require.NoError(t, err)
+	filePath := filepath.Join(repoPath, "pulled.md")
+	require.NoError(t, os.WriteFile(filePath, []byte(markdown), 0644))
+
+	accessed := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	stats := database.FormatAccessStats([]database.AccessStat{{Slug: "pulled", AccessCount: 6, LastAccessedAt: accessed}})
+	require.NoError(t, os.MkdirAll(filepath.Dir(database.GetAccessStatsPath(repoPath)), 0755))
+	require.NoError(t, os.WriteFile(database.GetAccessStatsPath(repoPath), stats, 0644))
+
+	userDB, err := database.OpenUserDB(repoPath)
+	require.NoError(t, err)
+	defer func() {
+		sqlDB, _ := userDB.DB()
+		sqlDB.Close()
+	}()
+	require.NoError(t, userDB.Create(&database.UserMemory{Slug: "stale", Title: "Stale", FilePath: "stale.md"}).Error)
+
+	result, err := rebuild.RegenerateUserIndex(userDB, repoPath)
+	require.NoError(t, err)
+	assert.Empty(t, result.Errors)
+
+	var memories []database.UserMemory
+	require.NoError(t, userDB.Find(&memories).Error)
+	require.Len(t, memories, 1)
+	assert.Equal(t, "pulled", memories[0].Slug)
+	assert.Equal(t, filePath, memories[0].FilePath)
+	assert.Equal(t, int64(4), memories[0].Version)
+	assert.Equal(t, 6, memories[0].AccessCount)
+	assert.True(t, accessed.Equal(memories[0].LastAccessedAt))
+}
+
+// TestRebuildUserDB_Incremental tests reindexing only what changed since the
+// last indexed commit
+func TestRebuildUserDB_Incremental(t *testing.T) {
+	repoPath := filepath.Join(t.TempDir(), "test-repo")
+	repo, err := git.InitRepository(repoPath)
+	require.NoError(t, err)
+	for _, slug := range []string{"kept", "edited", "archived", "deleted"} {
+		createTestMemoryFile(t, repoPath, slug, "Memory "+slug, []string{"before"})
+	}
+	require.NoError(t, repo.CommitAll("add memories"))
+
+	userDB, err := database.OpenUserDB(repoPath)
+	require.NoError(t, err)
+	defer func() {
+		sqlDB, _ := userDB.DB()
+		sqlDB.Close()
+	}()
+
+	// Without a recorded commit the index is built in full
+	result, err := rebuild.IncrementalRebuildUserIndex(userDB, repoPath)
+	require.NoError(t, err)
+	assert.Equal(t, 4, result.MemoriesCreated)
+	head, err := repo.HeadHash()
+	require.NoError(t, err)
+	last, err := database.GetLastIndexedCommit(userDB)
+	require.NoError(t, err)
+	assert.Equal(t, head.String(), last)
+
+	// Nothing committed since: nothing to do
+	result, err = rebuild.IncrementalRebuildUserIndex(userDB, repoPath)
+	require.NoError(t, err)
+	assert.Zero(t, result.MemoriesProcessed)
+
+	createTestMemoryFile(t, repoPath, "edited", "Edited Title", []string{"after"})
+	createTestMemoryFileWithAssociation(t, repoPath, "added", "Added", "kept")
+	require.NoError(t, os.MkdirAll(filepath.Join(repoPath, "archive"), 0755))
+	require.NoError(t, os.Rename(filepath.Join(repoPath, "archived.md"), filepath.Join(repoPath, "archive", "archived.md")))
+	require.NoError(t, os.Remove(filepath.Join(repoPath, "deleted.md")))
+	require.NoError(t, repo.CommitAll("change memories"))
+
+	result, err = rebuild.IncrementalRebuildUserIndex(userDB, repoPath)
+	require.NoError(t, err)
+	assert.Empty(t, result.Errors)
+	assert.Equal(t, 1, result.MemoriesCreated)
+	assert.Equal(t, 2, result.MemoriesUpdated)
+	assert.Equal(t, 1, result.MemoriesRemoved)
+	assert.Equal(t, 1, result.AssociationsCreated)
+
+	var edited database.UserMemory
+	require.NoError(t, userDB.Where("slug = ?", "edited").First(&edited).Error)
+	assert.Equal(t, "Edited Title", edited.Title)
+	var editedTags []string
+	userDB.Model(&database.UserMemoryTag{}).Where("memory_slug = ?", "edited").Pluck("tag_name", &editedTags)
+	assert.Equal(t, []string{"after"}, editedTags)
+
+	var archived database.UserMemory
+	require.NoError(t, userDB.Unscoped().Where("slug = ?", "archived").First(&archived).Error)
+	assert.True(t, archived.DeletedAt.Valid)
+	assert.Equal(t, filepath.Join(repoPath, "archive", "archived.md"), archived.FilePath)
+
+	var count int64
+	userDB.Unscoped().Model(&database.UserMemory{}).Where("slug = ?", "deleted").Count(&count)
+	assert.Zero(t, count)
+	userDB.Model(&database.UserMemory{}).Count(&count)
+	assert.Equal(t, int64(3), count, "kept, edited and added are live")
+
+	// A recorded commit that left the history falls back to a full rebuild
+	require.NoError(t, database.SetLastIndexedCommit(userDB, "0123456789abcdef0123456789abcdef01234567"))
+	result, err = rebuild.IncrementalRebuildUserIndex(userDB, repoPath)
+	require.NoError(t, err)
+	assert.Equal(t, 4, result.MemoriesCreated)
+}
+
+// TestRebuildUserDB_Watcher tests reindexing commits made outside Medha
+func TestRebuildUserDB_Watcher(t *testing.T) {
+	repoPath := filepath.Join(t.TempDir(), "test-repo")
+	repo, err := git.InitRepository(repoPath)
+	require.NoError(t, err)
+	createTestMemoryFile(t, repoPath, "first", "First", nil)
+	require.NoError(t, repo.CommitAll("add first"))
+
+	userDB, err := database.OpenUserDB(repoPath)
+	require.NoError(t, err)
+	defer func() {
+		sqlDB, _ := userDB.DB()
+		sqlDB.Close()
+	}()
+	_, err = rebuild.IncrementalRebuildUserIndex(userDB, repoPath)
+	require.NoError(t, err)
+
+	results := make(chan error, 10)
+	watcher := rebuild.NewWatcher(repoPath, func() (*gorm.DB, error) { return userDB, nil }, 50*time.Millisecond,
+		func(_ *rebuild.Result, err error) { results <- err })
+	require.NoError(t, watcher.Start())
+	defer watcher.Stop()
+
+	createTestMemoryFile(t, repoPath, "second", "Second", nil)
+	require.NoError(t, repo.CommitAll("add second"))
+
+	select {
+	case err := <-results:
+		require.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("watcher did not reindex the commit")
+	}
+	var second database.UserMemory
+	assert.NoError(t, userDB.Where("slug = ?", "second").First(&second).Error)
+}
+
 // Helper functions
 
 func createTestMemoryFile(t *testing.T, repoPath, slug, title string, tags []string) {
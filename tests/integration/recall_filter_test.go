@@ -0,0 +1,179 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package integration
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tejzpr/medha-mcp/internal/database"
+	"github.com/tejzpr/medha-mcp/internal/tools"
+)
+
+// setupFilterMemories stores memories with known tags, dates, access counts
+// and links for filter tests
+func setupFilterMemories(t *testing.T, setup *testSetup) {
+	rememberHandler := tools.RememberHandler(setup.ToolCtx, setup.User.ID)
+	memories := []map[string]interface{}{
+		{"slug": "project-x", "title": "Project X", "content": "The project"},
+		{"slug": "old-auth", "title": "Old Auth", "content": "Basic auth", "tags": []interface{}{"auth"}},
+		{"slug": "auth-design", "title": "Auth Design", "content": "OAuth2 everywhere", "tags": []interface{}{"auth", "decision"}, "replaces": "old-auth"},
+		{"slug": "auth-draft", "title": "Auth Draft", "content": "Maybe passkeys", "tags": []interface{}{"auth", "draft"}},
+		{"slug": "billing", "title": "Billing", "content": "Stripe webhooks", "tags": []interface{}{"billing"}},
+	}
+	for _, args := range memories {
+		request := mcp.CallToolRequest{}
+		request.Params.Arguments = args
+		result, err := rememberHandler(context.Background(), request)
+		require.NoError(t, err)
+		require.False(t, result.IsError, getResultText(result))
+	}
+
+	connectHandler := tools.ConnectHandler(setup.ToolCtx, setup.User.ID)
+	for _, link := range [][3]string{
+		{"auth-design", "project-x", "part_of"},
+		{"auth-draft", "project-x", "part_of"},
+		{"billing", "auth-design", "references"},
+	} {
+		request := mcp.CallToolRequest{}
+		request.Params.Arguments = map[string]interface{}{"from": link[0], "to": link[1], "relationship": link[2]}
+		result, err := connectHandler(context.Background(), request)
+		require.NoError(t, err)
+		require.False(t, result.IsError, getResultText(result))
+	}
+
+	// Pin timestamps and access counts so range filters are deterministic
+	day := func(s string) time.Time {
+		d, err := time.Parse("2006-01-02", s)
+		require.NoError(t, err)
+		return d.Add(12 * time.Hour)
+	}
+	for slug, cols := range map[string]map[string]interface{}{
+		"project-x":   {"created_at": day("2025-06-01"), "updated_at": day("2025-06-01"), "access_count": 0},
+		"old-auth":    {"created_at": day("2025-11-01"), "updated_at": day("2025-12-01"), "access_count": 2},
+		"auth-design": {"created_at": day("2026-01-01"), "updated_at": day("2026-03-10"), "access_count": 9},
+		"auth-draft":  {"created_at": day("2026-02-01"), "updated_at": day("2026-02-01"), "access_count": 1},
+		"billing":     {"created_at": day("2026-01-01"), "updated_at": day("2026-01-01"), "access_count": 4},
+	} {
+		require.NoError(t, setup.ToolCtx.UserDB.Model(&database.UserMemory{}).
+			Where("slug = ?", slug).UpdateColumns(cols).Error)
+	}
+}
+
+// recallFilter runs medha_recall with a filter and returns result titles
+func recallFilter(t *testing.T, setup *testSetup, filter string) []string {
+	return recallTitles(recallText(t, setup, map[string]interface{}{"filter": filter, "limit": 50}))
+}
+
+func TestRecallFilter_Tags(t *testing.T) {
+	setup := setupTestEnvironment(t)
+	defer setup.Cleanup()
+	setupFilterMemories(t, setup)
+
+	assert.ElementsMatch(t, []string{"Auth Design", "Auth Draft"}, recallFilter(t, setup, "tag:auth"),
+		"superseded memories stay hidden")
+	assert.ElementsMatch(t, []string{"Auth Design"}, recallFilter(t, setup, "tag:AUTH -tag:draft"))
+	assert.ElementsMatch(t, []string{"Auth Draft", "Billing"}, recallFilter(t, setup, "tag:draft OR tag:billing"))
+	assert.ElementsMatch(t, []string{"Auth Design", "Auth Draft"}, recallFilter(t, setup, "tag:dec* OR tag:dra*"))
+}
+
+func TestRecallFilter_Dates(t *testing.T) {
+	setup := setupTestEnvironment(t)
+	defer setup.Cleanup()
+	setupFilterMemories(t, setup)
+
+	assert.ElementsMatch(t, []string{"Auth Design", "Auth Draft"}, recallFilter(t, setup, "updated:>2026-01-01"),
+		"> excludes the whole day")
+	assert.ElementsMatch(t, []string{"Auth Design", "Auth Draft", "Billing"}, recallFilter(t, setup, "updated:>=2026-01-01"))
+	assert.ElementsMatch(t, []string{"Auth Design", "Billing"}, recallFilter(t, setup, "created:2026-01-01"))
+	assert.ElementsMatch(t, []string{"Project X"}, recallFilter(t, setup, "created:<2026-01-01"))
+	assert.ElementsMatch(t, []string{"Auth Draft", "Billing"}, recallFilter(t, setup, "updated:2026-01-01..2026-02-01"))
+	assert.Empty(t, recallFilter(t, setup, "updated:>today"))
+	assert.Len(t, recallFilter(t, setup, "created:<=1d"), 4)
+}
+
+func TestRecallFilter_AccessesAndState(t *testing.T) {
+	setup := setupTestEnvironment(t)
+	defer setup.Cleanup()
+	setupFilterMemories(t, setup)
+
+	assert.ElementsMatch(t, []string{"Auth Design"}, recallFilter(t, setup, "accesses:>5"))
+	assert.ElementsMatch(t, []string{"Auth Draft", "Billing"}, recallFilter(t, setup, "accesses:1..4"))
+
+	// is:superseded brings superseded memories into scope
+	assert.ElementsMatch(t, []string{"Old Auth"}, recallFilter(t, setup, "is:superseded"))
+
+	forget := mcp.CallToolRequest{}
+	forget.Params.Arguments = map[string]interface{}{"slug": "billing"}
+	result, err := tools.ForgetHandler(setup.ToolCtx, setup.User.ID)(context.Background(), forget)
+	require.NoError(t, err)
+	require.False(t, result.IsError, getResultText(result))
+
+	assert.NotContains(t, recallFilter(t, setup, "accesses:>=0"), "Billing")
+	assert.ElementsMatch(t, []string{"Billing"}, recallFilter(t, setup, "is:archived"))
+}
+
+func TestRecallFilter_Associations(t *testing.T) {
+	setup := setupTestEnvironment(t)
+	defer setup.Cleanup()
+	setupFilterMemories(t, setup)
+
+	assert.ElementsMatch(t, []string{"Auth Design", "Auth Draft"}, recallFilter(t, setup, "rel:part_of->project-x"))
+	assert.ElementsMatch(t, []string{"Auth Design"}, recallFilter(t, setup, "rel:part_of->project-x -tag:draft"))
+	assert.ElementsMatch(t, []string{"Billing"}, recallFilter(t, setup, "rel:ref->auth-design"), "aliases are accepted")
+	assert.ElementsMatch(t, []string{"Project X"}, recallFilter(t, setup, "rel:part_of<-auth-design"))
+	assert.ElementsMatch(t, []string{"Project X", "Billing"}, recallFilter(t, setup, "rel:<-auth-design"),
+		"non-directional links are stored both ways")
+	assert.Empty(t, recallFilter(t, setup, "rel:follows->project-x"))
+}
+
+func TestRecallFilter_WithTopic(t *testing.T) {
+	setup := setupTestEnvironment(t)
+	defer setup.Cleanup()
+	setupFilterMemories(t, setup)
+
+	titles := recallTitles(recallText(t, setup, map[string]interface{}{
+		"topic":  "auth",
+		"filter": "(tag:draft OR accesses:>5) AND updated:>=2026-02-01",
+	}))
+	assert.ElementsMatch(t, []string{"Auth Design", "Auth Draft"}, titles)
+
+	titles = recallTitles(recallText(t, setup, map[string]interface{}{
+		"exact":  "OAuth2",
+		"filter": "tag:draft",
+	}))
+	assert.Empty(t, titles)
+}
+
+func TestRecallFilter_InvalidFilter(t *testing.T) {
+	setup := setupTestEnvironment(t)
+	defer setup.Cleanup()
+	setupFilterMemories(t, setup)
+
+	recallHandler := tools.RecallHandler(setup.ToolCtx, setup.User.ID)
+	for _, filter := range []string{
+		"auth",
+		"colour:red",
+		"updated:>last-tuesday",
+		"accesses:many",
+		"rel:part_of",
+		"rel:likes->project-x",
+		"is:pinned",
+		"(tag:auth",
+		"tag:auth OR",
+		`tag:"open`,
+	} {
+		request := mcp.CallToolRequest{}
+		request.Params.Arguments = map[string]interface{}{"filter": filter}
+		result, err := recallHandler(context.Background(), request)
+		require.NoError(t, err)
+		assert.True(t, result.IsError, filter)
+		assert.Contains(t, getResultText(result), "invalid filter", filter)
+	}
+}
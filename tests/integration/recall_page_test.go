@@ -0,0 +1,158 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package integration
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tejzpr/medha-mcp/internal/database"
+	"github.com/tejzpr/medha-mcp/internal/tools"
+)
+
+var (
+	recallSlugLine   = regexp.MustCompile("\\*\\*Slug\\*\\*: `([^`]+)`")
+	recallNextCursor = regexp.MustCompile("\\*\\*next_cursor\\*\\*: `([^`]+)`")
+)
+
+// setupPagedMemories stores count memories about paging. Every third one
+// shares an updated_at with its neighbour so ties must be broken stably.
+func setupPagedMemories(t *testing.T, setup *testSetup, count int) []string {
+	rememberHandler := tools.RememberHandler(setup.ToolCtx, setup.User.ID)
+	base := time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)
+
+	slugs := make([]string, count)
+	for i := 0; i < count; i++ {
+		slugs[i] = fmt.Sprintf("page-note-%02d", i)
+		request := mcp.CallToolRequest{}
+		request.Params.Arguments = map[string]interface{}{
+			"slug":    slugs[i],
+			"title":   fmt.Sprintf("Paging note %c%02d", 'A'+rune(count-i)%26, i),
+			"content": fmt.Sprintf("Paging note number %d", i),
+		}
+		result, err := rememberHandler(context.Background(), request)
+		require.NoError(t, err)
+		require.False(t, result.IsError, getResultText(result))
+
+		updated := base.Add(time.Duration(i-i%3) * time.Hour)
+		require.NoError(t, setup.ToolCtx.UserDB.Model(&database.UserMemory{}).Where("slug = ?", slugs[i]).
+			UpdateColumns(map[string]interface{}{"updated_at": updated, "created_at": base.Add(-time.Duration(i) * time.Minute)}).Error)
+	}
+	return slugs
+}
+
+// recallPages follows next_cursor until the last page, returning each page's slugs
+func recallPages(t *testing.T, setup *testSetup, args map[string]interface{}) [][]string {
+	var pages [][]string
+	for len(pages) < 100 {
+		text := recallText(t, setup, args)
+
+		var slugs []string
+		for _, m := range recallSlugLine.FindAllStringSubmatch(text, -1) {
+			slugs = append(slugs, m[1])
+		}
+		pages = append(pages, slugs)
+
+		m := recallNextCursor.FindStringSubmatch(text)
+		if m == nil {
+			return pages
+		}
+		args["cursor"] = m[1]
+	}
+	t.Fatal("pagination did not terminate")
+	return nil
+}
+
+// flatten concatenates pages
+func flatten(pages [][]string) []string {
+	var all []string
+	for _, p := range pages {
+		all = append(all, p...)
+	}
+	return all
+}
+
+func TestRecallPaging_ListAll(t *testing.T) {
+	setup := setupTestEnvironment(t)
+	defer setup.Cleanup()
+	slugs := setupPagedMemories(t, setup, 25)
+
+	pages := recallPages(t, setup, map[string]interface{}{"list_all": true, "limit": 10})
+	require.Len(t, pages, 3)
+	assert.Len(t, pages[0], 10)
+	assert.Len(t, pages[2], 5)
+
+	// Most recently updated first, ties by slug
+	all := flatten(pages)
+	assert.ElementsMatch(t, slugs, all)
+	assert.Equal(t, []string{"page-note-24", "page-note-21", "page-note-22", "page-note-23"}, all[:4])
+}
+
+func TestRecallPaging_Orders(t *testing.T) {
+	setup := setupTestEnvironment(t)
+	defer setup.Cleanup()
+	slugs := setupPagedMemories(t, setup, 12)
+
+	created := flatten(recallPages(t, setup, map[string]interface{}{"list_all": true, "limit": 5, "order": "created"}))
+	assert.Equal(t, slugs, created, "created_at decreases with the index")
+
+	titles := flatten(recallPages(t, setup, map[string]interface{}{"list_all": true, "limit": 5, "order": "title"}))
+	reversed := append([]string(nil), slugs...)
+	sort.Sort(sort.Reverse(sort.StringSlice(reversed)))
+	assert.Equal(t, reversed, titles, "titles sort in reverse index order")
+
+	// Listing updates access times; pages must not repeat or skip memories
+	accessed := recallPages(t, setup, map[string]interface{}{"list_all": true, "limit": 5, "order": "accessed"})
+	assert.ElementsMatch(t, slugs, flatten(accessed))
+	assert.Len(t, accessed, 3)
+}
+
+func TestRecallPaging_TopicSearch(t *testing.T) {
+	setup := setupTestEnvironment(t)
+	defer setup.Cleanup()
+	slugs := setupPagedMemories(t, setup, 15)
+
+	unpaged := recallPages(t, setup, map[string]interface{}{"topic": "paging note", "limit": 50})
+	require.Len(t, unpaged, 1)
+
+	pages := recallPages(t, setup, map[string]interface{}{"topic": "paging note", "limit": 4})
+	assert.Len(t, pages, 4)
+	assert.Equal(t, unpaged[0], flatten(pages), "pages follow relevance order")
+	assert.ElementsMatch(t, slugs, flatten(pages))
+
+	filtered := recallPages(t, setup, map[string]interface{}{"filter": "updated:>=2026-03-01", "limit": 6, "order": "title"})
+	assert.Len(t, filtered, 3)
+	assert.ElementsMatch(t, slugs, flatten(filtered))
+}
+
+func TestRecallPaging_InvalidCursor(t *testing.T) {
+	setup := setupTestEnvironment(t)
+	defer setup.Cleanup()
+	setupPagedMemories(t, setup, 5)
+
+	text := recallText(t, setup, map[string]interface{}{"list_all": true, "limit": 2})
+	m := recallNextCursor.FindStringSubmatch(text)
+	require.NotNil(t, m)
+
+	recallHandler := tools.RecallHandler(setup.ToolCtx, setup.User.ID)
+	for name, args := range map[string]map[string]interface{}{
+		"other query": {"list_all": true, "limit": 2, "order": "title", "cursor": m[1]},
+		"malformed":   {"list_all": true, "limit": 2, "cursor": "not-a-cursor"},
+		"bad order":   {"list_all": true, "order": "random"},
+	} {
+		request := mcp.CallToolRequest{}
+		request.Params.Arguments = args
+		result, err := recallHandler(context.Background(), request)
+		require.NoError(t, err)
+		assert.True(t, result.IsError, name)
+	}
+}
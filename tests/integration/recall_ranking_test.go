@@ -0,0 +1,153 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package integration
+
+import (
+	"context"
+	"regexp"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tejzpr/medha-mcp/internal/database"
+	"github.com/tejzpr/medha-mcp/internal/ranking"
+	"github.com/tejzpr/medha-mcp/internal/tools"
+	"gorm.io/gorm"
+)
+
+// recallTitleLine matches the numbered heading of each recall result
+var recallTitleLine = regexp.MustCompile(`(?m)^## \d+\. (.+)$`)
+
+// recallTitles returns result titles in ranked order
+func recallTitles(text string) []string {
+	var titles []string
+	for _, m := range recallTitleLine.FindAllStringSubmatch(text, -1) {
+		titles = append(titles, m[1])
+	}
+	return titles
+}
+
+// setupRankingMemories stores memories that each match "caching" differently
+func setupRankingMemories(t *testing.T, setup *testSetup) {
+	rememberHandler := tools.RememberHandler(setup.ToolCtx, setup.User.ID)
+	memories := []map[string]interface{}{
+		{"slug": "caching-strategy", "title": "Caching Strategy", "content": "Redis in front of the caching layer", "tags": []interface{}{"caching"}},
+		{"slug": "meeting-notes", "title": "Meeting Notes", "content": "Brief mention of caching near the end"},
+		{"slug": "perf-labels", "title": "Performance", "content": "Latency budgets", "tags": []interface{}{"caching"}},
+		{"slug": "redis-ops", "title": "Redis Operations", "content": "Runbook for the cluster"},
+		{"slug": "lunch", "title": "Lunch Options", "content": "Tacos on Tuesday"},
+	}
+	for _, args := range memories {
+		request := mcp.CallToolRequest{}
+		request.Params.Arguments = args
+		result, err := rememberHandler(context.Background(), request)
+		require.NoError(t, err)
+		require.False(t, result.IsError, getResultText(result))
+	}
+
+	request := mcp.CallToolRequest{}
+	request.Params.Arguments = map[string]interface{}{
+		"from":         "caching-strategy",
+		"to":           "redis-ops",
+		"relationship": "references",
+	}
+	result, err := tools.ConnectHandler(setup.ToolCtx, setup.User.ID)(context.Background(), request)
+	require.NoError(t, err)
+	require.False(t, result.IsError, getResultText(result))
+}
+
+// recallText runs medha_recall and returns its text output
+func recallText(t *testing.T, setup *testSetup, args map[string]interface{}) string {
+	request := mcp.CallToolRequest{}
+	request.Params.Arguments = args
+	result, err := tools.RecallHandler(setup.ToolCtx, setup.User.ID)(context.Background(), request)
+	require.NoError(t, err)
+	require.False(t, result.IsError, getResultText(result))
+	return getResultText(result)
+}
+
+func TestRecallRanking_FusesSignals(t *testing.T) {
+	setup := setupTestEnvironment(t)
+	defer setup.Cleanup()
+	setupRankingMemories(t, setup)
+
+	text := recallText(t, setup, map[string]interface{}{"topic": "caching"})
+	titles := recallTitles(text)
+
+	require.NotEmpty(t, titles)
+	assert.Equal(t, "Caching Strategy", titles[0], "keyword and tag agreement ranks first")
+	assert.Contains(t, titles, "Meeting Notes")
+	assert.Contains(t, titles, "Performance")
+	assert.Contains(t, titles, "Redis Operations", "linked from the top match")
+	assert.NotContains(t, titles, "Lunch Options")
+
+	assert.Contains(t, text, "**Match**: keyword+tag")
+	assert.Contains(t, text, "**Match**: association")
+	assert.NotContains(t, text, "**Rank**:", "explanations are opt-in")
+}
+
+func TestRecallRanking_Explain(t *testing.T) {
+	setup := setupTestEnvironment(t)
+	defer setup.Cleanup()
+	setupRankingMemories(t, setup)
+
+	text := recallText(t, setup, map[string]interface{}{"topic": "caching", "explain": true})
+
+	assert.Contains(t, text, "**Rank**: keyword #1")
+	assert.Contains(t, text, "tag #")
+	assert.Contains(t, text, "association #1")
+}
+
+func TestRecallRanking_ConfigurableWeights(t *testing.T) {
+	setup := setupTestEnvironment(t)
+	defer setup.Cleanup()
+	setupRankingMemories(t, setup)
+
+	// Favour tags heavily and switch off associations
+	weights := ranking.DefaultWeights()
+	weights.Keyword = 0.1
+	weights.Tag = 5
+	weights.Association = 0
+	setup.ToolCtx.RankingWeights = weights
+
+	titles := recallTitles(recallText(t, setup, map[string]interface{}{"topic": "caching"}))
+
+	require.Len(t, titles, 3)
+	assert.Equal(t, "Meeting Notes", titles[2], "the only untagged match ranks last")
+	assert.NotContains(t, titles, "Redis Operations")
+}
+
+func TestRecallRanking_LoadsOnlyMatchedMemories(t *testing.T) {
+	setup := setupTestEnvironment(t)
+	defer setup.Cleanup()
+	setupRankingMemories(t, setup)
+
+	// Count the memory rows each query reads
+	loaded := 0
+	require.NoError(t, setup.ToolCtx.UserDB.Callback().Query().After("gorm:query").Register("test:count_memories", func(db *gorm.DB) {
+		if db.Statement.Table == "memories" {
+			loaded += int(db.Statement.RowsAffected)
+		}
+	}))
+
+	titles := recallTitles(recallText(t, setup, map[string]interface{}{"topic": "tacos"}))
+	assert.Equal(t, []string{"Lunch Options"}, titles)
+	assert.Equal(t, 1, loaded, "only the matched memory is loaded")
+}
+
+func TestRecallRanking_ReportsDatabaseErrors(t *testing.T) {
+	setup := setupTestEnvironment(t)
+	defer setup.Cleanup()
+	setupRankingMemories(t, setup)
+	require.NoError(t, setup.ToolCtx.UserDB.Migrator().DropTable(&database.UserMemoryTag{}))
+
+	request := mcp.CallToolRequest{}
+	request.Params.Arguments = map[string]interface{}{"topic": "caching"}
+	result, err := tools.RecallHandler(setup.ToolCtx, setup.User.ID)(context.Background(), request)
+	require.NoError(t, err)
+	assert.True(t, result.IsError)
+	assert.Contains(t, getResultText(result), "search failed")
+}
@@ -0,0 +1,287 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package integration
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mark3labs/mcp-go/client"
+	"github.com/mark3labs/mcp-go/client/transport"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tejzpr/medha-mcp/internal/config"
+	"github.com/tejzpr/medha-mcp/internal/server"
+	"github.com/tejzpr/medha-mcp/internal/tools"
+)
+
+// newStdioMCPClient serves the test user's tools over in-memory stdio pipes
+// and returns an initialized client
+func newStdioMCPClient(t *testing.T, setup *testSetup) *client.Client {
+	cfg := &config.Config{Security: config.SecurityConfig{TokenTTL: 24}}
+	mcpServer, err := server.NewMCPServer(cfg, setup.DBMgr, make([]byte, 32))
+	require.NoError(t, err)
+	require.NoError(t, mcpServer.RegisterToolsForUser(setup.User.ID, setup.RepoPath))
+
+	clientIn, serverOut := io.Pipe()
+	serverIn, clientOut := io.Pipe()
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() { _ = mcpServer.ServeStdioWith(ctx, serverIn, serverOut) }()
+
+	c := client.NewClient(transport.NewIO(clientIn, clientOut, io.NopCloser(strings.NewReader(""))))
+	t.Cleanup(func() {
+		c.Close()
+		cancel()
+		serverOut.Close()
+	})
+	require.NoError(t, c.Start(ctx))
+
+	initReq := mcp.InitializeRequest{}
+	initReq.Params.ProtocolVersion = mcp.LATEST_PROTOCOL_VERSION
+	initReq.Params.ClientInfo = mcp.Implementation{Name: "medha-test", Version: "1.0.0"}
+	result, err := c.Initialize(ctx, initReq)
+	require.NoError(t, err)
+	require.NotNil(t, result.Capabilities.Resources)
+	assert.True(t, result.Capabilities.Resources.Subscribe)
+
+	return c
+}
+
+// rememberResource stores a memory through medha_remember
+func rememberResource(t *testing.T, setup *testSetup, args map[string]interface{}) {
+	var out tools.MemoryChangeOutput
+	callJSON(t, tools.RememberHandler(setup.ToolCtx, setup.User.ID), args, &out)
+}
+
+// resourceUpdates collects the URIs of resources/updated notifications
+func resourceUpdates(c *client.Client) <-chan string {
+	updates := make(chan string, 100)
+	c.OnNotification(func(n mcp.JSONRPCNotification) {
+		if n.Method == mcp.MethodNotificationResourceUpdated {
+			if uri, ok := n.Params.AdditionalFields["uri"].(string); ok {
+				updates <- uri
+			}
+		}
+	})
+	return updates
+}
+
+// awaitUpdates waits until every URI in want has been notified
+func awaitUpdates(t *testing.T, updates <-chan string, want ...string) {
+	pending := make(map[string]bool)
+	for _, uri := range want {
+		pending[uri] = true
+	}
+	timeout := time.After(5 * time.Second)
+	for len(pending) > 0 {
+		select {
+		case uri := <-updates:
+			delete(pending, uri)
+		case <-timeout:
+			t.Fatalf("no resources/updated notification for %v", pending)
+		}
+	}
+}
+
+// readResourceText reads a resource and returns its text
+func readResourceText(t *testing.T, c *client.Client, uri string) string {
+	req := mcp.ReadResourceRequest{}
+	req.Params.URI = uri
+	result, err := c.ReadResource(context.Background(), req)
+	require.NoError(t, err)
+	require.Len(t, result.Contents, 1)
+	text, ok := result.Contents[0].(mcp.TextResourceContents)
+	require.True(t, ok)
+	return text.Text
+}
+
+// listedResourceURIs returns the URIs from resources/list
+func listedResourceURIs(t *testing.T, c *client.Client) []string {
+	result, err := c.ListResources(context.Background(), mcp.ListResourcesRequest{})
+	require.NoError(t, err)
+	var uris []string
+	for _, r := range result.Resources {
+		uris = append(uris, r.URI)
+	}
+	return uris
+}
+
+func TestResources_ListAndRead(t *testing.T) {
+	setup := setupTestEnvironment(t)
+	defer setup.Cleanup()
+
+	rememberResource(t, setup, map[string]interface{}{
+		"slug": "res-cache", "title": "Cache Design", "content": "Use Redis for caching", "tags": []interface{}{"Caching"},
+	})
+	rememberResource(t, setup, map[string]interface{}{
+		"slug": "res-queue", "title": "Queue Design", "content": "Use NATS", "tags": []interface{}{"messaging"},
+	})
+
+	c := newStdioMCPClient(t, setup)
+
+	resources, err := c.ListResources(context.Background(), mcp.ListResourcesRequest{})
+	require.NoError(t, err)
+	require.Len(t, resources.Resources, 2)
+	assert.Equal(t, "medha://memory/res-cache", resources.Resources[0].URI)
+	assert.Equal(t, "Cache Design", resources.Resources[0].Name)
+	assert.Equal(t, "text/markdown", resources.Resources[0].MIMEType)
+
+	templates, err := c.ListResourceTemplates(context.Background(), mcp.ListResourceTemplatesRequest{})
+	require.NoError(t, err)
+	var patterns []string
+	for _, tmpl := range templates.ResourceTemplates {
+		patterns = append(patterns, tmpl.URITemplate.Raw())
+	}
+	assert.ElementsMatch(t, []string{"medha://memory/{slug}", "medha://folder/{+path}", "medha://tag/{tag}"}, patterns)
+
+	markdown := readResourceText(t, c, "medha://memory/res-cache")
+	assert.Contains(t, markdown, "Use Redis for caching")
+	assert.Contains(t, markdown, "id: res-cache")
+
+	var byTag tools.MemoryResourceList
+	require.NoError(t, json.Unmarshal([]byte(readResourceText(t, c, "medha://tag/caching")), &byTag))
+	require.Len(t, byTag.Memories, 1)
+	assert.Equal(t, "medha://memory/res-cache", byTag.Memories[0].URI)
+	assert.Equal(t, "Cache Design", byTag.Memories[0].Title)
+
+	var mem struct{ FilePath string }
+	require.NoError(t, setup.ToolCtx.UserDB.Table("memories").Select("file_path").Where("slug = ?", "res-queue").Scan(&mem).Error)
+	rel, err := filepath.Rel(setup.RepoPath, filepath.Dir(mem.FilePath))
+	require.NoError(t, err)
+	folder := strings.SplitN(filepath.ToSlash(rel), "/", 2)[0]
+
+	var byFolder tools.MemoryResourceList
+	require.NoError(t, json.Unmarshal([]byte(readResourceText(t, c, "medha://folder/"+filepath.ToSlash(rel))), &byFolder))
+	require.Len(t, byFolder.Memories, 1)
+	assert.Equal(t, "res-queue", byFolder.Memories[0].Slug)
+
+	require.NoError(t, json.Unmarshal([]byte(readResourceText(t, c, "medha://folder/"+folder)), &byFolder))
+	assert.NotEmpty(t, byFolder.Memories, "folders list memories in subfolders")
+
+	req := mcp.ReadResourceRequest{}
+	req.Params.URI = "medha://memory/missing"
+	_, err = c.ReadResource(context.Background(), req)
+	assert.Error(t, err)
+}
+
+func TestResources_SubscribeStdio(t *testing.T) {
+	setup := setupTestEnvironment(t)
+	defer setup.Cleanup()
+
+	rememberResource(t, setup, map[string]interface{}{
+		"slug": "res-pinned", "title": "Pinned", "content": "Version one", "tags": []interface{}{"pins"},
+	})
+
+	c := newStdioMCPClient(t, setup)
+	updates := resourceUpdates(c)
+
+	for _, uri := range []string{"medha://memory/res-pinned", "medha://tag/pins"} {
+		sub := mcp.SubscribeRequest{}
+		sub.Params.URI = uri
+		require.NoError(t, c.Subscribe(context.Background(), sub))
+	}
+
+	text, isErr := callHTTPTool(t, c, "medha_remember", map[string]interface{}{
+		"slug": "res-pinned", "title": "Pinned", "content": "Version two",
+	})
+	require.False(t, isErr, text)
+	awaitUpdates(t, updates, "medha://memory/res-pinned", "medha://tag/pins")
+	assert.Contains(t, readResourceText(t, c, "medha://memory/res-pinned"), "Version two")
+
+	// Archiving removes the memory from the resource list
+	text, isErr = callHTTPTool(t, c, "medha_forget", map[string]interface{}{"slug": "res-pinned"})
+	require.False(t, isErr, text)
+	awaitUpdates(t, updates, "medha://memory/res-pinned")
+	assert.NotContains(t, listedResourceURIs(t, c), "medha://memory/res-pinned")
+
+	text, isErr = callHTTPTool(t, c, "medha_restore", map[string]interface{}{"slug": "res-pinned"})
+	require.False(t, isErr, text)
+	awaitUpdates(t, updates, "medha://memory/res-pinned")
+	assert.Contains(t, listedResourceURIs(t, c), "medha://memory/res-pinned")
+
+	// New memories are listed as soon as they are created
+	text, isErr = callHTTPTool(t, c, "medha_remember", map[string]interface{}{
+		"slug": "res-new", "title": "New", "content": "Fresh", "tags": []interface{}{"pins"},
+	})
+	require.False(t, isErr, text)
+	awaitUpdates(t, updates, "medha://tag/pins")
+	assert.Contains(t, listedResourceURIs(t, c), "medha://memory/res-new")
+
+	unsub := mcp.UnsubscribeRequest{}
+	unsub.Params.URI = "medha://tag/pins"
+	require.NoError(t, c.Unsubscribe(context.Background(), unsub))
+
+	sub := mcp.SubscribeRequest{}
+	sub.Params.URI = "file:///etc/passwd"
+	assert.Error(t, c.Subscribe(context.Background(), sub))
+}
+
+func TestResources_SubscribeHTTP(t *testing.T) {
+	ts, dbMgr, tokenManager := setupHTTPServer(t)
+	alice := createHTTPTestUser(t, dbMgr, tokenManager, "alice")
+	bob := createHTTPTestUser(t, dbMgr, tokenManager, "bob")
+
+	writer := newHTTPMCPClient(t, ts.URL, alice)
+	text, isErr := callHTTPTool(t, writer, "medha_remember", map[string]interface{}{
+		"slug": "shared-name", "title": "Alice Notes", "content": "Alice version one",
+	})
+	require.False(t, isErr, text)
+
+	// An IDE session listening for notifications while another session writes
+	watcher := newListeningHTTPMCPClient(t, ts.URL, alice)
+	aliceUpdates := resourceUpdates(watcher)
+	bobWatcher := newListeningHTTPMCPClient(t, ts.URL, bob)
+	bobUpdates := resourceUpdates(bobWatcher)
+
+	sub := mcp.SubscribeRequest{}
+	sub.Params.URI = "medha://memory/shared-name"
+	require.NoError(t, watcher.Subscribe(context.Background(), sub))
+	require.NoError(t, bobWatcher.Subscribe(context.Background(), sub))
+
+	text, isErr = callHTTPTool(t, writer, "medha_remember", map[string]interface{}{
+		"slug": "shared-name", "title": "Alice Notes", "content": "Alice version two",
+	})
+	require.False(t, isErr, text)
+	awaitUpdates(t, aliceUpdates, "medha://memory/shared-name")
+	assert.Contains(t, readResourceText(t, watcher, "medha://memory/shared-name"), "Alice version two")
+
+	select {
+	case uri := <-bobUpdates:
+		t.Fatalf("bob was notified of alice's memory %s", uri)
+	case <-time.After(200 * time.Millisecond):
+	}
+
+	req := mcp.ReadResourceRequest{}
+	req.Params.URI = "medha://memory/shared-name"
+	_, err := bobWatcher.ReadResource(context.Background(), req)
+	assert.Error(t, err, "bob cannot read alice's memory")
+}
+
+// newListeningHTTPMCPClient connects a client that keeps a GET stream open for notifications
+func newListeningHTTPMCPClient(t *testing.T, baseURL string, u *httpTestUser) *client.Client {
+	c, err := client.NewStreamableHttpClient(baseURL+"/mcp",
+		transport.WithHTTPHeaders(map[string]string{"Authorization": "Bearer " + u.Token}),
+		transport.WithContinuousListening(),
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() { c.Close() })
+
+	ctx := context.Background()
+	require.NoError(t, c.Start(ctx))
+
+	initReq := mcp.InitializeRequest{}
+	initReq.Params.ProtocolVersion = mcp.LATEST_PROTOCOL_VERSION
+	initReq.Params.ClientInfo = mcp.Implementation{Name: "medha-ide", Version: "1.0.0"}
+	_, err = c.Initialize(ctx, initReq)
+	require.NoError(t, err)
+
+	return c
+}
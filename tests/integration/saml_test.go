@@ -0,0 +1,270 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package integration
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"encoding/xml"
+	"math/big"
+	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/crewjam/saml"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tejzpr/medha-mcp/internal/auth"
+	"github.com/tejzpr/medha-mcp/internal/config"
+	"github.com/tejzpr/medha-mcp/internal/database"
+	"github.com/tejzpr/medha-mcp/internal/server"
+	"gorm.io/gorm/logger"
+)
+
+// fakeIdP is an in-process SAML identity provider that signs in one user
+type fakeIdP struct {
+	idp     *saml.IdentityProvider
+	session *saml.Session
+	sp      *saml.EntityDescriptor
+}
+
+// GetServiceProvider returns the metadata of the server under test
+func (f *fakeIdP) GetServiceProvider(r *http.Request, serviceProviderID string) (*saml.EntityDescriptor, error) {
+	if f.sp == nil || f.sp.EntityID != serviceProviderID {
+		return nil, os.ErrNotExist
+	}
+	return f.sp, nil
+}
+
+// respond answers the AuthnRequest in an IdP redirect URL with a signed response form
+func (f *fakeIdP) respond(t *testing.T, location string) saml.IdpAuthnRequestForm {
+	req, err := saml.NewIdpAuthnRequest(f.idp, httptest.NewRequest(http.MethodGet, location, nil))
+	require.NoError(t, err)
+	require.NoError(t, req.Validate())
+	require.NoError(t, saml.DefaultAssertionMaker{}.MakeAssertion(req, f.session))
+	form, err := req.PostBinding()
+	require.NoError(t, err)
+	return form
+}
+
+// newTestKeyPair generates a PEM-encoded self-signed certificate and RSA key
+func newTestKeyPair(t *testing.T, commonName string) (*x509.Certificate, *rsa.PrivateKey, string, string) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+	return cert, key, string(certPEM), string(keyPEM)
+}
+
+// startIdentityTestServer starts an empty HTTP mode server for an identity
+// provider auth type; the caller registers routes once its authenticator,
+// which needs the server URL, exists
+func startIdentityTestServer(t *testing.T, authType string) (*httptest.Server, *http.ServeMux, *database.Manager, *server.MCPServer) {
+	tempDir := t.TempDir()
+	t.Setenv("HOME", tempDir) // repositories are provisioned under ~/.medha/store
+
+	dbMgr, err := database.NewManager(&database.Config{
+		Type:       "sqlite",
+		SQLitePath: filepath.Join(tempDir, "system.db"),
+		LogLevel:   logger.Silent,
+	})
+	require.NoError(t, err)
+	t.Cleanup(func() { dbMgr.Close() })
+	require.NoError(t, database.Migrate(dbMgr.SystemDB()))
+
+	cfg := &config.Config{
+		Auth:       config.AuthConfig{Type: authType},
+		Security:   config.SecurityConfig{TokenTTL: 24},
+		Embeddings: config.EmbeddingConfig{Enabled: false},
+	}
+	mcpServer, err := server.NewMCPServer(cfg, dbMgr, make([]byte, 32))
+	require.NoError(t, err)
+
+	mux := http.NewServeMux()
+	ts := httptest.NewServer(mux)
+	t.Cleanup(ts.Close)
+
+	return ts, mux, dbMgr, mcpServer
+}
+
+// setupSAMLServer starts an HTTP mode server with SAML auth against a fake IdP
+func setupSAMLServer(t *testing.T, samlCfg auth.SAMLConfig, session *saml.Session) (*httptest.Server, *database.Manager, *fakeIdP) {
+	ts, mux, dbMgr, mcpServer := startIdentityTestServer(t, "saml")
+
+	idpCert, idpKey, _, _ := newTestKeyPair(t, "idp.example.com")
+	idp := &fakeIdP{session: session}
+	idp.idp = &saml.IdentityProvider{
+		Key:                     idpKey,
+		Certificate:             idpCert,
+		MetadataURL:             url.URL{Scheme: "https", Host: "idp.example.com", Path: "/metadata"},
+		SSOURL:                  url.URL{Scheme: "https", Host: "idp.example.com", Path: "/sso"},
+		ServiceProviderProvider: idp,
+	}
+	idpMetadata, err := xml.Marshal(idp.idp.Metadata())
+	require.NoError(t, err)
+
+	_, _, spCert, spKey := newTestKeyPair(t, "medha.example.com")
+	samlCfg.EntityID = ts.URL
+	samlCfg.ACSURL = ts.URL + "/saml/acs"
+	samlCfg.MetadataURL = ts.URL + "/saml/metadata"
+	samlCfg.IDPMetadata = string(idpMetadata)
+	samlCfg.Certificate = spCert
+	samlCfg.PrivateKey = spKey
+	samlAuth, err := auth.NewSAMLAuthenticator(&samlCfg, mcpServer.GetTokenManager())
+	require.NoError(t, err)
+
+	spMetadata, err := samlAuth.GetMetadata()
+	require.NoError(t, err)
+	idp.sp = &saml.EntityDescriptor{}
+	require.NoError(t, xml.Unmarshal(spMetadata, idp.sp))
+
+	httpServer := server.NewHTTPServer(mcpServer, samlAuth, nil, nil, "saml", make([]byte, 32))
+	httpServer.RegisterRoutes(mux)
+
+	return ts, dbMgr, idp
+}
+
+// newBrowser returns a client that keeps cookies and does not follow redirects
+func newBrowser(t *testing.T) *http.Client {
+	jar, err := cookiejar.New(nil)
+	require.NoError(t, err)
+	return &http.Client{
+		Jar: jar,
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+// startSAMLLogin opens /saml/login and returns the IdP redirect location
+func startSAMLLogin(t *testing.T, browser *http.Client, baseURL string) string {
+	resp, err := browser.Get(baseURL + "/saml/login")
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusFound, resp.StatusCode)
+	return resp.Header.Get("Location")
+}
+
+// postSAMLResponse posts an IdP response form to the ACS
+func postSAMLResponse(t *testing.T, browser *http.Client, form saml.IdpAuthnRequestForm) *http.Response {
+	resp, err := browser.PostForm(form.URL, url.Values{
+		"SAMLResponse": {form.SAMLResponse},
+		"RelayState":   {form.RelayState},
+	})
+	require.NoError(t, err)
+	resp.Body.Close()
+	return resp
+}
+
+// TestSAML_LoginProvisionsUserAndIssuesToken verifies a SAML login creates the
+// user and repository and returns a token that works on /mcp
+func TestSAML_LoginProvisionsUserAndIssuesToken(t *testing.T) {
+	ts, dbMgr, idp := setupSAMLServer(t, auth.SAMLConfig{
+		UsernameAttribute: "login",
+		EmailAttribute:    "workEmail",
+	}, &saml.Session{
+		ID:     "session-1",
+		NameID: "alice.smith@corp.example",
+		CustomAttributes: []saml.Attribute{
+			{Name: "login", Values: []saml.AttributeValue{{Type: "xs:string", Value: "alice"}}},
+			{Name: "workEmail", Values: []saml.AttributeValue{{Type: "xs:string", Value: "alice@corp.example"}}},
+		},
+	})
+
+	resp, err := http.Get(ts.URL + "/saml/metadata")
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	// Local auth is not offered in SAML mode
+	resp, err = http.Post(ts.URL+"/auth/local", "application/x-www-form-urlencoded", bytes.NewReader(nil))
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+	browser := newBrowser(t)
+	form := idp.respond(t, startSAMLLogin(t, browser, ts.URL))
+	resp = postSAMLResponse(t, browser, form)
+	require.Equal(t, http.StatusSeeOther, resp.StatusCode)
+
+	redirect, err := url.Parse(resp.Header.Get("Location"))
+	require.NoError(t, err)
+	assert.Equal(t, "/auth", redirect.Path)
+	assert.Equal(t, "alice", redirect.Query().Get("username"))
+	token := redirect.Query().Get("token")
+	require.NotEmpty(t, token)
+
+	db := dbMgr.SystemDB()
+	var user database.MedhaUser
+	require.NoError(t, db.Where("username = ?", "alice").First(&user).Error)
+	assert.Equal(t, "alice@corp.example", user.Email)
+
+	var repo database.MedhaGitRepo
+	require.NoError(t, db.Where("user_id = ?", user.ID).First(&repo).Error)
+	assert.DirExists(t, filepath.Join(repo.RepoPath, ".git"))
+
+	c := newHTTPMCPClient(t, ts.URL, &httpTestUser{User: &user, Token: token})
+	text, isErr := callHTTPTool(t, c, "medha_remember", map[string]interface{}{
+		"title":   "SAML Memory",
+		"content": "Stored after a SAML login",
+		"slug":    "saml-memory",
+	})
+	require.False(t, isErr, text)
+
+	// Logging in again reuses the user and repository
+	browser = newBrowser(t)
+	form = idp.respond(t, startSAMLLogin(t, browser, ts.URL))
+	resp = postSAMLResponse(t, browser, form)
+	require.Equal(t, http.StatusSeeOther, resp.StatusCode)
+
+	var users, repos int64
+	db.Model(&database.MedhaUser{}).Count(&users)
+	db.Model(&database.MedhaGitRepo{}).Count(&repos)
+	assert.Equal(t, int64(1), users)
+	assert.Equal(t, int64(1), repos)
+
+	tools, err := c.ListTools(context.Background(), mcp.ListToolsRequest{})
+	require.NoError(t, err)
+	assert.NotEmpty(t, tools.Tools)
+}
+
+// TestSAML_RejectsUnsolicitedResponse verifies the ACS only accepts a response
+// to a login started in the same browser
+func TestSAML_RejectsUnsolicitedResponse(t *testing.T) {
+	ts, dbMgr, idp := setupSAMLServer(t, auth.SAMLConfig{}, &saml.Session{
+		ID:     "session-1",
+		NameID: "mallory",
+	})
+
+	form := idp.respond(t, startSAMLLogin(t, newBrowser(t), ts.URL))
+	resp := postSAMLResponse(t, newBrowser(t), form)
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+
+	var users int64
+	dbMgr.SystemDB().Model(&database.MedhaUser{}).Count(&users)
+	assert.Equal(t, int64(0), users)
+}
@@ -6,6 +6,9 @@ package integration
 
 import (
 	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
 	"testing"
@@ -68,6 +71,69 @@ func TestServerV2Context(t *testing.T) {
 	assert.False(t, mcpServer.HasEmbeddings())
 }
 
+// TestServerEmbeddings_OllamaProvider verifies a keyless Ollama provider is probed at startup
+func TestServerEmbeddings_OllamaProvider(t *testing.T) {
+	ollama := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/embed", r.URL.Path)
+		vector := make([]float32, 768)
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"embeddings": [][]float32{vector}})
+	}))
+	defer ollama.Close()
+
+	dbMgr, err := database.NewManager(&database.Config{
+		Type:       "sqlite",
+		SQLitePath: filepath.Join(t.TempDir(), "test.db"),
+		LogLevel:   logger.Silent,
+	})
+	require.NoError(t, err)
+	defer dbMgr.Close()
+	require.NoError(t, database.Migrate(dbMgr.SystemDB()))
+
+	cfg := &config.Config{
+		Security: config.SecurityConfig{TokenTTL: 24},
+		Embeddings: config.EmbeddingConfig{
+			Enabled:    true,
+			Provider:   config.EmbeddingProviderOllama,
+			BaseURL:    ollama.URL,
+			Model:      "nomic-embed-text",
+			APIKeyEnv:  "MEDHA_TEST_UNSET_KEY",
+			Dimensions: 1536, // Overridden by the probe
+		},
+	}
+
+	mcpServer, err := server.NewMCPServer(cfg, dbMgr, make([]byte, 32))
+	require.NoError(t, err)
+	assert.True(t, mcpServer.HasEmbeddings())
+}
+
+// TestServerEmbeddings_ProviderUnreachable verifies an offline provider disables embeddings without failing startup
+func TestServerEmbeddings_ProviderUnreachable(t *testing.T) {
+	ollama := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	ollama.Close()
+
+	dbMgr, err := database.NewManager(&database.Config{
+		Type:       "sqlite",
+		SQLitePath: filepath.Join(t.TempDir(), "test.db"),
+		LogLevel:   logger.Silent,
+	})
+	require.NoError(t, err)
+	defer dbMgr.Close()
+	require.NoError(t, database.Migrate(dbMgr.SystemDB()))
+
+	cfg := &config.Config{
+		Security: config.SecurityConfig{TokenTTL: 24},
+		Embeddings: config.EmbeddingConfig{
+			Enabled:  true,
+			Provider: config.EmbeddingProviderOllama,
+			BaseURL:  ollama.URL,
+		},
+	}
+
+	mcpServer, err := server.NewMCPServer(cfg, dbMgr, make([]byte, 32))
+	require.NoError(t, err)
+	assert.False(t, mcpServer.HasEmbeddings())
+}
+
 // TestToolContextWithUserDB verifies that ToolContext properly sets up UserDB
 func TestToolContextWithUserDB(t *testing.T) {
 	// Create temp directory for test
@@ -0,0 +1,250 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package integration
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/client"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tejzpr/medha-mcp/internal/spaces"
+	"github.com/tejzpr/medha-mcp/internal/tools"
+)
+
+// createSpace creates a team space over HTTP and adds members with roles
+func createSpace(t *testing.T, baseURL string, admin *httpTestUser, name string, roles map[string]string) {
+	resp := apiTokenRequest(t, http.MethodPost, baseURL+"/api/spaces", admin.Token, map[string]string{
+		"name":        name,
+		"description": "Architecture decisions",
+	})
+	require.Equal(t, http.StatusCreated, resp.StatusCode)
+
+	for username, role := range roles {
+		resp := apiTokenRequest(t, http.MethodPut, baseURL+"/api/spaces/"+name+"/members", admin.Token, map[string]string{
+			"username": username,
+			"role":     role,
+		})
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+	}
+}
+
+// recallJSON recalls through an MCP client and decodes the structured output
+func recallJSON(t *testing.T, c *client.Client, args map[string]interface{}) tools.RecallOutput {
+	args["format"] = "json"
+	text, isErr := callHTTPTool(t, c, "medha_recall", args)
+	require.False(t, isErr, text)
+
+	var out tools.RecallOutput
+	require.NoError(t, json.Unmarshal([]byte(text), &out))
+	return out
+}
+
+// TestSpaces_RolesControlAccess verifies writers share memories through a
+// space, readers can only read them and non-members can't see the space
+func TestSpaces_RolesControlAccess(t *testing.T) {
+	t.Setenv("HOME", t.TempDir()) // space repositories are created under ~/.medha/store
+	ts, dbMgr, tokenManager := setupHTTPServer(t)
+	alice := createHTTPTestUser(t, dbMgr, tokenManager, "alice")
+	bob := createHTTPTestUser(t, dbMgr, tokenManager, "bob")
+	carol := createHTTPTestUser(t, dbMgr, tokenManager, "carol")
+	dave := createHTTPTestUser(t, dbMgr, tokenManager, "dave")
+	createSpace(t, ts.URL, alice, "platform", map[string]string{"bob": "writer", "carol": "reader"})
+
+	bobClient := newHTTPMCPClient(t, ts.URL, bob)
+	text, isErr := callHTTPTool(t, bobClient, "medha_remember", map[string]interface{}{
+		"title":   "Use Postgres for Billing",
+		"content": "Billing moves to Postgres for transactional guarantees",
+		"slug":    "billing-database",
+		"space":   "platform",
+	})
+	require.False(t, isErr, text)
+
+	// Other members see it in the space, not in their own memories
+	aliceClient := newHTTPMCPClient(t, ts.URL, alice)
+	out := recallJSON(t, aliceClient, map[string]interface{}{"topic": "billing postgres", "space": "platform"})
+	require.Equal(t, 1, out.Count)
+	assert.Equal(t, "billing-database", out.Results[0].Slug)
+	assert.Equal(t, "platform", out.Results[0].Space)
+
+	out = recallJSON(t, aliceClient, map[string]interface{}{"list_all": true})
+	assert.Equal(t, 0, out.Count, "space memories stay out of personal recall")
+
+	// Readers read but can't write
+	carolClient := newHTTPMCPClient(t, ts.URL, carol)
+	text, isErr = callHTTPTool(t, carolClient, "medha_history", map[string]interface{}{"slug": "billing-database", "space": "platform"})
+	require.False(t, isErr, text)
+	text, isErr = callHTTPTool(t, carolClient, "medha_forget", map[string]interface{}{"slug": "billing-database", "space": "platform"})
+	assert.True(t, isErr)
+	assert.Contains(t, text, "needs writer")
+
+	// Non-members can't tell the space exists
+	daveClient := newHTTPMCPClient(t, ts.URL, dave)
+	text, isErr = callHTTPTool(t, daveClient, "medha_recall", map[string]interface{}{"list_all": true, "space": "platform"})
+	assert.True(t, isErr)
+	assert.Contains(t, text, "not found, or you are not a member")
+}
+
+// TestSpaces_RecallAll verifies space "all" searches personal memories and
+// every space together, labelling each result with its source
+func TestSpaces_RecallAll(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	ts, dbMgr, tokenManager := setupHTTPServer(t)
+	alice := createHTTPTestUser(t, dbMgr, tokenManager, "alice")
+	createSpace(t, ts.URL, alice, "platform", nil)
+	createSpace(t, ts.URL, alice, "frontend", nil)
+
+	aliceClient := newHTTPMCPClient(t, ts.URL, alice)
+	for _, m := range []struct{ space, slug string }{
+		{"", "caching-notes"},
+		{"platform", "caching-decision"},
+		{"frontend", "caching-decision"}, // Same slug in another space
+	} {
+		args := map[string]interface{}{
+			"title":   "Caching " + m.slug,
+			"content": "We cache API responses in Redis",
+			"slug":    m.slug,
+		}
+		if m.space != "" {
+			args["space"] = m.space
+		}
+		text, isErr := callHTTPTool(t, aliceClient, "medha_remember", args)
+		require.False(t, isErr, text)
+	}
+
+	out := recallJSON(t, aliceClient, map[string]interface{}{"topic": "caching redis", "space": spaces.All})
+	require.Equal(t, 3, out.Count)
+	sources := map[string]bool{}
+	for _, r := range out.Results {
+		sources[r.Space+"/"+r.Slug] = true
+	}
+	assert.Equal(t, map[string]bool{
+		"personal/caching-notes":    true,
+		"platform/caching-decision": true,
+		"frontend/caching-decision": true,
+	}, sources)
+
+	// Listings page across sources without skipping or repeating
+	seen := map[string]bool{}
+	cursor := ""
+	for page := 0; page < 5; page++ {
+		args := map[string]interface{}{"list_all": true, "space": spaces.All, "limit": 1, "order": "title"}
+		if cursor != "" {
+			args["cursor"] = cursor
+		}
+		out := recallJSON(t, aliceClient, args)
+		for _, r := range out.Results {
+			key := r.Space + "/" + r.Slug
+			assert.False(t, seen[key], "%s listed twice", key)
+			seen[key] = true
+		}
+		if cursor = out.NextCursor; cursor == "" {
+			break
+		}
+	}
+	assert.Equal(t, sources, seen)
+
+	text, isErr := callHTTPTool(t, aliceClient, "medha_remember", map[string]interface{}{
+		"title": "Nowhere", "content": "x", "space": spaces.All,
+	})
+	assert.True(t, isErr)
+	assert.Contains(t, text, "only supported by medha_recall")
+}
+
+// TestSpaces_MembershipAPI verifies members are managed by admins, may leave,
+// lose access at once when removed, and that API tokens can't manage spaces
+func TestSpaces_MembershipAPI(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	ts, dbMgr, tokenManager := setupHTTPServer(t)
+	alice := createHTTPTestUser(t, dbMgr, tokenManager, "alice")
+	bob := createHTTPTestUser(t, dbMgr, tokenManager, "bob")
+	createSpace(t, ts.URL, alice, "platform", map[string]string{"bob": "writer"})
+
+	resp := apiTokenRequest(t, http.MethodGet, ts.URL+"/api/spaces", bob.Token, nil)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	var memberships []spaces.Membership
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&memberships))
+	require.Len(t, memberships, 1)
+	assert.Equal(t, "platform", memberships[0].Name)
+	assert.Equal(t, "writer", memberships[0].Role)
+
+	// Writers can't manage members; names must be valid and unused
+	resp = apiTokenRequest(t, http.MethodPut, ts.URL+"/api/spaces/platform/members", bob.Token, map[string]string{"username": "bob", "role": "admin"})
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+	resp = apiTokenRequest(t, http.MethodPost, ts.URL+"/api/spaces", bob.Token, map[string]string{"name": "platform"})
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	resp = apiTokenRequest(t, http.MethodPost, ts.URL+"/api/spaces", bob.Token, map[string]string{"name": "all"})
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	// The last admin can't leave
+	resp = apiTokenRequest(t, http.MethodDelete, ts.URL+"/api/spaces/platform/members/alice", alice.Token, nil)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	// API tokens can use spaces through tools but not manage them
+	apiToken := createAPIToken(t, ts.URL, alice.Token, map[string]interface{}{"name": "bot", "scopes": []string{"memories:read"}})
+	resp = apiTokenRequest(t, http.MethodGet, ts.URL+"/api/spaces", apiToken.Token, nil)
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+
+	// Removal takes effect on the next tool call
+	bobClient := newHTTPMCPClient(t, ts.URL, bob)
+	text, isErr := callHTTPTool(t, bobClient, "medha_recall", map[string]interface{}{"list_all": true, "space": "platform"})
+	require.False(t, isErr, text)
+
+	resp = apiTokenRequest(t, http.MethodDelete, ts.URL+"/api/spaces/platform/members/bob", alice.Token, nil)
+	require.Equal(t, http.StatusNoContent, resp.StatusCode)
+
+	text, isErr = callHTTPTool(t, bobClient, "medha_recall", map[string]interface{}{"list_all": true, "space": "platform"})
+	assert.True(t, isErr)
+	assert.Contains(t, text, "not a member")
+
+	resp = apiTokenRequest(t, http.MethodGet, ts.URL+"/api/spaces/platform/members", bob.Token, nil)
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+}
+
+// TestSpaces_MembersWriteConcurrently verifies members writing to a space at
+// the same time go through one shared context without losing writes
+func TestSpaces_MembersWriteConcurrently(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	ts, dbMgr, tokenManager := setupHTTPServer(t)
+	alice := createHTTPTestUser(t, dbMgr, tokenManager, "alice")
+	bob := createHTTPTestUser(t, dbMgr, tokenManager, "bob")
+	createSpace(t, ts.URL, alice, "platform", map[string]string{"bob": "writer"})
+
+	clients := map[string]*client.Client{
+		"alice": newHTTPMCPClient(t, ts.URL, alice),
+		"bob":   newHTTPMCPClient(t, ts.URL, bob),
+	}
+	var wg sync.WaitGroup
+	for name, c := range clients {
+		for i := 0; i < 4; i++ {
+			wg.Add(1)
+			go func(name string, c *client.Client, i int) {
+				defer wg.Done()
+				request := mcp.CallToolRequest{}
+				request.Params.Name = "medha_remember"
+				request.Params.Arguments = map[string]interface{}{
+					"title":   "Note",
+					"content": fmt.Sprintf("Written by %s", name),
+					"slug":    fmt.Sprintf("%s-note-%d", name, i),
+					"space":   "platform",
+				}
+				result, err := c.CallTool(context.Background(), request)
+				if assert.NoError(t, err) {
+					assert.False(t, result.IsError, getResultText(result))
+				}
+			}(name, c, i)
+		}
+	}
+	wg.Wait()
+
+	out := recallJSON(t, clients["bob"], map[string]interface{}{"list_all": true, "space": "platform"})
+	assert.Equal(t, 8, out.Count)
+}
@@ -5,14 +5,21 @@
 package integration
 
 import (
+	"context"
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 
+	gogit "github.com/go-git/go-git/v5"
+	"github.com/mark3labs/mcp-go/mcp"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"github.com/tejzpr/medha-mcp/internal/crypto"
 	"github.com/tejzpr/medha-mcp/internal/database"
 	"github.com/tejzpr/medha-mcp/internal/git"
+	"github.com/tejzpr/medha-mcp/internal/memory"
+	"github.com/tejzpr/medha-mcp/internal/tools"
 )
 
 // Note: These tests require a local git setup without remote
@@ -223,3 +230,99 @@ func TestSync_JournalModeDelete(t *testing.T) {
 		assert.NotContains(t, entry.Name(), "-shm")
 	}
 }
+
+// writeRemoteMemory writes a memory file in a clone and commits it
+func writeRemoteMemory(t *testing.T, repo *git.Repository, relPath string, mem *memory.Memory) {
+	markdown, err := mem.ToMarkdown()
+	require.NoError(t, err)
+	path := filepath.Join(repo.Path, relPath)
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+	require.NoError(t, os.WriteFile(path, []byte(markdown), 0644))
+	require.NoError(t, repo.CommitAll("update "+mem.ID))
+}
+
+func TestSync_ReindexesPulledMemories(t *testing.T) {
+	setup := setupTestEnvironment(t)
+	defer setup.Cleanup()
+
+	// A memory written here, pushed to a remote another machine cloned
+	request := mcp.CallToolRequest{}
+	request.Params.Arguments = map[string]interface{}{"title": "Local Memory", "content": "Written here", "tags": []interface{}{"notes"}}
+	result, err := tools.RememberHandler(setup.ToolCtx, setup.User.ID)(context.Background(), request)
+	require.NoError(t, err)
+	require.False(t, result.IsError, getResultText(result))
+	var local database.UserMemory
+	require.NoError(t, setup.ToolCtx.UserDB.First(&local).Error)
+	localPath, err := filepath.Rel(setup.RepoPath, local.FilePath)
+	require.NoError(t, err)
+
+	remotePath := filepath.Join(t.TempDir(), "remote.git")
+	_, err = gogit.PlainInit(remotePath, true)
+	require.NoError(t, err)
+	repo, err := git.OpenRepository(setup.RepoPath)
+	require.NoError(t, err)
+	require.NoError(t, repo.AddRemote("origin", remotePath))
+	require.NoError(t, repo.Push("pat"))
+	other, err := git.Clone(remotePath, "pat", filepath.Join(t.TempDir(), "other"))
+	require.NoError(t, err)
+
+	key, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	setup.Repo.PATTokenEncrypted, err = crypto.EncryptPAT("pat", key)
+	require.NoError(t, err)
+	require.NoError(t, setup.DBMgr.SystemDB().Save(setup.Repo).Error)
+	sync := func() {
+		result, err := tools.SyncHandler(setup.ToolCtx, setup.User.ID, key)(context.Background(), mcp.CallToolRequest{})
+		require.NoError(t, err)
+		require.False(t, result.IsError, getResultText(result))
+	}
+
+	// The other machine adds a memory and retitles ours
+	now := time.Now()
+	writeRemoteMemory(t, other, "tags/pulled/pulled.md", &memory.Memory{
+		ID: "pulled", Title: "Pulled", Tags: []string{"pulled"}, Created: now, Updated: now, Content: "From the other machine",
+		Associations: []memory.Association{{Target: local.Slug, Type: memory.AssociationTypeRelatedTo, Strength: 0.5}},
+	})
+	localMem, err := memory.ParseMarkdown(readRepoFile(t, other.Path, localPath))
+	require.NoError(t, err)
+	localMem.Title = "Retitled Elsewhere"
+	writeRemoteMemory(t, other, localPath, localMem)
+	require.NoError(t, other.Push("pat"))
+
+	sync()
+	pulled, err := setup.ToolCtx.GetUserMemoryBySlug("pulled")
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(setup.RepoPath, "tags/pulled/pulled.md"), pulled.FilePath)
+	var tagged int64
+	setup.ToolCtx.UserDB.Model(&database.UserMemoryTag{}).Where("memory_slug = ? AND tag_name = ?", "pulled", "pulled").Count(&tagged)
+	assert.Equal(t, int64(1), tagged)
+	var associations int64
+	setup.ToolCtx.UserDB.Model(&database.UserMemoryAssociation{}).Where("source_slug = ? AND target_slug = ?", "pulled", local.Slug).Count(&associations)
+	assert.Equal(t, int64(1), associations)
+	retitled, err := setup.ToolCtx.GetUserMemoryBySlug(local.Slug)
+	require.NoError(t, err)
+	assert.Equal(t, "Retitled Elsewhere", retitled.Title)
+
+	// The other machine archives the pulled memory and deletes ours
+	require.NoError(t, other.Pull("pat"))
+	require.NoError(t, os.MkdirAll(filepath.Join(other.Path, "archive"), 0755))
+	require.NoError(t, os.Rename(filepath.Join(other.Path, "tags/pulled/pulled.md"), filepath.Join(other.Path, "archive/pulled.md")))
+	require.NoError(t, os.Remove(filepath.Join(other.Path, localPath)))
+	require.NoError(t, other.CommitAll("archive pulled, delete local"))
+	require.NoError(t, other.Push("pat"))
+
+	sync()
+	var archived database.UserMemory
+	require.NoError(t, setup.ToolCtx.UserDB.Unscoped().Where("slug = ?", "pulled").First(&archived).Error)
+	assert.True(t, archived.DeletedAt.Valid)
+	assert.Equal(t, filepath.Join(setup.RepoPath, "archive/pulled.md"), archived.FilePath)
+	var remaining int64
+	setup.ToolCtx.UserDB.Unscoped().Model(&database.UserMemory{}).Where("slug = ?", local.Slug).Count(&remaining)
+	assert.Zero(t, remaining)
+}
+
+func readRepoFile(t *testing.T, repoPath, relPath string) string {
+	content, err := os.ReadFile(filepath.Join(repoPath, relPath))
+	require.NoError(t, err)
+	return string(content)
+}